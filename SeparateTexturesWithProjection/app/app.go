@@ -0,0 +1,56 @@
+// Package app runs an Application with a fixed timestep game loop.
+//
+// Updates happen in fixed steps so simulation speed doesn't depend on the
+// frame rate. Rendering happens once per frame with alpha, the fraction
+// of a step the clock is past the last update, for interpolating between
+// the previous and current state.
+package app
+
+// Application is the lifecycle the Runner drives.
+type Application interface {
+	// Init is called once before the first frame, the context is current.
+	Init() error
+	// Update advances the simulation by dt seconds, always the fixed step.
+	Update(dt float64)
+	// Render draws a frame. alpha in [0, 1) is how far the clock is
+	// between the last update and the next.
+	Render(alpha float64)
+	// Resize is called when the framebuffer size changes, and once before
+	// the first frame.
+	Resize(width, height int)
+	// Shutdown is called once when the loop ends.
+	Shutdown()
+}
+
+// Host is what the Runner runs on: a display.Window or Headless.
+type Host interface {
+	ShouldClose() bool
+	PollEvents()
+	// WaitEvents blocks until an event arrives or timeout seconds pass.
+	WaitEvents(timeout float64)
+	SwapBuffers()
+	Focused() bool
+	FramebufferSize() (width, height int)
+}
+
+// Headless is a Host without a window for running frames in tests. It
+// never closes on its own, use Runner.RunFrames or Runner.Stop.
+type Headless struct {
+	Width, Height int
+	// Unfocused simulates the window losing focus
+	Unfocused bool
+}
+
+func NewHeadless(width, height int) *Headless {
+	o := new(Headless)
+	o.Width = width
+	o.Height = height
+	return o
+}
+
+func (h *Headless) ShouldClose() bool                    { return false }
+func (h *Headless) PollEvents()                          {}
+func (h *Headless) WaitEvents(timeout float64)           {}
+func (h *Headless) SwapBuffers()                         {}
+func (h *Headless) Focused() bool                        { return !h.Unfocused }
+func (h *Headless) FramebufferSize() (width, height int) { return h.Width, h.Height }
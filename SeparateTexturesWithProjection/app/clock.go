@@ -0,0 +1,41 @@
+package app
+
+import "time"
+
+// clock abstracts time so RunFrames is deterministic.
+type clock interface {
+	Now() time.Duration
+	Sleep(d time.Duration)
+}
+
+// realClock measures from when it was created.
+type realClock struct {
+	start time.Time
+}
+
+func newRealClock() *realClock {
+	return &realClock{start: time.Now()}
+}
+
+func (c *realClock) Now() time.Duration {
+	return time.Since(c.start)
+}
+
+func (c *realClock) Sleep(d time.Duration) {
+	time.Sleep(d)
+}
+
+// simulatedClock only moves when slept.
+type simulatedClock struct {
+	now time.Duration
+}
+
+func (c *simulatedClock) Now() time.Duration {
+	return c.now
+}
+
+func (c *simulatedClock) Sleep(d time.Duration) {
+	if d > 0 {
+		c.now += d
+	}
+}
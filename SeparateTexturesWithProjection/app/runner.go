@@ -0,0 +1,186 @@
+package app
+
+import (
+	"time"
+)
+
+const (
+	// DefaultStep is the fixed update step, 60 updates per second.
+	DefaultStep = time.Second / 60
+
+	// A long stall (a breakpoint, dragging the window) would otherwise be
+	// caught up with a burst of updates.
+	maxFrameTime = 250 * time.Millisecond
+)
+
+// Runner drives an Application on a Host.
+type Runner struct {
+	app  Application
+	host Host
+
+	// Step is the fixed update step.
+	Step time.Duration
+	// MaxFrameRate caps frames per second, 0 is uncapped (vsync may still
+	// limit it).
+	MaxFrameRate int
+	// MaxUpdatesPerFrame bounds catch-up updates when frames are slow so
+	// the loop can't spiral.
+	MaxUpdatesPerFrame int
+	// PauseOnFocusLoss stops updating and rendering while the window isn't
+	// focused.
+	PauseOnFocusLoss bool
+
+	clock   clock
+	stopped bool
+	paused  bool
+
+	width, height int
+	accumulator   time.Duration
+
+	frames, updates uint64
+}
+
+func NewRunner(app Application, host Host) *Runner {
+	o := new(Runner)
+	o.app = app
+	o.host = host
+	o.Step = DefaultStep
+	o.MaxUpdatesPerFrame = 5
+	o.PauseOnFocusLoss = true
+	return o
+}
+
+// Run initializes the application and loops until the host closes or
+// Stop is called, then shuts the application down.
+func (r *Runner) Run() error {
+	r.clock = newRealClock()
+	return r.run(-1)
+}
+
+// RunFrames runs exactly frames frames on a simulated clock, each lasting
+// one frame interval (1/MaxFrameRate, or Step when uncapped), so the
+// number of updates is deterministic. It's meant for tests, usually with
+// a Headless host.
+func (r *Runner) RunFrames(frames int) error {
+	r.clock = new(simulatedClock)
+	return r.run(frames)
+}
+
+// Stop ends the loop after the current frame.
+func (r *Runner) Stop() {
+	r.stopped = true
+}
+
+// Paused reports whether the loop is paused for lost focus.
+func (r *Runner) Paused() bool {
+	return r.paused
+}
+
+// Frames returns the number of frames rendered.
+func (r *Runner) Frames() uint64 {
+	return r.frames
+}
+
+// Updates returns the number of fixed updates run.
+func (r *Runner) Updates() uint64 {
+	return r.updates
+}
+
+func (r *Runner) run(frames int) error {
+	if err := r.app.Init(); err != nil {
+		return err
+	}
+	defer r.app.Shutdown()
+
+	r.stopped = false
+	r.width, r.height = r.host.FramebufferSize()
+	r.app.Resize(r.width, r.height)
+
+	last := r.clock.Now()
+	if _, simulated := r.clock.(*simulatedClock); simulated {
+		// Every simulated frame, the first included, covers one interval
+		last -= r.frameInterval()
+	}
+	for n := 0; frames < 0 || n < frames; n++ {
+		if r.stopped || r.host.ShouldClose() {
+			break
+		}
+
+		frameStart := r.clock.Now()
+
+		r.host.PollEvents()
+
+		if r.PauseOnFocusLoss && !r.host.Focused() {
+			r.paused = true
+			// Sleep in the event queue rather than spin
+			r.host.WaitEvents(0.1)
+			r.clock.Sleep(r.frameInterval())
+			// Don't catch up on the time spent paused
+			last = r.clock.Now()
+			continue
+		}
+		r.paused = false
+
+		if width, height := r.host.FramebufferSize(); width != r.width || height != r.height {
+			r.width, r.height = width, height
+			// Minimized windows report 0x0, there's nothing to draw into
+			if width > 0 && height > 0 {
+				r.app.Resize(width, height)
+			}
+		}
+
+		now := r.clock.Now()
+		frameTime := now - last
+		last = now
+		if frameTime > maxFrameTime {
+			frameTime = maxFrameTime
+		}
+
+		r.advance(frameTime)
+
+		r.app.Render(float64(r.accumulator) / float64(r.Step))
+		r.host.SwapBuffers()
+		r.frames++
+
+		r.limit(frameStart)
+	}
+
+	return nil
+}
+
+// advance runs as many fixed updates as the frame time covers.
+func (r *Runner) advance(frameTime time.Duration) {
+	r.accumulator += frameTime
+
+	updates := 0
+	for r.accumulator >= r.Step {
+		if r.MaxUpdatesPerFrame > 0 && updates >= r.MaxUpdatesPerFrame {
+			// Drop the backlog, the simulation runs slow instead
+			r.accumulator = 0
+			break
+		}
+		r.app.Update(r.Step.Seconds())
+		r.accumulator -= r.Step
+		r.updates++
+		updates++
+	}
+}
+
+// limit sleeps out the rest of the frame interval. Uncapped real frames
+// return straight away.
+func (r *Runner) limit(frameStart time.Duration) {
+	if _, simulated := r.clock.(*simulatedClock); !simulated && r.MaxFrameRate <= 0 {
+		return
+	}
+
+	if remaining := r.frameInterval() - (r.clock.Now() - frameStart); remaining > 0 {
+		r.clock.Sleep(remaining)
+	}
+}
+
+func (r *Runner) frameInterval() time.Duration {
+	if r.MaxFrameRate > 0 {
+		return time.Second / time.Duration(r.MaxFrameRate)
+	}
+	return r.Step
+}
@@ -0,0 +1,205 @@
+package app
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// testApp counts the calls the Runner makes.
+type testApp struct {
+	initErr error
+	// onRender is called from Render with the number of frames so far
+	onRender func(frame int)
+
+	inits, updates, renders, shutdowns int
+	alphas                             []float64
+	sizes                              [][2]int
+}
+
+func (a *testApp) Init() error {
+	a.inits++
+	return a.initErr
+}
+
+func (a *testApp) Update(dt float64) {
+	a.updates++
+}
+
+func (a *testApp) Render(alpha float64) {
+	a.renders++
+	a.alphas = append(a.alphas, alpha)
+	if a.onRender != nil {
+		a.onRender(a.renders)
+	}
+}
+
+func (a *testApp) Resize(width, height int) {
+	a.sizes = append(a.sizes, [2]int{width, height})
+}
+
+func (a *testApp) Shutdown() {
+	a.shutdowns++
+}
+
+func TestRunFrames(t *testing.T) {
+	tests := []struct {
+		name               string
+		step               time.Duration
+		maxFrameRate       int
+		maxUpdatesPerFrame int
+		frames             int
+		updates            int
+	}{
+		{name: "uncapped", frames: 10, updates: 10},
+		{name: "30 fps", maxFrameRate: 30, frames: 10, updates: 20},
+		{name: "60 fps", maxFrameRate: 60, frames: 10, updates: 10},
+		{name: "120 fps", maxFrameRate: 120, frames: 10, updates: 5},
+		{name: "20 ms step", step: 20 * time.Millisecond, maxFrameRate: 25, frames: 10, updates: 20},
+		{name: "catch up bounded", maxFrameRate: 10, frames: 4, updates: 20},
+		{name: "catch up unbounded", maxFrameRate: 10, maxUpdatesPerFrame: -1, frames: 4, updates: 24},
+		{name: "no frames", frames: 0, updates: 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			a := new(testApp)
+			r := NewRunner(a, NewHeadless(640, 480))
+			if test.step > 0 {
+				r.Step = test.step
+			}
+			r.MaxFrameRate = test.maxFrameRate
+			if test.maxUpdatesPerFrame != 0 {
+				r.MaxUpdatesPerFrame = test.maxUpdatesPerFrame
+			}
+
+			if err := r.RunFrames(test.frames); err != nil {
+				t.Fatal(err)
+			}
+
+			if a.updates != test.updates || r.Updates() != uint64(test.updates) {
+				t.Errorf("%d updates (%d counted), want %d", a.updates, r.Updates(), test.updates)
+			}
+			if a.renders != test.frames || r.Frames() != uint64(test.frames) {
+				t.Errorf("%d renders (%d counted), want %d", a.renders, r.Frames(), test.frames)
+			}
+			if a.inits != 1 || a.shutdowns != 1 {
+				t.Errorf("%d inits and %d shutdowns, want 1 and 1", a.inits, a.shutdowns)
+			}
+		})
+	}
+}
+
+func TestRunFramesAlpha(t *testing.T) {
+	a := new(testApp)
+	r := NewRunner(a, NewHeadless(640, 480))
+	r.Step = 20 * time.Millisecond
+	r.MaxFrameRate = 200
+
+	if err := r.RunFrames(4); err != nil {
+		t.Fatal(err)
+	}
+
+	// 5 ms frames are a quarter step each
+	want := []float64{0.25, 0.5, 0.75, 0}
+	for i := range want {
+		if a.alphas[i] != want[i] {
+			t.Errorf("alphas %v, want %v", a.alphas, want)
+			break
+		}
+	}
+}
+
+func TestRunFramesPaused(t *testing.T) {
+	a := new(testApp)
+	host := NewHeadless(640, 480)
+	host.Unfocused = true
+	r := NewRunner(a, host)
+
+	if err := r.RunFrames(10); err != nil {
+		t.Fatal(err)
+	}
+	if a.updates != 0 || a.renders != 0 || !r.Paused() {
+		t.Errorf("%d updates and %d renders while unfocused, paused %v", a.updates, a.renders, r.Paused())
+	}
+
+	// Time spent paused isn't caught up on
+	host.Unfocused = false
+	a = new(testApp)
+	r.app = a
+	if err := r.RunFrames(3); err != nil {
+		t.Fatal(err)
+	}
+	if a.updates != 3 || r.Paused() {
+		t.Errorf("%d updates after focus came back, want 3", a.updates)
+	}
+
+	r = NewRunner(new(testApp), host)
+	r.PauseOnFocusLoss = false
+	host.Unfocused = true
+	if err := r.RunFrames(3); err != nil {
+		t.Fatal(err)
+	}
+	if r.Frames() != 3 {
+		t.Errorf("%d frames without pausing, want 3", r.Frames())
+	}
+}
+
+func TestRunFramesResize(t *testing.T) {
+	host := NewHeadless(640, 480)
+	a := new(testApp)
+	a.onRender = func(frame int) {
+		switch frame {
+		case 1:
+			host.Width, host.Height = 800, 600
+		case 2:
+			// Minimized
+			host.Width, host.Height = 0, 0
+		case 3:
+			host.Width, host.Height = 800, 600
+		}
+	}
+
+	if err := NewRunner(a, host).RunFrames(5); err != nil {
+		t.Fatal(err)
+	}
+
+	want := [][2]int{{640, 480}, {800, 600}, {800, 600}}
+	if len(a.sizes) != len(want) {
+		t.Fatalf("resized to %v, want %v", a.sizes, want)
+	}
+	for i := range want {
+		if a.sizes[i] != want[i] {
+			t.Fatalf("resized to %v, want %v", a.sizes, want)
+		}
+	}
+}
+
+func TestRunFramesStop(t *testing.T) {
+	a := new(testApp)
+	r := NewRunner(a, NewHeadless(640, 480))
+	a.onRender = func(frame int) {
+		if frame == 3 {
+			r.Stop()
+		}
+	}
+
+	if err := r.RunFrames(10); err != nil {
+		t.Fatal(err)
+	}
+	if r.Frames() != 3 || a.shutdowns != 1 {
+		t.Errorf("%d frames and %d shutdowns after Stop, want 3 and 1", r.Frames(), a.shutdowns)
+	}
+}
+
+func TestRunFramesInitError(t *testing.T) {
+	a := &testApp{initErr: errors.New("no context")}
+	r := NewRunner(a, NewHeadless(640, 480))
+
+	if err := r.RunFrames(10); err != a.initErr {
+		t.Errorf("error %v, want %v", err, a.initErr)
+	}
+	if a.renders != 0 || a.shutdowns != 0 {
+		t.Errorf("%d renders and %d shutdowns after Init failed", a.renders, a.shutdowns)
+	}
+}
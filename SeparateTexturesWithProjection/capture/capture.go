@@ -0,0 +1,139 @@
+// Package capture saves frames as PNG files, single screenshots or
+// numbered sequences to turn into GIFs or videos, e.g. with
+//
+//	ffmpeg -i sequence-20240101-120000.000/frame-%05d.png ships.gif
+//
+// Encoding and writing happen on a goroutine, so the render loop only
+// pays for reading the pixels back, see render.ReadScreen.
+package capture
+
+import (
+	"fmt"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// queueSize is the number of frames waiting to be written before Frame
+// blocks. Sequences keep every frame rather than dropping any.
+const queueSize = 8
+
+type job struct {
+	path string
+	img  image.Image
+}
+
+// Capturer writes the frames it's given to a directory.
+type Capturer struct {
+	dir  string
+	jobs chan job
+	wg   sync.WaitGroup
+
+	mu  sync.Mutex
+	err error
+
+	// The directory of the sequence being recorded, "" if none
+	sequence string
+	frame    int
+}
+
+// NewCapturer creates a capturer writing to dir, which is created when
+// the first file is written. Close it to wait for pending writes.
+func NewCapturer(dir string) *Capturer {
+	o := new(Capturer)
+	o.dir = dir
+	o.jobs = make(chan job, queueSize)
+	o.wg.Add(1)
+	go o.write()
+	return o
+}
+
+// Screenshot saves img as screenshot-<time>.png and returns the path.
+// The image mustn't be changed afterwards.
+func (c *Capturer) Screenshot(img image.Image) string {
+	path := filepath.Join(c.dir, "screenshot-"+timestamp()+".png")
+	c.jobs <- job{path, img}
+	return path
+}
+
+// StartSequence starts recording the images passed to Frame into a new
+// sequence-<time> directory and returns it.
+func (c *Capturer) StartSequence() string {
+	c.sequence = filepath.Join(c.dir, "sequence-"+timestamp())
+	c.frame = 0
+	return c.sequence
+}
+
+// StopSequence stops recording and returns the number of frames.
+func (c *Capturer) StopSequence() int {
+	c.sequence = ""
+	return c.frame
+}
+
+// Recording reports whether a sequence is being recorded.
+func (c *Capturer) Recording() bool {
+	return c.sequence != ""
+}
+
+// Frame adds img to the sequence as frame-<n>.png, numbered from 1. It
+// does nothing when not recording.
+func (c *Capturer) Frame(img image.Image) {
+	if c.sequence == "" {
+		return
+	}
+	c.frame++
+	path := filepath.Join(c.sequence, fmt.Sprintf("frame-%05d.png", c.frame))
+	c.jobs <- job{path, img}
+}
+
+// Err returns the first write error, the writing goes on after one.
+func (c *Capturer) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.err
+}
+
+// Close waits for the pending frames to be written and returns Err. The
+// capturer can't be used afterwards.
+func (c *Capturer) Close() error {
+	close(c.jobs)
+	c.wg.Wait()
+	return c.Err()
+}
+
+func (c *Capturer) write() {
+	defer c.wg.Done()
+	for j := range c.jobs {
+		if err := save(j.path, j.img); err != nil {
+			c.mu.Lock()
+			if c.err == nil {
+				c.err = err
+			}
+			c.mu.Unlock()
+		}
+	}
+}
+
+func save(path string, img image.Image) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(f, img); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// timestamp names files in the order they were made.
+func timestamp() string {
+	return time.Now().Format("20060102-150405.000")
+}
@@ -0,0 +1,125 @@
+package capture
+
+import (
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+// testImage is a small image filled with c.
+func testImage(c color.NRGBA) image.Image {
+	img := image.NewNRGBA(image.Rect(0, 0, 4, 3))
+	for y := 0; y < 3; y++ {
+		for x := 0; x < 4; x++ {
+			img.SetNRGBA(x, y, c)
+		}
+	}
+	return img
+}
+
+// checkPNG decodes the PNG at path and checks it's a copy of want.
+func checkPNG(t *testing.T, path string, want color.NRGBA) {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	img, err := png.Decode(f)
+	if err != nil {
+		t.Fatalf("%s: %v", path, err)
+	}
+	if b := img.Bounds(); b.Dx() != 4 || b.Dy() != 3 {
+		t.Fatalf("%s is %dx%d, want 4x3", path, b.Dx(), b.Dy())
+	}
+	if got := color.NRGBAModel.Convert(img.At(1, 2)); got != want {
+		t.Errorf("%s has %v, want %v", path, got, want)
+	}
+}
+
+func TestCapturer(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "captures")
+	c := NewCapturer(dir)
+
+	red := color.NRGBA{255, 0, 0, 255}
+	shot := c.Screenshot(testImage(red))
+	if ok, _ := regexp.MatchString(`^screenshot-\d{8}-\d{6}\.\d{3}\.png$`, filepath.Base(shot)); !ok || filepath.Dir(shot) != dir {
+		t.Errorf("screenshot path %s", shot)
+	}
+
+	// Not recording yet, so it's dropped
+	c.Frame(testImage(red))
+
+	sequence := c.StartSequence()
+	if ok, _ := regexp.MatchString(`^sequence-\d{8}-\d{6}\.\d{3}$`, filepath.Base(sequence)); !ok || filepath.Dir(sequence) != dir {
+		t.Errorf("sequence path %s", sequence)
+	}
+	if !c.Recording() {
+		t.Error("not recording after StartSequence")
+	}
+	frames := []color.NRGBA{{0, 255, 0, 255}, {0, 0, 255, 255}, {10, 20, 30, 128}}
+	for _, f := range frames {
+		c.Frame(testImage(f))
+	}
+	if n := c.StopSequence(); n != len(frames) {
+		t.Errorf("StopSequence %d, want %d", n, len(frames))
+	}
+	if c.Recording() {
+		t.Error("recording after StopSequence")
+	}
+	c.Frame(testImage(red))
+	if n := c.StopSequence(); n != len(frames) {
+		t.Errorf("%d frames after stopping, want %d", n, len(frames))
+	}
+
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	checkPNG(t, shot, red)
+	names := []string{}
+	entries, err := os.ReadDir(sequence)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	if want := []string{"frame-00001.png", "frame-00002.png", "frame-00003.png"}; !reflect.DeepEqual(names, want) {
+		t.Fatalf("sequence has %v, want %v", names, want)
+	}
+	for i, f := range frames {
+		checkPNG(t, filepath.Join(sequence, names[i]), f)
+	}
+
+	// Only the screenshot and the sequence
+	if entries, _ := os.ReadDir(dir); len(entries) != 2 {
+		t.Errorf("%d entries in %s, want 2", len(entries), dir)
+	}
+}
+
+func TestCapturerWriteError(t *testing.T) {
+	// The directory can't be created where a file is
+	file := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(file, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	c := NewCapturer(file)
+	c.Screenshot(testImage(color.NRGBA{}))
+	c.StartSequence()
+	c.Frame(testImage(color.NRGBA{}))
+
+	err := c.Close()
+	if err == nil {
+		t.Fatal("no error writing under a file")
+	}
+	if c.Err() != err {
+		t.Errorf("Err %v, Close %v", c.Err(), err)
+	}
+}
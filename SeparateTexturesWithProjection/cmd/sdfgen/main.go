@@ -0,0 +1,275 @@
+// Command sdfgen bakes signed distance field atlases offline.
+//
+// Font mode writes a BMFont (.fnt + .png) whose glyphs are distance fields:
+//
+//	go run ./cmd/sdfgen -size 32 -out assets/fonts/go-regular-sdf
+//	go run ./cmd/sdfgen -font my.ttf -size 48 -spread 6 -out assets/fonts/my-sdf
+//
+// Atlas mode converts the sprites of a texture manifest into silhouettes
+// and writes a new image plus a manifest that points at it:
+//
+//	go run ./cmd/sdfgen -manifest assets/texture_manifest.txt -out assets/texture-sdf
+//
+// Shapes are rendered at -scale times the output size and the field is
+// filtered down, which gives much smoother edges than working at 1x.
+package main
+
+import (
+	"SimpleOpenGL-Go/SeparateTexturesWithProjection/textures"
+	"bufio"
+	"flag"
+	"fmt"
+	"image"
+	"image/png"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/image/draw"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
+)
+
+var (
+	fontPath     = flag.String("font", "", "TrueType/OpenType font, defaults to Go Regular")
+	manifestPath = flag.String("manifest", "", "texture manifest to convert instead of a font")
+	size         = flag.Float64("size", 32, "output font size in pixels")
+	spread       = flag.Int("spread", 4, "distance in output pixels covered by the field")
+	scale        = flag.Int("scale", 4, "supersampling factor")
+	atlasWidth   = flag.Int("width", 512, "font atlas width")
+	first        = flag.Int("first", 32, "first rune")
+	last         = flag.Int("last", 126, "last rune")
+	out          = flag.String("out", "", "output path without extension")
+)
+
+func main() {
+	flag.Parse()
+
+	if *out == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	var err error
+	if *manifestPath != "" {
+		err = convertManifest()
+	} else {
+		err = bakeFont()
+	}
+
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+type bakedGlyph struct {
+	r        rune
+	img      image.Image
+	x, y     int
+	xoffset  int
+	yoffset  int
+	xadvance int
+}
+
+func bakeFont() error {
+	data := goregular.TTF
+	if *fontPath != "" {
+		var err error
+		if data, err = os.ReadFile(*fontPath); err != nil {
+			return err
+		}
+	}
+
+	f, err := opentype.Parse(data)
+	if err != nil {
+		return err
+	}
+
+	// Hinting is disabled so both sizes have the same outlines.
+	face, err := opentype.NewFace(f, &opentype.FaceOptions{Size: *size, DPI: 72, Hinting: font.HintingNone})
+	if err != nil {
+		return err
+	}
+	hiFace, err := opentype.NewFace(f, &opentype.FaceOptions{Size: *size * float64(*scale), DPI: 72, Hinting: font.HintingNone})
+	if err != nil {
+		return err
+	}
+
+	metrics := face.Metrics()
+	ascent := metrics.Ascent.Ceil()
+	pad := *spread
+
+	glyphs := []*bakedGlyph{}
+	for r := rune(*first); r <= rune(*last); r++ {
+		bounds, advance, ok := face.GlyphBounds(r)
+		if !ok {
+			continue
+		}
+
+		g := &bakedGlyph{r: r, xadvance: advance.Round()}
+		glyphs = append(glyphs, g)
+
+		x0, y0 := bounds.Min.X.Floor(), bounds.Min.Y.Floor()
+		x1, y1 := bounds.Max.X.Ceil(), bounds.Max.Y.Ceil()
+		if x1 <= x0 || y1 <= y0 {
+			continue
+		}
+
+		// The padding makes room for the field outside the outline.
+		g.xoffset = x0 - pad
+		g.yoffset = ascent + y0 - pad
+		w := x1 - x0 + 2*pad
+		h := y1 - y0 + 2*pad
+
+		s := *scale
+		hi := image.NewNRGBA(image.Rect(0, 0, w*s, h*s))
+		dot := fixed.P((pad-x0)*s, (pad-y0)*s)
+		dr, mask, maskp, _, _ := hiFace.Glyph(dot, r)
+		draw.DrawMask(hi, dr, image.White, image.Point{}, mask, maskp, draw.Src)
+
+		g.img = downsample(textures.GenerateSDF(hi, pad*s), w, h)
+	}
+
+	// Shelf pack into a fixed width atlas, the height is rounded up to a
+	// power of two.
+	x, y, shelf := 1, 1, 0
+	for _, g := range glyphs {
+		if g.img == nil {
+			continue
+		}
+		b := g.img.Bounds()
+		if x+b.Dx()+1 > *atlasWidth {
+			x = 1
+			y += shelf + 1
+			shelf = 0
+		}
+		g.x, g.y = x, y
+		x += b.Dx() + 1
+		if b.Dy() > shelf {
+			shelf = b.Dy()
+		}
+	}
+
+	height := 1
+	for height < y+shelf+1 {
+		height *= 2
+	}
+
+	atlas := image.NewNRGBA(image.Rect(0, 0, *atlasWidth, height))
+	for _, g := range glyphs {
+		if g.img != nil {
+			b := g.img.Bounds()
+			draw.Draw(atlas, image.Rect(g.x, g.y, g.x+b.Dx(), g.y+b.Dy()), g.img, b.Min, draw.Src)
+		}
+	}
+
+	pngName := filepath.Base(*out) + ".png"
+	if err := writePNG(*out+".png", atlas); err != nil {
+		return err
+	}
+
+	fnt, err := os.Create(*out + ".fnt")
+	if err != nil {
+		return err
+	}
+	defer fnt.Close()
+
+	wr := bufio.NewWriter(fnt)
+	fmt.Fprintf(wr, "info face=\"%s\" size=%d bold=0 italic=0 charset=\"\" unicode=1 stretchH=100 smooth=1 aa=1 padding=%d,%d,%d,%d spacing=1,1\n",
+		faceName(f), int(*size), pad, pad, pad, pad)
+	fmt.Fprintf(wr, "common lineHeight=%d base=%d scaleW=%d scaleH=%d pages=1 packed=0\n",
+		metrics.Height.Ceil(), ascent, *atlasWidth, height)
+	fmt.Fprintf(wr, "page id=0 file=\"%s\"\n", pngName)
+	fmt.Fprintf(wr, "chars count=%d\n", len(glyphs))
+	for _, g := range glyphs {
+		w, h := 0, 0
+		if g.img != nil {
+			w, h = g.img.Bounds().Dx(), g.img.Bounds().Dy()
+		}
+		fmt.Fprintf(wr, "char id=%d x=%d y=%d width=%d height=%d xoffset=%d yoffset=%d xadvance=%d page=0 chnl=15\n",
+			g.r, g.x, g.y, w, h, g.xoffset, g.yoffset, g.xadvance)
+	}
+
+	kernings := []string{}
+	for _, a := range glyphs {
+		for _, b := range glyphs {
+			if k := face.Kern(a.r, b.r).Round(); k != 0 {
+				kernings = append(kernings, fmt.Sprintf("kerning first=%d second=%d amount=%d", a.r, b.r, k))
+			}
+		}
+	}
+	fmt.Fprintf(wr, "kernings count=%d\n", len(kernings))
+	for _, k := range kernings {
+		fmt.Fprintln(wr, k)
+	}
+
+	return wr.Flush()
+}
+
+// convertManifest turns every sprite of an atlas into a distance field.
+// The sprites keep their positions so the manifest's coords still apply.
+func convertManifest() error {
+	data, err := os.ReadFile(*manifestPath)
+	if err != nil {
+		return err
+	}
+
+	lines := strings.Split(strings.TrimRight(string(data), "\n"), "\n")
+	imagePath := filepath.Join(filepath.Dir(*manifestPath), lines[0])
+
+	file, err := os.Open(imagePath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	src, _, err := image.Decode(file)
+	if err != nil {
+		return err
+	}
+
+	b := src.Bounds()
+	s := *scale
+	hi := image.NewNRGBA(image.Rect(0, 0, b.Dx()*s, b.Dy()*s))
+	draw.NearestNeighbor.Scale(hi, hi.Bounds(), src, b, draw.Src, nil)
+
+	field := downsample(textures.GenerateSDF(hi, *spread*s), b.Dx(), b.Dy())
+	if err := writePNG(*out+".png", field); err != nil {
+		return err
+	}
+
+	lines[0] = filepath.Base(*out) + ".png"
+	return os.WriteFile(*out+"_manifest.txt", []byte(strings.Join(lines, "\n")), 0644)
+}
+
+func downsample(src image.Image, w, h int) *image.NRGBA {
+	dst := image.NewNRGBA(image.Rect(0, 0, w, h))
+	draw.BiLinear.Scale(dst, dst.Bounds(), src, src.Bounds(), draw.Src, nil)
+	return dst
+}
+
+func faceName(f *opentype.Font) string {
+	name, err := f.Name(nil, sfnt.NameIDFamily)
+	if err != nil {
+		return "unknown"
+	}
+	return name
+}
+
+func writePNG(name string, img image.Image) error {
+	file, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+
+	if err := png.Encode(file, img); err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
+}
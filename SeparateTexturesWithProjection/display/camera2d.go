@@ -0,0 +1,327 @@
+package display
+
+import (
+	"SimpleOpenGL-Go/SeparateTexturesWithProjection/api"
+	"SimpleOpenGL-Go/SeparateTexturesWithProjection/maths"
+	"math"
+	"math/rand"
+)
+
+// Camera2D is an orthographic camera looking at a point in world space.
+// The point is at the center of the viewport, world +y is up and a zoom
+// of 2 shows everything twice as large.
+//
+// Call Update once per frame to advance follow smoothing and shake, then
+// hand Projection and View to the renderers.
+type Camera2D struct {
+	x, y     float32
+	zoom     float32
+	rotation float64
+
+	minZoom, maxZoom float32
+
+	// Viewport size in pixels
+	width, height float32
+	near, far     float32
+
+	projection *Projection
+	view       api.IMatrix4
+	dirty      bool
+
+	following        bool
+	targetX, targetY float32
+	// followSpeed is how quickly the gap to the target closes, 0 snaps
+	followSpeed float32
+
+	bounded                bool
+	minX, minY, maxX, maxY float32
+
+	shakeMagnitude, shakeDuration, shakeRemaining float32
+	shakeX, shakeY                                float32
+	rnd                                           *rand.Rand
+}
+
+// NewCamera2D creates a camera for a viewport of width x height pixels
+// looking at the world origin.
+func NewCamera2D(width, height float32) *Camera2D {
+	c := new(Camera2D)
+	c.zoom = 1.0
+	c.minZoom = 0.1
+	c.maxZoom = 10.0
+	c.near = -1.0
+	c.far = 1.0
+	c.projection = NewCamera()
+	c.view = maths.NewMatrix4()
+	c.rnd = rand.New(rand.NewSource(1))
+	c.SetViewport(width, height)
+	return c
+}
+
+// SetViewport changes the viewport size, e.g. when the window resizes.
+// With a Scaler the size is in world units rather than pixels.
+func (c *Camera2D) SetViewport(width, height float32) {
+	c.width = width
+	c.height = height
+	c.projection.Width = c.width
+	c.projection.Height = c.height
+	c.projection.SetCenteredProjection(c.near, c.far)
+	c.clamp()
+	c.dirty = true
+}
+
+// ViewportSize returns the viewport size in pixels.
+func (c *Camera2D) ViewportSize() (width, height float32) {
+	return c.width, c.height
+}
+
+// Projection returns the orthographic projection for the viewport.
+func (c *Camera2D) Projection() *Projection {
+	return c.projection
+}
+
+// View returns the world to view matrix.
+func (c *Camera2D) View() api.IMatrix4 {
+	if c.dirty {
+		c.buildView()
+	}
+	return c.view
+}
+
+// SetPosition moves the camera to look at x,y.
+func (c *Camera2D) SetPosition(x, y float32) {
+	c.x = x
+	c.y = y
+	c.clamp()
+	c.dirty = true
+}
+
+// Position returns the world point the camera looks at.
+func (c *Camera2D) Position() (x, y float32) {
+	return c.x, c.y
+}
+
+// Move pans the camera by dx,dy world units.
+func (c *Camera2D) Move(dx, dy float32) {
+	c.SetPosition(c.x+dx, c.y+dy)
+}
+
+// Pan moves the camera by dx,dy screen pixels (+y down), the way a drag
+// would, taking zoom and rotation into account.
+func (c *Camera2D) Pan(dx, dy float32) {
+	wx, wy := c.viewToWorldDelta(dx, -dy)
+	c.Move(-wx, -wy)
+}
+
+// SetZoom sets the zoom, clamped to the zoom limits.
+func (c *Camera2D) SetZoom(zoom float32) {
+	if zoom < c.minZoom {
+		zoom = c.minZoom
+	}
+	if zoom > c.maxZoom {
+		zoom = c.maxZoom
+	}
+	c.zoom = zoom
+	c.clamp()
+	c.dirty = true
+}
+
+// Zoom returns the current zoom.
+func (c *Camera2D) Zoom() float32 {
+	return c.zoom
+}
+
+// SetZoomLimits sets the range SetZoom clamps to.
+func (c *Camera2D) SetZoomLimits(min, max float32) {
+	c.minZoom = min
+	c.maxZoom = max
+	c.SetZoom(c.zoom)
+}
+
+// ZoomAt multiplies the zoom by factor keeping the world point under the
+// screen point sx,sy fixed, e.g. the mouse cursor.
+func (c *Camera2D) ZoomAt(factor, sx, sy float32) {
+	wx, wy := c.ScreenToWorld(sx, sy)
+	c.SetZoom(c.zoom * factor)
+
+	// Shift so the point is back under the cursor
+	nx, ny := c.ScreenToWorld(sx, sy)
+	c.Move(wx-nx, wy-ny)
+}
+
+// SetRotation sets the camera's rotation in radians. Rotating the camera
+// counter clockwise turns the world clockwise on screen.
+func (c *Camera2D) SetRotation(radians float64) {
+	c.rotation = radians
+	c.clamp()
+	c.dirty = true
+}
+
+// Rotation returns the rotation in radians.
+func (c *Camera2D) Rotation() float64 {
+	return c.rotation
+}
+
+// Rotate adds radians to the rotation.
+func (c *Camera2D) Rotate(radians float64) {
+	c.SetRotation(c.rotation + radians)
+}
+
+// Follow sets the point the camera moves towards on Update. Call it each
+// frame with the target's position.
+func (c *Camera2D) Follow(x, y float32) {
+	c.following = true
+	c.targetX = x
+	c.targetY = y
+}
+
+// StopFollowing leaves the camera where it is.
+func (c *Camera2D) StopFollowing() {
+	c.following = false
+}
+
+// SetFollowSpeed sets how quickly the camera catches up with its target,
+// higher is snappier. After 1/speed seconds about 63% of the gap is
+// closed. 0 snaps straight to the target.
+func (c *Camera2D) SetFollowSpeed(speed float32) {
+	c.followSpeed = speed
+}
+
+// SetBounds keeps the visible area inside the world rectangle. If the
+// rectangle is smaller than the view the camera centers on it.
+func (c *Camera2D) SetBounds(minX, minY, maxX, maxY float32) {
+	c.bounded = true
+	c.minX, c.minY, c.maxX, c.maxY = minX, minY, maxX, maxY
+	c.clamp()
+	c.dirty = true
+}
+
+// ClearBounds removes the bounds.
+func (c *Camera2D) ClearBounds() {
+	c.bounded = false
+}
+
+// Shake jitters the view by up to magnitude world units, fading out over
+// duration seconds. A stronger shake replaces a weaker one.
+func (c *Camera2D) Shake(magnitude, duration float32) {
+	if magnitude >= c.currentShake() {
+		c.shakeMagnitude = magnitude
+		c.shakeDuration = duration
+		c.shakeRemaining = duration
+	}
+}
+
+// Update advances following and shake by dt seconds.
+func (c *Camera2D) Update(dt float32) {
+	if c.following {
+		if c.followSpeed <= 0 {
+			c.SetPosition(c.targetX, c.targetY)
+		} else {
+			// Frame rate independent exponential smoothing
+			t := 1.0 - float32(math.Exp(-float64(c.followSpeed*dt)))
+			c.SetPosition(c.x+(c.targetX-c.x)*t, c.y+(c.targetY-c.y)*t)
+		}
+	}
+
+	if c.shakeRemaining > 0 {
+		c.shakeRemaining -= dt
+		if c.shakeRemaining < 0 {
+			c.shakeRemaining = 0
+		}
+		m := c.currentShake()
+		c.shakeX = (c.rnd.Float32()*2.0 - 1.0) * m
+		c.shakeY = (c.rnd.Float32()*2.0 - 1.0) * m
+		c.dirty = true
+	} else if c.shakeX != 0 || c.shakeY != 0 {
+		c.shakeX, c.shakeY = 0, 0
+		c.dirty = true
+	}
+}
+
+// ScreenToWorld converts a window position in pixels (origin top left,
+// +y down, as reported for the cursor) to world coordinates.
+func (c *Camera2D) ScreenToWorld(sx, sy float32) (x, y float32) {
+	vx := sx - c.width/2.0
+	vy := c.height/2.0 - sy
+	dx, dy := c.viewToWorldDelta(vx, vy)
+	return c.x + c.shakeX + dx, c.y + c.shakeY + dy
+}
+
+// WorldToScreen converts world coordinates to a window position in pixels.
+func (c *Camera2D) WorldToScreen(x, y float32) (sx, sy float32) {
+	dx := x - (c.x + c.shakeX)
+	dy := y - (c.y + c.shakeY)
+
+	cos := float32(math.Cos(c.rotation))
+	sin := float32(math.Sin(c.rotation))
+	vx := (cos*dx + sin*dy) * c.zoom
+	vy := (-sin*dx + cos*dy) * c.zoom
+
+	return vx + c.width/2.0, c.height/2.0 - vy
+}
+
+// VisibleBounds returns the axis aligned world rectangle covering the view.
+func (c *Camera2D) VisibleBounds() (minX, minY, maxX, maxY float32) {
+	hw, hh := c.halfExtents()
+	return c.x - hw, c.y - hh, c.x + hw, c.y + hh
+}
+
+// viewToWorldDelta converts an offset in view pixels (+y up) to world
+// units.
+func (c *Camera2D) viewToWorldDelta(vx, vy float32) (dx, dy float32) {
+	cos := float32(math.Cos(c.rotation))
+	sin := float32(math.Sin(c.rotation))
+	return (cos*vx - sin*vy) / c.zoom, (sin*vx + cos*vy) / c.zoom
+}
+
+// halfExtents is half the size of the visible area's bounding box in
+// world units.
+func (c *Camera2D) halfExtents() (hw, hh float32) {
+	w := c.width / 2.0 / c.zoom
+	h := c.height / 2.0 / c.zoom
+	cos := float32(math.Abs(math.Cos(c.rotation)))
+	sin := float32(math.Abs(math.Sin(c.rotation)))
+	return cos*w + sin*h, sin*w + cos*h
+}
+
+func (c *Camera2D) currentShake() float32 {
+	if c.shakeDuration <= 0 {
+		return 0
+	}
+	// Quadratic falloff feels smoother than linear
+	f := c.shakeRemaining / c.shakeDuration
+	return c.shakeMagnitude * f * f
+}
+
+func (c *Camera2D) clamp() {
+	if !c.bounded {
+		return
+	}
+
+	hw, hh := c.halfExtents()
+	c.x = clampAxis(c.x, c.minX+hw, c.maxX-hw)
+	c.y = clampAxis(c.y, c.minY+hh, c.maxY-hh)
+}
+
+func clampAxis(v, min, max float32) float32 {
+	if min > max {
+		// The view is larger than the bounds
+		return (min + max) / 2.0
+	}
+	if v < min {
+		return min
+	}
+	if v > max {
+		return max
+	}
+	return v
+}
+
+// buildView composes zoom * rotation * translation so world points are
+// moved to the camera, turned and then scaled.
+func (c *Camera2D) buildView() {
+	c.view.ToIdentity()
+	c.view.ScaleByComp(c.zoom, c.zoom, 1.0)
+	c.view.Rotate(-c.rotation)
+	c.view.TranslateBy2Comps(-(c.x + c.shakeX), -(c.y + c.shakeY))
+	c.dirty = false
+}
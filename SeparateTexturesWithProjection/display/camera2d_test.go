@@ -0,0 +1,275 @@
+package display
+
+import (
+	"SimpleOpenGL-Go/SeparateTexturesWithProjection/maths"
+	"math"
+	"testing"
+)
+
+func near(a, b float32) bool {
+	return math.Abs(float64(a-b)) < 1e-3
+}
+
+func checkPoint(t *testing.T, what string, x, y, wantX, wantY float32) {
+	t.Helper()
+	if !near(x, wantX) || !near(y, wantY) {
+		t.Errorf("%s is %g,%g, want %g,%g", what, x, y, wantX, wantY)
+	}
+}
+
+// testCameras are cameras on an 800x600 viewport in assorted states.
+var testCameras = []struct {
+	name     string
+	x, y     float32
+	zoom     float32
+	rotation float64
+}{
+	{"identity", 0, 0, 1, 0},
+	{"panned", 120, -45, 1, 0},
+	{"zoomed in", 0, 0, 2.5, 0},
+	{"zoomed out", -30, 70, 0.25, 0},
+	{"rotated", 0, 0, 1, math.Pi / 6},
+	{"all", 250, 125, 1.75, -2.2},
+}
+
+func TestCamera2DRoundTrip(t *testing.T) {
+	screen := [][2]float32{{0, 0}, {400, 300}, {800, 600}, {13, 587}, {640, 25}}
+
+	for _, test := range testCameras {
+		t.Run(test.name, func(t *testing.T) {
+			c := NewCamera2D(800, 600)
+			c.SetPosition(test.x, test.y)
+			c.SetZoom(test.zoom)
+			c.SetRotation(test.rotation)
+
+			for _, p := range screen {
+				wx, wy := c.ScreenToWorld(p[0], p[1])
+				sx, sy := c.WorldToScreen(wx, wy)
+				checkPoint(t, "screen to world and back", sx, sy, p[0], p[1])
+
+				// The view matrix agrees, it maps world to view pixels
+				// with +y up around the viewport center
+				e := c.View().Matrix()
+				vx := e[maths.M00]*wx + e[maths.M01]*wy + e[maths.M03]
+				vy := e[maths.M10]*wx + e[maths.M11]*wy + e[maths.M13]
+				checkPoint(t, "view matrix", vx+400, 300-vy, p[0], p[1])
+			}
+
+			// The camera's position is at the center of the screen
+			sx, sy := c.WorldToScreen(test.x, test.y)
+			checkPoint(t, "position", sx, sy, 400, 300)
+		})
+	}
+}
+
+func TestCamera2DScreenToWorld(t *testing.T) {
+	c := NewCamera2D(800, 600)
+	c.SetPosition(100, 50)
+	c.SetZoom(2)
+
+	// Top left, +y up in the world
+	x, y := c.ScreenToWorld(0, 0)
+	checkPoint(t, "top left", x, y, -100, 200)
+
+	// A quarter turn counter clockwise, screen right is world up
+	c.SetRotation(math.Pi / 2)
+	x, y = c.ScreenToWorld(600, 300)
+	checkPoint(t, "right of center", x, y, 100, 150)
+}
+
+func TestCamera2DPanAndZoomAt(t *testing.T) {
+	c := NewCamera2D(800, 600)
+	c.SetZoom(2)
+
+	// Dragging right by 10 pixels moves the world right under the cursor
+	c.Pan(10, 10)
+	x, y := c.Position()
+	checkPoint(t, "after panning", x, y, -5, 5)
+
+	// The world point under the cursor stays there
+	wx, wy := c.ScreenToWorld(100, 500)
+	c.ZoomAt(1.5, 100, 500)
+	if !near(c.Zoom(), 3) {
+		t.Errorf("zoom %g, want 3", c.Zoom())
+	}
+	sx, sy := c.WorldToScreen(wx, wy)
+	checkPoint(t, "point under the cursor", sx, sy, 100, 500)
+
+	// Clamped to the limits
+	c.SetZoomLimits(0.5, 2)
+	if c.Zoom() != 2 {
+		t.Errorf("zoom %g, want the limit 2", c.Zoom())
+	}
+	c.SetZoom(0.1)
+	if c.Zoom() != 0.5 {
+		t.Errorf("zoom %g, want the limit 0.5", c.Zoom())
+	}
+}
+
+func TestCamera2DVisibleBounds(t *testing.T) {
+	tests := []struct {
+		name                   string
+		zoom                   float32
+		rotation               float64
+		minX, minY, maxX, maxY float32
+	}{
+		{"identity", 1, 0, -390, -320, 410, 280},
+		{"zoomed in", 2, 0, -190, -170, 210, 130},
+		{"quarter turn", 1, math.Pi / 2, -290, -420, 310, 380},
+		{"eighth turn", 1, math.Pi / 4, 10 - 350*math.Sqrt2, -20 - 350*math.Sqrt2, 10 + 350*math.Sqrt2, -20 + 350*math.Sqrt2},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := NewCamera2D(800, 600)
+			c.SetPosition(10, -20)
+			c.SetZoom(test.zoom)
+			c.SetRotation(test.rotation)
+
+			minX, minY, maxX, maxY := c.VisibleBounds()
+			checkPoint(t, "min", minX, minY, test.minX, test.minY)
+			checkPoint(t, "max", maxX, maxY, test.maxX, test.maxY)
+
+			// Every screen corner is inside
+			for _, p := range [][2]float32{{0, 0}, {800, 0}, {0, 600}, {800, 600}} {
+				x, y := c.ScreenToWorld(p[0], p[1])
+				if x < minX-1e-3 || x > maxX+1e-3 || y < minY-1e-3 || y > maxY+1e-3 {
+					t.Errorf("corner %v at %g,%g is outside", p, x, y)
+				}
+			}
+		})
+	}
+}
+
+func TestCamera2DBounds(t *testing.T) {
+	tests := []struct {
+		name     string
+		bounds   [4]float32
+		zoom     float32
+		rotation float64
+		// Requested and clamped positions
+		x, y, wantX, wantY float32
+	}{
+		{"inside", [4]float32{-1000, -1000, 1000, 1000}, 1, 0, 100, -200, 100, -200},
+		{"past the right", [4]float32{-1000, -1000, 1000, 1000}, 1, 0, 900, 0, 600, 0},
+		{"past the bottom left", [4]float32{-1000, -1000, 1000, 1000}, 1, 0, -2000, -2000, -600, -700},
+		{"zoomed out", [4]float32{-1000, -1000, 1000, 1000}, 0.5, 0, 900, 900, 200, 400},
+		{"rotated", [4]float32{-1000, -1000, 1000, 1000}, 1, math.Pi / 2, 900, 900, 700, 600},
+		{"narrower than the view", [4]float32{0, -1000, 200, 1000}, 1, 0, 900, 900, 100, 700},
+		{"smaller than the view", [4]float32{-50, 20, 50, 40}, 1, 0, 900, 900, 0, 30},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := NewCamera2D(800, 600)
+			c.SetZoom(test.zoom)
+			c.SetRotation(test.rotation)
+			b := test.bounds
+			c.SetBounds(b[0], b[1], b[2], b[3])
+			c.SetPosition(test.x, test.y)
+
+			x, y := c.Position()
+			checkPoint(t, "position", x, y, test.wantX, test.wantY)
+
+			// The view stays inside bounds that are large enough
+			minX, minY, maxX, maxY := c.VisibleBounds()
+			if maxX-minX <= b[2]-b[0] && (minX < b[0]-1e-3 || maxX > b[2]+1e-3) {
+				t.Errorf("view x %g to %g outside %g to %g", minX, maxX, b[0], b[2])
+			}
+			if maxY-minY <= b[3]-b[1] && (minY < b[1]-1e-3 || maxY > b[3]+1e-3) {
+				t.Errorf("view y %g to %g outside %g to %g", minY, maxY, b[1], b[3])
+			}
+		})
+	}
+}
+
+func TestCamera2DBoundsReclamp(t *testing.T) {
+	c := NewCamera2D(800, 600)
+	c.SetBounds(-1000, -1000, 1000, 1000)
+	c.SetPosition(600, 0)
+
+	// Zooming out widens the view, so the camera is pushed back in
+	c.SetZoom(0.5)
+	x, _ := c.Position()
+	if !near(x, 200) {
+		t.Errorf("x %g after zooming out, want 200", x)
+	}
+
+	// Without bounds it goes anywhere
+	c.ClearBounds()
+	c.SetPosition(5000, 5000)
+	if x, y := c.Position(); x != 5000 || y != 5000 {
+		t.Errorf("position %g,%g without bounds", x, y)
+	}
+}
+
+func TestCamera2DFollow(t *testing.T) {
+	// Snaps without a speed
+	c := NewCamera2D(800, 600)
+	c.Follow(100, -50)
+	c.Update(1.0 / 60.0)
+	x, y := c.Position()
+	checkPoint(t, "snapped", x, y, 100, -50)
+
+	// With a speed the gap shrinks by exp(-speed * dt)
+	c = NewCamera2D(800, 600)
+	c.SetFollowSpeed(4)
+	c.Follow(100, 0)
+	c.Update(0.25)
+	x, _ = c.Position()
+	if want := float32(100 * (1 - math.Exp(-1))); !near(x, want) {
+		t.Errorf("x %g after a quarter second, want %g", x, want)
+	}
+
+	// Independent of the frame rate
+	c2 := NewCamera2D(800, 600)
+	c2.SetFollowSpeed(4)
+	c2.Follow(100, 0)
+	for i := 0; i < 10; i++ {
+		c2.Update(0.025)
+	}
+	if x2, _ := c2.Position(); !near(x, x2) {
+		t.Errorf("x %g in ten steps, %g in one", x2, x)
+	}
+
+	// Stopping leaves it where it is
+	c.StopFollowing()
+	c.Update(1)
+	if x2, _ := c.Position(); x2 != x {
+		t.Errorf("moved to %g after stopping", x2)
+	}
+}
+
+func TestCamera2DShake(t *testing.T) {
+	c := NewCamera2D(800, 600)
+	c.SetPosition(10, 20)
+	c.Shake(5, 1)
+
+	moved := false
+	for i := 0; i < 10; i++ {
+		c.Update(0.05)
+		sx, sy := c.WorldToScreen(10, 20)
+		dx, dy := sx-400, sy-300
+		if dx != 0 || dy != 0 {
+			moved = true
+		}
+		if math.Abs(float64(dx)) > 5 || math.Abs(float64(dy)) > 5 {
+			t.Fatalf("shaken by %g,%g, more than 5", dx, dy)
+		}
+	}
+	if !moved {
+		t.Error("the view didn't shake")
+	}
+
+	// A weaker shake doesn't replace a stronger one
+	c.Shake(0.1, 10)
+	for i := 0; i < 20; i++ {
+		c.Update(0.05)
+	}
+	sx, sy := c.WorldToScreen(10, 20)
+	checkPoint(t, "after the shake", sx, sy, 400, 300)
+
+	// The camera itself never moved
+	x, y := c.Position()
+	checkPoint(t, "position", x, y, 10, 20)
+}
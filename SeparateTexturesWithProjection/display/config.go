@@ -0,0 +1,165 @@
+package display
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// Config describes the window and its OpenGL context.
+type Config struct {
+	// Width and Height are the initial window size in screen coordinates
+	// and the virtual resolution the scene is laid out for.
+	Width  int    `json:"width" toml:"width"`
+	Height int    `json:"height" toml:"height"`
+	Title  string `json:"title" toml:"title"`
+
+	Resizable  bool `json:"resizable" toml:"resizable"`
+	Fullscreen bool `json:"fullscreen" toml:"fullscreen"`
+
+	// SwapInterval is the number of refreshes per buffer swap, 1 is vsync
+	// and 0 disables it.
+	SwapInterval int `json:"swap_interval" toml:"swap_interval"`
+	// Samples is the MSAA sample count, 0 disables multisampling.
+	Samples int `json:"samples" toml:"samples"`
+
+	// GLMajor.GLMinor is the preferred core profile version, at least 4.5.
+	// If the driver can't create it lower versions are tried down to 4.5.
+	GLMajor int `json:"gl_major" toml:"gl_major"`
+	GLMinor int `json:"gl_minor" toml:"gl_minor"`
+	// Debug requests a debug context.
+	Debug bool `json:"debug" toml:"debug"`
+}
+
+// DefaultConfig returns the settings the demo has always used.
+func DefaultConfig() Config {
+	return Config{
+		Width:        1200,
+		Height:       800,
+		Title:        "Simple",
+		Resizable:    true,
+		SwapInterval: 1,
+		GLMajor:      4,
+		GLMinor:      5,
+	}
+}
+
+// LoadConfig reads a JSON or TOML file, chosen by extension, over the
+// defaults. Settings missing from the file keep their default.
+func LoadConfig(path string) (Config, error) {
+	config := DefaultConfig()
+
+	f, err := os.Open(path)
+	if err != nil {
+		return config, err
+	}
+	defer f.Close()
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		err = config.decodeJSON(f)
+	case ".toml":
+		err = config.decodeTOML(f)
+	default:
+		return config, fmt.Errorf("config: unknown format '%s'", filepath.Ext(path))
+	}
+	if err != nil {
+		return config, fmt.Errorf("config: %s: %w", path, err)
+	}
+
+	return config, config.Validate()
+}
+
+func (c *Config) decodeJSON(r io.Reader) error {
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	return dec.Decode(c)
+}
+
+// decodeTOML fails on unknown keys like decodeJSON, so a misspelt
+// setting isn't silently ignored.
+func (c *Config) decodeTOML(r io.Reader) error {
+	md, err := toml.NewDecoder(r).Decode(c)
+	if err != nil {
+		return err
+	}
+	if keys := md.Undecoded(); len(keys) > 0 {
+		return fmt.Errorf("unknown key '%s'", keys[0])
+	}
+	return nil
+}
+
+// RegisterFlags binds the settings to command line flags with the
+// current values as defaults.
+func (c *Config) RegisterFlags(fs *flag.FlagSet) {
+	fs.IntVar(&c.Width, "width", c.Width, "window width")
+	fs.IntVar(&c.Height, "height", c.Height, "window height")
+	fs.StringVar(&c.Title, "title", c.Title, "window title")
+	fs.BoolVar(&c.Resizable, "resizable", c.Resizable, "allow resizing the window")
+	fs.BoolVar(&c.Fullscreen, "fullscreen", c.Fullscreen, "start fullscreen")
+	fs.IntVar(&c.SwapInterval, "swap-interval", c.SwapInterval, "refreshes per swap, 0 disables vsync")
+	fs.IntVar(&c.Samples, "samples", c.Samples, "MSAA samples")
+	fs.IntVar(&c.GLMajor, "gl-major", c.GLMajor, "preferred OpenGL major version")
+	fs.IntVar(&c.GLMinor, "gl-minor", c.GLMinor, "preferred OpenGL minor version")
+	fs.BoolVar(&c.Debug, "gl-debug", c.Debug, "request a debug context")
+}
+
+// ParseConfig builds a Config from the command line. A -config file is
+// loaded first and the other flags override it. The extra functions
+// register the application's own flags.
+func ParseConfig(name string, args []string, extra ...func(fs *flag.FlagSet)) (Config, error) {
+	// First pass only finds the file
+	var path string
+	pre := flag.NewFlagSet(name, flag.ContinueOnError)
+	pre.SetOutput(io.Discard)
+	pre.StringVar(&path, "config", "", "")
+	scratch := DefaultConfig()
+	scratch.RegisterFlags(pre)
+	for _, register := range extra {
+		register(pre)
+	}
+	if err := pre.Parse(args); err != nil && err != flag.ErrHelp {
+		// Reported by the second pass
+		path = ""
+	}
+
+	config := DefaultConfig()
+	if path != "" {
+		var err error
+		if config, err = LoadConfig(path); err != nil {
+			return config, err
+		}
+	}
+
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.String("config", "", "JSON or TOML settings file")
+	config.RegisterFlags(fs)
+	for _, register := range extra {
+		register(fs)
+	}
+	if err := fs.Parse(args); err != nil {
+		return config, err
+	}
+
+	return config, config.Validate()
+}
+
+// Validate checks the settings are usable.
+func (c Config) Validate() error {
+	if c.Width <= 0 || c.Height <= 0 {
+		return fmt.Errorf("config: invalid size %dx%d", c.Width, c.Height)
+	}
+	if c.Samples < 0 {
+		return fmt.Errorf("config: invalid sample count %d", c.Samples)
+	}
+	if c.GLMajor < 4 || (c.GLMajor == 4 && c.GLMinor < 5) {
+		return fmt.Errorf("config: OpenGL %d.%d is older than 4.5 core", c.GLMajor, c.GLMinor)
+	}
+	return nil
+}
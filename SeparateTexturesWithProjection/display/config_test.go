@@ -0,0 +1,156 @@
+package display
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadConfig(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		data string
+		// Substring of the error, empty if it loads
+		err string
+	}{
+		{
+			name: "json",
+			file: "config.json",
+			data: `{"width": 640, "height": 360, "samples": 4, "gl_major": 4, "gl_minor": 6}`,
+		},
+		{
+			name: "toml",
+			file: "config.toml",
+			data: "width = 640\nheight = 360\nsamples = 4\ngl_major = 4\ngl_minor = 6\n",
+		},
+		{
+			name: "json unknown key",
+			file: "config.json",
+			data: `{"width": 640, "height": 360, "sample": 4}`,
+			err:  `unknown field "sample"`,
+		},
+		{
+			name: "toml unknown key",
+			file: "config.toml",
+			data: "width = 640\nheight = 360\nsample = 4\n",
+			err:  "unknown key 'sample'",
+		},
+		{
+			name: "toml unknown table",
+			file: "config.toml",
+			data: "[window]\nwidth = 640\n",
+			err:  "unknown key 'window",
+		},
+		{
+			name: "broken toml",
+			file: "config.toml",
+			data: "width = \n",
+			err:  "config.toml",
+		},
+		{
+			name: "unknown format",
+			file: "config.yaml",
+			data: "width: 640\n",
+			err:  "unknown format '.yaml'",
+		},
+		{
+			name: "gl 4.3",
+			file: "config.toml",
+			data: "gl_major = 4\ngl_minor = 3\n",
+			err:  "OpenGL 4.3 is older than 4.5 core",
+		},
+		{
+			name: "gl 3.3",
+			file: "config.json",
+			data: `{"gl_major": 3, "gl_minor": 3}`,
+			err:  "OpenGL 3.3 is older than 4.5 core",
+		},
+		{
+			name: "invalid size",
+			file: "config.toml",
+			data: "width = 0\n",
+			err:  "invalid size 0x800",
+		},
+		{
+			name: "negative samples",
+			file: "config.toml",
+			data: "samples = -1\n",
+			err:  "invalid sample count -1",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), test.file)
+			if err := os.WriteFile(path, []byte(test.data), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			config, err := LoadConfig(path)
+			switch {
+			case test.err == "" && err != nil:
+				t.Fatalf("unexpected error: %v", err)
+			case test.err != "" && err == nil:
+				t.Fatalf("no error, want %q", test.err)
+			case test.err != "" && !strings.Contains(err.Error(), test.err):
+				t.Fatalf("error %q, want %q", err, test.err)
+			case test.err != "":
+				return
+			}
+
+			// Set values override the defaults, the rest are kept
+			want := DefaultConfig()
+			want.Width, want.Height, want.Samples, want.GLMinor = 640, 360, 4, 6
+			if config != want {
+				t.Errorf("got %+v, want %+v", config, want)
+			}
+		})
+	}
+}
+
+func TestLoadConfigMissing(t *testing.T) {
+	if _, err := LoadConfig(filepath.Join(t.TempDir(), "missing.toml")); !os.IsNotExist(err) {
+		t.Fatalf("error %v, want not exist", err)
+	}
+}
+
+func TestParseConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.toml")
+	if err := os.WriteFile(path, []byte("width = 640\nheight = 360\ntitle = \"File\"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var record string
+	config, err := ParseConfig("demo", []string{"-config", path, "-width", "800", "-record", "input.rec"}, func(fs *flag.FlagSet) {
+		fs.StringVar(&record, "record", "", "")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Flags override the file, which overrides the defaults
+	want := DefaultConfig()
+	want.Width, want.Height, want.Title = 800, 360, "File"
+	if config != want {
+		t.Errorf("got %+v, want %+v", config, want)
+	}
+	if record != "input.rec" {
+		t.Errorf("extra flag is %q", record)
+	}
+
+	if _, err := ParseConfig("demo", []string{"-gl-minor", "1"}); err == nil || !strings.Contains(err.Error(), "older than 4.5") {
+		t.Errorf("error %v for GL 4.1", err)
+	}
+	if _, err := ParseConfig("demo", []string{"-config", filepath.Join(filepath.Dir(path), "missing.toml")}); err == nil {
+		t.Error("no error for a missing config file")
+	}
+}
+
+func TestExampleConfig(t *testing.T) {
+	if _, err := LoadConfig("../config.example.toml"); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -2,39 +2,8 @@ package display
 
 import (
 	"math"
-
-	"github.com/go-gl/glfw/v3.3/glfw"
 )
 
 const (
-	Width           = 1200
-	Height          = 800
 	DegreeToRadians = math.Pi / 180.0
 )
-
-var (
-	QuitTriggered bool
-	PolygonMode   bool
-	PointMode     bool
-)
-
-// initGlfw initializes glfw and returns a Window to use.
-func InitGlfw() *glfw.Window {
-	if err := glfw.Init(); err != nil {
-		panic(err)
-	}
-
-	glfw.WindowHint(glfw.Resizable, glfw.False)
-	glfw.WindowHint(glfw.ContextVersionMajor, 4) // OR 2
-	glfw.WindowHint(glfw.ContextVersionMinor, 5)
-	glfw.WindowHint(glfw.OpenGLProfile, glfw.OpenGLCoreProfile)
-	glfw.WindowHint(glfw.OpenGLForwardCompatible, glfw.True)
-
-	window, err := glfw.CreateWindow(Width, Height, "Simple", nil, nil)
-	if err != nil {
-		panic(err)
-	}
-	window.MakeContextCurrent()
-
-	return window
-}
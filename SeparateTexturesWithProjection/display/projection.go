@@ -10,7 +10,6 @@ type Projection struct {
 	near, far                float32
 	left, right, bottom, top float32
 	Width, Height            float32
-	ratioCorrection          float32
 
 	// Projection matrix (orthographic)
 	matrix api.IMatrix4
@@ -44,13 +43,10 @@ func (c *Projection) SetProjection(bottom, left, top, right, near, far float32)
 	c.matrix.SetToOrtho(c.left, c.right, c.bottom, c.top, near, far)
 }
 
-// SetCenteredProjection centers the projection and adjusts for aspect ratio.
+// SetCenteredProjection sets a frustum of Width x Height centered on the
+// origin.
 func (c *Projection) SetCenteredProjection(near, far float32) {
-	// Adjust for aspect ratio
-	left := -c.Width / 2.0 / c.ratioCorrection
-	right := c.Width / 2.0 / c.ratioCorrection
-	bottom := -c.Height / 2.0 / c.ratioCorrection
-	top := c.Height / 2.0 / c.ratioCorrection
-
-	c.matrix.SetToOrtho(left, right, bottom, top, near, far)
+	sW := c.Width / 2.0
+	sH := c.Height / 2.0
+	c.SetProjection(-sH, -sW, sH, sW, near, far)
 }
@@ -0,0 +1,222 @@
+package display
+
+import (
+	"math"
+
+	"github.com/go-gl/glfw/v3.3/glfw"
+)
+
+// ScalingPolicy decides how the virtual resolution maps onto a window of
+// a different size.
+type ScalingPolicy int
+
+const (
+	// ScaleStretch fills the window with the virtual area, distorting the
+	// aspect ratio.
+	ScaleStretch ScalingPolicy = iota
+	// ScaleFit shows the whole virtual area at the largest size that fits,
+	// leaving bars (letterbox) on the sides that don't match.
+	ScaleFit
+	// ScaleFill fills the window keeping the aspect ratio, cropping the
+	// virtual area on the sides that don't match.
+	ScaleFill
+	// ScalePixelPerfect scales by the largest whole number that fits, so
+	// pixel art stays sharp, leaving bars around it.
+	ScalePixelPerfect
+	// ScaleExtend scales like ScaleFit but shows more of the world instead
+	// of bars.
+	ScaleExtend
+)
+
+var policyNames = []string{"stretch", "fit", "fill", "pixel-perfect", "extend"}
+
+func (p ScalingPolicy) String() string {
+	if p < 0 || int(p) >= len(policyNames) {
+		return "unknown"
+	}
+	return policyNames[p]
+}
+
+// Scaler keeps a Viewport and a set of cameras in step with the window.
+// Game code works in a fixed virtual resolution and the Scaler decides
+// the viewport in framebuffer pixels and how much of the world it shows.
+//
+// On HiDPI displays the framebuffer has more pixels than the window has
+// screen coordinates. The viewport is always in framebuffer pixels while
+// cursor positions arrive in screen coordinates, see WindowToView.
+type Scaler struct {
+	policy ScalingPolicy
+
+	virtualWidth, virtualHeight float32
+
+	windowWidth, windowHeight int
+	fbWidth, fbHeight         int
+
+	viewport *Viewport
+	// Visible world size in world units
+	worldWidth, worldHeight float32
+
+	cameras []*Camera2D
+}
+
+// NewScaler creates a scaler for a virtual resolution.
+func NewScaler(virtualWidth, virtualHeight int, policy ScalingPolicy) *Scaler {
+	s := new(Scaler)
+	s.virtualWidth = float32(virtualWidth)
+	s.virtualHeight = float32(virtualHeight)
+	s.policy = policy
+	s.viewport = NewViewport()
+	s.worldWidth = s.virtualWidth
+	s.worldHeight = s.virtualHeight
+	return s
+}
+
+// Attach registers size callbacks on the window and applies the current
+// size. The cameras' viewports follow the visible world size.
+func (s *Scaler) Attach(win *Window, cameras ...*Camera2D) {
+	s.cameras = append(s.cameras, cameras...)
+
+	window := win.GLFW()
+	window.SetFramebufferSizeCallback(func(w *glfw.Window, width, height int) {
+		winW, winH := w.GetSize()
+		s.Update(winW, winH, width, height)
+	})
+	// The window size can change without the framebuffer size, e.g. when
+	// moving between monitors with different content scales.
+	window.SetSizeCallback(func(w *glfw.Window, width, height int) {
+		fbW, fbH := w.GetFramebufferSize()
+		s.Update(width, height, fbW, fbH)
+	})
+
+	winW, winH := window.GetSize()
+	fbW, fbH := window.GetFramebufferSize()
+	s.Update(winW, winH, fbW, fbH)
+}
+
+// SetPolicy changes the policy and re-applies it.
+func (s *Scaler) SetPolicy(policy ScalingPolicy) {
+	s.policy = policy
+	s.Update(s.windowWidth, s.windowHeight, s.fbWidth, s.fbHeight)
+}
+
+// Policy returns the current policy.
+func (s *Scaler) Policy() ScalingPolicy {
+	return s.policy
+}
+
+// Viewport returns the viewport in framebuffer pixels.
+func (s *Scaler) Viewport() *Viewport {
+	return s.viewport
+}
+
+// WorldSize returns the visible world size in world units.
+func (s *Scaler) WorldSize() (width, height float32) {
+	return s.worldWidth, s.worldHeight
+}
+
+// ContentScale returns framebuffer pixels per screen coordinate.
+func (s *Scaler) ContentScale() (x, y float32) {
+	if s.windowWidth == 0 || s.windowHeight == 0 {
+		return 1.0, 1.0
+	}
+	return float32(s.fbWidth) / float32(s.windowWidth), float32(s.fbHeight) / float32(s.windowHeight)
+}
+
+// Update recomputes the viewport and world size for a window of
+// windowWidth x windowHeight screen coordinates backed by a framebuffer of
+// fbWidth x fbHeight pixels, applies the viewport and resizes the cameras.
+func (s *Scaler) Update(windowWidth, windowHeight, fbWidth, fbHeight int) {
+	if s.resize(windowWidth, windowHeight, fbWidth, fbHeight) {
+		s.viewport.Apply()
+	}
+}
+
+// resize is Update without touching GL. It returns false, leaving the
+// viewport and cameras alone, while the window is minimized.
+func (s *Scaler) resize(windowWidth, windowHeight, fbWidth, fbHeight int) bool {
+	s.windowWidth, s.windowHeight = windowWidth, windowHeight
+	s.fbWidth, s.fbHeight = fbWidth, fbHeight
+
+	// A minimized window has a zero sized framebuffer
+	if fbWidth <= 0 || fbHeight <= 0 {
+		return false
+	}
+
+	fw := float32(fbWidth)
+	fh := float32(fbHeight)
+	sx := fw / s.virtualWidth
+	sy := fh / s.virtualHeight
+
+	switch s.policy {
+	case ScaleStretch:
+		s.setViewport(0, 0, fw, fh)
+		s.worldWidth, s.worldHeight = s.virtualWidth, s.virtualHeight
+	case ScaleFit:
+		scale := min32(sx, sy)
+		s.centerViewport(s.virtualWidth*scale, s.virtualHeight*scale)
+		s.worldWidth, s.worldHeight = s.virtualWidth, s.virtualHeight
+	case ScaleFill:
+		scale := max32(sx, sy)
+		s.setViewport(0, 0, fw, fh)
+		s.worldWidth, s.worldHeight = fw/scale, fh/scale
+	case ScalePixelPerfect:
+		// Below 1x the window crops rather than blurs
+		scale := float32(math.Max(1.0, math.Floor(float64(min32(sx, sy)))))
+		s.centerViewport(s.virtualWidth*scale, s.virtualHeight*scale)
+		s.worldWidth, s.worldHeight = s.virtualWidth, s.virtualHeight
+	case ScaleExtend:
+		scale := min32(sx, sy)
+		s.setViewport(0, 0, fw, fh)
+		s.worldWidth, s.worldHeight = fw/scale, fh/scale
+	}
+
+	for _, c := range s.cameras {
+		c.SetViewport(s.worldWidth, s.worldHeight)
+	}
+
+	return true
+}
+
+// WindowToView converts a position in window screen coordinates (e.g. the
+// cursor) to the cameras' screen space: world units from the top left of
+// the viewport, as Camera2D.ScreenToWorld expects.
+func (s *Scaler) WindowToView(x, y float32) (vx, vy float32) {
+	csx, csy := s.ContentScale()
+	px := x * csx
+	// Viewports are bottom up, the cursor is top down
+	py := float32(s.fbHeight) - y*csy
+
+	vpx, vpy, vpw, vph := s.viewport.Dimensions()
+	if vpw == 0 || vph == 0 {
+		return 0, 0
+	}
+
+	vx = (px - float32(vpx)) * s.worldWidth / float32(vpw)
+	vy = (float32(vpy+vph) - py) * s.worldHeight / float32(vph)
+	return vx, vy
+}
+
+func (s *Scaler) centerViewport(width, height float32) {
+	x := (float32(s.fbWidth) - width) / 2.0
+	y := (float32(s.fbHeight) - height) / 2.0
+	s.setViewport(x, y, width, height)
+}
+
+func (s *Scaler) setViewport(x, y, width, height float32) {
+	s.viewport.SetDimensions(int(math.Round(float64(x))), int(math.Round(float64(y))),
+		int(math.Round(float64(width))), int(math.Round(float64(height))))
+}
+
+func min32(a, b float32) float32 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func max32(a, b float32) float32 {
+	if a > b {
+		return a
+	}
+	return b
+}
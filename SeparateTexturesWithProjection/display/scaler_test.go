@@ -0,0 +1,196 @@
+package display
+
+import (
+	"testing"
+)
+
+func TestScaler(t *testing.T) {
+	type mapping struct {
+		// Window screen coordinates and the view position they map to
+		wx, wy, vx, vy float32
+	}
+
+	// The virtual resolution is 320x180 throughout
+	tests := []struct {
+		name   string
+		policy ScalingPolicy
+		// Window and framebuffer sizes
+		window, fb [2]int
+		viewport   [4]int
+		world      [2]float32
+		scale      float32
+		points     []mapping
+	}{
+		{
+			name:   "stretch",
+			policy: ScaleStretch,
+			window: [2]int{1000, 500}, fb: [2]int{1000, 500},
+			viewport: [4]int{0, 0, 1000, 500},
+			world:    [2]float32{320, 180},
+			scale:    1,
+			points:   []mapping{{0, 0, 0, 0}, {500, 250, 160, 90}, {1000, 500, 320, 180}, {250, 125, 80, 45}},
+		},
+		{
+			name:   "fit letterboxed",
+			policy: ScaleFit,
+			window: [2]int{960, 1080}, fb: [2]int{960, 1080},
+			viewport: [4]int{0, 270, 960, 540},
+			world:    [2]float32{320, 180},
+			scale:    1,
+			points:   []mapping{{0, 270, 0, 0}, {480, 540, 160, 90}, {960, 810, 320, 180}, {0, 0, 0, -90}},
+		},
+		{
+			name:   "fit pillarboxed",
+			policy: ScaleFit,
+			window: [2]int{1200, 360}, fb: [2]int{1200, 360},
+			viewport: [4]int{280, 0, 640, 360},
+			world:    [2]float32{320, 180},
+			scale:    1,
+			points:   []mapping{{280, 0, 0, 0}, {600, 180, 160, 90}, {920, 360, 320, 180}},
+		},
+		{
+			name:   "fill crops",
+			policy: ScaleFill,
+			window: [2]int{960, 1080}, fb: [2]int{960, 1080},
+			viewport: [4]int{0, 0, 960, 1080},
+			world:    [2]float32{160, 180},
+			scale:    1,
+			points:   []mapping{{0, 0, 0, 0}, {480, 540, 80, 90}, {960, 1080, 160, 180}},
+		},
+		{
+			name:   "pixel perfect",
+			policy: ScalePixelPerfect,
+			window: [2]int{1000, 600}, fb: [2]int{1000, 600},
+			viewport: [4]int{20, 30, 960, 540},
+			world:    [2]float32{320, 180},
+			scale:    1,
+			points:   []mapping{{20, 30, 0, 0}, {500, 300, 160, 90}, {980, 570, 320, 180}},
+		},
+		{
+			name:   "pixel perfect below 1x",
+			policy: ScalePixelPerfect,
+			window: [2]int{200, 100}, fb: [2]int{200, 100},
+			viewport: [4]int{-60, -40, 320, 180},
+			world:    [2]float32{320, 180},
+			scale:    1,
+			points:   []mapping{{0, 0, 60, 40}, {100, 50, 160, 90}},
+		},
+		{
+			name:   "extend",
+			policy: ScaleExtend,
+			window: [2]int{960, 1080}, fb: [2]int{960, 1080},
+			viewport: [4]int{0, 0, 960, 1080},
+			world:    [2]float32{320, 360},
+			scale:    1,
+			points:   []mapping{{0, 0, 0, 0}, {480, 540, 160, 180}, {960, 1080, 320, 360}},
+		},
+		{
+			name:   "fit at 2x",
+			policy: ScaleFit,
+			window: [2]int{480, 540}, fb: [2]int{960, 1080},
+			viewport: [4]int{0, 270, 960, 540},
+			world:    [2]float32{320, 180},
+			scale:    2,
+			points:   []mapping{{0, 135, 0, 0}, {240, 270, 160, 90}, {480, 405, 320, 180}},
+		},
+		{
+			name:   "fit at 1.5x",
+			policy: ScaleFit,
+			window: [2]int{640, 360}, fb: [2]int{960, 540},
+			viewport: [4]int{0, 0, 960, 540},
+			world:    [2]float32{320, 180},
+			scale:    1.5,
+			points:   []mapping{{0, 0, 0, 0}, {320, 180, 160, 90}, {640, 360, 320, 180}},
+		},
+		{
+			name:   "pixel perfect at 1.25x",
+			policy: ScalePixelPerfect,
+			window: [2]int{800, 600}, fb: [2]int{1000, 750},
+			viewport: [4]int{20, 105, 960, 540},
+			world:    [2]float32{320, 180},
+			scale:    1.25,
+			points:   []mapping{{16, 84, 0, 0}, {400, 300, 160, 90}, {784, 516, 320, 180}},
+		},
+		{
+			name:   "extend at 1.5x",
+			policy: ScaleExtend,
+			window: [2]int{800, 300}, fb: [2]int{1200, 450},
+			viewport: [4]int{0, 0, 1200, 450},
+			world:    [2]float32{480, 180},
+			scale:    1.5,
+			points:   []mapping{{0, 0, 0, 0}, {400, 150, 240, 90}, {800, 300, 480, 180}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := NewScaler(320, 180, test.policy)
+			camera := NewCamera2D(320, 180)
+			s.cameras = append(s.cameras, camera)
+
+			if !s.resize(test.window[0], test.window[1], test.fb[0], test.fb[1]) {
+				t.Fatal("resize reported a minimized window")
+			}
+
+			x, y, w, h := s.Viewport().Dimensions()
+			if got := [4]int{x, y, w, h}; got != test.viewport {
+				t.Errorf("viewport %v, want %v", got, test.viewport)
+			}
+			ww, wh := s.WorldSize()
+			if ww != test.world[0] || wh != test.world[1] {
+				t.Errorf("world %gx%g, want %gx%g", ww, wh, test.world[0], test.world[1])
+			}
+			if cw, ch := camera.ViewportSize(); cw != ww || ch != wh {
+				t.Errorf("camera viewport %gx%g, want the world size", cw, ch)
+			}
+			if sx, sy := s.ContentScale(); sx != test.scale || sy != test.scale {
+				t.Errorf("content scale %g,%g, want %g", sx, sy, test.scale)
+			}
+
+			for _, p := range test.points {
+				vx, vy := s.WindowToView(p.wx, p.wy)
+				if !near(vx, p.vx) || !near(vy, p.vy) {
+					t.Errorf("window %g,%g maps to %g,%g, want %g,%g", p.wx, p.wy, vx, vy, p.vx, p.vy)
+				}
+			}
+		})
+	}
+}
+
+func TestScalerMinimized(t *testing.T) {
+	s := NewScaler(320, 180, ScaleFit)
+	camera := NewCamera2D(100, 100)
+	s.cameras = append(s.cameras, camera)
+	s.resize(640, 360, 640, 360)
+
+	if s.resize(0, 0, 0, 0) {
+		t.Error("a zero framebuffer wasn't reported as minimized")
+	}
+	x, y, w, h := s.Viewport().Dimensions()
+	if got := [4]int{x, y, w, h}; got != [4]int{0, 0, 640, 360} {
+		t.Errorf("viewport %v changed while minimized", got)
+	}
+	if sx, sy := s.ContentScale(); sx != 1 || sy != 1 {
+		t.Errorf("content scale %g,%g while minimized", sx, sy)
+	}
+	if w, h := camera.ViewportSize(); w != 320 || h != 180 {
+		t.Errorf("camera resized to %gx%g while minimized", w, h)
+	}
+}
+
+func TestScalingPolicyString(t *testing.T) {
+	tests := map[ScalingPolicy]string{
+		ScaleStretch:      "stretch",
+		ScaleFit:          "fit",
+		ScaleFill:         "fill",
+		ScalePixelPerfect: "pixel-perfect",
+		ScaleExtend:       "extend",
+		ScalingPolicy(-1): "unknown",
+		ScalingPolicy(99): "unknown",
+	}
+	for policy, want := range tests {
+		if got := policy.String(); got != want {
+			t.Errorf("%d is %q, want %q", int(policy), got, want)
+		}
+	}
+}
@@ -1,7 +1,7 @@
 // Package graphics provides visual
 package display
 
-import "github.com/go-gl/gl/v4.5-core/gl"
+import "SimpleOpenGL-Go/SeparateTexturesWithProjection/glstate"
 
 // Viewport is a basic wrapper of an OpenGL viewport
 type Viewport struct {
@@ -22,7 +22,12 @@ func (v *Viewport) SetDimensions(x, y, width, height int) {
 	v.height = int32(height)
 }
 
+// Dimensions returns the viewport's position and size
+func (v *Viewport) Dimensions() (x, y, width, height int) {
+	return int(v.x), int(v.y), int(v.width), int(v.height)
+}
+
 // Apply set the actual OpenGL viewport
 func (v *Viewport) Apply() {
-	gl.Viewport(v.x, v.y, v.width, v.height)
+	glstate.Viewport(v.x, v.y, v.width, v.height)
 }
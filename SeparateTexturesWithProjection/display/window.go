@@ -0,0 +1,216 @@
+package display
+
+import (
+	"fmt"
+
+	"github.com/go-gl/glfw/v3.3/glfw"
+)
+
+// glVersions are the core profiles tried, newest first, when the
+// configured version isn't available. The shaders are GLSL 4.50 and the
+// bindings 4.5 core, so nothing older can run them.
+var glVersions = [][2]int{{4, 6}, {4, 5}}
+
+// Window owns the glfw window, its context and the display state the
+// demo toggles.
+type Window struct {
+	window *glfw.Window
+	config Config
+
+	glMajor, glMinor int
+
+	quit        bool
+	polygonMode bool
+	pointMode   bool
+
+	// The windowed placement restored when leaving fullscreen
+	windowedX, windowedY, windowedWidth, windowedHeight int
+}
+
+// NewWindow initializes glfw and opens a window with a current context.
+// Call Destroy to terminate glfw.
+func NewWindow(config Config) (*Window, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	if err := glfw.Init(); err != nil {
+		return nil, err
+	}
+
+	w := new(Window)
+	w.config = config
+
+	var monitor *glfw.Monitor
+	width, height := config.Width, config.Height
+	refreshRate := 0
+	if config.Fullscreen {
+		monitor = glfw.GetPrimaryMonitor()
+		mode := monitor.GetVideoMode()
+		width, height = mode.Width, mode.Height
+		refreshRate = mode.RefreshRate
+	}
+
+	var err error
+	for _, v := range w.versions() {
+		w.hints(v[0], v[1], refreshRate)
+		w.window, err = glfw.CreateWindow(width, height, config.Title, monitor, nil)
+		if err == nil {
+			w.glMajor, w.glMinor = v[0], v[1]
+			break
+		}
+	}
+	if w.window == nil {
+		glfw.Terminate()
+		return nil, fmt.Errorf("display: couldn't create an OpenGL %d.%d to 4.5 context: %w", config.GLMajor, config.GLMinor, err)
+	}
+
+	w.window.MakeContextCurrent()
+	glfw.SwapInterval(config.SwapInterval)
+
+	w.windowedX, w.windowedY = 100, 100
+	w.windowedWidth, w.windowedHeight = config.Width, config.Height
+
+	return w, nil
+}
+
+// versions lists the configured version followed by older ones down to
+// 4.5.
+func (w *Window) versions() [][2]int {
+	versions := [][2]int{{w.config.GLMajor, w.config.GLMinor}}
+	for _, v := range glVersions {
+		if v[0] < w.config.GLMajor || (v[0] == w.config.GLMajor && v[1] < w.config.GLMinor) {
+			versions = append(versions, v)
+		}
+	}
+	return versions
+}
+
+// hints resets the window hints for a context of the version, with the
+// monitor's refresh rate in fullscreen or 0 when windowed.
+func (w *Window) hints(major, minor, refreshRate int) {
+	glfw.DefaultWindowHints()
+
+	if refreshRate > 0 {
+		glfw.WindowHint(glfw.RefreshRate, refreshRate)
+	}
+
+	glfw.WindowHint(glfw.Resizable, glfwBool(w.config.Resizable))
+	glfw.WindowHint(glfw.ContextVersionMajor, major)
+	glfw.WindowHint(glfw.ContextVersionMinor, minor)
+	glfw.WindowHint(glfw.OpenGLProfile, glfw.OpenGLCoreProfile)
+	glfw.WindowHint(glfw.OpenGLForwardCompatible, glfw.True)
+	glfw.WindowHint(glfw.OpenGLDebugContext, glfwBool(w.config.Debug))
+	glfw.WindowHint(glfw.Samples, w.config.Samples)
+
+	// Size the window in screen coordinates on HiDPI monitors, the
+	// framebuffer gets the extra pixels.
+	glfw.WindowHint(glfw.ScaleToMonitor, glfw.True)
+	glfw.WindowHint(glfw.CocoaRetinaFramebuffer, glfw.True)
+}
+
+func glfwBool(b bool) int {
+	if b {
+		return glfw.True
+	}
+	return glfw.False
+}
+
+// GLFW returns the underlying glfw window, e.g. to set callbacks.
+func (w *Window) GLFW() *glfw.Window {
+	return w.window
+}
+
+// Config returns the settings the window was created with.
+func (w *Window) Config() Config {
+	return w.config
+}
+
+// GLVersion returns the version of the context that was created, which
+// may be older than the configured one.
+func (w *Window) GLVersion() (major, minor int) {
+	return w.glMajor, w.glMinor
+}
+
+// Quit asks the main loop to stop.
+func (w *Window) Quit() {
+	w.quit = true
+}
+
+// ShouldClose reports whether the window was closed or Quit called.
+func (w *Window) ShouldClose() bool {
+	return w.quit || w.window.ShouldClose()
+}
+
+// PollEvents processes pending events, running the callbacks.
+func (w *Window) PollEvents() {
+	glfw.PollEvents()
+}
+
+// WaitEvents sleeps until an event arrives or timeout seconds pass.
+func (w *Window) WaitEvents(timeout float64) {
+	glfw.WaitEventsTimeout(timeout)
+}
+
+// Focused reports whether the window has input focus.
+func (w *Window) Focused() bool {
+	return w.window.GetAttrib(glfw.Focused) == glfw.True
+}
+
+// FramebufferSize returns the size in pixels, larger than the window size
+// on HiDPI displays.
+func (w *Window) FramebufferSize() (width, height int) {
+	return w.window.GetFramebufferSize()
+}
+
+// SwapBuffers presents the frame.
+func (w *Window) SwapBuffers() {
+	w.window.SwapBuffers()
+}
+
+// Destroy closes the window and terminates glfw.
+func (w *Window) Destroy() {
+	w.window.Destroy()
+	glfw.Terminate()
+}
+
+// PolygonMode reports whether wireframe mode is on.
+func (w *Window) PolygonMode() bool {
+	return w.polygonMode
+}
+
+// SetPolygonMode records wireframe mode, the caller sets the GL state.
+func (w *Window) SetPolygonMode(on bool) {
+	w.polygonMode = on
+}
+
+// PointMode reports whether point mode is on.
+func (w *Window) PointMode() bool {
+	return w.pointMode
+}
+
+// SetPointMode records point mode, the caller sets the GL state.
+func (w *Window) SetPointMode(on bool) {
+	w.pointMode = on
+}
+
+// Fullscreen reports whether the window covers a monitor.
+func (w *Window) Fullscreen() bool {
+	return w.window.GetMonitor() != nil
+}
+
+// ToggleFullscreen switches between fullscreen on the primary monitor and
+// the previous windowed placement.
+func (w *Window) ToggleFullscreen() {
+	if w.Fullscreen() {
+		w.window.SetMonitor(nil, w.windowedX, w.windowedY, w.windowedWidth, w.windowedHeight, 0)
+		return
+	}
+
+	w.windowedX, w.windowedY = w.window.GetPos()
+	w.windowedWidth, w.windowedHeight = w.window.GetSize()
+
+	monitor := glfw.GetPrimaryMonitor()
+	mode := monitor.GetVideoMode()
+	w.window.SetMonitor(monitor, 0, 0, mode.Width, mode.Height, mode.RefreshRate)
+}
@@ -0,0 +1,65 @@
+package geometry
+
+import (
+	"sort"
+)
+
+// ConvexHull returns the convex hull of a set of x,y pairs as a CCW
+// polygon using Andrew's monotone chain. Collinear and duplicate points
+// are dropped.
+func ConvexHull(points []float32) []float32 {
+	pts := toVecs(points)
+	sort.Slice(pts, func(i, j int) bool {
+		if pts[i].x == pts[j].x {
+			return pts[i].y < pts[j].y
+		}
+		return pts[i].x < pts[j].x
+	})
+	pts = dedupe(pts)
+
+	if len(pts) < 3 {
+		return fromVecs(pts)
+	}
+
+	hull := make([]vec2, 0, len(pts)*2)
+
+	// Lower hull
+	for _, p := range pts {
+		for len(hull) >= 2 && cross(hull[len(hull)-2], hull[len(hull)-1], p) <= 0 {
+			hull = hull[:len(hull)-1]
+		}
+		hull = append(hull, p)
+	}
+
+	// Upper hull
+	lower := len(hull) + 1
+	for i := len(pts) - 2; i >= 0; i-- {
+		p := pts[i]
+		for len(hull) >= lower && cross(hull[len(hull)-2], hull[len(hull)-1], p) <= 0 {
+			hull = hull[:len(hull)-1]
+		}
+		hull = append(hull, p)
+	}
+
+	// The last point is the first one again
+	hull = hull[:len(hull)-1]
+
+	return fromVecs(hull)
+}
+
+func fromVecs(vs []vec2) []float32 {
+	out := make([]float32, 0, len(vs)*2)
+	for _, p := range vs {
+		out = append(out, float32(p.x), float32(p.y))
+	}
+	return out
+}
+
+// FanIndices returns triangle indices for a convex polygon of n vertices.
+func FanIndices(n int) []uint32 {
+	indices := []uint32{}
+	for i := 1; i < n-1; i++ {
+		indices = append(indices, 0, uint32(i), uint32(i+1))
+	}
+	return indices
+}
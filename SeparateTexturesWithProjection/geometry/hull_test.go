@@ -0,0 +1,91 @@
+package geometry
+
+import (
+	"testing"
+)
+
+func TestConvexHull(t *testing.T) {
+	tests := []struct {
+		name   string
+		points []float32
+		want   []float32
+	}{
+		{
+			name:   "square",
+			points: []float32{0, 0, 2, 0, 2, 2, 0, 2},
+			want:   []float32{0, 0, 2, 0, 2, 2, 0, 2},
+		},
+		{
+			name:   "clockwise with an interior point",
+			points: []float32{0, 2, 2, 2, 1, 1, 2, 0, 0, 0},
+			want:   []float32{0, 0, 2, 0, 2, 2, 0, 2},
+		},
+		{
+			name:   "collinear edge points",
+			points: []float32{0, 0, 1, 0, 2, 0, 2, 1, 2, 2, 1, 2, 0, 2, 0, 1},
+			want:   []float32{0, 0, 2, 0, 2, 2, 0, 2},
+		},
+		{
+			name:   "duplicate points",
+			points: []float32{0, 0, 2, 0, 0, 0, 2, 2, 2, 2, 0, 2, 2, 0},
+			want:   []float32{0, 0, 2, 0, 2, 2, 0, 2},
+		},
+		{
+			name:   "all on a line",
+			points: []float32{3, 0, 0, 0, 1, 0, 2, 0},
+			want:   []float32{0, 0, 3, 0},
+		},
+		{
+			name:   "one point repeated",
+			points: []float32{1, 1, 1, 1, 1, 1},
+			want:   []float32{1, 1},
+		},
+		{
+			name:   "two points repeated",
+			points: []float32{1, 1, 0, 0, 1, 1},
+			want:   []float32{0, 0, 1, 1},
+		},
+		{
+			name:   "empty",
+			points: nil,
+			want:   []float32{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := ConvexHull(test.points)
+			if !equalFloats(got, test.want) {
+				t.Fatalf("got %v, want %v", got, test.want)
+			}
+			if len(got) >= 6 && SignedArea(got) <= 0 {
+				t.Errorf("%v isn't CCW", got)
+			}
+		})
+	}
+}
+
+func TestFanIndices(t *testing.T) {
+	got := FanIndices(5)
+	want := []uint32{0, 1, 2, 0, 2, 3, 0, 3, 4}
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("got %v, want %v", got, want)
+		}
+	}
+}
+
+func equalFloats(a, b []float32) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
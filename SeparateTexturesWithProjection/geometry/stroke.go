@@ -0,0 +1,227 @@
+package geometry
+
+import (
+	"math"
+)
+
+// JoinStyle selects how consecutive segments of a stroke are connected.
+type JoinStyle int
+
+const (
+	// JoinMiter extends the edges until they meet, falling back to a
+	// bevel when the point would exceed the miter limit.
+	JoinMiter JoinStyle = iota
+	// JoinBevel cuts the corner with a single triangle.
+	JoinBevel
+	// JoinRound fills the corner with an arc.
+	JoinRound
+)
+
+// StrokeOptions controls Stroke.
+type StrokeOptions struct {
+	Width float32
+	Join  JoinStyle
+	// MiterLimit is the maximum ratio of miter length to half the width.
+	// Zero means 4.
+	MiterLimit float32
+	// RoundSegments is the number of segments for a half circle. Zero
+	// means 8.
+	RoundSegments int
+	// Closed connects the last point back to the first.
+	Closed bool
+}
+
+// Stroke turns a polyline into a triangle strip of the given width. The
+// ends use butt caps. The returned indices are in strip order (use
+// GL_TRIANGLE_STRIP), see StripToTriangles for a GL_TRIANGLES list.
+func Stroke(points []float32, opts StrokeOptions) (vertices []float32, indices []uint32) {
+	pts := dedupe(toVecs(points))
+	if opts.Closed && len(pts) > 2 && pts[0] == pts[len(pts)-1] {
+		pts = pts[:len(pts)-1]
+	}
+
+	n := len(pts)
+	if n < 2 {
+		return nil, nil
+	}
+
+	half := float64(opts.Width) / 2.0
+	limit := float64(opts.MiterLimit)
+	if limit == 0 {
+		limit = 4.0
+	}
+	segments := opts.RoundSegments
+	if segments == 0 {
+		segments = 8
+	}
+
+	emit := func(l, r vec2) {
+		vertices = append(vertices, float32(l.x), float32(l.y), float32(r.x), float32(r.y))
+	}
+
+	// Left hand normal of the segment a->b scaled to half the width
+	normal := func(a, b vec2) vec2 {
+		d := b.sub(a)
+		length := math.Hypot(d.x, d.y)
+		return vec2{-d.y / length * half, d.x / length * half}
+	}
+
+	count := n
+	if opts.Closed {
+		count = n + 1 // revisit the first point to close the strip
+	}
+
+	for k := 0; k < count; k++ {
+		i := k % n
+		p := pts[i]
+
+		hasPrev := opts.Closed || i > 0
+		hasNext := opts.Closed || i < n-1
+		if k == n {
+			hasNext = true
+		}
+
+		if !hasPrev {
+			nn := normal(p, pts[i+1])
+			emit(vec2{p.x + nn.x, p.y + nn.y}, vec2{p.x - nn.x, p.y - nn.y})
+			continue
+		}
+		prev := pts[(i+n-1)%n]
+		n0 := normal(prev, p)
+
+		if !hasNext {
+			emit(vec2{p.x + n0.x, p.y + n0.y}, vec2{p.x - n0.x, p.y - n0.y})
+			continue
+		}
+		next := pts[(i+1)%n]
+		n1 := normal(p, next)
+
+		if opts.Closed && k == n {
+			// Finish where the first pair started so the strip closes
+			// without overlapping the first join.
+			m := vertices[:4]
+			emit(vec2{float64(m[0]), float64(m[1])}, vec2{float64(m[2]), float64(m[3])})
+			continue
+		}
+
+		join(p, n0, n1, half, limit, segments, opts.Join, emit)
+	}
+
+	for i := 0; i < len(vertices)/2; i++ {
+		indices = append(indices, uint32(i))
+	}
+
+	return vertices, indices
+}
+
+// join emits the vertex pairs for the corner at p between a segment with
+// normal n0 and one with normal n1.
+func join(p, n0, n1 vec2, half, limit float64, segments int, style JoinStyle, emit func(l, r vec2)) {
+	turn := n0.x*n1.y - n0.y*n1.x // > 0 turning left
+
+	// Miter direction is the normalized sum of the normals
+	mx, my := n0.x+n1.x, n0.y+n1.y
+	ml := math.Hypot(mx, my)
+	cosHalf := 0.0
+	if ml > 0 {
+		cosHalf = (mx*n0.x + my*n0.y) / (ml * half)
+	}
+
+	var miter vec2
+	miterOK := cosHalf > 1e-6
+	if miterOK {
+		length := half / cosHalf
+		miter = vec2{mx / ml * length, my / ml * length}
+	}
+
+	if math.Abs(turn) < 1e-9 || (style == JoinMiter && miterOK && half/cosHalf <= limit*half) {
+		if !miterOK {
+			miter = n0
+		}
+		emit(vec2{p.x + miter.x, p.y + miter.y}, vec2{p.x - miter.x, p.y - miter.y})
+		return
+	}
+
+	// For bevel and round the inner side uses the miter point, limited so
+	// very sharp corners don't shoot past the segments, and the outer
+	// side fans.
+	inner := miter
+	if !miterOK {
+		inner = vec2{(n0.x + n1.x) / 2.0, (n0.y + n1.y) / 2.0}
+	} else if length := half / cosHalf; length > limit*half {
+		s := limit * half / length
+		inner = vec2{miter.x * s, miter.y * s}
+	}
+
+	if turn > 0 {
+		// Turning left: the outer side is on the right (-normal)
+		in := vec2{p.x + inner.x, p.y + inner.y}
+		for _, o := range arc(p, vec2{-n0.x, -n0.y}, vec2{-n1.x, -n1.y}, style, segments) {
+			emit(in, o)
+		}
+	} else {
+		in := vec2{p.x - inner.x, p.y - inner.y}
+		for _, o := range arc(p, n0, n1, style, segments) {
+			emit(o, in)
+		}
+	}
+}
+
+// arc returns the outer corner points from p+a to p+b. Bevels only have
+// the two end points.
+func arc(p, a, b vec2, style JoinStyle, segments int) []vec2 {
+	if style != JoinRound {
+		return []vec2{{p.x + a.x, p.y + a.y}, {p.x + b.x, p.y + b.y}}
+	}
+
+	a0 := math.Atan2(a.y, a.x)
+	a1 := math.Atan2(b.y, b.x)
+	delta := a1 - a0
+	for delta > math.Pi {
+		delta -= 2 * math.Pi
+	}
+	for delta < -math.Pi {
+		delta += 2 * math.Pi
+	}
+
+	r := math.Hypot(a.x, a.y)
+	steps := int(math.Ceil(math.Abs(delta) / math.Pi * float64(segments)))
+	if steps < 1 {
+		steps = 1
+	}
+
+	out := make([]vec2, 0, steps+1)
+	for s := 0; s <= steps; s++ {
+		t := a0 + delta*float64(s)/float64(steps)
+		out = append(out, vec2{p.x + math.Cos(t)*r, p.y + math.Sin(t)*r})
+	}
+	return out
+}
+
+func dedupe(pts []vec2) []vec2 {
+	out := pts[:0:0]
+	for i, p := range pts {
+		if i > 0 && p == pts[i-1] {
+			continue
+		}
+		out = append(out, p)
+	}
+	return out
+}
+
+// StripToTriangles converts triangle strip indices into a triangle list
+// with consistent winding, skipping degenerate triangles.
+func StripToTriangles(strip []uint32) []uint32 {
+	out := []uint32{}
+	for i := 2; i < len(strip); i++ {
+		a, b, c := strip[i-2], strip[i-1], strip[i]
+		if a == b || b == c || a == c {
+			continue
+		}
+		if i%2 == 1 {
+			a, b = b, a
+		}
+		out = append(out, a, b, c)
+	}
+	return out
+}
@@ -0,0 +1,184 @@
+package geometry
+
+import (
+	"math"
+	"testing"
+)
+
+func TestStroke(t *testing.T) {
+	corner := []float32{0, 0, 10, 0, 10, 10}
+
+	tests := []struct {
+		name   string
+		points []float32
+		opts   StrokeOptions
+		// Vertices in the strip
+		count int
+	}{
+		{
+			name:   "miter",
+			points: corner,
+			opts:   StrokeOptions{Width: 2, Join: JoinMiter},
+			count:  6,
+		},
+		{
+			name:   "bevel",
+			points: corner,
+			opts:   StrokeOptions{Width: 2, Join: JoinBevel},
+			count:  8,
+		},
+		{
+			// A quarter turn is half of the 8 segments per half circle
+			name:   "round",
+			points: corner,
+			opts:   StrokeOptions{Width: 2, Join: JoinRound},
+			count:  14,
+		},
+		{
+			name:   "round segments",
+			points: corner,
+			opts:   StrokeOptions{Width: 2, Join: JoinRound, RoundSegments: 4},
+			count:  10,
+		},
+		{
+			// A right angle's miter is sqrt(2) times half the width
+			name:   "miter over the limit",
+			points: corner,
+			opts:   StrokeOptions{Width: 2, Join: JoinMiter, MiterLimit: 1.2},
+			count:  8,
+		},
+		{
+			name:   "sharp miter",
+			points: []float32{0, 0, 10, 0, 0, 1},
+			opts:   StrokeOptions{Width: 2, Join: JoinMiter},
+			count:  8,
+		},
+		{
+			name:   "collinear",
+			points: []float32{0, 0, 5, 0, 10, 0},
+			opts:   StrokeOptions{Width: 2, Join: JoinRound},
+			count:  6,
+		},
+		{
+			name:   "duplicate points",
+			points: []float32{0, 0, 0, 0, 10, 0, 10, 0},
+			opts:   StrokeOptions{Width: 2},
+			count:  4,
+		},
+		{
+			name:   "closed",
+			points: square(0, 0, 10),
+			opts:   StrokeOptions{Width: 2, Closed: true},
+			count:  10,
+		},
+		{
+			name:   "closed repeating the first point",
+			points: append(square(0, 0, 10), 0, 0),
+			opts:   StrokeOptions{Width: 2, Closed: true},
+			count:  10,
+		},
+		{
+			name:   "one point",
+			points: []float32{0, 0, 0, 0},
+			opts:   StrokeOptions{Width: 2},
+			count:  0,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			vertices, indices := Stroke(test.points, test.opts)
+			if len(vertices) != test.count*2 {
+				t.Errorf("%d vertex floats, want %d", len(vertices), test.count*2)
+			}
+			if len(indices) != test.count {
+				t.Fatalf("%d indices, want %d", len(indices), test.count)
+			}
+			for i, index := range indices {
+				if index != uint32(i) {
+					t.Fatalf("index %d is %d", i, index)
+				}
+			}
+		})
+	}
+}
+
+func TestStrokeArea(t *testing.T) {
+	tests := []struct {
+		name   string
+		points []float32
+		opts   StrokeOptions
+		area   float64
+	}{
+		{
+			name:   "segment",
+			points: []float32{0, 0, 10, 0},
+			opts:   StrokeOptions{Width: 2},
+			area:   20,
+		},
+		{
+			// The outer square minus the inner one
+			name:   "closed square",
+			points: square(0, 0, 10),
+			opts:   StrokeOptions{Width: 2, Closed: true},
+			area:   144 - 64,
+		},
+		{
+			// An 11x2 arm out to the miter point and a 2x9 arm
+			name:   "miter corner",
+			points: []float32{0, 0, 10, 0, 10, 10},
+			opts:   StrokeOptions{Width: 2, Join: JoinMiter},
+			area:   22 + 18,
+		},
+		{
+			// The miter less the corner cut off by the bevel
+			name:   "bevel corner",
+			points: []float32{0, 0, 10, 0, 10, 10},
+			opts:   StrokeOptions{Width: 2, Join: JoinBevel},
+			area:   22 + 18 - 0.5,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			vertices, strip := Stroke(test.points, test.opts)
+			triangles := StripToTriangles(strip)
+
+			pts := toVecs(vertices)
+			area := 0.0
+			for i := 0; i < len(triangles); i += 3 {
+				area += math.Abs(cross(pts[triangles[i]], pts[triangles[i+1]], pts[triangles[i+2]])) / 2.0
+			}
+			if math.Abs(area-test.area) > 1e-3 {
+				t.Errorf("triangles cover %g, want %g", area, test.area)
+			}
+		})
+	}
+}
+
+func TestStripToTriangles(t *testing.T) {
+	tests := []struct {
+		name  string
+		strip []uint32
+		want  []uint32
+	}{
+		{"quad", []uint32{0, 1, 2, 3}, []uint32{0, 1, 2, 2, 1, 3}},
+		{"three quads", []uint32{0, 1, 2, 3, 4, 5}, []uint32{0, 1, 2, 2, 1, 3, 2, 3, 4, 4, 3, 5}},
+		{"degenerate", []uint32{0, 1, 1, 2, 3}, []uint32{1, 2, 3}},
+		{"too short", []uint32{0, 1}, []uint32{}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := StripToTriangles(test.strip)
+			if len(got) != len(test.want) {
+				t.Fatalf("got %v, want %v", got, test.want)
+			}
+			for i := range got {
+				if got[i] != test.want[i] {
+					t.Fatalf("got %v, want %v", got, test.want)
+				}
+			}
+		})
+	}
+}
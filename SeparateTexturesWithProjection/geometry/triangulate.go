@@ -0,0 +1,291 @@
+// Package geometry provides polygon utilities that produce vertex and
+// index slices ready for a VBO/EBO upload.
+//
+// Polygons and polylines are flat x,y pairs, the same form the
+// ShapeRenderer takes. Vertices returned are x,y pairs too, use To3D
+// for the x,y,z layouts the renderers upload.
+package geometry
+
+import (
+	"errors"
+	"math"
+)
+
+// ErrNotSimple is returned when a polygon self intersects or is
+// degenerate so no ear can be found.
+var ErrNotSimple = errors.New("geometry: polygon is not simple")
+
+type vec2 struct {
+	x, y float64
+}
+
+func (a vec2) sub(b vec2) vec2 {
+	return vec2{a.x - b.x, a.y - b.y}
+}
+
+func cross(o, a, b vec2) float64 {
+	return (a.x-o.x)*(b.y-o.y) - (a.y-o.y)*(b.x-o.x)
+}
+
+func toVecs(points []float32) []vec2 {
+	vs := make([]vec2, len(points)/2)
+	for i := range vs {
+		vs[i] = vec2{float64(points[i*2]), float64(points[i*2+1])}
+	}
+	return vs
+}
+
+// SignedArea returns the area of a polygon, positive when the points
+// wind counter clockwise.
+func SignedArea(points []float32) float32 {
+	return float32(signedArea(toVecs(points)))
+}
+
+func signedArea(vs []vec2) float64 {
+	area := 0.0
+	for i := range vs {
+		j := (i + 1) % len(vs)
+		area += vs[i].x*vs[j].y - vs[j].x*vs[i].y
+	}
+	return area / 2.0
+}
+
+// Triangulate splits a simple polygon, optionally with holes, into
+// triangles using ear clipping. Either winding is accepted for the
+// outline and the holes. The returned vertices are the outline followed
+// by each hole, and the indices (CCW triangles) refer to them.
+func Triangulate(outline []float32, holes ...[]float32) (vertices []float32, indices []uint32, err error) {
+	if len(outline) < 6 {
+		return nil, nil, ErrNotSimple
+	}
+
+	vertices = append(vertices, outline...)
+	for _, hole := range holes {
+		vertices = append(vertices, hole...)
+	}
+	pts := toVecs(vertices)
+
+	// The outline runs CCW
+	outer := makeRing(0, len(outline)/2)
+	if signedArea(ringPoints(pts, outer)) < 0 {
+		reverse(outer)
+	}
+
+	// Holes run CW and are merged into the outline through bridges,
+	// starting with the hole that reaches furthest right.
+	base := len(outline) / 2
+	rings := [][]int{}
+	for _, hole := range holes {
+		n := len(hole) / 2
+		if n < 3 {
+			base += n
+			continue
+		}
+		ring := makeRing(base, n)
+		if signedArea(ringPoints(pts, ring)) > 0 {
+			reverse(ring)
+		}
+		rings = append(rings, ring)
+		base += n
+	}
+
+	sortByMaxX(pts, rings)
+	for _, ring := range rings {
+		outer = bridge(pts, outer, ring)
+	}
+
+	indices, err = earClip(pts, outer)
+	return vertices, indices, err
+}
+
+func makeRing(start, n int) []int {
+	ring := make([]int, n)
+	for i := range ring {
+		ring[i] = start + i
+	}
+	return ring
+}
+
+func ringPoints(pts []vec2, ring []int) []vec2 {
+	vs := make([]vec2, len(ring))
+	for i, idx := range ring {
+		vs[i] = pts[idx]
+	}
+	return vs
+}
+
+func reverse(ring []int) {
+	for i, j := 0, len(ring)-1; i < j; i, j = i+1, j-1 {
+		ring[i], ring[j] = ring[j], ring[i]
+	}
+}
+
+func maxX(pts []vec2, ring []int) (at int, x float64) {
+	x = math.Inf(-1)
+	for i, idx := range ring {
+		if pts[idx].x > x {
+			at, x = i, pts[idx].x
+		}
+	}
+	return at, x
+}
+
+func sortByMaxX(pts []vec2, rings [][]int) {
+	// Insertion sort, there are never many holes
+	for i := 1; i < len(rings); i++ {
+		for j := i; j > 0; j-- {
+			_, a := maxX(pts, rings[j-1])
+			_, b := maxX(pts, rings[j])
+			if a >= b {
+				break
+			}
+			rings[j-1], rings[j] = rings[j], rings[j-1]
+		}
+	}
+}
+
+// bridge joins a hole to the polygon by finding a polygon vertex visible
+// from the hole's rightmost vertex (Eberly, "Triangulation by Ear Clipping").
+func bridge(pts []vec2, poly, hole []int) []int {
+	hi, _ := maxX(pts, hole)
+	m := pts[hole[hi]]
+
+	// Cast a ray to +x and find the closest edge it hits.
+	best := -1
+	bestX := math.Inf(1)
+	for i := range poly {
+		a := pts[poly[i]]
+		b := pts[poly[(i+1)%len(poly)]]
+
+		// The polygon is CCW so edges crossing the ray upwards face it.
+		if (a.y > m.y) == (b.y > m.y) {
+			continue
+		}
+		x := a.x + (m.y-a.y)*(b.x-a.x)/(b.y-a.y)
+		if x < m.x || x >= bestX {
+			continue
+		}
+		bestX = x
+		// Candidate is the endpoint with the larger x
+		if a.x > b.x {
+			best = i
+		} else {
+			best = (i + 1) % len(poly)
+		}
+	}
+
+	if best < 0 {
+		// The hole isn't inside the polygon, leave it out
+		return poly
+	}
+
+	// Any reflex vertex inside the triangle (m, hit, candidate) could
+	// block the view, pick the one with the smallest angle to the ray.
+	hit := vec2{bestX, m.y}
+	p := pts[poly[best]]
+	bestAngle := math.Inf(1)
+	for i, idx := range poly {
+		v := pts[idx]
+		if idx == poly[best] || !isReflex(pts, poly, i) {
+			continue
+		}
+		if !pointInTriangle(v, m, hit, p) && !pointInTriangle(v, m, p, hit) {
+			continue
+		}
+		d := v.sub(m)
+		angle := math.Abs(math.Atan2(d.y, d.x))
+		if angle < bestAngle || (angle == bestAngle && d.x < p.sub(m).x) {
+			bestAngle = angle
+			best = i
+		}
+	}
+
+	// poly[..best], hole from hi around to hi, back to poly[best], rest.
+	merged := make([]int, 0, len(poly)+len(hole)+2)
+	merged = append(merged, poly[:best+1]...)
+	for k := 0; k <= len(hole); k++ {
+		merged = append(merged, hole[(hi+k)%len(hole)])
+	}
+	merged = append(merged, poly[best])
+	merged = append(merged, poly[best+1:]...)
+
+	return merged
+}
+
+func isReflex(pts []vec2, ring []int, i int) bool {
+	n := len(ring)
+	a := pts[ring[(i+n-1)%n]]
+	b := pts[ring[i]]
+	c := pts[ring[(i+1)%n]]
+	return cross(a, b, c) < 0
+}
+
+func pointInTriangle(p, a, b, c vec2) bool {
+	return cross(a, b, p) >= 0 && cross(b, c, p) >= 0 && cross(c, a, p) >= 0
+}
+
+func earClip(pts []vec2, ring []int) ([]uint32, error) {
+	indices := make([]uint32, 0, (len(ring)-2)*3)
+	ring = append([]int(nil), ring...)
+
+	for len(ring) > 3 {
+		n := len(ring)
+		clipped := false
+
+		for i := 0; i < n; i++ {
+			ia, ib, ic := ring[(i+n-1)%n], ring[i], ring[(i+1)%n]
+			a, b, c := pts[ia], pts[ib], pts[ic]
+
+			c0 := cross(a, b, c)
+			if c0 < 0 {
+				continue // reflex
+			}
+
+			if c0 > 0 && earContainsPoint(pts, ring, a, b, c) {
+				continue
+			}
+
+			// Collinear vertices (c0 == 0) are dropped without a triangle
+			if c0 > 0 {
+				indices = append(indices, uint32(ia), uint32(ib), uint32(ic))
+			}
+			ring = append(ring[:i], ring[i+1:]...)
+			clipped = true
+			break
+		}
+
+		if !clipped {
+			return indices, ErrNotSimple
+		}
+	}
+
+	if cross(pts[ring[0]], pts[ring[1]], pts[ring[2]]) > 0 {
+		indices = append(indices, uint32(ring[0]), uint32(ring[1]), uint32(ring[2]))
+	}
+
+	return indices, nil
+}
+
+// earContainsPoint tests the remaining vertices against a candidate ear.
+// Vertices that coincide with a corner (bridge duplicates) don't count.
+func earContainsPoint(pts []vec2, ring []int, a, b, c vec2) bool {
+	for _, idx := range ring {
+		p := pts[idx]
+		if p == a || p == b || p == c {
+			continue
+		}
+		if pointInTriangle(p, a, b, c) {
+			return true
+		}
+	}
+	return false
+}
+
+// To3D expands x,y pairs into x,y,z triples with z set to 0.
+func To3D(vertices []float32) []float32 {
+	out := make([]float32, 0, len(vertices)/2*3)
+	for i := 0; i+1 < len(vertices); i += 2 {
+		out = append(out, vertices[i], vertices[i+1], 0.0)
+	}
+	return out
+}
@@ -0,0 +1,159 @@
+package geometry
+
+import (
+	"math"
+	"testing"
+)
+
+// square is a CCW axis aligned square.
+func square(x, y, size float32) []float32 {
+	return []float32{x, y, x + size, y, x + size, y + size, x, y + size}
+}
+
+// reversed returns the x,y pairs in the opposite order, flipping the winding.
+func reversed(points []float32) []float32 {
+	out := make([]float32, 0, len(points))
+	for i := len(points) - 2; i >= 0; i -= 2 {
+		out = append(out, points[i], points[i+1])
+	}
+	return out
+}
+
+// triangleArea sums the signed areas of the indexed triangles.
+func triangleArea(t *testing.T, vertices []float32, indices []uint32) float64 {
+	t.Helper()
+	if len(indices)%3 != 0 {
+		t.Fatalf("%d indices isn't whole triangles", len(indices))
+	}
+	pts := toVecs(vertices)
+	area := 0.0
+	for i := 0; i < len(indices); i += 3 {
+		a, b, c := pts[indices[i]], pts[indices[i+1]], pts[indices[i+2]]
+		tri := cross(a, b, c) / 2.0
+		if tri <= 0 {
+			t.Errorf("triangle %d (%v) isn't CCW", i/3, indices[i:i+3])
+		}
+		area += tri
+	}
+	return area
+}
+
+func TestTriangulate(t *testing.T) {
+	tests := []struct {
+		name    string
+		outline []float32
+		holes   [][]float32
+		// The polygon's area minus the holes
+		area float64
+	}{
+		{
+			name:    "convex",
+			outline: square(0, 0, 10),
+			area:    100,
+		},
+		{
+			name:    "clockwise",
+			outline: reversed(square(0, 0, 10)),
+			area:    100,
+		},
+		{
+			name:    "concave L",
+			outline: []float32{0, 0, 10, 0, 10, 4, 4, 4, 4, 10, 0, 10},
+			area:    64,
+		},
+		{
+			name:    "concave arrow",
+			outline: []float32{0, 0, 5, 3, 10, 0, 5, 10},
+			area:    35,
+		},
+		{
+			name:    "collinear points",
+			outline: []float32{0, 0, 5, 0, 10, 0, 10, 5, 10, 10, 0, 10},
+			area:    100,
+		},
+		{
+			name:    "duplicate point",
+			outline: []float32{0, 0, 10, 0, 10, 0, 10, 10, 0, 10},
+			area:    100,
+		},
+		{
+			name:    "hole",
+			outline: square(0, 0, 10),
+			holes:   [][]float32{square(3, 3, 4)},
+			area:    84,
+		},
+		{
+			name:    "clockwise outline and hole",
+			outline: reversed(square(0, 0, 10)),
+			holes:   [][]float32{reversed(square(3, 3, 4))},
+			area:    84,
+		},
+		{
+			name:    "two holes",
+			outline: square(0, 0, 20),
+			holes:   [][]float32{square(2, 2, 5), square(12, 10, 6)},
+			area:    400 - 25 - 36,
+		},
+		{
+			name:    "hole in a concave outline",
+			outline: []float32{0, 0, 20, 0, 20, 8, 8, 8, 8, 20, 0, 20},
+			holes:   [][]float32{square(2, 2, 4)},
+			area:    20*8 + 8*12 - 16,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			vertices, indices, err := Triangulate(test.outline, test.holes...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			want := len(test.outline)
+			for _, hole := range test.holes {
+				want += len(hole)
+			}
+			if len(vertices) != want {
+				t.Errorf("%d vertex floats, want %d", len(vertices), want)
+			}
+
+			if area := triangleArea(t, vertices, indices); math.Abs(area-test.area) > 1e-3 {
+				t.Errorf("triangles cover %g, want %g", area, test.area)
+			}
+		})
+	}
+}
+
+func TestTriangulateNotSimple(t *testing.T) {
+	tests := []struct {
+		name    string
+		outline []float32
+	}{
+		{"empty", nil},
+		{"two points", []float32{0, 0, 1, 1}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, _, err := Triangulate(test.outline); err != ErrNotSimple {
+				t.Fatalf("error %v, want %v", err, ErrNotSimple)
+			}
+		})
+	}
+}
+
+func TestSignedArea(t *testing.T) {
+	if got := SignedArea(square(0, 0, 3)); got != 9 {
+		t.Errorf("CCW area %g, want 9", got)
+	}
+	if got := SignedArea(reversed(square(0, 0, 3))); got != -9 {
+		t.Errorf("CW area %g, want -9", got)
+	}
+}
+
+func TestTo3D(t *testing.T) {
+	got := To3D([]float32{1, 2, 3, 4})
+	if want := []float32{1, 2, 0, 3, 4, 0}; !equalFloats(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,154 @@
+package gldebug
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"unsafe"
+
+	"github.com/go-gl/gl/v4.5-core/gl"
+)
+
+// Install routes the driver's KHR_debug messages to the logger and reports
+// whether it could, which needs a debug context. Output is synchronous so
+// each message is raised inside the GL call causing it, and the site field
+// points at that call.
+func Install() bool {
+	var flags int32
+	gl.GetIntegerv(gl.CONTEXT_FLAGS, &flags)
+	if flags&gl.CONTEXT_FLAG_DEBUG_BIT == 0 {
+		return false
+	}
+
+	gl.Enable(gl.DEBUG_OUTPUT)
+	gl.Enable(gl.DEBUG_OUTPUT_SYNCHRONOUS)
+	gl.DebugMessageCallback(callback, nil)
+	return true
+}
+
+func callback(source, gltype, id, severity uint32, length int32, message string, userParam unsafe.Pointer) {
+	report(severityOf(severity), strings.TrimSpace(message),
+		Field{"source", sourceName(source)},
+		Field{"type", typeName(gltype)},
+		Field{"id", id},
+		Field{"site", site()},
+	)
+}
+
+func severityOf(severity uint32) Severity {
+	switch severity {
+	case gl.DEBUG_SEVERITY_HIGH:
+		return SeverityHigh
+	case gl.DEBUG_SEVERITY_MEDIUM:
+		return SeverityMedium
+	case gl.DEBUG_SEVERITY_LOW:
+		return SeverityLow
+	}
+	return SeverityNotification
+}
+
+func sourceName(source uint32) string {
+	switch source {
+	case gl.DEBUG_SOURCE_API:
+		return "api"
+	case gl.DEBUG_SOURCE_WINDOW_SYSTEM:
+		return "window system"
+	case gl.DEBUG_SOURCE_SHADER_COMPILER:
+		return "shader compiler"
+	case gl.DEBUG_SOURCE_THIRD_PARTY:
+		return "third party"
+	case gl.DEBUG_SOURCE_APPLICATION:
+		return "application"
+	}
+	return "other"
+}
+
+func typeName(gltype uint32) string {
+	switch gltype {
+	case gl.DEBUG_TYPE_ERROR:
+		return "error"
+	case gl.DEBUG_TYPE_DEPRECATED_BEHAVIOR:
+		return "deprecated"
+	case gl.DEBUG_TYPE_UNDEFINED_BEHAVIOR:
+		return "undefined behavior"
+	case gl.DEBUG_TYPE_PORTABILITY:
+		return "portability"
+	case gl.DEBUG_TYPE_PERFORMANCE:
+		return "performance"
+	case gl.DEBUG_TYPE_MARKER:
+		return "marker"
+	case gl.DEBUG_TYPE_PUSH_GROUP:
+		return "push group"
+	case gl.DEBUG_TYPE_POP_GROUP:
+		return "pop group"
+	}
+	return "other"
+}
+
+// errorName names a glGetError code.
+func errorName(code uint32) string {
+	switch code {
+	case gl.INVALID_ENUM:
+		return "INVALID_ENUM"
+	case gl.INVALID_VALUE:
+		return "INVALID_VALUE"
+	case gl.INVALID_OPERATION:
+		return "INVALID_OPERATION"
+	case gl.STACK_OVERFLOW:
+		return "STACK_OVERFLOW"
+	case gl.STACK_UNDERFLOW:
+		return "STACK_UNDERFLOW"
+	case gl.OUT_OF_MEMORY:
+		return "OUT_OF_MEMORY"
+	case gl.INVALID_FRAMEBUFFER_OPERATION:
+		return "INVALID_FRAMEBUFFER_OPERATION"
+	}
+	return fmt.Sprintf("0x%x", code)
+}
+
+// Packages whose frames aren't the site of a call, they pass it on.
+var passThrough = []string{
+	"runtime.",
+	"github.com/go-gl/gl/",
+	"SimpleOpenGL-Go/SeparateTexturesWithProjection/gldebug.",
+	"SimpleOpenGL-Go/SeparateTexturesWithProjection/glstate.",
+}
+
+// site returns file:line of the first caller outside the pass through
+// packages, "unknown" if the callback came from a driver thread.
+func site() string {
+	pc := make([]uintptr, 32)
+	n := runtime.Callers(2, pc)
+	frames := runtime.CallersFrames(pc[:n])
+	for {
+		frame, more := frames.Next()
+		if frame.Function == "" {
+			return "unknown"
+		}
+		if !isPassThrough(frame.Function) {
+			return fmt.Sprintf("%s:%d", trimPath(frame.File), frame.Line)
+		}
+		if !more {
+			return "unknown"
+		}
+	}
+}
+
+func isPassThrough(function string) bool {
+	for _, prefix := range passThrough {
+		if strings.HasPrefix(function, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// trimPath keeps the package directory and file name.
+func trimPath(file string) string {
+	if i := strings.LastIndex(file, "/"); i >= 0 {
+		if j := strings.LastIndex(file[:i], "/"); j >= 0 {
+			return file[j+1:]
+		}
+	}
+	return file
+}
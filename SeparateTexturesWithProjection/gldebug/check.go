@@ -0,0 +1,22 @@
+//go:build gldebug
+
+package gldebug
+
+import "github.com/go-gl/gl/v4.5-core/gl"
+
+// Enabled reports whether Check is compiled in.
+const Enabled = true
+
+// Check logs every error glGetError has queued, each with the site of the
+// first caller outside glstate. The error happened somewhere since the
+// previous Check, usually in the call just before.
+func Check() {
+	for code := gl.GetError(); code != gl.NO_ERROR; code = gl.GetError() {
+		report(SeverityHigh, "GL error "+errorName(code),
+			Field{"source", "api"},
+			Field{"type", "error"},
+			Field{"id", code},
+			Field{"site", site()},
+		)
+	}
+}
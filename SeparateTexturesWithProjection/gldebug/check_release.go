@@ -0,0 +1,9 @@
+//go:build !gldebug
+
+package gldebug
+
+// Enabled reports whether Check is compiled in.
+const Enabled = false
+
+// Check does nothing without the gldebug build tag.
+func Check() {}
@@ -0,0 +1,118 @@
+// Package gldebug reports OpenGL errors and driver messages through a
+// structured Logger rather than panicking.
+//
+// Two sources feed it:
+//
+//   - Install registers a KHR_debug callback when the context is a debug
+//     context (display.Config.Debug, -gl-debug). Messages arrive as the
+//     driver raises them, with the Go call site that caused them.
+//   - Check polls glGetError. It's compiled in only with the gldebug build
+//     tag (go run -tags gldebug .), in other builds it's an empty function
+//     and costs nothing. glstate checks after each call it makes, the
+//     renderers after their uploads and draws.
+package gldebug
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"sync"
+)
+
+// Severity orders messages, the driver's notifications are the least.
+type Severity int
+
+const (
+	SeverityNotification Severity = iota
+	SeverityLow
+	SeverityMedium
+	SeverityHigh
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityNotification:
+		return "notification"
+	case SeverityLow:
+		return "low"
+	case SeverityMedium:
+		return "medium"
+	case SeverityHigh:
+		return "high"
+	}
+	return fmt.Sprintf("Severity(%d)", int(s))
+}
+
+// Field is a key value pair attached to a message.
+type Field struct {
+	Key   string
+	Value interface{}
+}
+
+// Logger receives the messages that pass the severity filter.
+type Logger interface {
+	Log(severity Severity, msg string, fields ...Field)
+}
+
+// TextLogger writes messages as key=value lines.
+type TextLogger struct {
+	logger *log.Logger
+}
+
+// NewTextLogger creates a logger writing to w with the log package's
+// standard date and time prefix.
+func NewTextLogger(w io.Writer) *TextLogger {
+	o := new(TextLogger)
+	o.logger = log.New(w, "", log.LstdFlags)
+	return o
+}
+
+func (l *TextLogger) Log(severity Severity, msg string, fields ...Field) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "gl severity=%s msg=%q", severity, msg)
+	for _, f := range fields {
+		fmt.Fprintf(&b, " %s=", f.Key)
+		if s, ok := f.Value.(string); ok && strings.ContainsAny(s, " \"=") {
+			fmt.Fprintf(&b, "%q", s)
+		} else {
+			fmt.Fprint(&b, f.Value)
+		}
+	}
+	l.logger.Print(b.String())
+}
+
+var (
+	mu          sync.Mutex
+	logger      Logger = NewTextLogger(log.Writer())
+	minSeverity        = SeverityLow
+)
+
+// SetLogger replaces the logger, the default writes to the log package's
+// output.
+func SetLogger(l Logger) {
+	mu.Lock()
+	defer mu.Unlock()
+	logger = l
+}
+
+// SetMinSeverity drops messages below severity, by default the driver's
+// notifications.
+func SetMinSeverity(severity Severity) {
+	mu.Lock()
+	defer mu.Unlock()
+	minSeverity = severity
+}
+
+// report logs a message if it passes the filter. Drivers may call back
+// from their own threads, hence the lock.
+func report(severity Severity, msg string, fields ...Field) {
+	mu.Lock()
+	l, min := logger, minSeverity
+	mu.Unlock()
+
+	if severity < min || l == nil {
+		return
+	}
+	l.Log(severity, msg, fields...)
+}
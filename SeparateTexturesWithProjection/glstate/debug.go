@@ -0,0 +1,259 @@
+package glstate
+
+import (
+	"SimpleOpenGL-Go/SeparateTexturesWithProjection/gldebug"
+	"fmt"
+	"log"
+
+	"github.com/go-gl/gl/v4.5-core/gl"
+)
+
+// SetDebug turns validation on or off. While on, every call first checks
+// the shadow it's about to rely on against GL, logging any difference and
+// adopting GL's value. It's slow, each check is a round trip to the driver.
+func SetDebug(on bool) {
+	debug = on
+}
+
+// Debug reports whether validation is on.
+func Debug() bool {
+	return debug
+}
+
+// Validate checks every known value of the shadow against GL and reports
+// whether they all matched. Mismatches are logged and the shadow fixed.
+func Validate() bool {
+	ok := checkProgram()
+	ok = checkVertexArray() && ok
+	ok = checkFramebuffers() && ok
+	ok = checkActiveTexture() && ok
+
+	// Checking other units means switching to them
+	if current.activeUnitKnown {
+		active := current.activeUnit
+		for slot := range current.textures {
+			if slot.unit != active {
+				gl.ActiveTexture(slot.unit)
+				gldebug.Check()
+			}
+			ok = checkTexture(slot) && ok
+			if slot.unit != active {
+				gl.ActiveTexture(active)
+				gldebug.Check()
+			}
+		}
+	}
+
+	for capability := range current.caps {
+		ok = checkCapability(capability) && ok
+	}
+	ok = checkBlendFunc() && ok
+	ok = checkDepthFunc() && ok
+	ok = checkCullFace() && ok
+	ok = checkPolygonMode() && ok
+	ok = checkPointSize() && ok
+	ok = checkViewport() && ok
+	return ok
+}
+
+func mismatch(what string, cached, actual interface{}) {
+	log.Printf("glstate: %s is %v but the cache has %v, something bypassed glstate", what, actual, cached)
+}
+
+func getInteger(name uint32) uint32 {
+	var v int32
+	gl.GetIntegerv(name, &v)
+	gldebug.Check()
+	return uint32(v)
+}
+
+func checkProgram() bool {
+	if !current.programKnown {
+		return true
+	}
+	if actual := getInteger(gl.CURRENT_PROGRAM); actual != current.program {
+		mismatch("the program", current.program, actual)
+		current.program = actual
+		return false
+	}
+	return true
+}
+
+func checkVertexArray() bool {
+	if !current.vaoKnown {
+		return true
+	}
+	if actual := getInteger(gl.VERTEX_ARRAY_BINDING); actual != current.vao {
+		mismatch("the vertex array", current.vao, actual)
+		current.vao = actual
+		return false
+	}
+	return true
+}
+
+func checkFramebuffers() bool {
+	ok := true
+	if current.drawFboKnown {
+		if actual := getInteger(gl.DRAW_FRAMEBUFFER_BINDING); actual != current.drawFbo {
+			mismatch("the draw framebuffer", current.drawFbo, actual)
+			current.drawFbo = actual
+			ok = false
+		}
+	}
+	if current.readFboKnown {
+		if actual := getInteger(gl.READ_FRAMEBUFFER_BINDING); actual != current.readFbo {
+			mismatch("the read framebuffer", current.readFbo, actual)
+			current.readFbo = actual
+			ok = false
+		}
+	}
+	return ok
+}
+
+func checkActiveTexture() bool {
+	if !current.activeUnitKnown {
+		return true
+	}
+	if actual := getInteger(gl.ACTIVE_TEXTURE); actual != current.activeUnit {
+		mismatch("the active texture unit", current.activeUnit-gl.TEXTURE0, actual-gl.TEXTURE0)
+		current.activeUnit = actual
+		return false
+	}
+	return true
+}
+
+// textureBindings are the queries for the targets that can be checked.
+var textureBindings = map[uint32]uint32{
+	gl.TEXTURE_2D:             gl.TEXTURE_BINDING_2D,
+	gl.TEXTURE_2D_ARRAY:       gl.TEXTURE_BINDING_2D_ARRAY,
+	gl.TEXTURE_2D_MULTISAMPLE: gl.TEXTURE_BINDING_2D_MULTISAMPLE,
+	gl.TEXTURE_CUBE_MAP:       gl.TEXTURE_BINDING_CUBE_MAP,
+}
+
+// checkTexture checks a slot on the unit GL has active.
+func checkTexture(slot textureSlot) bool {
+	bound, known := current.textures[slot]
+	query, ok := textureBindings[slot.target]
+	if !known || !ok {
+		return true
+	}
+	if actual := getInteger(query); actual != bound {
+		mismatch("the texture bound", bound, actual)
+		current.textures[slot] = actual
+		return false
+	}
+	return true
+}
+
+func checkCapability(capability uint32) bool {
+	on, known := current.caps[capability]
+	if !known {
+		return true
+	}
+	actual := gl.IsEnabled(capability)
+	gldebug.Check()
+	if actual != on {
+		mismatch("capability "+capabilityName(capability), on, actual)
+		current.caps[capability] = actual
+		return false
+	}
+	return true
+}
+
+func capabilityName(capability uint32) string {
+	switch capability {
+	case gl.BLEND:
+		return "BLEND"
+	case gl.DEPTH_TEST:
+		return "DEPTH_TEST"
+	case gl.CULL_FACE:
+		return "CULL_FACE"
+	case gl.SCISSOR_TEST:
+		return "SCISSOR_TEST"
+	}
+	return fmt.Sprintf("0x%x", capability)
+}
+
+func checkBlendFunc() bool {
+	if !current.blendKnown {
+		return true
+	}
+	src := getInteger(gl.BLEND_SRC_RGB)
+	dst := getInteger(gl.BLEND_DST_RGB)
+	if src != current.blendSrc || dst != current.blendDst {
+		mismatch("the blend func", [2]uint32{current.blendSrc, current.blendDst}, [2]uint32{src, dst})
+		current.blendSrc, current.blendDst = src, dst
+		return false
+	}
+	return true
+}
+
+func checkDepthFunc() bool {
+	if !current.depthFuncKnown {
+		return true
+	}
+	if actual := getInteger(gl.DEPTH_FUNC); actual != current.depthFunc {
+		mismatch("the depth func", current.depthFunc, actual)
+		current.depthFunc = actual
+		return false
+	}
+	return true
+}
+
+func checkCullFace() bool {
+	if !current.cullFaceKnown {
+		return true
+	}
+	if actual := getInteger(gl.CULL_FACE_MODE); actual != current.cullFace {
+		mismatch("the cull face", current.cullFace, actual)
+		current.cullFace = actual
+		return false
+	}
+	return true
+}
+
+func checkPolygonMode() bool {
+	if !current.polygonModeKnown {
+		return true
+	}
+	// Front and back, always equal in a core profile
+	var modes [2]int32
+	gl.GetIntegerv(gl.POLYGON_MODE, &modes[0])
+	gldebug.Check()
+	if actual := uint32(modes[0]); actual != current.polygonMode {
+		mismatch("the polygon mode", current.polygonMode, actual)
+		current.polygonMode = actual
+		return false
+	}
+	return true
+}
+
+func checkPointSize() bool {
+	if !current.pointSizeKnown {
+		return true
+	}
+	var actual float32
+	gl.GetFloatv(gl.POINT_SIZE, &actual)
+	gldebug.Check()
+	if actual != current.pointSize {
+		mismatch("the point size", current.pointSize, actual)
+		current.pointSize = actual
+		return false
+	}
+	return true
+}
+
+func checkViewport() bool {
+	if !current.viewportKnown {
+		return true
+	}
+	var actual [4]int32
+	gl.GetIntegerv(gl.VIEWPORT, &actual[0])
+	gldebug.Check()
+	if actual != current.viewport {
+		mismatch("the viewport", current.viewport, actual)
+		current.viewport = actual
+		return false
+	}
+	return true
+}
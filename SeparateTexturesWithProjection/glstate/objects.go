@@ -0,0 +1,100 @@
+package glstate
+
+import (
+	"SimpleOpenGL-Go/SeparateTexturesWithProjection/gldebug"
+
+	"github.com/go-gl/gl/v4.5-core/gl"
+)
+
+// Objects counts the live GL objects created and deleted through this
+// package, a count that keeps growing is a leak.
+type Objects struct {
+	VertexArrays  int
+	Buffers       int
+	Textures      int
+	Framebuffers  int
+	Renderbuffers int
+	Programs      int
+}
+
+var objects Objects
+
+// GetObjects returns the live object counts.
+func GetObjects() Objects {
+	return objects
+}
+
+func GenVertexArray() uint32 {
+	var vao uint32
+	gl.GenVertexArrays(1, &vao)
+	gldebug.Check()
+	objects.VertexArrays++
+	return vao
+}
+
+func GenBuffer() uint32 {
+	var buffer uint32
+	gl.GenBuffers(1, &buffer)
+	gldebug.Check()
+	objects.Buffers++
+	return buffer
+}
+
+func DeleteBuffer(buffer uint32) {
+	if buffer == 0 {
+		return
+	}
+	gl.DeleteBuffers(1, &buffer)
+	gldebug.Check()
+	objects.Buffers--
+}
+
+func GenTexture() uint32 {
+	var texture uint32
+	gl.GenTextures(1, &texture)
+	gldebug.Check()
+	objects.Textures++
+	return texture
+}
+
+func GenFramebuffer() uint32 {
+	var fbo uint32
+	gl.GenFramebuffers(1, &fbo)
+	gldebug.Check()
+	objects.Framebuffers++
+	return fbo
+}
+
+func GenRenderbuffer() uint32 {
+	var rbo uint32
+	gl.GenRenderbuffers(1, &rbo)
+	gldebug.Check()
+	objects.Renderbuffers++
+	return rbo
+}
+
+func DeleteRenderbuffer(rbo uint32) {
+	if rbo == 0 {
+		return
+	}
+	gl.DeleteRenderbuffers(1, &rbo)
+	gldebug.Check()
+	objects.Renderbuffers--
+}
+
+func CreateProgram() uint32 {
+	program := gl.CreateProgram()
+	gldebug.Check()
+	objects.Programs++
+	return program
+}
+
+// DeleteProgram deletes program, GL keeps it until it's no longer current.
+func DeleteProgram(program uint32) {
+	if program == 0 {
+		return
+	}
+	gl.DeleteProgram(program)
+	gldebug.Check()
+	objects.Programs--
+}
@@ -0,0 +1,385 @@
+// Package glstate shadows the OpenGL state the renderers change so that
+// redundant calls can be skipped. There's one context, made current on
+// the main thread, so the shadow is package state.
+//
+// Everything that changes the tracked state must go through this package,
+// otherwise call Invalidate afterwards. SetDebug checks the shadow against
+// glGet queries to catch code that doesn't.
+package glstate
+
+import (
+	"SimpleOpenGL-Go/SeparateTexturesWithProjection/gldebug"
+
+	"github.com/go-gl/gl/v4.5-core/gl"
+)
+
+type textureSlot struct {
+	unit, target uint32
+}
+
+// The shadow, a value is unknown until it's first set
+type shadow struct {
+	program      uint32
+	programKnown bool
+
+	vao      uint32
+	vaoKnown bool
+
+	drawFbo, readFbo           uint32
+	drawFboKnown, readFboKnown bool
+
+	activeUnit      uint32
+	activeUnitKnown bool
+	textures        map[textureSlot]uint32
+
+	caps map[uint32]bool
+
+	blendSrc, blendDst uint32
+	blendKnown         bool
+
+	depthFunc      uint32
+	depthFuncKnown bool
+
+	cullFace      uint32
+	cullFaceKnown bool
+
+	polygonMode      uint32
+	polygonModeKnown bool
+
+	pointSize      float32
+	pointSizeKnown bool
+
+	viewport      [4]int32
+	viewportKnown bool
+}
+
+// Stats counts the calls made and skipped since the last ResetStats.
+type Stats struct {
+	Calls   int
+	Skipped int
+
+	// The calls made of two kinds worth watching
+	ProgramChanges int
+	TextureBinds   int
+}
+
+var (
+	current = newShadow()
+	stats   Stats
+	debug   bool
+)
+
+func newShadow() shadow {
+	return shadow{
+		textures: make(map[textureSlot]uint32),
+		caps:     make(map[uint32]bool),
+	}
+}
+
+// Invalidate forgets the shadow, the next call of each kind always
+// reaches GL. Use it after code outside this package changed the state.
+func Invalidate() {
+	current = newShadow()
+}
+
+// GetStats returns the call counts.
+func GetStats() Stats {
+	return stats
+}
+
+// ResetStats zeroes the call counts, e.g. at the start of a frame.
+func ResetStats() {
+	stats = Stats{}
+}
+
+// skip counts a call and reports whether it's redundant.
+func skip(known, same bool) bool {
+	if known && same {
+		stats.Skipped++
+		return true
+	}
+	stats.Calls++
+	return false
+}
+
+// UseProgram makes program current.
+func UseProgram(program uint32) {
+	if debug {
+		checkProgram()
+	}
+	if skip(current.programKnown, current.program == program) {
+		return
+	}
+	gl.UseProgram(program)
+	gldebug.Check()
+	stats.ProgramChanges++
+	current.program, current.programKnown = program, true
+}
+
+// Program returns the current program, 0 if unknown.
+func Program() uint32 {
+	return current.program
+}
+
+// BindVertexArray binds vao, 0 unbinds.
+func BindVertexArray(vao uint32) {
+	if debug {
+		checkVertexArray()
+	}
+	if skip(current.vaoKnown, current.vao == vao) {
+		return
+	}
+	gl.BindVertexArray(vao)
+	gldebug.Check()
+	current.vao, current.vaoKnown = vao, true
+}
+
+// DeleteVertexArray deletes vao, unbinding it if it's bound.
+func DeleteVertexArray(vao uint32) {
+	if vao == 0 {
+		return
+	}
+	gl.DeleteVertexArrays(1, &vao)
+	gldebug.Check()
+	objects.VertexArrays--
+	if current.vao == vao {
+		current.vao = 0
+	}
+}
+
+// BindFramebuffer binds fbo to gl.FRAMEBUFFER, gl.DRAW_FRAMEBUFFER or
+// gl.READ_FRAMEBUFFER, 0 is the default framebuffer.
+func BindFramebuffer(target, fbo uint32) {
+	if debug {
+		checkFramebuffers()
+	}
+	draw := target == gl.FRAMEBUFFER || target == gl.DRAW_FRAMEBUFFER
+	read := target == gl.FRAMEBUFFER || target == gl.READ_FRAMEBUFFER
+	same := (!draw || current.drawFbo == fbo) && (!read || current.readFbo == fbo)
+	known := (!draw || current.drawFboKnown) && (!read || current.readFboKnown)
+	if skip(known, same) {
+		return
+	}
+	gl.BindFramebuffer(target, fbo)
+	gldebug.Check()
+	if draw {
+		current.drawFbo, current.drawFboKnown = fbo, true
+	}
+	if read {
+		current.readFbo, current.readFboKnown = fbo, true
+	}
+}
+
+// Framebuffer returns the framebuffer bound for drawing, 0 if unknown.
+func Framebuffer() uint32 {
+	return current.drawFbo
+}
+
+// ReadFramebuffer returns the framebuffer bound for reading, 0 if unknown.
+func ReadFramebuffer() uint32 {
+	return current.readFbo
+}
+
+// DeleteFramebuffer deletes fbo, the default framebuffer takes the place
+// of it where it's bound.
+func DeleteFramebuffer(fbo uint32) {
+	if fbo == 0 {
+		return
+	}
+	gl.DeleteFramebuffers(1, &fbo)
+	gldebug.Check()
+	objects.Framebuffers--
+	if current.drawFbo == fbo {
+		current.drawFbo = 0
+	}
+	if current.readFbo == fbo {
+		current.readFbo = 0
+	}
+}
+
+// ActiveTexture selects the texture unit, gl.TEXTURE0 + n.
+func ActiveTexture(unit uint32) {
+	if debug {
+		checkActiveTexture()
+	}
+	if skip(current.activeUnitKnown, current.activeUnit == unit) {
+		return
+	}
+	gl.ActiveTexture(unit)
+	gldebug.Check()
+	current.activeUnit, current.activeUnitKnown = unit, true
+}
+
+// BindTexture binds texture to target on the active unit. The unit must
+// have been selected with ActiveTexture.
+func BindTexture(target, texture uint32) {
+	if !current.activeUnitKnown {
+		// The slot can't be tracked without knowing the unit
+		ActiveTexture(gl.TEXTURE0)
+	}
+	slot := textureSlot{current.activeUnit, target}
+	if debug {
+		checkTexture(slot)
+	}
+
+	bound, known := current.textures[slot]
+	if skip(known, bound == texture) {
+		return
+	}
+	gl.BindTexture(target, texture)
+	gldebug.Check()
+	stats.TextureBinds++
+	current.textures[slot] = texture
+}
+
+// BindTextureUnit selects unit gl.TEXTURE0 + n and binds texture to it.
+func BindTextureUnit(n int, target, texture uint32) {
+	ActiveTexture(gl.TEXTURE0 + uint32(n))
+	BindTexture(target, texture)
+}
+
+// DeleteTexture deletes texture and forgets the units it was bound to.
+func DeleteTexture(texture uint32) {
+	if texture == 0 {
+		return
+	}
+	gl.DeleteTextures(1, &texture)
+	gldebug.Check()
+	objects.Textures--
+	for slot, bound := range current.textures {
+		if bound == texture {
+			current.textures[slot] = 0
+		}
+	}
+}
+
+// Enable turns on a capability, e.g. gl.BLEND, gl.DEPTH_TEST or
+// gl.CULL_FACE.
+func Enable(capability uint32) {
+	setCapability(capability, true)
+}
+
+// Disable turns off a capability.
+func Disable(capability uint32) {
+	setCapability(capability, false)
+}
+
+// SetEnabled turns a capability on or off.
+func SetEnabled(capability uint32, enabled bool) {
+	setCapability(capability, enabled)
+}
+
+// IsEnabled reports whether a capability is on, false if unknown.
+func IsEnabled(capability uint32) bool {
+	return current.caps[capability]
+}
+
+func setCapability(capability uint32, enabled bool) {
+	if debug {
+		checkCapability(capability)
+	}
+	on, known := current.caps[capability]
+	if skip(known, on == enabled) {
+		return
+	}
+	if enabled {
+		gl.Enable(capability)
+	} else {
+		gl.Disable(capability)
+	}
+	gldebug.Check()
+	current.caps[capability] = enabled
+}
+
+// BlendFunc sets the blend factors.
+func BlendFunc(src, dst uint32) {
+	if debug {
+		checkBlendFunc()
+	}
+	if skip(current.blendKnown, current.blendSrc == src && current.blendDst == dst) {
+		return
+	}
+	gl.BlendFunc(src, dst)
+	gldebug.Check()
+	current.blendSrc, current.blendDst, current.blendKnown = src, dst, true
+}
+
+// DepthFunc sets the depth comparison.
+func DepthFunc(fn uint32) {
+	if debug {
+		checkDepthFunc()
+	}
+	if skip(current.depthFuncKnown, current.depthFunc == fn) {
+		return
+	}
+	gl.DepthFunc(fn)
+	gldebug.Check()
+	current.depthFunc, current.depthFuncKnown = fn, true
+}
+
+// CullFace sets the faces culled when gl.CULL_FACE is enabled.
+func CullFace(mode uint32) {
+	if debug {
+		checkCullFace()
+	}
+	if skip(current.cullFaceKnown, current.cullFace == mode) {
+		return
+	}
+	gl.CullFace(mode)
+	gldebug.Check()
+	current.cullFace, current.cullFaceKnown = mode, true
+}
+
+// PolygonMode sets gl.FILL, gl.LINE or gl.POINT for both faces, the only
+// choice in a core profile.
+func PolygonMode(mode uint32) {
+	if debug {
+		checkPolygonMode()
+	}
+	if skip(current.polygonModeKnown, current.polygonMode == mode) {
+		return
+	}
+	gl.PolygonMode(gl.FRONT_AND_BACK, mode)
+	gldebug.Check()
+	current.polygonMode, current.polygonModeKnown = mode, true
+}
+
+// GetPolygonMode returns the polygon mode, gl.FILL if it was never set.
+func GetPolygonMode() uint32 {
+	if !current.polygonModeKnown {
+		return gl.FILL
+	}
+	return current.polygonMode
+}
+
+// PointSize sets the rasterized point diameter.
+func PointSize(size float32) {
+	if debug {
+		checkPointSize()
+	}
+	if skip(current.pointSizeKnown, current.pointSize == size) {
+		return
+	}
+	gl.PointSize(size)
+	gldebug.Check()
+	current.pointSize, current.pointSizeKnown = size, true
+}
+
+// Viewport sets the viewport in framebuffer pixels.
+func Viewport(x, y, width, height int32) {
+	if debug {
+		checkViewport()
+	}
+	vp := [4]int32{x, y, width, height}
+	if skip(current.viewportKnown, current.viewport == vp) {
+		return
+	}
+	gl.Viewport(x, y, width, height)
+	gldebug.Check()
+	current.viewport, current.viewportKnown = vp, true
+}
+
+// GetViewport returns the viewport, zero if it was never set.
+func GetViewport() (x, y, width, height int32) {
+	return current.viewport[0], current.viewport[1], current.viewport[2], current.viewport[3]
+}
@@ -1,8 +1,12 @@
 module SimpleOpenGL-Go/SeparateTexturesWithProjection
 
-go 1.15
+go 1.18
 
 require (
+	github.com/BurntSushi/toml v1.5.0
 	github.com/go-gl/gl v0.0.0-20190320180904-bf2b1f2f34d7
 	github.com/go-gl/glfw/v3.3/glfw v0.0.0-20200707082815-5321531c36a2
+	golang.org/x/image v0.20.0
 )
+
+require golang.org/x/text v0.18.0 // indirect
@@ -0,0 +1,137 @@
+package input
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/go-gl/glfw/v3.3/glfw"
+)
+
+// Binding triggers an action when its Source is down along with every
+// Chord source, while the Mods modifiers are held.
+type Binding struct {
+	Source Source
+	Mods   glfw.ModifierKey
+	Chord  []Source
+}
+
+// ParseBinding parses "+" separated names, e.g. "Escape", "Ctrl+S" or
+// "Shift+G+H". Modifiers may come in any order, the first button is the
+// Source and any further ones the Chord.
+func ParseBinding(s string) (Binding, error) {
+	var b Binding
+	found := false
+	for _, part := range strings.Split(s, "+") {
+		if mod, ok := modifierNames[strings.ToLower(strings.TrimSpace(part))]; ok {
+			b.Mods |= mod
+			continue
+		}
+		src, err := ParseSource(part)
+		if err != nil {
+			return b, err
+		}
+		if !found {
+			b.Source = src
+			found = true
+		} else {
+			b.Chord = append(b.Chord, src)
+		}
+	}
+	if !found {
+		return b, fmt.Errorf("input: binding '%s' has no button", s)
+	}
+	return b, nil
+}
+
+// specific bindings need more than their Source, they win over plain
+// bindings of the same Source.
+func (b Binding) specific() bool {
+	return b.Mods != 0 || len(b.Chord) > 0
+}
+
+func (b Binding) String() string {
+	var parts []string
+	for _, m := range []struct {
+		mod  glfw.ModifierKey
+		name string
+	}{{glfw.ModControl, "ctrl"}, {glfw.ModShift, "shift"}, {glfw.ModAlt, "alt"}, {glfw.ModSuper, "super"}} {
+		if b.Mods&m.mod != 0 {
+			parts = append(parts, m.name)
+		}
+	}
+	parts = append(parts, b.Source.String())
+	for _, c := range b.Chord {
+		parts = append(parts, c.String())
+	}
+	return strings.Join(parts, "+")
+}
+
+// Axis combines buttons and analog sources into a value. Each held
+// Positive binding adds 1 and each Negative one subtracts 1, gamepad
+// sticks and triggers add their position and together they're clamped to
+// [-1, 1]. Pointer sources (scroll and mouse motion) add the distance
+// moved since the last update, unclamped. The total is multiplied by
+// Scale, 0 means 1.
+type Axis struct {
+	Negative []Binding
+	Positive []Binding
+	Analog   []Source
+
+	// DeadZone is the stick travel ignored around the center.
+	DeadZone float32
+	Scale    float32
+}
+
+// Map holds the bindings of named actions and axes.
+type Map struct {
+	actions map[string][]Binding
+	axes    map[string]Axis
+}
+
+func NewMap() *Map {
+	o := new(Map)
+	o.actions = make(map[string][]Binding)
+	o.axes = make(map[string]Axis)
+	return o
+}
+
+// Bind adds bindings to an action.
+func (m *Map) Bind(action string, bindings ...Binding) {
+	m.actions[action] = append(m.actions[action], bindings...)
+}
+
+// BindAxis sets an axis, replacing any previous one of the same name.
+func (m *Map) BindAxis(name string, axis Axis) {
+	m.axes[name] = axis
+}
+
+// Unbind removes an action.
+func (m *Map) Unbind(action string) {
+	delete(m.actions, action)
+}
+
+// Bindings returns the bindings of an action.
+func (m *Map) Bindings(action string) []Binding {
+	return m.actions[action]
+}
+
+// Actions returns the action names in order.
+func (m *Map) Actions() []string {
+	names := make([]string, 0, len(m.actions))
+	for n := range m.actions {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Axes returns the axis names in order.
+func (m *Map) Axes() []string {
+	names := make([]string, 0, len(m.axes))
+	for n := range m.axes {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,128 @@
+package input
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// Config is the file form of a Map, bindings are written as names, see
+// ParseBinding and ParseAnalog.
+//
+//	[actions]
+//	quit = ["Escape", "PadBack"]
+//	save = ["Ctrl+S"]
+//
+//	[axes.move_x]
+//	negative = ["A", "Left"]
+//	positive = ["D", "Right"]
+//	analog = ["PadLeftX"]
+//	dead_zone = 0.2
+type Config struct {
+	Actions map[string][]string   `json:"actions" toml:"actions"`
+	Axes    map[string]AxisConfig `json:"axes" toml:"axes"`
+}
+
+// AxisConfig is the file form of an Axis.
+type AxisConfig struct {
+	Negative []string `json:"negative" toml:"negative"`
+	Positive []string `json:"positive" toml:"positive"`
+	Analog   []string `json:"analog" toml:"analog"`
+	DeadZone float32  `json:"dead_zone" toml:"dead_zone"`
+	Scale    float32  `json:"scale" toml:"scale"`
+}
+
+// LoadConfig reads a JSON or TOML file, chosen by extension. Use
+// os.DirFS for files on disk.
+func LoadConfig(fsys fs.FS, path string) (Config, error) {
+	var config Config
+
+	data, err := fs.ReadFile(fsys, path)
+	if err != nil {
+		return config, err
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		err = dec.Decode(&config)
+	case ".toml":
+		var md toml.MetaData
+		if md, err = toml.Decode(string(data), &config); err == nil {
+			err = undecoded(md)
+		}
+	default:
+		return config, fmt.Errorf("input: unknown format '%s'", filepath.Ext(path))
+	}
+	if err != nil {
+		return config, fmt.Errorf("input: %s: %w", path, err)
+	}
+
+	return config, nil
+}
+
+// undecoded fails on keys Config doesn't have, as DisallowUnknownFields
+// does for JSON, so a misspelt key isn't silently ignored.
+func undecoded(md toml.MetaData) error {
+	if keys := md.Undecoded(); len(keys) > 0 {
+		return fmt.Errorf("unknown key '%s'", keys[0])
+	}
+	return nil
+}
+
+// Map parses the bindings.
+func (c Config) Map() (*Map, error) {
+	m := NewMap()
+
+	for action, names := range c.Actions {
+		bindings, err := parseBindings(names)
+		if err != nil {
+			return nil, fmt.Errorf("%w in action '%s'", err, action)
+		}
+		m.Bind(action, bindings...)
+	}
+
+	for name, ac := range c.Axes {
+		var axis Axis
+		var err error
+		if axis.Negative, err = parseBindings(ac.Negative); err != nil {
+			return nil, fmt.Errorf("%w in axis '%s'", err, name)
+		}
+		if axis.Positive, err = parseBindings(ac.Positive); err != nil {
+			return nil, fmt.Errorf("%w in axis '%s'", err, name)
+		}
+		for _, n := range ac.Analog {
+			src, err := ParseAnalog(n)
+			if err != nil {
+				return nil, fmt.Errorf("%w in axis '%s'", err, name)
+			}
+			axis.Analog = append(axis.Analog, src)
+		}
+		if ac.DeadZone < 0 || ac.DeadZone >= 1 {
+			return nil, fmt.Errorf("input: dead zone %g out of [0, 1) in axis '%s'", ac.DeadZone, name)
+		}
+		axis.DeadZone = ac.DeadZone
+		axis.Scale = ac.Scale
+		m.BindAxis(name, axis)
+	}
+
+	return m, nil
+}
+
+func parseBindings(names []string) ([]Binding, error) {
+	bindings := make([]Binding, 0, len(names))
+	for _, n := range names {
+		b, err := ParseBinding(n)
+		if err != nil {
+			return nil, err
+		}
+		bindings = append(bindings, b)
+	}
+	return bindings, nil
+}
@@ -0,0 +1,109 @@
+package input
+
+import (
+	"os"
+	"testing"
+	"testing/fstest"
+
+	"github.com/go-gl/glfw/v3.3/glfw"
+)
+
+func TestLoadConfig(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		data string
+		ok   bool
+	}{
+		{
+			name: "json",
+			file: "input.json",
+			data: `{"actions": {"save": ["Ctrl+S"]}, "axes": {"move_x": {"negative": ["A"], "positive": ["D"], "dead_zone": 0.2}}}`,
+			ok:   true,
+		},
+		{
+			name: "toml",
+			file: "input.toml",
+			data: "[actions]\nsave = [\"Ctrl+S\"]\n\n[axes.move_x]\nnegative = [\"A\"]\npositive = [\"D\"]\ndead_zone = 0.2\n",
+			ok:   true,
+		},
+		{
+			name: "json unknown key",
+			file: "input.json",
+			data: `{"actions": {"save": ["Ctrl+S"]}, "axes": {"move_x": {"negative": ["A"], "positive": ["D"], "deadzone": 0.2}}}`,
+		},
+		{
+			name: "toml unknown key",
+			file: "input.toml",
+			data: "[actions]\nsave = [\"Ctrl+S\"]\n\n[axes.move_x]\nnegative = [\"A\"]\npositive = [\"D\"]\ndeadzone = 0.2\n",
+		},
+		{
+			name: "toml unknown table",
+			file: "input.toml",
+			data: "[action]\nsave = [\"Ctrl+S\"]\n",
+		},
+		{
+			name: "broken toml",
+			file: "input.toml",
+			data: "[actions\n",
+		},
+		{
+			name: "unknown format",
+			file: "input.yaml",
+			data: "actions: {}\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fsys := fstest.MapFS{test.file: {Data: []byte(test.data)}}
+			config, err := LoadConfig(fsys, test.file)
+			if (err == nil) != test.ok {
+				t.Fatalf("error %v", err)
+			}
+			if !test.ok {
+				return
+			}
+
+			m, err := config.Map()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if b := m.Bindings("save"); len(b) != 1 || b[0].Source != Key(glfw.KeyS) || b[0].Mods != glfw.ModControl {
+				t.Errorf("save is bound to %v", b)
+			}
+			if axis := m.axes["move_x"]; len(axis.Negative) != 1 || len(axis.Positive) != 1 || axis.DeadZone != 0.2 {
+				t.Errorf("move_x is %+v", axis)
+			}
+		})
+	}
+}
+
+func TestConfigMapErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		config Config
+	}{
+		{"unknown button", Config{Actions: map[string][]string{"jump": {"Nope"}}}},
+		{"no button", Config{Actions: map[string][]string{"jump": {"Ctrl"}}}},
+		{"unknown axis button", Config{Axes: map[string]AxisConfig{"move": {Positive: []string{"Nope"}}}}},
+		{"unknown analog", Config{Axes: map[string]AxisConfig{"move": {Analog: []string{"PadMiddleX"}}}}},
+		{"dead zone", Config{Axes: map[string]AxisConfig{"move": {DeadZone: 1}}}},
+	}
+
+	for _, test := range tests {
+		if _, err := test.config.Map(); err == nil {
+			t.Errorf("%s: no error", test.name)
+		}
+	}
+}
+
+func TestDemoConfig(t *testing.T) {
+	config, err := LoadConfig(os.DirFS("../assets"), "input.toml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := config.Map(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,447 @@
+package input
+
+import (
+	"SimpleOpenGL-Go/SeparateTexturesWithProjection/display"
+
+	"github.com/go-gl/glfw/v3.3/glfw"
+)
+
+type actionState struct {
+	down, prev bool
+}
+
+type padState struct {
+	connected bool
+	state     glfw.GamepadState
+}
+
+// Handler turns input events into action and axis states. Events arrive
+// at any time, from glfw callbacks after Attach or from the *Event
+// methods, and Update samples them once per game update. A button pressed
+// and released between two updates still counts as pressed for one.
+type Handler struct {
+	bindings *Map
+
+	// Buttons down now, and pressed since the last update
+	down map[Source]bool
+	hits map[Source]bool
+
+	pads        [glfw.JoystickLast + 1]padState
+	pollGamepad bool
+
+	cursorX, cursorY float64
+	// Pointer motion since the last update, and as of it
+	pendingScrollX, pendingScrollY float64
+	pendingMoveX, pendingMoveY     float64
+	scrollX, scrollY               float64
+	moveX, moveY                   float64
+
+	actions map[string]*actionState
+	axes    map[string]float32
+
+	step     uint64
+	recorder *Recorder
+	player   *Player
+}
+
+func NewHandler(bindings *Map) *Handler {
+	o := new(Handler)
+	o.bindings = bindings
+	o.down = make(map[Source]bool)
+	o.hits = make(map[Source]bool)
+	o.actions = make(map[string]*actionState)
+	o.axes = make(map[string]float32)
+	return o
+}
+
+// Attach routes the window's keyboard and mouse callbacks to the handler
+// and polls the gamepads on every Update. Live events are ignored while a
+// recording plays.
+func (h *Handler) Attach(win *display.Window) {
+	window := win.GLFW()
+	window.SetKeyCallback(func(w *glfw.Window, key glfw.Key, scancode int, action glfw.Action, mods glfw.ModifierKey) {
+		if !h.Replaying() {
+			h.KeyEvent(key, action)
+		}
+	})
+	window.SetMouseButtonCallback(func(w *glfw.Window, button glfw.MouseButton, action glfw.Action, mods glfw.ModifierKey) {
+		if !h.Replaying() {
+			h.MouseButtonEvent(button, action)
+		}
+	})
+	window.SetScrollCallback(func(w *glfw.Window, xoff, yoff float64) {
+		if !h.Replaying() {
+			h.ScrollEvent(xoff, yoff)
+		}
+	})
+	window.SetCursorPosCallback(func(w *glfw.Window, x, y float64) {
+		if !h.Replaying() {
+			h.CursorEvent(x, y)
+		}
+	})
+
+	h.cursorX, h.cursorY = window.GetCursorPos()
+	h.pollGamepad = true
+}
+
+// Map returns the bindings.
+func (h *Handler) Map() *Map {
+	return h.bindings
+}
+
+// SetMap replaces the bindings, e.g. after the player rebinds a key.
+func (h *Handler) SetMap(bindings *Map) {
+	h.bindings = bindings
+}
+
+// KeyEvent records a key press or release, repeats are ignored.
+func (h *Handler) KeyEvent(key glfw.Key, action glfw.Action) {
+	h.buttonEvent(Key(key), action)
+}
+
+// MouseButtonEvent records a mouse button press or release.
+func (h *Handler) MouseButtonEvent(button glfw.MouseButton, action glfw.Action) {
+	h.buttonEvent(MouseButton(button), action)
+}
+
+// ScrollEvent records a wheel movement, each direction also taps its
+// Wheel* button.
+func (h *Handler) ScrollEvent(xoff, yoff float64) {
+	h.record(Event{Type: EventScroll, X: xoff, Y: yoff})
+
+	h.pendingScrollX += xoff
+	h.pendingScrollY += yoff
+
+	if yoff > 0 {
+		h.hits[Source{Mouse, WheelUp}] = true
+	} else if yoff < 0 {
+		h.hits[Source{Mouse, WheelDown}] = true
+	}
+	if xoff > 0 {
+		h.hits[Source{Mouse, WheelRight}] = true
+	} else if xoff < 0 {
+		h.hits[Source{Mouse, WheelLeft}] = true
+	}
+}
+
+// CursorEvent records the cursor position in window screen coordinates.
+func (h *Handler) CursorEvent(x, y float64) {
+	h.record(Event{Type: EventCursor, X: x, Y: y})
+
+	h.pendingMoveX += x - h.cursorX
+	h.pendingMoveY += y - h.cursorY
+	h.cursorX, h.cursorY = x, y
+}
+
+// GamepadEvent sets the state of a gamepad, nil disconnects it. Attached
+// handlers overwrite it with the polled state on the next Update.
+func (h *Handler) GamepadEvent(pad glfw.Joystick, state *glfw.GamepadState) {
+	if pad < 0 || pad > glfw.JoystickLast {
+		return
+	}
+
+	// Polling reports every update, only changes are recorded
+	if state == nil && h.pads[pad].connected {
+		h.record(Event{Type: EventGamepad, Pad: int(pad)})
+	} else if state != nil && (!h.pads[pad].connected || h.pads[pad].state != *state) {
+		h.record(Event{Type: EventGamepad, Pad: int(pad), State: state})
+	}
+
+	if state == nil {
+		h.pads[pad] = padState{}
+	} else {
+		h.pads[pad] = padState{connected: true, state: *state}
+	}
+
+	// Buttons are down on any gamepad
+	for b := 0; b < padButtons; b++ {
+		down := false
+		for i := range h.pads {
+			if h.pads[i].connected && padButtonDown(&h.pads[i].state, b) {
+				down = true
+				break
+			}
+		}
+		h.setButton(Source{Gamepad, b}, down)
+	}
+}
+
+func padButtonDown(state *glfw.GamepadState, button int) bool {
+	switch button {
+	case PadLeftTrigger:
+		return state.Axes[glfw.AxisLeftTrigger] > 0.0
+	case PadRightTrigger:
+		return state.Axes[glfw.AxisRightTrigger] > 0.0
+	}
+	return state.Buttons[button] == glfw.Press
+}
+
+func (h *Handler) buttonEvent(src Source, action glfw.Action) {
+	if action == glfw.Press || action == glfw.Release {
+		typ := EventKey
+		if src.Device == Mouse {
+			typ = EventMouse
+		}
+		// Unnamed keys can't be bound, they aren't worth recording
+		if name, ok := src.name(); ok {
+			h.record(Event{Type: typ, Button: name, Press: action == glfw.Press})
+		}
+	}
+
+	switch action {
+	case glfw.Press:
+		h.setButton(src, true)
+	case glfw.Release:
+		h.setButton(src, false)
+	}
+}
+
+func (h *Handler) setButton(src Source, down bool) {
+	if !down {
+		delete(h.down, src)
+		return
+	}
+	if !h.down[src] {
+		h.down[src] = true
+		h.hits[src] = true
+	}
+}
+
+// Update samples the events since the last call. Call it once at the
+// start of every game update, before querying.
+func (h *Handler) Update() {
+	if h.player != nil {
+		h.play()
+	}
+	if h.pollGamepad && !h.Replaying() {
+		h.pollGamepads()
+	}
+
+	h.scrollX, h.scrollY = h.pendingScrollX, h.pendingScrollY
+	h.moveX, h.moveY = h.pendingMoveX, h.pendingMoveY
+	h.pendingScrollX, h.pendingScrollY = 0, 0
+	h.pendingMoveX, h.pendingMoveY = 0, 0
+
+	// A source used by a matching modifier or chord binding doesn't also
+	// trigger the plain bindings, so Ctrl+S doesn't save and move down.
+	claimed := make(map[Source]bool)
+	for _, bindings := range h.bindings.actions {
+		for _, b := range bindings {
+			if b.specific() && h.matches(b) {
+				claimed[b.Source] = true
+			}
+		}
+	}
+
+	for _, st := range h.actions {
+		st.prev = st.down
+		st.down = false
+	}
+	for name, bindings := range h.bindings.actions {
+		st := h.actions[name]
+		if st == nil {
+			st = new(actionState)
+			h.actions[name] = st
+		}
+		st.down = h.anyMatch(bindings, claimed)
+	}
+
+	for name := range h.axes {
+		delete(h.axes, name)
+	}
+	for name, axis := range h.bindings.axes {
+		h.axes[name] = h.axisValue(axis, claimed)
+	}
+
+	for src := range h.hits {
+		delete(h.hits, src)
+	}
+
+	h.step++
+}
+
+func (h *Handler) pollGamepads() {
+	for pad := glfw.Joystick1; pad <= glfw.JoystickLast; pad++ {
+		if pad.Present() && pad.IsGamepad() {
+			if state := pad.GetGamepadState(); state != nil {
+				h.GamepadEvent(pad, state)
+				continue
+			}
+		}
+		if h.pads[pad].connected {
+			h.GamepadEvent(pad, nil)
+		}
+	}
+}
+
+func (h *Handler) active(src Source) bool {
+	return h.down[src] || h.hits[src]
+}
+
+func (h *Handler) mods() glfw.ModifierKey {
+	var mods glfw.ModifierKey
+	if h.active(Key(glfw.KeyLeftShift)) || h.active(Key(glfw.KeyRightShift)) {
+		mods |= glfw.ModShift
+	}
+	if h.active(Key(glfw.KeyLeftControl)) || h.active(Key(glfw.KeyRightControl)) {
+		mods |= glfw.ModControl
+	}
+	if h.active(Key(glfw.KeyLeftAlt)) || h.active(Key(glfw.KeyRightAlt)) {
+		mods |= glfw.ModAlt
+	}
+	if h.active(Key(glfw.KeyLeftSuper)) || h.active(Key(glfw.KeyRightSuper)) {
+		mods |= glfw.ModSuper
+	}
+	return mods
+}
+
+func (h *Handler) matches(b Binding) bool {
+	if !h.active(b.Source) || h.mods()&b.Mods != b.Mods {
+		return false
+	}
+	for _, c := range b.Chord {
+		if !h.active(c) {
+			return false
+		}
+	}
+	return true
+}
+
+func (h *Handler) anyMatch(bindings []Binding, claimed map[Source]bool) bool {
+	for _, b := range bindings {
+		if !b.specific() && claimed[b.Source] {
+			continue
+		}
+		if h.matches(b) {
+			return true
+		}
+	}
+	return false
+}
+
+func (h *Handler) axisValue(axis Axis, claimed map[Source]bool) float32 {
+	var value, pointer float32
+	if h.anyMatch(axis.Positive, claimed) {
+		value += 1.0
+	}
+	if h.anyMatch(axis.Negative, claimed) {
+		value -= 1.0
+	}
+
+	for _, src := range axis.Analog {
+		switch {
+		case src.Device == Gamepad:
+			value += h.padAxis(glfw.GamepadAxis(src.Code), axis.DeadZone)
+		case src.Code == ScrollX:
+			pointer += float32(h.scrollX)
+		case src.Code == ScrollY:
+			pointer += float32(h.scrollY)
+		case src.Code == MouseX:
+			pointer += float32(h.moveX)
+		case src.Code == MouseY:
+			pointer += float32(h.moveY)
+		}
+	}
+
+	if value > 1.0 {
+		value = 1.0
+	} else if value < -1.0 {
+		value = -1.0
+	}
+
+	scale := axis.Scale
+	if scale == 0 {
+		scale = 1.0
+	}
+	return (value + pointer) * scale
+}
+
+// padAxis returns the axis of the gamepad pushed furthest, triggers
+// range over [0, 1] and sticks over [-1, 1], past the dead zone.
+func (h *Handler) padAxis(axis glfw.GamepadAxis, deadZone float32) float32 {
+	var best float32
+	for i := range h.pads {
+		if !h.pads[i].connected {
+			continue
+		}
+		v := h.pads[i].state.Axes[axis]
+		if axis == glfw.AxisLeftTrigger || axis == glfw.AxisRightTrigger {
+			// Triggers rest at -1
+			v = (v + 1.0) / 2.0
+		}
+		if abs32(v) > abs32(best) {
+			best = v
+		}
+	}
+
+	if abs32(best) <= deadZone {
+		return 0.0
+	}
+	// Rescale so the value still starts from 0 at the dead zone edge
+	if best > 0 {
+		return (best - deadZone) / (1.0 - deadZone)
+	}
+	return (best + deadZone) / (1.0 - deadZone)
+}
+
+func abs32(v float32) float32 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+// Pressed reports whether the action started in this update.
+func (h *Handler) Pressed(action string) bool {
+	st := h.actions[action]
+	return st != nil && st.down && !st.prev
+}
+
+// Held reports whether the action is active in this update.
+func (h *Handler) Held(action string) bool {
+	st := h.actions[action]
+	return st != nil && st.down
+}
+
+// Released reports whether the action ended in this update.
+func (h *Handler) Released(action string) bool {
+	st := h.actions[action]
+	return st != nil && !st.down && st.prev
+}
+
+// Axis returns the value of an axis in this update, 0 if it isn't bound.
+func (h *Handler) Axis(name string) float32 {
+	return h.axes[name]
+}
+
+// Down reports whether a button is held right now, outside of any
+// binding.
+func (h *Handler) Down(src Source) bool {
+	return h.down[src]
+}
+
+// Cursor returns the latest cursor position in window screen coordinates.
+func (h *Handler) Cursor() (x, y float64) {
+	return h.cursorX, h.cursorY
+}
+
+// CursorDelta returns the cursor motion over this update.
+func (h *Handler) CursorDelta() (dx, dy float64) {
+	return h.moveX, h.moveY
+}
+
+// Scroll returns the wheel movement over this update.
+func (h *Handler) Scroll() (x, y float64) {
+	return h.scrollX, h.scrollY
+}
+
+// Gamepads returns the connected gamepads.
+func (h *Handler) Gamepads() []glfw.Joystick {
+	var pads []glfw.Joystick
+	for i := range h.pads {
+		if h.pads[i].connected {
+			pads = append(pads, glfw.Joystick(i))
+		}
+	}
+	return pads
+}
@@ -0,0 +1,278 @@
+package input
+
+import (
+	"testing"
+
+	"github.com/go-gl/glfw/v3.3/glfw"
+)
+
+func testMap(t *testing.T) *Map {
+	t.Helper()
+	m := NewMap()
+	for action, names := range map[string][]string{
+		"down":  {"S", "Down"},
+		"save":  {"Ctrl+S"},
+		"jump":  {"Space", "PadA"},
+		"combo": {"G+H"},
+		"fire":  {"MouseLeft"},
+		"zoom":  {"WheelUp"},
+	} {
+		bindings, err := parseBindings(names)
+		if err != nil {
+			t.Fatal(err)
+		}
+		m.Bind(action, bindings...)
+	}
+	return m
+}
+
+func press(h *Handler, keys ...glfw.Key) {
+	for _, k := range keys {
+		h.KeyEvent(k, glfw.Press)
+	}
+}
+
+func release(h *Handler, keys ...glfw.Key) {
+	for _, k := range keys {
+		h.KeyEvent(k, glfw.Release)
+	}
+}
+
+func TestHandlerActions(t *testing.T) {
+	// Each step's events arrive before its Update
+	steps := []struct {
+		name                    string
+		events                  func(h *Handler)
+		pressed, held, released []string
+	}{
+		{
+			name:    "press",
+			events:  func(h *Handler) { press(h, glfw.KeyS) },
+			pressed: []string{"down"}, held: []string{"down"},
+		},
+		{
+			name:   "hold",
+			events: func(h *Handler) {},
+			held:   []string{"down"},
+		},
+		{
+			name:   "repeats are ignored",
+			events: func(h *Handler) { h.KeyEvent(glfw.KeyS, glfw.Repeat) },
+			held:   []string{"down"},
+		},
+		{
+			name:     "release",
+			events:   func(h *Handler) { release(h, glfw.KeyS) },
+			released: []string{"down"},
+		},
+		{
+			name:   "idle",
+			events: func(h *Handler) {},
+		},
+		{
+			name:    "ctrl+s claims s",
+			events:  func(h *Handler) { press(h, glfw.KeyLeftControl, glfw.KeyS) },
+			pressed: []string{"save"}, held: []string{"save"},
+		},
+		{
+			name:     "release ctrl+s",
+			events:   func(h *Handler) { release(h, glfw.KeyS, glfw.KeyLeftControl) },
+			released: []string{"save"},
+		},
+		{
+			name:    "other bindings of a claimed action still work",
+			events:  func(h *Handler) { press(h, glfw.KeyRightControl, glfw.KeyS, glfw.KeyDown) },
+			pressed: []string{"save", "down"}, held: []string{"save", "down"},
+		},
+		{
+			name:     "release all",
+			events:   func(h *Handler) { release(h, glfw.KeyRightControl, glfw.KeyS, glfw.KeyDown) },
+			released: []string{"save", "down"},
+		},
+		{
+			name:   "chord half down",
+			events: func(h *Handler) { press(h, glfw.KeyG) },
+		},
+		{
+			name:    "chord",
+			events:  func(h *Handler) { press(h, glfw.KeyH) },
+			pressed: []string{"combo"}, held: []string{"combo"},
+		},
+		{
+			name:     "chord broken",
+			events:   func(h *Handler) { release(h, glfw.KeyG) },
+			released: []string{"combo"},
+		},
+		{
+			name: "tap between updates",
+			events: func(h *Handler) {
+				release(h, glfw.KeyH)
+				press(h, glfw.KeySpace)
+				release(h, glfw.KeySpace)
+			},
+			pressed: []string{"jump"}, held: []string{"jump"},
+		},
+		{
+			name:     "tap over",
+			events:   func(h *Handler) {},
+			released: []string{"jump"},
+		},
+		{
+			name: "mouse and wheel",
+			events: func(h *Handler) {
+				h.MouseButtonEvent(glfw.MouseButtonLeft, glfw.Press)
+				h.ScrollEvent(0, 1)
+			},
+			pressed: []string{"fire", "zoom"}, held: []string{"fire", "zoom"},
+		},
+		{
+			name:     "wheel notches are taps",
+			events:   func(h *Handler) {},
+			held:     []string{"fire"},
+			released: []string{"zoom"},
+		},
+		{
+			name: "gamepad",
+			events: func(h *Handler) {
+				h.MouseButtonEvent(glfw.MouseButtonLeft, glfw.Release)
+				var state glfw.GamepadState
+				state.Buttons[glfw.ButtonA] = glfw.Press
+				h.GamepadEvent(glfw.Joystick1, &state)
+			},
+			pressed: []string{"jump"}, held: []string{"jump"}, released: []string{"fire"},
+		},
+		{
+			name:     "gamepad disconnected",
+			events:   func(h *Handler) { h.GamepadEvent(glfw.Joystick1, nil) },
+			released: []string{"jump"},
+		},
+	}
+
+	m := testMap(t)
+	h := NewHandler(m)
+	for _, step := range steps {
+		step.events(h)
+		h.Update()
+
+		for _, action := range m.Actions() {
+			for _, q := range []struct {
+				query string
+				got   bool
+				want  []string
+			}{
+				{"pressed", h.Pressed(action), step.pressed},
+				{"held", h.Held(action), step.held},
+				{"released", h.Released(action), step.released},
+			} {
+				if want := contains(q.want, action); q.got != want {
+					t.Errorf("%s: %s %s is %v, want %v", step.name, action, q.query, q.got, want)
+				}
+			}
+		}
+	}
+}
+
+func contains(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+func TestHandlerAxes(t *testing.T) {
+	m := NewMap()
+	m.BindAxis("move", Axis{
+		Negative: []Binding{{Source: Key(glfw.KeyA)}},
+		Positive: []Binding{{Source: Key(glfw.KeyD)}},
+		Analog:   []Source{{Gamepad, int(glfw.AxisLeftX)}},
+		DeadZone: 0.2,
+	})
+	m.BindAxis("zoom", Axis{Analog: []Source{{Mouse, ScrollY}}, Scale: 2})
+	m.BindAxis("look", Axis{Analog: []Source{{Mouse, MouseX}}})
+
+	tests := []struct {
+		name             string
+		events           func(h *Handler)
+		move, zoom, look float32
+	}{
+		{"idle", func(h *Handler) {}, 0, 0, 0},
+		{"positive", func(h *Handler) { press(h, glfw.KeyD) }, 1, 0, 0},
+		{"both cancel", func(h *Handler) { press(h, glfw.KeyA) }, 0, 0, 0},
+		{"negative", func(h *Handler) { release(h, glfw.KeyD) }, -1, 0, 0},
+		{"clamped", func(h *Handler) { h.GamepadEvent(glfw.Joystick1, stick(-0.6)) }, -1, 0, 0},
+		{"stick", func(h *Handler) { release(h, glfw.KeyA) }, -0.5, 0, 0},
+		{"dead zone", func(h *Handler) { h.GamepadEvent(glfw.Joystick1, stick(0.1)) }, 0, 0, 0},
+		{"scroll is scaled", func(h *Handler) { h.ScrollEvent(0, 1); h.ScrollEvent(0, 0.5) }, 0, 3, 0},
+		{"scroll resets", func(h *Handler) {}, 0, 0, 0},
+		{"cursor motion", func(h *Handler) { h.CursorEvent(10, 0); h.CursorEvent(25, 5) }, 0, 0, 25},
+		{"cursor still", func(h *Handler) {}, 0, 0, 0},
+	}
+
+	h := NewHandler(m)
+	for _, test := range tests {
+		test.events(h)
+		h.Update()
+
+		if move, zoom, look := h.Axis("move"), h.Axis("zoom"), h.Axis("look"); !near(move, test.move) || zoom != test.zoom || look != test.look {
+			t.Errorf("%s: move %v zoom %v look %v, want %v %v %v", test.name, move, zoom, look, test.move, test.zoom, test.look)
+		}
+	}
+}
+
+func stick(x float32) *glfw.GamepadState {
+	var state glfw.GamepadState
+	state.Axes[glfw.AxisLeftX] = x
+	// Triggers rest at -1
+	state.Axes[glfw.AxisLeftTrigger] = -1
+	state.Axes[glfw.AxisRightTrigger] = -1
+	return &state
+}
+
+func near(a, b float32) bool {
+	return abs32(a-b) < 1e-6
+}
+
+func TestParseBinding(t *testing.T) {
+	tests := []struct {
+		text string
+		want Binding
+		ok   bool
+	}{
+		{"Escape", Binding{Source: Key(glfw.KeyEscape)}, true},
+		{"ctrl+s", Binding{Source: Key(glfw.KeyS), Mods: glfw.ModControl}, true},
+		{"S + Shift + Ctrl", Binding{Source: Key(glfw.KeyS), Mods: glfw.ModControl | glfw.ModShift}, true},
+		{"Shift+G+H", Binding{Source: Key(glfw.KeyG), Mods: glfw.ModShift, Chord: []Source{Key(glfw.KeyH)}}, true},
+		{"MouseLeft", Binding{Source: MouseButton(glfw.MouseButtonLeft)}, true},
+		{"PadRT", Binding{Source: Source{Gamepad, PadRightTrigger}}, true},
+		{"Ctrl", Binding{}, false},
+		{"Ctrl+Nope", Binding{}, false},
+	}
+
+	for _, test := range tests {
+		b, err := ParseBinding(test.text)
+		if (err == nil) != test.ok {
+			t.Errorf("%q: error %v", test.text, err)
+			continue
+		}
+		if !test.ok {
+			continue
+		}
+		if b.Source != test.want.Source || b.Mods != test.want.Mods || len(b.Chord) != len(test.want.Chord) {
+			t.Errorf("%q is %v, want %v", test.text, b, test.want)
+			continue
+		}
+		for i := range b.Chord {
+			if b.Chord[i] != test.want.Chord[i] {
+				t.Errorf("%q is %v, want %v", test.text, b, test.want)
+			}
+		}
+
+		// String round trips
+		again, err := ParseBinding(b.String())
+		if err != nil || again.String() != b.String() {
+			t.Errorf("%q doesn't round trip through %q", test.text, b.String())
+		}
+	}
+}
@@ -0,0 +1,211 @@
+package input
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/go-gl/glfw/v3.3/glfw"
+)
+
+// Event types in a recording.
+const (
+	EventStart   = "start"
+	EventKey     = "key"
+	EventMouse   = "mouse"
+	EventScroll  = "scroll"
+	EventCursor  = "cursor"
+	EventGamepad = "gamepad"
+)
+
+// Event is one recorded input event. Step is the number of updates since
+// recording started when the event arrived, so it's sampled by update
+// Step+1 whatever the frame rate was.
+type Event struct {
+	Step uint64 `json:"step"`
+	Type string `json:"type"`
+
+	// Key and mouse events, Button is a ParseSource name
+	Button string `json:"button,omitempty"`
+	Press  bool   `json:"press,omitempty"`
+
+	// Scroll offsets and cursor positions
+	X float64 `json:"x,omitempty"`
+	Y float64 `json:"y,omitempty"`
+
+	// Gamepad events, a nil State is a disconnect
+	Pad   int                `json:"pad,omitempty"`
+	State *glfw.GamepadState `json:"state,omitempty"`
+}
+
+// Recorder writes events as JSON lines, one event per line.
+type Recorder struct {
+	enc   *json.Encoder
+	start uint64
+	err   error
+}
+
+func NewRecorder(w io.Writer) *Recorder {
+	o := new(Recorder)
+	o.enc = json.NewEncoder(w)
+	return o
+}
+
+// Err returns the first write error, later events are dropped after one.
+func (r *Recorder) Err() error {
+	return r.err
+}
+
+func (r *Recorder) write(step uint64, e Event) {
+	if r.err != nil {
+		return
+	}
+	e.Step = step - r.start
+	r.err = r.enc.Encode(e)
+}
+
+// Player feeds recorded events back to a Handler. Without a window it
+// makes input tests and bug reproductions headless:
+//
+//	player, _ := input.LoadRecording(f)
+//	controls.SetPlayer(player)
+//	app.NewRunner(game, app.NewHeadless(1200, 800)).RunFrames(n)
+type Player struct {
+	events []Event
+	next   int
+	start  uint64
+}
+
+func NewPlayer(events []Event) *Player {
+	o := new(Player)
+	o.events = events
+	return o
+}
+
+// LoadRecording reads the events a Recorder wrote.
+func LoadRecording(r io.Reader) (*Player, error) {
+	var events []Event
+
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var e Event
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return nil, fmt.Errorf("input: recording line %d: %w", line, err)
+		}
+		if n := len(events); n > 0 && e.Step < events[n-1].Step {
+			return nil, fmt.Errorf("input: recording line %d: step %d is out of order", line, e.Step)
+		}
+		events = append(events, e)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return NewPlayer(events), nil
+}
+
+// Done reports whether every event has been played.
+func (p *Player) Done() bool {
+	return p.next >= len(p.events)
+}
+
+// Events returns the recording.
+func (p *Player) Events() []Event {
+	return p.events
+}
+
+// SetRecorder starts recording every event the handler receives, nil
+// stops. The cursor position is written first so replays start from it.
+func (h *Handler) SetRecorder(r *Recorder) {
+	h.recorder = r
+	if r != nil {
+		r.start = h.step
+		r.write(h.step, Event{Type: EventStart, X: h.cursorX, Y: h.cursorY})
+	}
+}
+
+// SetPlayer replays a recording from the next Update on, nil stops. The
+// held buttons and actions are cleared, so the replay starts as the
+// recording did, and live events are ignored until it's done.
+func (h *Handler) SetPlayer(p *Player) {
+	h.player = p
+	if p == nil {
+		return
+	}
+
+	p.next = 0
+	p.start = h.step
+	for src := range h.down {
+		delete(h.down, src)
+	}
+	for src := range h.hits {
+		delete(h.hits, src)
+	}
+	for _, st := range h.actions {
+		st.down = false
+	}
+	h.pads = [glfw.JoystickLast + 1]padState{}
+	h.pendingScrollX, h.pendingScrollY = 0, 0
+	h.pendingMoveX, h.pendingMoveY = 0, 0
+}
+
+// Replaying reports whether a recording is still playing.
+func (h *Handler) Replaying() bool {
+	return h.player != nil && !h.player.Done()
+}
+
+// Step returns the number of updates run.
+func (h *Handler) Step() uint64 {
+	return h.step
+}
+
+func (h *Handler) record(e Event) {
+	if h.recorder != nil {
+		h.recorder.write(h.step, e)
+	}
+}
+
+// play dispatches the events due before this update through the same
+// methods live events use.
+func (h *Handler) play() {
+	p := h.player
+	for ; p.next < len(p.events); p.next++ {
+		e := p.events[p.next]
+		if p.start+e.Step > h.step {
+			break
+		}
+		// Events a hand edited recording got wrong are skipped
+		h.dispatch(e)
+	}
+}
+
+func (h *Handler) dispatch(e Event) error {
+	action := glfw.Release
+	if e.Press {
+		action = glfw.Press
+	}
+
+	switch e.Type {
+	case EventStart:
+		h.cursorX, h.cursorY = e.X, e.Y
+	case EventKey, EventMouse:
+		src, err := ParseSource(e.Button)
+		if err != nil {
+			return err
+		}
+		h.buttonEvent(src, action)
+	case EventScroll:
+		h.ScrollEvent(e.X, e.Y)
+	case EventCursor:
+		h.CursorEvent(e.X, e.Y)
+	case EventGamepad:
+		h.GamepadEvent(glfw.Joystick(e.Pad), e.State)
+	default:
+		return fmt.Errorf("input: unknown event type '%s'", e.Type)
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+package input
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/go-gl/glfw/v3.3/glfw"
+)
+
+// snapshot describes everything a game can query after an Update.
+func snapshot(h *Handler) string {
+	var b strings.Builder
+	for _, action := range h.Map().Actions() {
+		fmt.Fprintf(&b, "%s:%v/%v/%v ", action, h.Pressed(action), h.Held(action), h.Released(action))
+	}
+	for _, axis := range h.Map().Axes() {
+		fmt.Fprintf(&b, "%s:%v ", axis, h.Axis(axis))
+	}
+	x, y := h.Cursor()
+	dx, dy := h.CursorDelta()
+	sx, sy := h.Scroll()
+	fmt.Fprintf(&b, "cursor:%v,%v delta:%v,%v scroll:%v,%v", x, y, dx, dy, sx, sy)
+	return b.String()
+}
+
+func TestRecordingRoundTrip(t *testing.T) {
+	m := testMap(t)
+	m.BindAxis("look", Axis{Analog: []Source{{Mouse, MouseX}, {Gamepad, int(glfw.AxisRightX)}}})
+
+	// Events per update, some updates have none
+	steps := []func(h *Handler){
+		func(h *Handler) { press(h, glfw.KeyS) },
+		func(h *Handler) {},
+		func(h *Handler) { press(h, glfw.KeyLeftControl); release(h, glfw.KeyS); press(h, glfw.KeyS) },
+		func(h *Handler) { release(h, glfw.KeyS, glfw.KeyLeftControl); h.CursorEvent(120, 80) },
+		func(h *Handler) { press(h, glfw.KeySpace); release(h, glfw.KeySpace); h.ScrollEvent(0, 2) },
+		func(h *Handler) {},
+		func(h *Handler) { press(h, glfw.KeyG, glfw.KeyH); h.CursorEvent(100, 90) },
+		func(h *Handler) {
+			release(h, glfw.KeyG, glfw.KeyH)
+			var state glfw.GamepadState
+			state.Buttons[glfw.ButtonA] = glfw.Press
+			state.Axes[glfw.AxisRightX] = 0.5
+			h.GamepadEvent(glfw.Joystick2, &state)
+		},
+		func(h *Handler) { h.MouseButtonEvent(glfw.MouseButtonLeft, glfw.Press) },
+		func(h *Handler) { h.GamepadEvent(glfw.Joystick2, nil) },
+		func(h *Handler) { h.MouseButtonEvent(glfw.MouseButtonLeft, glfw.Release) },
+		func(h *Handler) {},
+	}
+
+	live := NewHandler(m)
+	live.CursorEvent(50, 60)
+	// Updates before recording don't shift the recorded steps
+	live.Update()
+	live.Update()
+
+	var buf bytes.Buffer
+	recorder := NewRecorder(&buf)
+	live.SetRecorder(recorder)
+	var want []string
+	for _, events := range steps {
+		events(live)
+		live.Update()
+		want = append(want, snapshot(live))
+	}
+	live.SetRecorder(nil)
+	if err := recorder.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	player, err := LoadRecording(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	replay := NewHandler(m)
+	// Held buttons and the cursor are replaced by the recording's
+	press(replay, glfw.KeyDown)
+	replay.CursorEvent(5, 5)
+	replay.Update()
+	replay.SetPlayer(player)
+	for i := range steps {
+		if i == 1 {
+			// Live events are ignored while replaying
+			press(replay, glfw.KeyS)
+		}
+		replay.Update()
+		if got := snapshot(replay); got != want[i] {
+			t.Errorf("update %d replayed as\n%s\nwant\n%s", i, got, want[i])
+		}
+	}
+	if !player.Done() || replay.Replaying() {
+		t.Errorf("%d of %d events played", player.next, len(player.Events()))
+	}
+}
+
+func TestLoadRecordingErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		ok   bool
+	}{
+		{"empty", "", true},
+		{"blank lines", "{\"step\":0,\"type\":\"start\"}\n\n{\"step\":1,\"type\":\"key\",\"button\":\"s\",\"press\":true}\n", true},
+		{"broken json", "{\"step\":0,\"type\":\"start\"}\n{\"step\":\n", false},
+		{"out of order", "{\"step\":2,\"type\":\"start\"}\n{\"step\":1,\"type\":\"scroll\",\"y\":1}\n", false},
+	}
+
+	for _, test := range tests {
+		_, err := LoadRecording(strings.NewReader(test.text))
+		if (err == nil) != test.ok {
+			t.Errorf("%s: error %v", test.name, err)
+		}
+	}
+}
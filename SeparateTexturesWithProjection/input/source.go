@@ -0,0 +1,193 @@
+package input
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/go-gl/glfw/v3.3/glfw"
+)
+
+// Device is the kind of hardware a Source belongs to.
+type Device int
+
+const (
+	Keyboard Device = iota
+	Mouse
+	Gamepad
+)
+
+// Source is one physical input: a key, a mouse button or wheel direction,
+// a gamepad button, or for axes an analog stick, trigger or pointer
+// motion.
+type Source struct {
+	Device Device
+	Code   int
+}
+
+// The mouse wheel has no buttons, each notch taps one of these.
+const (
+	WheelUp = int(glfw.MouseButtonLast) + 1 + iota
+	WheelDown
+	WheelLeft
+	WheelRight
+)
+
+// Triggers are analog but can be bound as buttons, they're down past
+// half way.
+const (
+	PadLeftTrigger = int(glfw.ButtonLast) + 1 + iota
+	PadRightTrigger
+	padButtons
+)
+
+// Analog pointer sources, the cursor motion and the scroll since the last
+// update.
+const (
+	ScrollX = iota
+	ScrollY
+	MouseX
+	MouseY
+)
+
+var keyNames = map[string]glfw.Key{
+	"a": glfw.KeyA, "b": glfw.KeyB, "c": glfw.KeyC, "d": glfw.KeyD, "e": glfw.KeyE,
+	"f": glfw.KeyF, "g": glfw.KeyG, "h": glfw.KeyH, "i": glfw.KeyI, "j": glfw.KeyJ,
+	"k": glfw.KeyK, "l": glfw.KeyL, "m": glfw.KeyM, "n": glfw.KeyN, "o": glfw.KeyO,
+	"p": glfw.KeyP, "q": glfw.KeyQ, "r": glfw.KeyR, "s": glfw.KeyS, "t": glfw.KeyT,
+	"u": glfw.KeyU, "v": glfw.KeyV, "w": glfw.KeyW, "x": glfw.KeyX, "y": glfw.KeyY,
+	"z": glfw.KeyZ,
+
+	"0": glfw.Key0, "1": glfw.Key1, "2": glfw.Key2, "3": glfw.Key3, "4": glfw.Key4,
+	"5": glfw.Key5, "6": glfw.Key6, "7": glfw.Key7, "8": glfw.Key8, "9": glfw.Key9,
+
+	"f1": glfw.KeyF1, "f2": glfw.KeyF2, "f3": glfw.KeyF3, "f4": glfw.KeyF4,
+	"f5": glfw.KeyF5, "f6": glfw.KeyF6, "f7": glfw.KeyF7, "f8": glfw.KeyF8,
+	"f9": glfw.KeyF9, "f10": glfw.KeyF10, "f11": glfw.KeyF11, "f12": glfw.KeyF12,
+
+	"kp0": glfw.KeyKP0, "kp1": glfw.KeyKP1, "kp2": glfw.KeyKP2, "kp3": glfw.KeyKP3,
+	"kp4": glfw.KeyKP4, "kp5": glfw.KeyKP5, "kp6": glfw.KeyKP6, "kp7": glfw.KeyKP7,
+	"kp8": glfw.KeyKP8, "kp9": glfw.KeyKP9,
+	"kpdecimal": glfw.KeyKPDecimal, "kpdivide": glfw.KeyKPDivide, "kpmultiply": glfw.KeyKPMultiply,
+	"kpsubtract": glfw.KeyKPSubtract, "kpadd": glfw.KeyKPAdd, "kpenter": glfw.KeyKPEnter,
+	"kpequal": glfw.KeyKPEqual,
+
+	"space": glfw.KeySpace, "apostrophe": glfw.KeyApostrophe, "comma": glfw.KeyComma,
+	"minus": glfw.KeyMinus, "period": glfw.KeyPeriod, "slash": glfw.KeySlash,
+	"semicolon": glfw.KeySemicolon, "equal": glfw.KeyEqual, "leftbracket": glfw.KeyLeftBracket,
+	"backslash": glfw.KeyBackslash, "rightbracket": glfw.KeyRightBracket, "graveaccent": glfw.KeyGraveAccent,
+
+	"escape": glfw.KeyEscape, "enter": glfw.KeyEnter, "tab": glfw.KeyTab, "backspace": glfw.KeyBackspace,
+	"insert": glfw.KeyInsert, "delete": glfw.KeyDelete, "right": glfw.KeyRight, "left": glfw.KeyLeft,
+	"down": glfw.KeyDown, "up": glfw.KeyUp, "pageup": glfw.KeyPageUp, "pagedown": glfw.KeyPageDown,
+	"home": glfw.KeyHome, "end": glfw.KeyEnd, "capslock": glfw.KeyCapsLock, "scrolllock": glfw.KeyScrollLock,
+	"numlock": glfw.KeyNumLock, "printscreen": glfw.KeyPrintScreen, "pause": glfw.KeyPause, "menu": glfw.KeyMenu,
+
+	"leftshift": glfw.KeyLeftShift, "rightshift": glfw.KeyRightShift,
+	"leftcontrol": glfw.KeyLeftControl, "rightcontrol": glfw.KeyRightControl,
+	"leftalt": glfw.KeyLeftAlt, "rightalt": glfw.KeyRightAlt,
+	"leftsuper": glfw.KeyLeftSuper, "rightsuper": glfw.KeyRightSuper,
+}
+
+var mouseNames = map[string]int{
+	"mouseleft": int(glfw.MouseButtonLeft), "mouseright": int(glfw.MouseButtonRight),
+	"mousemiddle": int(glfw.MouseButtonMiddle), "mouse4": int(glfw.MouseButton4),
+	"mouse5": int(glfw.MouseButton5), "mouse6": int(glfw.MouseButton6),
+	"mouse7": int(glfw.MouseButton7), "mouse8": int(glfw.MouseButton8),
+	"wheelup": WheelUp, "wheeldown": WheelDown, "wheelleft": WheelLeft, "wheelright": WheelRight,
+}
+
+var padNames = map[string]int{
+	"pada": int(glfw.ButtonA), "padb": int(glfw.ButtonB), "padx": int(glfw.ButtonX), "pady": int(glfw.ButtonY),
+	"padlb": int(glfw.ButtonLeftBumper), "padrb": int(glfw.ButtonRightBumper),
+	"padback": int(glfw.ButtonBack), "padstart": int(glfw.ButtonStart), "padguide": int(glfw.ButtonGuide),
+	"padls": int(glfw.ButtonLeftThumb), "padrs": int(glfw.ButtonRightThumb),
+	"padup": int(glfw.ButtonDpadUp), "padright": int(glfw.ButtonDpadRight),
+	"paddown": int(glfw.ButtonDpadDown), "padleft": int(glfw.ButtonDpadLeft),
+	"padlt": PadLeftTrigger, "padrt": PadRightTrigger,
+}
+
+var analogNames = map[string]Source{
+	"padleftx": {Gamepad, int(glfw.AxisLeftX)}, "padlefty": {Gamepad, int(glfw.AxisLeftY)},
+	"padrightx": {Gamepad, int(glfw.AxisRightX)}, "padrighty": {Gamepad, int(glfw.AxisRightY)},
+	"padlt": {Gamepad, int(glfw.AxisLeftTrigger)}, "padrt": {Gamepad, int(glfw.AxisRightTrigger)},
+	"scrollx": {Mouse, ScrollX}, "scrolly": {Mouse, ScrollY},
+	"mousex": {Mouse, MouseX}, "mousey": {Mouse, MouseY},
+}
+
+var modifierNames = map[string]glfw.ModifierKey{
+	"shift": glfw.ModShift, "ctrl": glfw.ModControl, "control": glfw.ModControl,
+	"alt": glfw.ModAlt, "super": glfw.ModSuper,
+}
+
+// Key returns the Source of a keyboard key.
+func Key(key glfw.Key) Source {
+	return Source{Keyboard, int(key)}
+}
+
+// MouseButton returns the Source of a mouse button.
+func MouseButton(button glfw.MouseButton) Source {
+	return Source{Mouse, int(button)}
+}
+
+// PadButton returns the Source of a gamepad button, on any gamepad.
+func PadButton(button glfw.GamepadButton) Source {
+	return Source{Gamepad, int(button)}
+}
+
+// ParseSource looks up a button by name, e.g. "Escape", "F11", "MouseLeft",
+// "WheelUp" or "PadA". Names are case insensitive.
+func ParseSource(name string) (Source, error) {
+	n := strings.ToLower(strings.TrimSpace(name))
+	if key, ok := keyNames[n]; ok {
+		return Key(key), nil
+	}
+	if code, ok := mouseNames[n]; ok {
+		return Source{Mouse, code}, nil
+	}
+	if code, ok := padNames[n]; ok {
+		return Source{Gamepad, code}, nil
+	}
+	return Source{}, fmt.Errorf("input: unknown button '%s'", name)
+}
+
+// ParseAnalog looks up an analog source by name: "PadLeftX", "PadLeftY",
+// "PadRightX", "PadRightY", "PadLT", "PadRT", "ScrollX", "ScrollY",
+// "MouseX" or "MouseY".
+func ParseAnalog(name string) (Source, error) {
+	if src, ok := analogNames[strings.ToLower(strings.TrimSpace(name))]; ok {
+		return src, nil
+	}
+	return Source{}, fmt.Errorf("input: unknown axis '%s'", name)
+}
+
+// String returns the lower case name ParseSource accepts.
+func (s Source) String() string {
+	if n, ok := s.name(); ok {
+		return n
+	}
+	return fmt.Sprintf("unknown(%d:%d)", s.Device, s.Code)
+}
+
+func (s Source) name() (string, bool) {
+	switch s.Device {
+	case Keyboard:
+		for n, key := range keyNames {
+			if int(key) == s.Code {
+				return n, true
+			}
+		}
+	case Mouse:
+		for n, code := range mouseNames {
+			if code == s.Code {
+				return n, true
+			}
+		}
+	case Gamepad:
+		for n, code := range padNames {
+			if code == s.Code {
+				return n, true
+			}
+		}
+	}
+	return "", false
+}
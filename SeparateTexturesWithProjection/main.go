@@ -1,181 +1,716 @@
 package main
 
 import (
-	"SimpleOpenGL-Go/SeparateTexturesWithProjection/api"
+	"SimpleOpenGL-Go/SeparateTexturesWithProjection/app"
+	"SimpleOpenGL-Go/SeparateTexturesWithProjection/capture"
 	"SimpleOpenGL-Go/SeparateTexturesWithProjection/display"
-	"SimpleOpenGL-Go/SeparateTexturesWithProjection/maths"
+	"SimpleOpenGL-Go/SeparateTexturesWithProjection/gldebug"
+	"SimpleOpenGL-Go/SeparateTexturesWithProjection/glstate"
+	"SimpleOpenGL-Go/SeparateTexturesWithProjection/input"
+	"SimpleOpenGL-Go/SeparateTexturesWithProjection/overlay"
+	"SimpleOpenGL-Go/SeparateTexturesWithProjection/profile"
 	"SimpleOpenGL-Go/SeparateTexturesWithProjection/render"
+	"SimpleOpenGL-Go/SeparateTexturesWithProjection/scene"
 	"SimpleOpenGL-Go/SeparateTexturesWithProjection/textures"
+	"SimpleOpenGL-Go/SeparateTexturesWithProjection/tiled"
+	"bufio"
+	"embed"
+	"flag"
 	"fmt"
 	"log"
+	"math"
+	"os"
+	"path/filepath"
 	"runtime"
 	"time"
 
 	"github.com/go-gl/gl/v4.5-core/gl"
-	"github.com/go-gl/glfw/v3.3/glfw"
+	"golang.org/x/image/font/gofont/goregular"
 )
 
+// The assets are embedded so the binary can be launched from anywhere.
+//
+//go:embed assets
+var assets embed.FS
+
 var (
-	textureAtlas        = textures.NewTextureAtlas("assets/texture_manifest.txt")
-	texture2Atlas       = textures.NewTextureAtlas("assets/texture2_manifest.txt")
+	textureAtlas        = textures.NewTextureAtlas(assets, "assets/texture_manifest.txt")
+	texture2Atlas       = textures.NewTextureAtlas(assets, "assets/texture2_manifest.txt")
+	font                = textures.NewBMFont(assets, "assets/fonts/go-regular-24.fnt")
+	sdfFont             = textures.NewBMFont(assets, "assets/fonts/go-regular-sdf.fnt")
 	textureRender       *render.TextureRender
 	texture2Render      *render.TextureRender
 	activeTextureRender *render.TextureRender
+	mineSwarm           *swarm
+	window              *display.Window
+	controls            *input.Handler
+	scaler              *display.Scaler
+	camera              *display.Camera2D
+	followTriangle      bool
+)
+
+// The triangle orbits at 60 degrees per second.
+const orbitSpeed = 60.0
+
+// postActions maps the input actions toggling post effects to the effects.
+var postActions = map[string]string{
+	"post_bloom":     "bloom",
+	"post_blur":      "blur",
+	"post_grading":   "color grading",
+	"post_chromatic": "chromatic aberration",
+	"post_pixelate":  "pixelate",
+	"post_crt":       "crt",
+	"post_vignette":  "vignette",
+}
+
+// warmGrade is the demo's color grading, warmer and with more contrast.
+func warmGrade(r, g, b float32) (float32, float32, float32) {
+	contrast := func(v float32) float32 {
+		return (v-0.5)*1.2 + 0.5
+	}
+	return contrast(r * 1.08), contrast(g * 1.02), contrast(b * 0.88)
+}
+
+// The minimap's size in pixels and HUD units
+const (
+	minimapWidth  = 256
+	minimapHeight = 192
+)
+
+// Render queue layers, drawn in this order.
+const (
+	layerBackground uint8 = iota
+	layerOverlay
+	layerWorld
+	layerHUD
 )
 
 func main() {
 	runtime.LockOSThread()
-	display.PolygonMode = false
 
-	window := display.InitGlfw()
-	defer glfw.Terminate()
+	var recordPath, replayPath, captureDir string
+	config, err := display.ParseConfig(os.Args[0], os.Args[1:], func(fs *flag.FlagSet) {
+		fs.StringVar(&recordPath, "record", "", "record the input to a file")
+		fs.StringVar(&replayPath, "replay", "", "replay the input recorded in a file")
+		fs.StringVar(&captureDir, "capture-dir", "captures", "directory for screenshots and frame sequences")
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	window, err = display.NewWindow(config)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer window.Destroy()
+
+	d := newDemo(config)
+	d.recordPath = recordPath
+	d.replayPath = replayPath
+	d.captureDir = captureDir
+
+	runner := app.NewRunner(d, window)
+	if err := runner.Run(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// demo is the Application showing off the renderers.
+type demo struct {
+	config display.Config
 
+	hudCamera *display.Camera2D
+
+	triangleRender *render.TriangleRender
+	spriteRender   *render.SpriteRenderer
+	shapeRender    *render.ShapeRenderer
+	textRender     *render.TextRenderer
+	titleRender    *render.TextRenderer
+	sdfRender      *render.TextRenderer
+	glyphCache     *textures.GlyphCache
+	shipRender     *render.TextureRender
+	tilemapRender  *render.TilemapRenderer
+
+	// A ship carrying a turret, the turret inherits the ship's motion
+	sceneRoot, ship, turret *scene.Node
+
+	queue *render.RenderQueue
+
+	// The world seen from further out, drawn in a corner of the HUD
+	minimap       *render.RenderTarget
+	minimapCamera *display.Camera2D
+	minimapRender *render.TextureRender
+	showMinimap   bool
+
+	// Full-screen effects, all off until toggled
+	post *render.PostChain
+
+	// Screenshots and frame sequences, see capture.Capturer
+	captureDir string
+	capturer   *capture.Capturer
+	screenshot bool
+
+	stats *overlay.Overlay
+
+	// CPU and GPU times of the frame's passes, see profile.Profiler
+	profiler *profile.Profiler
+	gpuTimer *profile.GLTimer
+
+	// The orbit angle in degrees before and after the last update
+	prevAngle, angle float64
+
+	// Input recording and replay, see input.Recorder
+	recordPath, replayPath string
+	recording              *os.File
+	recordingBuffer        *bufio.Writer
+	recorder               *input.Recorder
+	replaying              bool
+}
+
+func newDemo(config display.Config) *demo {
+	o := new(demo)
+	o.config = config
+	return o
+}
+
+func (d *demo) Init() error {
 	if err := gl.Init(); err != nil {
-		panic(err)
+		return err
 	}
 
 	version := gl.GoStr(gl.GetString(gl.VERSION))
+	gldebug.Check()
 	log.Println("OpenGL version", version)
 
-	window.SetKeyCallback(KeyCallback)
+	// A debug context also checks the state cache against GL and routes
+	// the driver's messages to the log
+	glstate.SetDebug(d.config.Debug)
+	if d.config.Debug && !gldebug.Install() {
+		log.Println("No debug context, GL errors are only logged when built with -tags gldebug")
+	}
 
-	// -----------------------------------------------------------
-	viewport := display.NewViewport()
-	viewport.SetDimensions(0, 0, display.Width, display.Height)
-	viewport.Apply()
+	inputConfig, err := input.LoadConfig(assets, "assets/input.toml")
+	if err != nil {
+		return err
+	}
+	bindings, err := inputConfig.Map()
+	if err != nil {
+		return err
+	}
+	controls = input.NewHandler(bindings)
+	controls.Attach(window)
 
-	projection := buildProjection()
+	if d.replayPath != "" {
+		f, err := os.Open(d.replayPath)
+		if err != nil {
+			return err
+		}
+		player, err := input.LoadRecording(f)
+		f.Close()
+		if err != nil {
+			return err
+		}
+		controls.SetPlayer(player)
+		d.replaying = true
+		log.Println("Replaying", d.replayPath)
+	}
 
-	view := buildView()
+	if d.recordPath != "" {
+		if d.recording, err = os.Create(d.recordPath); err != nil {
+			return err
+		}
+		d.recordingBuffer = bufio.NewWriter(d.recording)
+		d.recorder = input.NewRecorder(d.recordingBuffer)
+		controls.SetRecorder(d.recorder)
+		log.Println("Recording to", d.recordPath)
+	}
+
+	// -----------------------------------------------------------
+	// The world camera pans and zooms, the HUD camera keeps text in place.
+	width := float32(d.config.Width)
+	height := float32(d.config.Height)
+	camera = display.NewCamera2D(width, height)
+	camera.SetFollowSpeed(4.0)
+	camera.SetBounds(-width, -height, width, height)
+	d.hudCamera = display.NewCamera2D(width, height)
+
+	// The scene is laid out for Width x Height, the scaler adapts it to
+	// the window.
+	scaler = display.NewScaler(d.config.Width, d.config.Height, display.ScaleFit)
+	scaler.Attach(window, camera, d.hudCamera)
 
 	textureAtlas.Build()
 	texture2Atlas.Build()
 
 	textureRender = render.NewTextureRender(textureAtlas)
 	textureRender.Build("orange ship")
-	textureRender.SetUniforms(projection, view)
 	activeTextureRender = textureRender
 	textureRender.SetPosition(-200.0, 0.0)
 
 	texture2Render = render.NewTextureRender(texture2Atlas)
 	texture2Render.Build("green ship")
-	texture2Render.SetUniforms(projection, view)
 	texture2Render.SetPosition(200.0, 0.0)
 
-	triangleRender := render.NewTriangleRender()
-	triangleRender.Build("Triangle")
-	triangleRender.SetUniforms(projection, view)
-	triangleRender.SetAngle(0.0)
+	// A Tiled map under everything, centered on the origin
+	meadow := tiled.NewMap(assets, "assets/maps/meadow.tmx")
+	meadow.Build()
+	d.tilemapRender = render.NewTilemapRenderer(meadow)
+	d.tilemapRender.Build()
+	mapWidth, mapHeight := d.tilemapRender.Size()
+	d.tilemapRender.SetPosition(-mapWidth/2.0, mapHeight/2.0)
+
+	d.triangleRender = render.NewTriangleRender()
+	d.triangleRender.Build("Triangle")
+	d.triangleRender.SetAngle(0.0)
+
+	d.shipRender = render.NewTextureRender(textureAtlas)
+	d.shipRender.Build("ctype ship")
+
+	d.sceneRoot = scene.NewNode("root")
+	d.ship = scene.NewNode("ship")
+	d.ship.SetPosition(0.0, -250.0)
+	d.ship.SetDrawable(d.shipRender)
+	d.sceneRoot.AddChild(d.ship)
+
+	d.turret = scene.NewNode("turret")
+	d.turret.SetPosition(0.0, 24.0)
+	d.turret.SetScale(0.8, 0.8)
+	d.turret.SetDrawable(d.triangleRender)
+	d.ship.AddChild(d.turret)
+
+	d.gpuTimer = profile.NewGLTimer()
+	d.profiler = profile.NewProfiler(d.gpuTimer)
+
+	d.queue = render.NewRenderQueue()
+	d.queue.SetProfiler(d.profiler)
+
+	d.minimap = render.NewRenderTarget(minimapWidth, minimapHeight)
+	d.minimap.Build()
+	d.minimapCamera = display.NewCamera2D(width, height)
+	d.minimapCamera.SetZoom(0.5)
+	d.minimapRender = render.NewTargetTextureRender(d.minimap)
+	d.minimapRender.Build("")
+	d.minimapRender.SetSize(minimapWidth, minimapHeight)
+	d.showMinimap = true
+
+	d.post = render.NewPostChain(d.config.Width, d.config.Height)
+	d.post.AddEffect(render.NewBloom())
+	d.post.AddEffect(render.NewBlur())
+	d.post.AddEffect(render.NewColorGrading(render.NewLUT(16, warmGrade)))
+	d.post.AddEffect(render.NewChromaticAberration())
+	d.post.AddEffect(render.NewPixelate())
+	d.post.AddEffect(render.NewCRT())
+	d.post.AddEffect(render.NewVignette())
+	for _, e := range d.post.Effects() {
+		e.SetEnabled(false)
+	}
+	d.post.Build()
+
+	d.capturer = capture.NewCapturer(d.captureDir)
+
+	// The help text and the stats overlay share the font
+	font.Build()
+
+	d.stats = overlay.NewOverlay(font)
+	d.stats.Build()
+	d.stats.SetProfiler(d.profiler)
+
+	d.spriteRender = render.NewSpriteRenderer(textureAtlas)
+	d.spriteRender.Build()
+	mineSwarm = newSwarm(d.spriteRender, width, height)
+
+	d.shapeRender = render.NewShapeRenderer()
+	d.shapeRender.Build()
+
+	d.textRender = render.NewTextRenderer(font)
+	d.textRender.Build()
+	d.textRender.SetScale(0.75)
+	d.textRender.SetText("0: switch ship  1-5: change shape\nM: wireframe  P: points  Esc: quit\nB: mine swarm  I: toggle instancing\nArrows: pan  Wheel: zoom  Q/E: rotate  F: follow  K: shake  R: reset\nV: scaling policy  F11: fullscreen  O: queue sorting  N: minimap\nF1-F7: bloom, blur, grading, chromatic, pixelate, CRT, vignette\nF12: screenshot  Ctrl+F12: record frames  F9: stats  F8: trace")
+
+	// Glyphs are rasterized from the TrueType font as they are needed.
+	d.glyphCache = textures.NewGlyphCache(goregular.TTF, 40)
+	d.glyphCache.Build()
+	d.titleRender = render.NewTextRenderer(d.glyphCache)
+	d.titleRender.Build()
+	d.titleRender.SetAlignment(textures.AlignCenter)
+	d.titleRender.SetText("Separate Textures With Projection")
+	d.titleRender.SetColor(1.0, 0.8, 0.3, 1.0)
+
+	// Distance field text stays crisp when scaled up.
+	sdfFont.Build()
+	d.sdfRender = render.NewTextRenderer(sdfFont)
+	d.sdfRender.UseDistanceField(render.DistanceField{
+		OutlineWidth:   0.2,
+		OutlineColor:   [4]float32{0.1, 0.1, 0.4, 1.0},
+		ShadowOffset:   [2]float32{2.0, 2.0},
+		ShadowSoftness: 0.1,
+		ShadowColor:    [4]float32{0.0, 0.0, 0.0, 0.6},
+	})
+	d.sdfRender.Build()
+	d.sdfRender.SetScale(2.5)
+	d.sdfRender.SetAlignment(textures.AlignCenter)
+	d.sdfRender.SetText("Ships")
 
 	// -----------------------------------------------------------
-	angle := 0.0
-
 	gl.ClearColor(0.25, 0.25, 0.25, 1.0)
+	gldebug.Check()
+
+	glstate.Enable(gl.BLEND)
+	glstate.BlendFunc(gl.SRC_ALPHA, gl.ONE_MINUS_SRC_ALPHA)
+
+	return nil
+}
+
+func (d *demo) Update(dt float64) {
+	controls.Update()
+	if d.replaying && !controls.Replaying() {
+		d.replaying = false
+		log.Println("Replay finished at update", controls.Step())
+	}
+	handleActions()
+	if controls.Pressed("queue_sorting") {
+		d.queue.SetSorted(!d.queue.Sorted())
+		log.Printf("Render queue sorted: %v, last frame: %+v", d.queue.Sorted(), d.queue.Stats())
+	}
+	if controls.Pressed("stats") {
+		d.stats.Toggle()
+	}
+	if controls.Pressed("trace") {
+		d.toggleTrace()
+	}
+	if controls.Pressed("minimap") {
+		d.showMinimap = !d.showMinimap
+	}
+	if controls.Pressed("screenshot") {
+		d.screenshot = true
+	}
+	if controls.Pressed("record_frames") {
+		if d.capturer.Recording() {
+			log.Println("Recorded", d.capturer.StopSequence(), "frames")
+		} else {
+			log.Println("Recording frames to", d.capturer.StartSequence())
+		}
+	}
+	for action, effect := range postActions {
+		if controls.Pressed(action) {
+			log.Printf("Post effect %s: %v", effect, d.post.Effect(effect).Toggle())
+		}
+	}
+
+	if followTriangle {
+		radians := d.angle * display.DegreeToRadians
+		camera.Follow(float32(100.0*math.Cos(radians)), float32(100.0*math.Sin(radians)))
+	}
+	updateCamera(float32(dt))
+
+	d.prevAngle = d.angle
+	d.angle += orbitSpeed * dt
+
+	mineSwarm.Update(float32(dt))
+
+	radians := d.angle * display.DegreeToRadians
+	d.ship.SetPosition(float32(150.0*math.Sin(radians)), -250.0)
+	d.ship.Rotate(0.5 * dt)
+	d.turret.Rotate(-2.0 * dt)
+}
+
+func (d *demo) Render(alpha float64) {
+	d.stats.FrameStart()
+	d.profiler.BeginFrame()
+
+	d.triangleRender.SetAngle(d.prevAngle + (d.angle-d.prevAngle)*alpha)
+
+	if d.showMinimap {
+		d.profiler.Time("minimap", d.drawMinimap)
+	}
+
+	proj, view := camera.Projection(), camera.View()
+	textureRender.SetUniforms(proj, view)
+	texture2Render.SetUniforms(proj, view)
+	d.triangleRender.SetUniforms(proj, view)
+	d.shipRender.SetUniforms(proj, view)
+	d.shapeRender.SetUniforms(proj, view)
+	d.spriteRender.SetUniforms(proj, view)
+	d.tilemapRender.SetUniforms(proj, view)
+	d.tilemapRender.SetVisibleBounds(camera.VisibleBounds())
+
+	hudProj, hudView := d.hudCamera.Projection(), d.hudCamera.View()
+	d.textRender.SetUniforms(hudProj, hudView)
+	d.titleRender.SetUniforms(hudProj, hudView)
+	d.sdfRender.SetUniforms(hudProj, hudView)
+	d.stats.SetUniforms(hudProj, hudView)
+	d.minimapRender.SetUniforms(hudProj, hudView)
+
+	d.profiler.Begin("scene")
+	d.post.Begin()
+	gl.Clear(gl.COLOR_BUFFER_BIT | gl.DEPTH_BUFFER_BIT)
+	gldebug.Check()
+
+	// The farthest of the background layer, drawn first
+	d.tilemapRender.Submit(d.queue, layerBackground, 1.0)
+	d.queue.Submit(render.DrawCommand{Name: "SpriteRenderer", Layer: layerBackground, Translucent: true, Custom: mineSwarm.Draw})
+	d.queue.Submit(render.DrawCommand{Name: "ShapeRenderer", Layer: layerOverlay, Translucent: true, Custom: d.drawOverlay})
+
+	d.triangleRender.Submit(d.queue, layerWorld, 0.5)
+
+	textureRender.Submit(d.queue, layerWorld, 0.5)
+	texture2Render.Submit(d.queue, layerWorld, 0.5)
+
+	d.sceneRoot.Submit(d.queue, layerWorld)
+
+	for _, text := range []*render.TextRenderer{d.textRender, d.titleRender, d.sdfRender} {
+		d.queue.Submit(render.DrawCommand{Name: "TextRenderer", Layer: layerHUD, Translucent: true, Custom: text.Draw})
+	}
+	if d.showMinimap {
+		d.minimapRender.Submit(d.queue, layerHUD, 0.0)
+	}
+
+	d.glyphCache.NextFrame()
+	d.queue.Flush()
+	d.profiler.End()
+
+	d.profiler.Time("post", d.post.End)
+
+	// Not captured and not post processed
+	d.capture()
+	d.profiler.Time("overlay", d.stats.Draw)
+
+	d.profiler.EndFrame()
+	d.stats.FrameEnd()
+}
+
+// toggleTrace starts recording a trace or saves the one recorded to the
+// capture directory.
+func (d *demo) toggleTrace() {
+	if !d.profiler.Tracing() {
+		d.profiler.StartTrace()
+		log.Println("Tracing, press again to save")
+		return
+	}
+
+	d.profiler.StopTrace()
+	path := filepath.Join(d.captureDir, "trace-"+time.Now().Format("20060102-150405")+".json")
+	if err := d.profiler.SaveTrace(path); err != nil {
+		log.Println("Trace failed:", err)
+		return
+	}
+	log.Println("Trace saved to", path)
+}
+
+// capture reads back the frame if a screenshot was asked for or a
+// sequence is being recorded. Only the viewport is read, not the bars
+// around it.
+func (d *demo) capture() {
+	if !d.screenshot && !d.capturer.Recording() {
+		return
+	}
+
+	img := render.ReadScreen(scaler.Viewport().Dimensions())
+	if d.screenshot {
+		d.screenshot = false
+		log.Println("Screenshot saved to", d.capturer.Screenshot(img))
+	}
+	d.capturer.Frame(img)
+}
+
+// Resize keeps the HUD text anchored to the edges of the visible area,
+// which the scaler has already updated.
+func (d *demo) Resize(width, height int) {
+	w, h := scaler.WorldSize()
+
+	d.textRender.SetPosition(-w/2.0+10.0, h/2.0-10.0)
 
-	gl.Enable(gl.BLEND)
-	gl.BlendFunc(gl.SRC_ALPHA, gl.ONE_MINUS_SRC_ALPHA)
-
-	for !window.ShouldClose() && !display.QuitTriggered {
-		gl.Clear(gl.COLOR_BUFFER_BIT | gl.DEPTH_BUFFER_BIT)
-
-		triangleRender.Draw()
-		angle++
-		triangleRender.SetAngle(angle)
-
-		textureRender.Draw()
-		texture2Render.Draw()
-
-		glfw.PollEvents()
-		window.SwapBuffers()
-
-		time.Sleep(time.Millisecond)
-	}
-}
-
-func buildProjection() *display.Projection {
-	projection := display.NewCamera()
-
-	// This projection contains centering "built in". You don't need
-	// a view matrix as long as your not interested in moving the view.
-	// Otherwise you would need to rebuilt the projection. Typically
-	// a second view-matrix is used for controlling a "camera".
-	// sH := float32(height) / 2
-	// sW := float32(width) / 2
-	// projection.SetProjection(
-	// 	-sH, -sW, // bottom,left
-	// 	sH, sW, //top,right
-	// 	-1.0, 1.0)
-
-	// This projection is "just" a projection without any centering.
-	projection.SetProjection(
-		0.0, 0.0, // bottom,left
-		float32(display.Height), float32(display.Width), //top,right
-		-1.0, 1.0) //0.1, 100.0
-
-	return projection
-}
-
-func buildView() api.IMatrix4 {
-	centered := true
-	offsetX := float32(0.0)
-	offsetY := float32(0.0)
-
-	if centered {
-		offsetX = float32(display.Width) / 2.0
-		offsetY = float32(display.Height) / 2.0
-	}
-
-	view := maths.NewMatrix4()
-	view.SetTranslate3Comp(offsetX, offsetY, 0.5)
-
-	return view
-}
-
-func KeyCallback(glfwW *glfw.Window, key glfw.Key, scancode int, action glfw.Action, mods glfw.ModifierKey) {
-	// fmt.Println("key pressed ", key)
-
-	if action == glfw.Press {
-		switch key {
-		case glfw.KeyEscape:
-			display.QuitTriggered = true
-		case glfw.KeyM:
-			if !display.PolygonMode {
-				gl.PolygonMode(gl.FRONT_AND_BACK, gl.LINE)
-			} else {
-				gl.PolygonMode(gl.FRONT_AND_BACK, gl.FILL)
-			}
-			display.PolygonMode = !display.PolygonMode
-		case glfw.KeyP:
-			if !display.PointMode {
-				gl.PointSize(5)
-				gl.PolygonMode(gl.FRONT_AND_BACK, gl.POINT)
-			} else {
-				gl.PointSize(1)
-				gl.PolygonMode(gl.FRONT_AND_BACK, gl.FILL)
-			}
-			display.PointMode = !display.PointMode
-		case glfw.Key0:
-			if activeTextureRender == textureRender {
-				activeTextureRender = texture2Render
-			} else {
-				activeTextureRender = textureRender
-			}
-
-		case glfw.Key1:
-			fmt.Println("mine")
-			activeTextureRender.ChangeShape("mine")
-		case glfw.Key2:
-			fmt.Println("green ship")
-			activeTextureRender.ChangeShape("green ship")
-		case glfw.Key3:
-			fmt.Println("orange ship")
-			activeTextureRender.ChangeShape("orange ship")
-		case glfw.Key4:
-			fmt.Println("ctype ship")
-			activeTextureRender.ChangeShape("ctype ship")
-		case glfw.Key5:
-			fmt.Println("bomb")
-			activeTextureRender.ChangeShape("bomb")
+	d.titleRender.SetWrapWidth(w)
+	d.titleRender.SetPosition(-w/2.0, -h/2.0+60.0)
+
+	d.sdfRender.SetWrapWidth(w)
+	d.sdfRender.SetPosition(-w/2.0, 250.0)
+
+	d.minimapRender.SetPosition(w/2.0-minimapWidth/2.0-10.0, h/2.0-minimapHeight/2.0-10.0)
+
+	// Below the help text
+	d.stats.SetPosition(-w/2.0+10.0, h/2.0-190.0)
+
+	_, _, vw, vh := scaler.Viewport().Dimensions()
+	if vw > 0 && vh > 0 {
+		d.post.Resize(vw, vh)
+	}
+}
+
+// drawMinimap draws the world, without the overlay, into the minimap
+// target. It leaves the world renderers' uniforms set for the minimap.
+// The swarm is left out, it's drawn once per frame so its benchmark
+// times whole frames.
+func (d *demo) drawMinimap() {
+	proj, view := d.minimapCamera.Projection(), d.minimapCamera.View()
+	textureRender.SetUniforms(proj, view)
+	texture2Render.SetUniforms(proj, view)
+	d.triangleRender.SetUniforms(proj, view)
+	d.shipRender.SetUniforms(proj, view)
+	d.tilemapRender.SetUniforms(proj, view)
+	d.tilemapRender.SetVisibleBounds(d.minimapCamera.VisibleBounds())
+
+	d.minimap.Bind()
+	d.minimap.Clear(0.1, 0.1, 0.15, 1.0)
+
+	d.tilemapRender.Draw()
+	d.triangleRender.Draw()
+	textureRender.Draw()
+	texture2Render.Draw()
+	d.sceneRoot.Draw()
+
+	d.minimap.Unbind()
+}
+
+func (d *demo) drawOverlay() {
+	drawOverlay(d.shapeRender)
+}
+
+func (d *demo) Shutdown() {
+	if err := d.capturer.Close(); err != nil {
+		log.Println("Capture failed:", err)
+	}
+
+	if d.recording == nil {
+		return
+	}
+
+	err := d.recorder.Err()
+	if err == nil {
+		err = d.recordingBuffer.Flush()
+	}
+	if cerr := d.recording.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		log.Println("Recording failed:", err)
+	}
+}
+
+// drawOverlay outlines the ships, highlighting the one the number keys change,
+// and the triangle's orbit.
+func drawOverlay(shapes *render.ShapeRenderer) {
+	active := render.Color{R: 1.0, G: 1.0, B: 0.0, A: 1.0}
+	inactive := render.Color{R: 0.6, G: 0.6, B: 0.6, A: 0.5}
+
+	shapes.Begin()
+
+	c := inactive
+	if activeTextureRender == textureRender {
+		c = active
+	}
+	shapes.DrawRect(-200.0-36.0, -36.0, 72.0, 72.0, 2.0, c)
+
+	c = inactive
+	if activeTextureRender == texture2Render {
+		c = active
+	}
+	shapes.DrawRect(200.0-36.0, -36.0, 72.0, 72.0, 2.0, c)
+
+	shapes.DrawCircle(0.0, 0.0, 100.0, 1.0, 64, render.Color{R: 0.4, G: 0.7, B: 1.0, A: 0.4})
+	shapes.FillCircle(0.0, 0.0, 4.0, 16, render.Color{R: 0.4, G: 0.7, B: 1.0, A: 1.0})
+
+	// A concave "level" outline with a hole
+	level := []float32{-560, -300, -360, -300, -360, -160, -420, -160, -420, -240, -500, -240, -500, -160, -560, -160}
+	hole := []float32{-545, -280, -515, -280, -515, -255, -545, -255}
+	shapes.FillPolygon(level, render.Color{R: 0.3, G: 0.5, B: 0.3, A: 0.6}, hole)
+	shapes.DrawPolygon(level, 2.0, render.Color{R: 0.6, G: 0.9, B: 0.6, A: 1.0})
+
+	shapes.End()
+}
+
+// updateCamera pans, rotates and zooms with the camera axes.
+func updateCamera(dt float32) {
+	const panSpeed = 400.0  // pixels per second
+	const rotateSpeed = 1.0 // radians per second
+
+	camera.Pan(controls.Axis("pan_x")*panSpeed*dt, controls.Axis("pan_y")*panSpeed*dt)
+	camera.Rotate(float64(controls.Axis("rotate")) * rotateSpeed * float64(dt))
+
+	// Zoom about the cursor, a wheel notch is 10%
+	if zoom := controls.Axis("zoom"); zoom != 0 {
+		x, y := controls.Cursor()
+		vx, vy := scaler.WindowToView(float32(x), float32(y))
+		camera.ZoomAt(float32(math.Pow(1.1, float64(zoom))), vx, vy)
+	}
+
+	camera.Update(dt)
+}
+
+// shapeActions are the actions that change the active ship's shape.
+var shapeActions = []struct {
+	action, shape string
+}{
+	{"mine", "mine"},
+	{"green_ship", "green ship"},
+	{"orange_ship", "orange ship"},
+	{"ctype_ship", "ctype ship"},
+	{"bomb", "bomb"},
+}
+
+// handleActions applies the actions pressed in this update.
+func handleActions() {
+	if controls.Pressed("quit") {
+		window.Quit()
+	}
+	if controls.Pressed("wireframe") {
+		if !window.PolygonMode() {
+			glstate.PolygonMode(gl.LINE)
+		} else {
+			glstate.PolygonMode(gl.FILL)
+		}
+		window.SetPolygonMode(!window.PolygonMode())
+	}
+	if controls.Pressed("points") {
+		if !window.PointMode() {
+			glstate.PointSize(5)
+			glstate.PolygonMode(gl.POINT)
+		} else {
+			glstate.PointSize(1)
+			glstate.PolygonMode(gl.FILL)
+		}
+		window.SetPointMode(!window.PointMode())
+	}
+	if controls.Pressed("fullscreen") {
+		window.ToggleFullscreen()
+	}
+	if controls.Pressed("scaling_policy") {
+		scaler.SetPolicy((scaler.Policy() + 1) % (display.ScaleExtend + 1))
+		log.Println("Scaling policy:", scaler.Policy())
+	}
+	if controls.Pressed("follow") {
+		followTriangle = !followTriangle
+		if !followTriangle {
+			camera.StopFollowing()
+		}
+	}
+	if controls.Pressed("shake") {
+		camera.Shake(12.0, 0.5)
+	}
+	if controls.Pressed("reset_camera") {
+		followTriangle = false
+		camera.StopFollowing()
+		camera.SetPosition(0.0, 0.0)
+		camera.SetZoom(1.0)
+		camera.SetRotation(0.0)
+	}
+	if controls.Pressed("swarm") {
+		mineSwarm.Toggle()
+	}
+	if controls.Pressed("instancing") {
+		mineSwarm.ToggleInstancing()
+	}
+	if controls.Pressed("switch_ship") {
+		if activeTextureRender == textureRender {
+			activeTextureRender = texture2Render
+		} else {
+			activeTextureRender = textureRender
+		}
+	}
+	for _, s := range shapeActions {
+		if controls.Pressed(s.action) {
+			fmt.Println(s.shape)
+			activeTextureRender.ChangeShape(s.shape)
 		}
 	}
 }
@@ -0,0 +1,230 @@
+// Package overlay draws performance statistics on top of the scene: the
+// frame rate, a graph of recent CPU frame times, the renderers' draw
+// counts, glstate's binds, the live GL objects and, given a profiler, the
+// CPU and GPU time of each scope.
+package overlay
+
+import (
+	"SimpleOpenGL-Go/SeparateTexturesWithProjection/api"
+	"SimpleOpenGL-Go/SeparateTexturesWithProjection/display"
+	"SimpleOpenGL-Go/SeparateTexturesWithProjection/glstate"
+	"SimpleOpenGL-Go/SeparateTexturesWithProjection/profile"
+	"SimpleOpenGL-Go/SeparateTexturesWithProjection/render"
+	"SimpleOpenGL-Go/SeparateTexturesWithProjection/textures"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-gl/gl/v4.5-core/gl"
+)
+
+const (
+	// Frames kept for the graph, one bar each
+	historySize = 120
+	barWidth    = 2.0
+	graphHeight = 60.0
+	// The graph's full height in milliseconds, two 60 Hz frames
+	graphScale = 1000.0 / 30.0
+	padding    = 8.0
+	// How often the text changes, faster is unreadable
+	textInterval = 250 * time.Millisecond
+)
+
+var (
+	background = render.Color{R: 0.0, G: 0.0, B: 0.0, A: 0.6}
+	budgetLine = render.Color{R: 1.0, G: 1.0, B: 1.0, A: 0.5}
+	fast       = render.Color{R: 0.3, G: 0.9, B: 0.3, A: 1.0}
+	slow       = render.Color{R: 1.0, G: 0.8, B: 0.2, A: 1.0}
+	late       = render.Color{R: 1.0, G: 0.3, B: 0.3, A: 1.0}
+)
+
+// Overlay shows the statistics of the previous frame. Call FrameStart at
+// the start of each frame, before anything is drawn, and FrameEnd once
+// everything has been submitted.
+type Overlay struct {
+	shapes *render.ShapeRenderer
+	text   *render.TextRenderer
+
+	visible bool
+	// Upper left corner in the HUD camera's units
+	x, y float32
+
+	frameStart, lastStart time.Time
+
+	// CPU frame times in milliseconds, a ring starting at next
+	history []float32
+	next    int
+
+	// Frames and time since the text last changed
+	frames  int
+	elapsed time.Duration
+
+	draws   render.FrameStats
+	state   glstate.Stats
+	objects glstate.Objects
+
+	profiler *profile.Profiler
+}
+
+func NewOverlay(font textures.Font) *Overlay {
+	o := new(Overlay)
+	o.shapes = render.NewShapeRenderer()
+	o.text = render.NewTextRenderer(font)
+	o.history = make([]float32, historySize)
+	return o
+}
+
+func (o *Overlay) Build() {
+	o.shapes.Build()
+	o.text.Build()
+	o.text.SetScale(0.6)
+	o.text.SetText("Measuring...")
+}
+
+func (o *Overlay) SetVisible(visible bool) {
+	o.visible = visible
+}
+
+func (o *Overlay) Visible() bool {
+	return o.visible
+}
+
+// Toggle shows or hides the overlay and returns whether it's visible.
+func (o *Overlay) Toggle() bool {
+	o.visible = !o.visible
+	return o.visible
+}
+
+// SetProfiler adds a line per scope of p, nil removes them.
+func (o *Overlay) SetProfiler(p *profile.Profiler) {
+	o.profiler = p
+}
+
+// SetPosition sets the upper left corner of the panel.
+func (o *Overlay) SetPosition(x, y float32) {
+	o.x, o.y = x, y
+}
+
+// SetUniforms sets the camera, usually the HUD's.
+func (o *Overlay) SetUniforms(proj *display.Projection, view api.IMatrix4) {
+	o.shapes.SetUniforms(proj, view)
+	o.text.SetUniforms(proj, view)
+}
+
+// FrameStart takes the previous frame's counts and resets them for this
+// frame, so it must be called whether or not the overlay is visible.
+func (o *Overlay) FrameStart() {
+	now := time.Now()
+	if !o.lastStart.IsZero() {
+		o.frames++
+		o.elapsed += now.Sub(o.lastStart)
+	}
+	o.lastStart = now
+	o.frameStart = now
+
+	o.draws = render.GetFrameStats()
+	o.state = glstate.GetStats()
+	o.objects = glstate.GetObjects()
+	render.ResetFrameStats()
+	glstate.ResetStats()
+
+	if o.elapsed >= textInterval {
+		if o.visible {
+			o.updateText()
+		}
+		o.frames = 0
+		o.elapsed = 0
+	}
+}
+
+// FrameEnd records the CPU time since FrameStart.
+func (o *Overlay) FrameEnd() {
+	o.history[o.next] = float32(time.Since(o.frameStart).Seconds() * 1000.0)
+	o.next = (o.next + 1) % len(o.history)
+}
+
+func (o *Overlay) updateText() {
+	fps := float64(o.frames) / o.elapsed.Seconds()
+
+	last := o.history[(o.next+len(o.history)-1)%len(o.history)]
+	var max float32
+	for _, ms := range o.history {
+		if ms > max {
+			max = ms
+		}
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b,
+		"FPS %.1f  CPU %.2f ms (max %.2f)\n"+
+			"Draws %d  Vertices %d  Instances %d\n"+
+			"Programs %d  Texture binds %d  GL calls %d (%d skipped)\n"+
+			"VAOs %d  Buffers %d  Textures %d  FBOs %d  RBOs %d  Programs %d",
+		fps, last, max,
+		o.draws.DrawCalls, o.draws.Vertices, o.draws.Instances,
+		o.state.ProgramChanges, o.state.TextureBinds, o.state.Calls, o.state.Skipped,
+		o.objects.VertexArrays, o.objects.Buffers, o.objects.Textures,
+		o.objects.Framebuffers, o.objects.Renderbuffers, o.objects.Programs,
+	)
+
+	if o.profiler != nil {
+		for _, s := range o.profiler.Stats() {
+			fmt.Fprintf(&b, "\n%s%s  CPU %.2f ms  GPU %.2f ms (max %.2f)",
+				strings.Repeat("  ", s.Depth), s.Name, millis(s.CPU), millis(s.GPU), millis(s.GPUMax))
+		}
+	}
+
+	o.text.SetText(b.String())
+}
+
+func millis(d time.Duration) float64 {
+	return d.Seconds() * 1000.0
+}
+
+// Draw draws the panel, filled whatever the polygon mode.
+func (o *Overlay) Draw() {
+	if !o.visible {
+		return
+	}
+
+	mode := glstate.GetPolygonMode()
+	glstate.PolygonMode(gl.FILL)
+
+	layout := o.text.Layout()
+	graphWidth := float32(historySize * barWidth)
+	width := layout.Width
+	if graphWidth > width {
+		width = graphWidth
+	}
+	width += 2 * padding
+	height := layout.Height + graphHeight + 3*padding
+
+	graphLeft := o.x + padding
+	graphBottom := o.y - height + padding
+
+	o.shapes.Begin()
+	o.shapes.FillRect(o.x, o.y-height, width, height, background)
+
+	for i := 0; i < historySize; i++ {
+		// Oldest on the left
+		ms := o.history[(o.next+i)%historySize]
+		c := fast
+		if ms > graphScale {
+			ms = graphScale
+			c = late
+		} else if ms > graphScale/2 {
+			c = slow
+		}
+		barHeight := graphHeight * ms / graphScale
+		o.shapes.FillRect(graphLeft+float32(i)*barWidth, graphBottom, barWidth, barHeight, c)
+	}
+
+	budget := graphBottom + graphHeight/2
+	o.shapes.DrawLine(graphLeft, budget, graphLeft+graphWidth, budget, 1.0, budgetLine)
+	o.shapes.End()
+
+	o.text.SetPosition(o.x+padding, o.y-padding)
+	o.text.Draw()
+
+	glstate.PolygonMode(mode)
+}
@@ -0,0 +1,191 @@
+package profile
+
+import (
+	"SimpleOpenGL-Go/SeparateTexturesWithProjection/gldebug"
+	"time"
+
+	"github.com/go-gl/gl/v4.5-core/gl"
+)
+
+// Frames in flight. The queries of frame n are read while frame n+1 is
+// recorded, so reading rarely waits for the GPU.
+const timerSlots = 2
+
+// segment is one GL_TIME_ELAPSED query. Only one can be active at a time
+// so a scope beginning inside another ends the outer one's segment, and
+// each segment counts for every scope open during it.
+type segment struct {
+	query  uint32
+	scopes []int
+}
+
+type timerSlot struct {
+	frame    uint64
+	queries  []uint32
+	segments []segment
+	scopes   int
+	pending  bool
+}
+
+// GLTimer is a GPUTimer using GL_TIME_ELAPSED queries.
+type GLTimer struct {
+	slots   [timerSlots]timerSlot
+	current *timerSlot
+
+	stack  []int
+	active bool
+
+	// Slots waiting to be read, oldest first
+	waiting []*timerSlot
+	// Results read early because a slot was needed again
+	ready []timerResult
+}
+
+type timerResult struct {
+	frame uint64
+	times []time.Duration
+}
+
+func NewGLTimer() *GLTimer {
+	o := new(GLTimer)
+	return o
+}
+
+func (t *GLTimer) BeginFrame(frame uint64) {
+	slot := &t.slots[frame%timerSlots]
+	if slot.pending {
+		// The GPU is more than a frame behind, wait for it
+		t.ready = append(t.ready, t.read(slot))
+	}
+
+	slot.frame = frame
+	slot.segments = slot.segments[:0]
+	slot.scopes = 0
+	t.current = slot
+	t.stack = t.stack[:0]
+	t.active = false
+}
+
+func (t *GLTimer) Begin(id int) {
+	if t.current == nil {
+		return
+	}
+	t.stopSegment()
+	t.stack = append(t.stack, id)
+	if id >= t.current.scopes {
+		t.current.scopes = id + 1
+	}
+	t.startSegment()
+}
+
+func (t *GLTimer) End(id int) {
+	if t.current == nil || len(t.stack) == 0 {
+		return
+	}
+	t.stopSegment()
+	t.stack = t.stack[:len(t.stack)-1]
+	if len(t.stack) > 0 {
+		t.startSegment()
+	}
+}
+
+func (t *GLTimer) EndFrame() {
+	if t.current == nil {
+		return
+	}
+	t.stopSegment()
+	t.stack = t.stack[:0]
+
+	t.current.pending = true
+	t.waiting = append(t.waiting, t.current)
+	t.current = nil
+}
+
+func (t *GLTimer) Poll() (uint64, []time.Duration, bool) {
+	if len(t.ready) > 0 {
+		r := t.ready[0]
+		t.ready = t.ready[1:]
+		return r.frame, r.times, true
+	}
+
+	if len(t.waiting) == 0 {
+		return 0, nil, false
+	}
+	slot := t.waiting[0]
+	if n := len(slot.segments); n > 0 {
+		// Queries finish in order, the last one being ready means all are
+		var available int32
+		gl.GetQueryObjectiv(slot.segments[n-1].query, gl.QUERY_RESULT_AVAILABLE, &available)
+		gldebug.Check()
+		if available == gl.FALSE {
+			return 0, nil, false
+		}
+	}
+	r := t.read(slot)
+	return r.frame, r.times, true
+}
+
+// Delete frees the queries.
+func (t *GLTimer) Delete() {
+	for i := range t.slots {
+		slot := &t.slots[i]
+		if len(slot.queries) > 0 {
+			gl.DeleteQueries(int32(len(slot.queries)), &slot.queries[0])
+			gldebug.Check()
+		}
+		*slot = timerSlot{}
+	}
+	t.current = nil
+	t.waiting = nil
+	t.ready = nil
+}
+
+func (t *GLTimer) startSegment() {
+	slot := t.current
+	n := len(slot.segments)
+	if n >= len(slot.queries) {
+		var q uint32
+		gl.GenQueries(1, &q)
+		gldebug.Check()
+		slot.queries = append(slot.queries, q)
+	}
+
+	scopes := make([]int, len(t.stack))
+	copy(scopes, t.stack)
+	slot.segments = append(slot.segments, segment{query: slot.queries[n], scopes: scopes})
+
+	gl.BeginQuery(gl.TIME_ELAPSED, slot.queries[n])
+	gldebug.Check()
+	t.active = true
+}
+
+func (t *GLTimer) stopSegment() {
+	if t.active {
+		gl.EndQuery(gl.TIME_ELAPSED)
+		gldebug.Check()
+		t.active = false
+	}
+}
+
+// read waits for a slot's queries and sums the segments into its scopes.
+func (t *GLTimer) read(slot *timerSlot) timerResult {
+	for i, s := range t.waiting {
+		if s == slot {
+			t.waiting = append(t.waiting[:i], t.waiting[i+1:]...)
+			break
+		}
+	}
+
+	times := make([]time.Duration, slot.scopes)
+	for _, seg := range slot.segments {
+		var ns uint64
+		gl.GetQueryObjectui64v(seg.query, gl.QUERY_RESULT, &ns)
+		gldebug.Check()
+		for _, id := range seg.scopes {
+			times[id] += time.Duration(ns)
+		}
+	}
+
+	slot.pending = false
+	return timerResult{frame: slot.frame, times: times}
+}
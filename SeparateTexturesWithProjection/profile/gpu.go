@@ -0,0 +1,18 @@
+package profile
+
+import (
+	"time"
+)
+
+// GPUTimer measures the GPU time of the scopes in a frame. Scope ids are
+// numbered from 0 in the order they begin, and Begin/End nest. Results
+// come later than the frame, Poll returns them oldest first.
+type GPUTimer interface {
+	BeginFrame(frame uint64)
+	Begin(id int)
+	End(id int)
+	EndFrame()
+	// Poll returns a finished frame's time per scope id, ok is false
+	// when none is ready.
+	Poll() (frame uint64, times []time.Duration, ok bool)
+}
@@ -0,0 +1,311 @@
+// Package profile times named scopes of a frame on the CPU and, through a
+// GPUTimer, on the GPU. Times are averaged over a window of frames and
+// can be recorded as a Chrome trace (chrome://tracing, ui.perfetto.dev).
+//
+// Only GLTimer needs a GL context. With a nil GPUTimer and SetClock the
+// rest runs anywhere, e.g. in tests.
+package profile
+
+import (
+	"time"
+)
+
+// Sample is one timed scope in one frame.
+type Sample struct {
+	Name string
+	// Nesting depth, 0 for the outermost scopes
+	Depth int
+	Frame uint64
+	// Start is relative to the first frame, CPU is the wall time between
+	// Begin and End, GPU the time the GPU spent on the commands issued
+	// between them (0 without a GPUTimer).
+	Start, CPU, GPU time.Duration
+}
+
+// Stat is a scope's times over the last window. A scope entered more than
+// once in a frame counts as the sum of its calls.
+type Stat struct {
+	Name  string
+	Depth int
+	// Calls per frame
+	Calls float64
+	// Per frame averages and the largest frame
+	CPU, CPUMax time.Duration
+	GPU, GPUMax time.Duration
+}
+
+// frameRecord is a frame's samples, waiting for the GPU's times.
+type frameRecord struct {
+	frame   uint64
+	samples []Sample
+	// trace is the trace the frame was begun in, 0 for none
+	trace uint64
+}
+
+// accum sums a scope's times over the frames of a window.
+type accum struct {
+	stat  Stat
+	calls int
+	cpu   time.Duration
+	gpu   time.Duration
+}
+
+// Profiler collects the samples of the scopes between BeginFrame and
+// EndFrame. Scopes nest, End closes the latest one open.
+type Profiler struct {
+	now func() time.Time
+	gpu GPUTimer
+
+	epoch   time.Time
+	frame   uint64
+	inFrame bool
+
+	current *frameRecord
+	open    []int
+	// Frames waiting for the GPU, oldest first
+	pending []*frameRecord
+
+	window  int
+	counted int
+	accums  map[string]*accum
+	order   []string
+	stats   []Stat
+
+	tracing bool
+	// traceID counts the traces started, frames are kept by the one they
+	// were begun in
+	traceID uint64
+	trace   []Sample
+}
+
+// NewProfiler creates a profiler averaging over 60 frames. gpu may be
+// nil to time the CPU only.
+func NewProfiler(gpu GPUTimer) *Profiler {
+	o := new(Profiler)
+	o.now = time.Now
+	o.gpu = gpu
+	o.window = 60
+	o.accums = make(map[string]*accum)
+	return o
+}
+
+// SetClock replaces time.Now, e.g. with a fake clock in tests.
+func (p *Profiler) SetClock(now func() time.Time) {
+	p.now = now
+}
+
+// SetWindow sets the number of frames Stats averages over.
+func (p *Profiler) SetWindow(frames int) {
+	if frames < 1 {
+		frames = 1
+	}
+	p.window = frames
+}
+
+// Frame returns the number of frames begun.
+func (p *Profiler) Frame() uint64 {
+	return p.frame
+}
+
+func (p *Profiler) BeginFrame() {
+	if p.inFrame {
+		p.EndFrame()
+	}
+	now := p.now()
+	if p.epoch.IsZero() {
+		p.epoch = now
+	}
+
+	p.frame++
+	p.inFrame = true
+	p.current = &frameRecord{frame: p.frame}
+	if p.tracing {
+		p.current.trace = p.traceID
+	}
+	p.open = p.open[:0]
+	if p.gpu != nil {
+		p.gpu.BeginFrame(p.frame)
+	}
+}
+
+// Begin opens a scope. Outside a frame it does nothing.
+func (p *Profiler) Begin(name string) {
+	if !p.inFrame {
+		return
+	}
+	id := len(p.current.samples)
+	p.current.samples = append(p.current.samples, Sample{
+		Name:  name,
+		Depth: len(p.open),
+		Frame: p.frame,
+		Start: p.now().Sub(p.epoch),
+	})
+	p.open = append(p.open, id)
+	if p.gpu != nil {
+		p.gpu.Begin(id)
+	}
+}
+
+// End closes the latest scope open.
+func (p *Profiler) End() {
+	if !p.inFrame || len(p.open) == 0 {
+		return
+	}
+	id := p.open[len(p.open)-1]
+	p.open = p.open[:len(p.open)-1]
+
+	s := &p.current.samples[id]
+	s.CPU = p.now().Sub(p.epoch) - s.Start
+	if p.gpu != nil {
+		p.gpu.End(id)
+	}
+}
+
+// Time runs f in a scope.
+func (p *Profiler) Time(name string, f func()) {
+	p.Begin(name)
+	f()
+	p.End()
+}
+
+// EndFrame closes any scopes left open and ends the frame. Its times are
+// counted once the GPU's are known, usually a frame or two later.
+func (p *Profiler) EndFrame() {
+	if !p.inFrame {
+		return
+	}
+	for len(p.open) > 0 {
+		p.End()
+	}
+	p.inFrame = false
+
+	if p.gpu == nil {
+		p.finish(p.current)
+		p.current = nil
+		return
+	}
+
+	p.gpu.EndFrame()
+	p.pending = append(p.pending, p.current)
+	p.current = nil
+
+	for {
+		frame, times, ok := p.gpu.Poll()
+		if !ok {
+			break
+		}
+		p.resolve(frame, times)
+	}
+}
+
+// resolve adds the GPU times to a pending frame and finishes it, and any
+// older frames the timer skipped.
+func (p *Profiler) resolve(frame uint64, times []time.Duration) {
+	for len(p.pending) > 0 && p.pending[0].frame <= frame {
+		r := p.pending[0]
+		p.pending = p.pending[1:]
+		if r.frame == frame {
+			for i := range r.samples {
+				if i < len(times) {
+					r.samples[i].GPU = times[i]
+				}
+			}
+		}
+		p.finish(r)
+	}
+}
+
+// finish counts a complete frame.
+func (p *Profiler) finish(r *frameRecord) {
+	if r.trace != 0 && r.trace == p.traceID {
+		p.trace = append(p.trace, r.samples...)
+	}
+
+	// Sum each scope over the frame first so Max is per frame
+	type total struct {
+		calls    int
+		cpu, gpu time.Duration
+	}
+	totals := make(map[string]*total)
+	for _, s := range r.samples {
+		a, ok := p.accums[s.Name]
+		if !ok {
+			a = &accum{stat: Stat{Name: s.Name, Depth: s.Depth}}
+			p.accums[s.Name] = a
+			p.order = append(p.order, s.Name)
+		}
+		t, ok := totals[s.Name]
+		if !ok {
+			t = new(total)
+			totals[s.Name] = t
+		}
+		t.calls++
+		t.cpu += s.CPU
+		t.gpu += s.GPU
+	}
+	for name, t := range totals {
+		a := p.accums[name]
+		a.calls += t.calls
+		a.cpu += t.cpu
+		a.gpu += t.gpu
+		if t.cpu > a.stat.CPUMax {
+			a.stat.CPUMax = t.cpu
+		}
+		if t.gpu > a.stat.GPUMax {
+			a.stat.GPUMax = t.gpu
+		}
+	}
+
+	p.counted++
+	if p.counted >= p.window {
+		p.publish()
+	}
+}
+
+// publish turns the window's sums into Stats and starts a new window.
+func (p *Profiler) publish() {
+	n := time.Duration(p.counted)
+	p.stats = p.stats[:0]
+	for _, name := range p.order {
+		a := p.accums[name]
+		s := a.stat
+		s.Calls = float64(a.calls) / float64(p.counted)
+		s.CPU = a.cpu / n
+		s.GPU = a.gpu / n
+		p.stats = append(p.stats, s)
+	}
+
+	p.counted = 0
+	p.accums = make(map[string]*accum)
+	p.order = p.order[:0]
+}
+
+// Stats returns the scopes of the last complete window in the order they
+// were first entered. It's empty until a window has passed.
+func (p *Profiler) Stats() []Stat {
+	return p.stats
+}
+
+// StartTrace starts keeping the samples of every frame begun from now on
+// for WriteTrace, dropping those kept before.
+func (p *Profiler) StartTrace() {
+	p.tracing = true
+	p.traceID++
+	p.trace = p.trace[:0]
+}
+
+// StopTrace stops keeping samples of the frames begun after it. Frames
+// begun before and still waiting for the GPU are added when their times
+// arrive.
+func (p *Profiler) StopTrace() {
+	p.tracing = false
+}
+
+func (p *Profiler) Tracing() bool {
+	return p.tracing
+}
+
+// Trace returns the samples kept since StartTrace.
+func (p *Profiler) Trace() []Sample {
+	return p.trace
+}
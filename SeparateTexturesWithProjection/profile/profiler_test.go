@@ -0,0 +1,271 @@
+package profile
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// fakeClock only moves when advanced.
+type fakeClock struct {
+	now time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Unix(1000, 0)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func (c *fakeClock) advance(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+// fakeGPU reports scope id's time as gpuTimes[id], latency frames after
+// the frame ended. Frames in skip are never reported.
+type fakeGPU struct {
+	latency  int
+	gpuTimes []time.Duration
+	skip     map[uint64]bool
+
+	frame   uint64
+	scopes  int
+	open    int
+	ended   []uint64
+	results []uint64
+}
+
+func (g *fakeGPU) BeginFrame(frame uint64) {
+	g.frame = frame
+	g.scopes = 0
+}
+
+func (g *fakeGPU) Begin(id int) {
+	if id >= g.scopes {
+		g.scopes = id + 1
+	}
+	g.open++
+}
+
+func (g *fakeGPU) End(id int) {
+	g.open--
+}
+
+func (g *fakeGPU) EndFrame() {
+	if !g.skip[g.frame] {
+		g.ended = append(g.ended, g.frame)
+	}
+	g.results = append(g.results, g.frame)
+}
+
+func (g *fakeGPU) Poll() (uint64, []time.Duration, bool) {
+	// The last frame ended is results[len-1], a frame is ready once
+	// latency more have ended
+	if len(g.ended) == 0 || g.results[len(g.results)-1] < g.ended[0]+uint64(g.latency) {
+		return 0, nil, false
+	}
+	frame := g.ended[0]
+	g.ended = g.ended[1:]
+	return frame, g.gpuTimes, true
+}
+
+// runFrame times a frame of three scopes, "update" and two "draw" calls
+// nested in "frame". scale multiplies the CPU times.
+func runFrame(p *Profiler, clock *fakeClock, scale time.Duration) {
+	p.BeginFrame()
+	p.Begin("frame")
+	clock.advance(1 * time.Millisecond * scale)
+	p.Time("update", func() { clock.advance(2 * time.Millisecond * scale) })
+	for i := 0; i < 2; i++ {
+		p.Begin("draw")
+		clock.advance(3 * time.Millisecond * scale)
+		p.End()
+	}
+	p.End()
+	p.EndFrame()
+}
+
+func TestProfilerStats(t *testing.T) {
+	clock := newFakeClock()
+	p := NewProfiler(nil)
+	p.SetClock(clock.Now)
+	p.SetWindow(2)
+
+	runFrame(p, clock, 1)
+	if len(p.Stats()) != 0 {
+		t.Fatalf("%d stats before the window passed", len(p.Stats()))
+	}
+	runFrame(p, clock, 3)
+
+	ms := time.Millisecond
+	want := []Stat{
+		{Name: "frame", Depth: 0, Calls: 1, CPU: 18 * ms, CPUMax: 27 * ms},
+		{Name: "update", Depth: 1, Calls: 1, CPU: 4 * ms, CPUMax: 6 * ms},
+		{Name: "draw", Depth: 1, Calls: 2, CPU: 12 * ms, CPUMax: 18 * ms},
+	}
+	checkStats(t, p.Stats(), want)
+
+	// Scopes left open and ones outside a frame
+	p.BeginFrame()
+	p.Begin("frame")
+	clock.advance(ms)
+	p.EndFrame()
+	p.Begin("late")
+	p.End()
+	p.BeginFrame()
+	p.EndFrame()
+	want = []Stat{
+		{Name: "frame", Depth: 0, Calls: 0.5, CPU: ms / 2, CPUMax: ms},
+	}
+	checkStats(t, p.Stats(), want)
+	if p.Frame() != 4 {
+		t.Errorf("frame %d, want 4", p.Frame())
+	}
+}
+
+func TestProfilerGPU(t *testing.T) {
+	ms := time.Millisecond
+	clock := newFakeClock()
+	gpu := &fakeGPU{
+		latency:  2,
+		gpuTimes: []time.Duration{10 * ms, 1 * ms, 4 * ms, 5 * ms},
+		skip:     map[uint64]bool{3: true},
+	}
+	p := NewProfiler(gpu)
+	p.SetClock(clock.Now)
+	p.SetWindow(2)
+
+	// Frames 1 and 2 are waiting for the GPU until frames 3 and 4 end.
+	// Frame 3 is never reported, frame 4's result finishes it without GPU
+	// times.
+	for frame := 1; frame <= 4; frame++ {
+		runFrame(p, clock, 1)
+		if gpu.open != 0 {
+			t.Fatalf("frame %d: %d GPU scopes open", frame, gpu.open)
+		}
+	}
+	want := []Stat{
+		{Name: "frame", Depth: 0, Calls: 1, CPU: 9 * ms, CPUMax: 9 * ms, GPU: 10 * ms, GPUMax: 10 * ms},
+		{Name: "update", Depth: 1, Calls: 1, CPU: 2 * ms, CPUMax: 2 * ms, GPU: 1 * ms, GPUMax: 1 * ms},
+		{Name: "draw", Depth: 1, Calls: 2, CPU: 6 * ms, CPUMax: 6 * ms, GPU: 9 * ms, GPUMax: 9 * ms},
+	}
+	checkStats(t, p.Stats(), want)
+	if len(p.pending) != 2 {
+		t.Fatalf("%d frames pending, want 2", len(p.pending))
+	}
+
+	for frame := 5; frame <= 6; frame++ {
+		runFrame(p, clock, 1)
+	}
+	// Frames 3 and 4, one without GPU times
+	want = []Stat{
+		{Name: "frame", Depth: 0, Calls: 1, CPU: 9 * ms, CPUMax: 9 * ms, GPU: 5 * ms, GPUMax: 10 * ms},
+		{Name: "update", Depth: 1, Calls: 1, CPU: 2 * ms, CPUMax: 2 * ms, GPU: ms / 2, GPUMax: 1 * ms},
+		{Name: "draw", Depth: 1, Calls: 2, CPU: 6 * ms, CPUMax: 6 * ms, GPU: 9 * ms / 2, GPUMax: 9 * ms},
+	}
+	checkStats(t, p.Stats(), want)
+}
+
+func checkStats(t *testing.T, stats, want []Stat) {
+	t.Helper()
+	if len(stats) != len(want) {
+		t.Fatalf("stats %+v, want %+v", stats, want)
+	}
+	for i := range want {
+		if stats[i] != want[i] {
+			t.Errorf("stat %d is %+v, want %+v", i, stats[i], want[i])
+		}
+	}
+}
+
+func TestProfilerTrace(t *testing.T) {
+	clock := newFakeClock()
+	gpu := &fakeGPU{latency: 1, gpuTimes: []time.Duration{500 * time.Microsecond, 0, 250 * time.Microsecond}}
+	p := NewProfiler(gpu)
+	p.SetClock(clock.Now)
+
+	runFrame(p, clock, 1)
+	p.StartTrace()
+	runFrame(p, clock, 1)
+	runFrame(p, clock, 1)
+	// Frame 3 is still waiting for the GPU
+	p.StopTrace()
+	if p.Tracing() {
+		t.Error("still tracing")
+	}
+	runFrame(p, clock, 1)
+	runFrame(p, clock, 1)
+
+	frames := map[uint64]int{}
+	for _, s := range p.Trace() {
+		frames[s.Frame]++
+	}
+	if len(frames) != 2 || frames[2] != 4 || frames[3] != 4 {
+		t.Fatalf("samples per frame %v, want 4 of frames 2 and 3", frames)
+	}
+
+	// Frames begun in an earlier trace don't leak into the next
+	p.StartTrace()
+	p.StopTrace()
+	runFrame(p, clock, 1)
+	if len(p.Trace()) != 0 {
+		t.Errorf("%d samples in an empty trace", len(p.Trace()))
+	}
+}
+
+func TestWriteTrace(t *testing.T) {
+	ms := time.Millisecond
+	tests := []struct {
+		golden  string
+		samples []Sample
+	}{
+		{
+			golden: "trace_cpu.json",
+			samples: []Sample{
+				{Name: "frame", Depth: 0, Frame: 1, Start: 0, CPU: 9 * ms},
+				{Name: "update", Depth: 1, Frame: 1, Start: 1 * ms, CPU: 2 * ms},
+				{Name: "draw", Depth: 1, Frame: 1, Start: 3 * ms, CPU: 0},
+			},
+		},
+		{
+			golden: "trace_gpu.json",
+			samples: []Sample{
+				{Name: "frame", Depth: 0, Frame: 7, Start: 1500 * time.Microsecond, CPU: 9 * ms, GPU: 4 * ms},
+				{Name: "draw", Depth: 1, Frame: 7, Start: 2 * ms, CPU: 3 * ms, GPU: 0},
+			},
+		},
+		{
+			golden: "trace_empty.json",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.golden, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := WriteTrace(&buf, test.samples); err != nil {
+				t.Fatal(err)
+			}
+
+			path := filepath.Join("testdata", test.golden)
+			if *update {
+				if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(buf.Bytes(), want) {
+				t.Errorf("trace\n%s\nwant\n%s", buf.Bytes(), want)
+			}
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package profile
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Thread ids of the trace's two tracks
+const (
+	cpuThread = 1
+	gpuThread = 2
+)
+
+// traceEvent is an event of the Chrome trace event format. Times are in
+// microseconds.
+type traceEvent struct {
+	Name  string                 `json:"name"`
+	Cat   string                 `json:"cat,omitempty"`
+	Phase string                 `json:"ph"`
+	TS    float64                `json:"ts"`
+	Dur   float64                `json:"dur"`
+	PID   int                    `json:"pid"`
+	TID   int                    `json:"tid"`
+	Args  map[string]interface{} `json:"args,omitempty"`
+}
+
+type traceFile struct {
+	TraceEvents     []traceEvent `json:"traceEvents"`
+	DisplayTimeUnit string       `json:"displayTimeUnit"`
+}
+
+// WriteTrace writes the samples kept since StartTrace as a Chrome trace.
+func (p *Profiler) WriteTrace(w io.Writer) error {
+	return WriteTrace(w, p.trace)
+}
+
+// SaveTrace writes the trace to a file, creating its directory.
+func (p *Profiler) SaveTrace(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := p.WriteTrace(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// WriteTrace writes samples as a Chrome trace with a CPU and a GPU track.
+// The GPU doesn't say when it ran a scope, only for how long, so a GPU
+// event starts with its CPU event. The GPU track is left out when no
+// sample has a GPU time.
+func WriteTrace(w io.Writer, samples []Sample) error {
+	events := []traceEvent{
+		threadName(cpuThread, "CPU"),
+	}
+
+	gpu := false
+	for _, s := range samples {
+		if s.GPU > 0 {
+			gpu = true
+			break
+		}
+	}
+	if gpu {
+		events = append(events, threadName(gpuThread, "GPU"))
+	}
+
+	for _, s := range samples {
+		events = append(events, traceEvent{
+			Name:  s.Name,
+			Cat:   "cpu",
+			Phase: "X",
+			TS:    micros(s.Start),
+			Dur:   micros(s.CPU),
+			PID:   1,
+			TID:   cpuThread,
+			Args:  map[string]interface{}{"frame": s.Frame},
+		})
+		if gpu {
+			events = append(events, traceEvent{
+				Name:  s.Name,
+				Cat:   "gpu",
+				Phase: "X",
+				TS:    micros(s.Start),
+				Dur:   micros(s.GPU),
+				PID:   1,
+				TID:   gpuThread,
+				Args:  map[string]interface{}{"frame": s.Frame},
+			})
+		}
+	}
+
+	enc := json.NewEncoder(w)
+	return enc.Encode(traceFile{TraceEvents: events, DisplayTimeUnit: "ms"})
+}
+
+func threadName(tid int, name string) traceEvent {
+	return traceEvent{
+		Name:  "thread_name",
+		Phase: "M",
+		PID:   1,
+		TID:   tid,
+		Args:  map[string]interface{}{"name": name},
+	}
+}
+
+func micros(d time.Duration) float64 {
+	return float64(d) / float64(time.Microsecond)
+}
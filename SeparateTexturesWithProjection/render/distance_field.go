@@ -0,0 +1,59 @@
+package render
+
+import (
+	"SimpleOpenGL-Go/SeparateTexturesWithProjection/gldebug"
+
+	"github.com/go-gl/gl/v4.5-core/gl"
+)
+
+// DistanceField holds the effect parameters of the SDF fragment shader.
+// Widths are in distance units where 0.5 spans the field's spread, so an
+// OutlineWidth of 0.25 is half the spread used when the atlas was baked.
+type DistanceField struct {
+	OutlineWidth float32
+	OutlineColor [4]float32
+
+	// ShadowOffset is in atlas pixels, +y is down the screen.
+	ShadowOffset   [2]float32
+	ShadowSoftness float32
+	ShadowColor    [4]float32
+}
+
+type distanceFieldUniforms struct {
+	outlineWidthLoc, outlineColorLoc                 int32
+	shadowOffsetLoc, shadowSoftnessLoc, shadowColLoc int32
+}
+
+func (u *distanceFieldUniforms) locate(prog uint32, owner string) {
+	find := func(name string) int32 {
+		loc := gl.GetUniformLocation(prog, gl.Str(name+"\x00"))
+		gldebug.Check()
+		if loc < 0 {
+			panic(owner + ": couldn't find '" + name + "' uniform variable")
+		}
+		return loc
+	}
+
+	u.outlineWidthLoc = find("outlineWidth")
+	u.outlineColorLoc = find("outlineColor")
+	u.shadowOffsetLoc = find("shadowOffset")
+	u.shadowSoftnessLoc = find("shadowSoftness")
+	u.shadowColLoc = find("shadowColor")
+}
+
+// apply sets the uniforms, the program must be in use. The shadow offset
+// is converted to texture coords using the atlas size.
+func (u *distanceFieldUniforms) apply(df *DistanceField, atlasWidth, atlasHeight int) {
+	gl.Uniform1f(u.outlineWidthLoc, df.OutlineWidth)
+	gldebug.Check()
+	gl.Uniform4fv(u.outlineColorLoc, 1, &df.OutlineColor[0])
+	gldebug.Check()
+
+	// The atlas is flipped so screen-down is -t.
+	gl.Uniform2f(u.shadowOffsetLoc, df.ShadowOffset[0]/float32(atlasWidth), -df.ShadowOffset[1]/float32(atlasHeight))
+	gldebug.Check()
+	gl.Uniform1f(u.shadowSoftnessLoc, df.ShadowSoftness)
+	gldebug.Check()
+	gl.Uniform4fv(u.shadowColLoc, 1, &df.ShadowColor[0])
+	gldebug.Check()
+}
@@ -0,0 +1,315 @@
+package render
+
+import (
+	"SimpleOpenGL-Go/SeparateTexturesWithProjection/gldebug"
+	"SimpleOpenGL-Go/SeparateTexturesWithProjection/glstate"
+	"unsafe"
+
+	"github.com/go-gl/gl/v4.5-core/gl"
+)
+
+// Primitive is how a Mesh's vertices are assembled.
+type Primitive uint32
+
+const (
+	Triangles     Primitive = gl.TRIANGLES
+	TriangleStrip Primitive = gl.TRIANGLE_STRIP
+	TriangleFan   Primitive = gl.TRIANGLE_FAN
+	Lines         Primitive = gl.LINES
+	LineStrip     Primitive = gl.LINE_STRIP
+	LineLoop      Primitive = gl.LINE_LOOP
+	Points        Primitive = gl.POINTS
+)
+
+// Usage hints how often a Mesh's buffers change.
+type Usage uint32
+
+const (
+	// StaticDraw is for data set once and drawn many times.
+	StaticDraw Usage = gl.STATIC_DRAW
+	// DynamicDraw is for data changed occasionally, e.g. a sub texture swap.
+	DynamicDraw Usage = gl.DYNAMIC_DRAW
+	// StreamDraw is for data rewritten every frame.
+	StreamDraw Usage = gl.STREAM_DRAW
+)
+
+// Mesh owns a VAO with its vertex buffer and optional index buffer.
+// Vertices are interleaved floats described by a VertexLayout. Indices
+// are either 16 or 32 bit, without indices the vertices are drawn in
+// order.
+//
+// The Mesh doesn't know about shaders, the caller uses its program and
+// sets uniforms before calling Draw.
+type Mesh struct {
+	vao, vbo, ebo uint32
+
+	layout    *VertexLayout
+	primitive Primitive
+	usage     Usage
+
+	vertices  []float32
+	indices   []uint32
+	indices16 []uint16
+
+	// Buffer sizes in bytes, the data is re-allocated when it outgrows them
+	vboSize, eboSize int
+
+	built bool
+}
+
+func NewMesh(layout *VertexLayout, primitive Primitive, usage Usage) *Mesh {
+	o := new(Mesh)
+	o.layout = layout
+	o.primitive = primitive
+	o.usage = usage
+	return o
+}
+
+// SetVertices replaces the vertices. After Build they are uploaded
+// immediately.
+func (m *Mesh) SetVertices(vertices []float32) {
+	m.vertices = vertices
+	if m.built {
+		m.uploadVertices()
+	}
+}
+
+// SetIndices replaces the indices with 32 bit ones.
+func (m *Mesh) SetIndices(indices []uint32) {
+	m.indices = indices
+	m.indices16 = nil
+	if m.built {
+		m.uploadIndices()
+	}
+}
+
+// SetIndices16 replaces the indices with 16 bit ones, halving the index
+// buffer for meshes with at most 65536 vertices.
+func (m *Mesh) SetIndices16(indices []uint16) {
+	m.indices16 = indices
+	m.indices = nil
+	if m.built {
+		m.uploadIndices()
+	}
+}
+
+// Vertices returns the vertex data. Edits must be followed by
+// UpdateVertices to reach the GPU.
+func (m *Mesh) Vertices() []float32 {
+	return m.vertices
+}
+
+// Layout returns the vertex format.
+func (m *Mesh) Layout() *VertexLayout {
+	return m.layout
+}
+
+// VertexCount returns the number of vertices.
+func (m *Mesh) VertexCount() int {
+	return len(m.vertices) / m.layout.FloatsPerVertex()
+}
+
+// IndexCount returns the number of indices, 0 if the mesh isn't indexed.
+func (m *Mesh) IndexCount() int {
+	if m.indices16 != nil {
+		return len(m.indices16)
+	}
+	return len(m.indices)
+}
+
+// SetPrimitive changes how the vertices are assembled.
+func (m *Mesh) SetPrimitive(primitive Primitive) {
+	m.primitive = primitive
+}
+
+// Build creates the GL objects and uploads whatever data has been set.
+func (m *Mesh) Build() {
+	m.vao = glstate.GenVertexArray()
+	m.vbo = glstate.GenBuffer()
+	m.ebo = glstate.GenBuffer()
+
+	glstate.BindVertexArray(m.vao)
+
+	gl.BindBuffer(gl.ARRAY_BUFFER, m.vbo)
+	gldebug.Check()
+	m.vboSize = 4 * len(m.vertices)
+	gl.BufferData(gl.ARRAY_BUFFER, m.vboSize, ptr(m.vertices), uint32(m.usage))
+	gldebug.Check()
+	m.layout.Apply()
+
+	// The element buffer binding is part of the VAO's state
+	gl.BindBuffer(gl.ELEMENT_ARRAY_BUFFER, m.ebo)
+	gldebug.Check()
+	m.eboSize = m.indexBytes()
+	gl.BufferData(gl.ELEMENT_ARRAY_BUFFER, m.eboSize, m.indexPtr(), uint32(m.usage))
+	gldebug.Check()
+
+	glstate.BindVertexArray(0) // close scope
+	gl.BindBuffer(gl.ARRAY_BUFFER, 0)
+	gldebug.Check()
+
+	m.built = true
+}
+
+// UpdateVertices copies vertices into the mesh starting at vertex first
+// and uploads just that range.
+func (m *Mesh) UpdateVertices(first int, vertices []float32) {
+	start := first * m.layout.FloatsPerVertex()
+	if end := start + len(vertices); end > len(m.vertices) {
+		m.vertices = append(m.vertices, make([]float32, end-len(m.vertices))...)
+	}
+	copy(m.vertices[start:], vertices)
+
+	if !m.built {
+		return
+	}
+
+	if 4*len(m.vertices) > m.vboSize {
+		m.uploadVertices()
+		return
+	}
+
+	gl.BindBuffer(gl.ARRAY_BUFFER, m.vbo)
+	gldebug.Check()
+	gl.BufferSubData(gl.ARRAY_BUFFER, 4*start, 4*len(vertices), gl.Ptr(vertices))
+	gldebug.Check()
+	gl.BindBuffer(gl.ARRAY_BUFFER, 0)
+	gldebug.Check()
+}
+
+// Draw draws every index, or every vertex if the mesh isn't indexed. The
+// caller's program must be in use.
+func (m *Mesh) Draw() {
+	count := m.IndexCount()
+	if count == 0 {
+		count = m.VertexCount()
+	}
+	m.DrawRange(0, count)
+}
+
+// DrawRange draws count indices (or vertices) starting at first.
+func (m *Mesh) DrawRange(first, count int) {
+	if count <= 0 {
+		return
+	}
+
+	glstate.BindVertexArray(m.vao)
+
+	switch {
+	case m.indices16 != nil:
+		gl.DrawElements(uint32(m.primitive), int32(count), gl.UNSIGNED_SHORT, gl.PtrOffset(2*first))
+		gldebug.Check()
+	case m.indices != nil:
+		gl.DrawElements(uint32(m.primitive), int32(count), gl.UNSIGNED_INT, gl.PtrOffset(4*first))
+		gldebug.Check()
+	default:
+		gl.DrawArrays(uint32(m.primitive), int32(first), int32(count))
+		gldebug.Check()
+	}
+	countDraw(count, 1)
+}
+
+// DrawInstanced draws the whole mesh instances times. Per-instance
+// attributes come from buffers the caller attached to the VAO.
+func (m *Mesh) DrawInstanced(instances int) {
+	if instances <= 0 {
+		return
+	}
+
+	glstate.BindVertexArray(m.vao)
+
+	switch {
+	case m.indices16 != nil:
+		gl.DrawElementsInstanced(uint32(m.primitive), int32(len(m.indices16)), gl.UNSIGNED_SHORT, gl.PtrOffset(0), int32(instances))
+		gldebug.Check()
+	case m.indices != nil:
+		gl.DrawElementsInstanced(uint32(m.primitive), int32(len(m.indices)), gl.UNSIGNED_INT, gl.PtrOffset(0), int32(instances))
+		gldebug.Check()
+	default:
+		gl.DrawArraysInstanced(uint32(m.primitive), 0, int32(m.VertexCount()), int32(instances))
+		gldebug.Check()
+	}
+
+	count := m.IndexCount()
+	if count == 0 {
+		count = m.VertexCount()
+	}
+	countDraw(count, instances)
+}
+
+// VAO returns the vertex array, e.g. to attach an instance buffer.
+func (m *Mesh) VAO() uint32 {
+	return m.vao
+}
+
+// Delete releases the GL objects.
+func (m *Mesh) Delete() {
+	if !m.built {
+		return
+	}
+	glstate.DeleteVertexArray(m.vao)
+	glstate.DeleteBuffer(m.vbo)
+	glstate.DeleteBuffer(m.ebo)
+	m.built = false
+}
+
+func (m *Mesh) uploadVertices() {
+	gl.BindBuffer(gl.ARRAY_BUFFER, m.vbo)
+	gldebug.Check()
+	size := 4 * len(m.vertices)
+	if size > m.vboSize {
+		m.vboSize = size
+		gl.BufferData(gl.ARRAY_BUFFER, m.vboSize, ptr(m.vertices), uint32(m.usage))
+		gldebug.Check()
+	} else if size > 0 {
+		if m.usage == StreamDraw {
+			// Orphan the old storage so the driver doesn't have to wait on it
+			gl.BufferData(gl.ARRAY_BUFFER, m.vboSize, nil, uint32(m.usage))
+			gldebug.Check()
+		}
+		gl.BufferSubData(gl.ARRAY_BUFFER, 0, size, gl.Ptr(m.vertices))
+		gldebug.Check()
+	}
+	gl.BindBuffer(gl.ARRAY_BUFFER, 0)
+	gldebug.Check()
+}
+
+func (m *Mesh) uploadIndices() {
+	// Bind through the VAO so its element buffer binding stays intact
+	glstate.BindVertexArray(m.vao)
+	size := m.indexBytes()
+	if size > m.eboSize {
+		m.eboSize = size
+		gl.BufferData(gl.ELEMENT_ARRAY_BUFFER, m.eboSize, m.indexPtr(), uint32(m.usage))
+		gldebug.Check()
+	} else if size > 0 {
+		gl.BufferSubData(gl.ELEMENT_ARRAY_BUFFER, 0, size, m.indexPtr())
+		gldebug.Check()
+	}
+	glstate.BindVertexArray(0)
+}
+
+func (m *Mesh) indexBytes() int {
+	if m.indices16 != nil {
+		return 2 * len(m.indices16)
+	}
+	return 4 * len(m.indices)
+}
+
+func (m *Mesh) indexPtr() unsafe.Pointer {
+	if len(m.indices16) > 0 {
+		return gl.Ptr(m.indices16)
+	}
+	if len(m.indices) > 0 {
+		return gl.Ptr(m.indices)
+	}
+	return nil
+}
+
+// ptr is gl.Ptr that allows empty slices.
+func ptr(data []float32) unsafe.Pointer {
+	if len(data) == 0 {
+		return nil
+	}
+	return gl.Ptr(data)
+}
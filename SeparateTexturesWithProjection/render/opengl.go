@@ -16,10 +16,10 @@ func InitDefaultProgram() uint32 {
 		panic(err)
 	}
 
-	prog := gl.CreateProgram()
-	gl.AttachShader(prog, vertexShader)
-	gl.AttachShader(prog, fragmentShader)
-	gl.LinkProgram(prog)
+	prog, err := linkProgram(vertexShader, fragmentShader)
+	if err != nil {
+		panic(err)
+	}
 
 	return prog
 }
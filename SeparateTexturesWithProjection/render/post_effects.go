@@ -0,0 +1,283 @@
+package render
+
+import (
+	"image"
+	"image/color"
+)
+
+const (
+	// Keeps the parts brighter than threshold, fading in over 0.1
+	fragmentBrightShaderSource = `
+    #version 450
+    out vec4 FragColor;
+    in vec2 TexCoord;
+
+    uniform sampler2D source;
+    uniform float threshold;
+
+    void main() {
+        vec3 c = texture(source, TexCoord).rgb;
+        float brightness = max(c.r, max(c.g, c.b));
+        FragColor = vec4(c * smoothstep(threshold, threshold + 0.1, brightness), 1.0);
+    }
+` + "\x00"
+
+	// One direction of a separable 9 tap gaussian, spread scales the
+	// distance between taps in pixels.
+	fragmentBlurShaderSource = `
+    #version 450
+    out vec4 FragColor;
+    in vec2 TexCoord;
+
+    uniform sampler2D source;
+    uniform vec2 resolution;
+    uniform vec2 direction;
+    uniform float spread;
+
+    const float weights[5] = float[](0.227027, 0.1945946, 0.1216216, 0.054054, 0.016216);
+
+    void main() {
+        vec2 stride = direction * spread / resolution;
+        vec3 sum = texture(source, TexCoord).rgb * weights[0];
+        for (int i = 1; i < 5; i++) {
+            sum += texture(source, TexCoord + stride * float(i)).rgb * weights[i];
+            sum += texture(source, TexCoord - stride * float(i)).rgb * weights[i];
+        }
+        FragColor = vec4(sum, 1.0);
+    }
+` + "\x00"
+
+	// Adds the blurred highlights back onto the effect's input
+	fragmentBloomShaderSource = `
+    #version 450
+    out vec4 FragColor;
+    in vec2 TexCoord;
+
+    uniform sampler2D source;
+    uniform sampler2D base;
+    uniform float intensity;
+
+    void main() {
+        vec3 c = texture(base, TexCoord).rgb + texture(source, TexCoord).rgb * intensity;
+        FragColor = vec4(c, 1.0);
+    }
+` + "\x00"
+
+	// Barrel distortion and darkened alternate lines
+	fragmentCRTShaderSource = `
+    #version 450
+    out vec4 FragColor;
+    in vec2 TexCoord;
+
+    uniform sampler2D source;
+    uniform vec2 resolution;
+    uniform float curvature;
+    uniform float scanlines;
+
+    void main() {
+        vec2 uv = TexCoord * 2.0 - 1.0;
+        uv += uv * uv.yx * uv.yx * curvature;
+        uv = uv * 0.5 + 0.5;
+        if (uv.x < 0.0 || uv.x > 1.0 || uv.y < 0.0 || uv.y > 1.0) {
+            FragColor = vec4(0.0, 0.0, 0.0, 1.0);
+            return;
+        }
+
+        vec3 c = texture(source, uv).rgb;
+        float line = sin(uv.y * resolution.y * 3.14159265) * 0.5 + 0.5;
+        FragColor = vec4(c * mix(1.0, line, scanlines), 1.0);
+    }
+` + "\x00"
+
+	// The lut is a strip of lutSize slices, one per blue level, each
+	// lutSize square with red across and green up. Blue is interpolated
+	// between the two nearest slices.
+	fragmentLUTShaderSource = `
+    #version 450
+    out vec4 FragColor;
+    in vec2 TexCoord;
+
+    uniform sampler2D source;
+    uniform sampler2D lut;
+    uniform float lutSize;
+    uniform float strength;
+
+    vec3 lookup(vec3 c) {
+        float n = lutSize;
+        float b = c.b * (n - 1.0);
+        float b0 = floor(b);
+        float b1 = min(b0 + 1.0, n - 1.0);
+        vec2 rg = (c.rg * (n - 1.0) + 0.5) / vec2(n * n, n);
+        vec3 c0 = texture(lut, rg + vec2(b0 / n, 0.0)).rgb;
+        vec3 c1 = texture(lut, rg + vec2(b1 / n, 0.0)).rgb;
+        return mix(c0, c1, b - b0);
+    }
+
+    void main() {
+        vec3 c = clamp(texture(source, TexCoord).rgb, 0.0, 1.0);
+        FragColor = vec4(mix(c, lookup(c), strength), 1.0);
+    }
+` + "\x00"
+
+	// Darkens towards the corners, radius is a fraction of the height
+	fragmentVignetteShaderSource = `
+    #version 450
+    out vec4 FragColor;
+    in vec2 TexCoord;
+
+    uniform sampler2D source;
+    uniform vec2 resolution;
+    uniform float radius;
+    uniform float softness;
+
+    void main() {
+        vec2 d = TexCoord - 0.5;
+        d.x *= resolution.x / resolution.y;
+        float v = 1.0 - smoothstep(radius - softness, radius, length(d));
+        FragColor = vec4(texture(source, TexCoord).rgb * v, 1.0);
+    }
+` + "\x00"
+
+	// Samples the center of pixelSize blocks
+	fragmentPixelateShaderSource = `
+    #version 450
+    out vec4 FragColor;
+    in vec2 TexCoord;
+
+    uniform sampler2D source;
+    uniform vec2 resolution;
+    uniform float pixelSize;
+
+    void main() {
+        vec2 cell = pixelSize / resolution;
+        vec2 uv = (floor(TexCoord / cell) + 0.5) * cell;
+        FragColor = vec4(texture(source, uv).rgb, 1.0);
+    }
+` + "\x00"
+
+	// Splits red and blue apart towards the edges, amount is the
+	// separation in pixels at the edges.
+	fragmentChromaticShaderSource = `
+    #version 450
+    out vec4 FragColor;
+    in vec2 TexCoord;
+
+    uniform sampler2D source;
+    uniform vec2 resolution;
+    uniform float amount;
+
+    void main() {
+        vec2 offset = (TexCoord - 0.5) * 2.0 * amount / resolution;
+        float r = texture(source, TexCoord + offset).r;
+        float g = texture(source, TexCoord).g;
+        float b = texture(source, TexCoord - offset).b;
+        FragColor = vec4(r, g, b, 1.0);
+    }
+` + "\x00"
+)
+
+// NewBloom makes the parts brighter than 'threshold' glow, the glow is
+// scaled by 'intensity' and blurred over 'spread' pixel taps.
+func NewBloom() *PostEffect {
+	bright := NewPostPass("bloom bright", fragmentBrightShaderSource)
+	bright.SetParam("threshold", 0.7)
+
+	blurX, blurY := newBlurPasses("bloom", 2.0)
+
+	combine := NewPostPass("bloom combine", fragmentBloomShaderSource)
+	combine.SetParam("intensity", 1.0)
+
+	return NewPostEffect("bloom", bright, blurX, blurY, combine)
+}
+
+// NewBlur makes a gaussian blur, 'spread' widens it.
+func NewBlur() *PostEffect {
+	blurX, blurY := newBlurPasses("blur", 1.5)
+	return NewPostEffect("blur", blurX, blurY)
+}
+
+func newBlurPasses(name string, spread float32) (horizontal, vertical *PostPass) {
+	horizontal = NewPostPass(name+" horizontal", fragmentBlurShaderSource)
+	horizontal.SetParam("direction", 1.0, 0.0)
+	horizontal.SetParam("spread", spread)
+
+	vertical = NewPostPass(name+" vertical", fragmentBlurShaderSource)
+	vertical.SetParam("direction", 0.0, 1.0)
+	vertical.SetParam("spread", spread)
+	return horizontal, vertical
+}
+
+// NewCRT curves the picture like an old monitor, by 'curvature', and
+// darkens alternate lines, by 'scanlines' (0 - 1).
+func NewCRT() *PostEffect {
+	crt := NewPostPass("crt", fragmentCRTShaderSource)
+	crt.SetParam("curvature", 0.1)
+	crt.SetParam("scanlines", 0.3)
+	return NewPostEffect("crt", crt)
+}
+
+// NewColorGrading remaps colors through a lookup table made by NewLUT,
+// blended with the original by 'strength' (0 - 1).
+func NewColorGrading(lut *image.NRGBA) *PostEffect {
+	grading := NewPostPass("color grading", fragmentLUTShaderSource)
+	grading.SetImage("lut", lut)
+	grading.SetParam("lutSize", float32(lut.Bounds().Dy()))
+	grading.SetParam("strength", 1.0)
+	return NewPostEffect("color grading", grading)
+}
+
+// NewVignette darkens the corners, fading over 'softness' to black at
+// 'radius', both fractions of the height.
+func NewVignette() *PostEffect {
+	vignette := NewPostPass("vignette", fragmentVignetteShaderSource)
+	vignette.SetParam("radius", 0.8)
+	vignette.SetParam("softness", 0.5)
+	return NewPostEffect("vignette", vignette)
+}
+
+// NewPixelate draws the picture in blocks of 'pixelSize' pixels.
+func NewPixelate() *PostEffect {
+	pixelate := NewPostPass("pixelate", fragmentPixelateShaderSource)
+	pixelate.SetParam("pixelSize", 4.0)
+	return NewPostEffect("pixelate", pixelate)
+}
+
+// NewChromaticAberration separates the color channels by 'amount' pixels
+// at the edges.
+func NewChromaticAberration() *PostEffect {
+	chromatic := NewPostPass("chromatic aberration", fragmentChromaticShaderSource)
+	chromatic.SetParam("amount", 4.0)
+	return NewPostEffect("chromatic aberration", chromatic)
+}
+
+// NewLUT makes a color grading lookup table of size levels per channel,
+// laid out as NewColorGrading expects. grade maps a color, components
+// 0 - 1, to its graded color; nil makes the identity table.
+func NewLUT(size int, grade func(r, g, b float32) (float32, float32, float32)) *image.NRGBA {
+	lut := image.NewNRGBA(image.Rect(0, 0, size*size, size))
+	max := float32(size - 1)
+
+	for b := 0; b < size; b++ {
+		for g := 0; g < size; g++ {
+			for r := 0; r < size; r++ {
+				cr, cg, cb := float32(r)/max, float32(g)/max, float32(b)/max
+				if grade != nil {
+					cr, cg, cb = grade(cr, cg, cb)
+				}
+				lut.SetNRGBA(b*size+r, g, color.NRGBA{R: toByte(cr), G: toByte(cg), B: toByte(cb), A: 255})
+			}
+		}
+	}
+
+	return lut
+}
+
+func toByte(v float32) uint8 {
+	if v <= 0.0 {
+		return 0
+	}
+	if v >= 1.0 {
+		return 255
+	}
+	return uint8(v*255.0 + 0.5)
+}
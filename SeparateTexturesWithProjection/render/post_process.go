@@ -0,0 +1,459 @@
+package render
+
+import (
+	"SimpleOpenGL-Go/SeparateTexturesWithProjection/gldebug"
+	"SimpleOpenGL-Go/SeparateTexturesWithProjection/glstate"
+	"image"
+
+	"github.com/go-gl/gl/v4.5-core/gl"
+)
+
+// Full-screen passes draw a single triangle covering the viewport, its
+// corners come from gl_VertexID so no vertex buffer is needed.
+const postVertexShaderSource = `
+    #version 450
+    out vec2 TexCoord;
+
+    void main() {
+        vec2 corner = vec2((gl_VertexID << 1) & 2, gl_VertexID & 2);
+        TexCoord = corner;
+        gl_Position = vec4(corner * 2.0 - 1.0, 0.0, 1.0);
+    }
+` + "\x00"
+
+// Texture units of the samplers every pass can use, extra textures follow.
+const (
+	postSourceUnit = 0
+	postBaseUnit   = 1
+	postExtraUnit  = 2
+)
+
+type postParam struct {
+	name   string
+	values []float32
+	loc    int32
+}
+
+type postTexture struct {
+	name    string
+	image   *image.NRGBA
+	texture uint32
+	loc     int32
+}
+
+// PostPass is one full-screen draw of a fragment shader. The shader gets
+// the previous pass's output as 'source', the input of the pass's effect
+// as 'base' and the target size in pixels as 'resolution', all optional.
+// Its other uniforms are set with SetParam and SetImage.
+type PostPass struct {
+	name           string
+	fragmentSource string
+
+	program                           uint32
+	sourceLoc, baseLoc, resolutionLoc int32
+	params                            []*postParam
+	textures                          []*postTexture
+}
+
+// NewPostPass creates a pass, the source must be null terminated.
+func NewPostPass(name, fragmentSource string) *PostPass {
+	o := new(PostPass)
+	o.name = name
+	o.fragmentSource = fragmentSource
+	return o
+}
+
+func (p *PostPass) Name() string {
+	return p.name
+}
+
+// SetParam sets a float, vec2, vec3 or vec4 uniform, depending on the
+// number of values.
+func (p *PostPass) SetParam(name string, values ...float32) {
+	if param := p.param(name); param != nil {
+		param.values = append(param.values[:0], values...)
+		return
+	}
+
+	param := &postParam{name: name, values: values, loc: -1}
+	p.params = append(p.params, param)
+	if p.program != 0 {
+		param.loc = p.locate(name)
+	}
+}
+
+// Param returns the values of a parameter, nil if it isn't set.
+func (p *PostPass) Param(name string) []float32 {
+	if param := p.param(name); param != nil {
+		return param.values
+	}
+	return nil
+}
+
+func (p *PostPass) param(name string) *postParam {
+	for _, param := range p.params {
+		if param.name == name {
+			return param
+		}
+	}
+	return nil
+}
+
+// SetImage sets a sampler uniform to a texture made from img, e.g. a
+// color grading lookup table. It's sampled linearly and clamped.
+func (p *PostPass) SetImage(name string, img *image.NRGBA) {
+	for _, t := range p.textures {
+		if t.name == name {
+			t.image = img
+			if p.program != 0 {
+				t.upload()
+			}
+			return
+		}
+	}
+
+	t := &postTexture{name: name, image: img, loc: -1}
+	p.textures = append(p.textures, t)
+	if p.program != 0 {
+		p.buildTexture(len(p.textures)-1, t)
+	}
+}
+
+func (p *PostPass) Build() {
+	vertexShader, err := compileShader(postVertexShaderSource, gl.VERTEX_SHADER)
+	if err != nil {
+		panic(err)
+	}
+
+	fragmentShader, err := compileShader(p.fragmentSource, gl.FRAGMENT_SHADER)
+	if err != nil {
+		panic(err)
+	}
+
+	prog, err := linkProgram(vertexShader, fragmentShader)
+	if err != nil {
+		panic(err)
+	}
+	p.program = prog
+
+	glstate.UseProgram(prog)
+
+	// The built in uniforms are optional
+	p.sourceLoc = gl.GetUniformLocation(prog, gl.Str("source\x00"))
+	gldebug.Check()
+	p.baseLoc = gl.GetUniformLocation(prog, gl.Str("base\x00"))
+	gldebug.Check()
+	p.resolutionLoc = gl.GetUniformLocation(prog, gl.Str("resolution\x00"))
+	gldebug.Check()
+	gl.Uniform1i(p.sourceLoc, postSourceUnit)
+	gldebug.Check()
+	gl.Uniform1i(p.baseLoc, postBaseUnit)
+	gldebug.Check()
+
+	for _, param := range p.params {
+		param.loc = p.locate(param.name)
+	}
+	for i, t := range p.textures {
+		p.buildTexture(i, t)
+	}
+}
+
+func (p *PostPass) locate(name string) int32 {
+	loc := gl.GetUniformLocation(p.program, gl.Str(name+"\x00"))
+	gldebug.Check()
+	if loc < 0 {
+		panic("PostPass " + p.name + ": couldn't find '" + name + "' uniform variable")
+	}
+	return loc
+}
+
+func (p *PostPass) buildTexture(i int, t *postTexture) {
+	t.loc = p.locate(t.name)
+	glstate.UseProgram(p.program)
+	gl.Uniform1i(t.loc, int32(postExtraUnit+i))
+	gldebug.Check()
+
+	t.texture = glstate.GenTexture()
+	t.upload()
+}
+
+func (t *postTexture) upload() {
+	glstate.ActiveTexture(gl.TEXTURE0)
+	glstate.BindTexture(gl.TEXTURE_2D, t.texture)
+
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, gl.LINEAR)
+	gldebug.Check()
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MAG_FILTER, gl.LINEAR)
+	gldebug.Check()
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_S, gl.CLAMP_TO_EDGE)
+	gldebug.Check()
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_T, gl.CLAMP_TO_EDGE)
+	gldebug.Check()
+
+	width := int32(t.image.Bounds().Dx())
+	height := int32(t.image.Bounds().Dy())
+	gl.PixelStorei(gl.UNPACK_ALIGNMENT, 1)
+	gldebug.Check()
+	gl.TexImage2D(gl.TEXTURE_2D, 0, gl.RGBA, width, height, 0, gl.RGBA, gl.UNSIGNED_BYTE, gl.Ptr(t.image.Pix))
+	gldebug.Check()
+}
+
+// draw runs the pass into the bound framebuffer.
+func (p *PostPass) draw(source, base uint32, width, height int) {
+	glstate.UseProgram(p.program)
+
+	glstate.BindTextureUnit(postSourceUnit, gl.TEXTURE_2D, source)
+	glstate.BindTextureUnit(postBaseUnit, gl.TEXTURE_2D, base)
+	for i, t := range p.textures {
+		glstate.BindTextureUnit(postExtraUnit+i, gl.TEXTURE_2D, t.texture)
+	}
+
+	gl.Uniform2f(p.resolutionLoc, float32(width), float32(height))
+	gldebug.Check()
+	for _, param := range p.params {
+		v := param.values
+		switch len(v) {
+		case 1:
+			gl.Uniform1f(param.loc, v[0])
+			gldebug.Check()
+		case 2:
+			gl.Uniform2f(param.loc, v[0], v[1])
+			gldebug.Check()
+		case 3:
+			gl.Uniform3f(param.loc, v[0], v[1], v[2])
+			gldebug.Check()
+		case 4:
+			gl.Uniform4f(param.loc, v[0], v[1], v[2], v[3])
+			gldebug.Check()
+		}
+	}
+
+	gl.DrawArrays(gl.TRIANGLES, 0, 3)
+	gldebug.Check()
+	countDraw(3, 1)
+}
+
+func (p *PostPass) Delete() {
+	for _, t := range p.textures {
+		glstate.DeleteTexture(t.texture)
+	}
+	glstate.DeleteProgram(p.program)
+	p.program = 0
+}
+
+// PostEffect is a named group of passes toggled together, e.g. bloom's
+// extract, blur and combine passes.
+type PostEffect struct {
+	name    string
+	passes  []*PostPass
+	enabled bool
+}
+
+// NewPostEffect creates an enabled effect running the passes in order.
+func NewPostEffect(name string, passes ...*PostPass) *PostEffect {
+	o := new(PostEffect)
+	o.name = name
+	o.passes = passes
+	o.enabled = true
+	return o
+}
+
+func (e *PostEffect) Name() string {
+	return e.name
+}
+
+func (e *PostEffect) Passes() []*PostPass {
+	return e.passes
+}
+
+func (e *PostEffect) Enabled() bool {
+	return e.enabled
+}
+
+func (e *PostEffect) SetEnabled(enabled bool) {
+	e.enabled = enabled
+}
+
+// Toggle flips the effect on or off and returns the new state.
+func (e *PostEffect) Toggle() bool {
+	e.enabled = !e.enabled
+	return e.enabled
+}
+
+// SetParam sets a parameter on every pass that already has it, the
+// effect constructors set all their parameters to defaults.
+func (e *PostEffect) SetParam(name string, values ...float32) {
+	found := false
+	for _, p := range e.passes {
+		if p.param(name) != nil {
+			p.SetParam(name, values...)
+			found = true
+		}
+	}
+	if !found {
+		panic("PostEffect " + e.name + ": no pass has the '" + name + "' parameter")
+	}
+}
+
+// PostChain applies a sequence of effects to everything drawn between
+// Begin and End. The scene is drawn into a target, then each enabled
+// pass draws into the next of three targets, ping-ponging between them,
+// and the last pass draws into the framebuffer Begin replaced. With no
+// effect enabled Begin and End do nothing and the scene is drawn
+// directly.
+type PostChain struct {
+	width, height int
+
+	// The scene is drawn into the first, the three take turns after that
+	targets [3]*RenderTarget
+	effects []*PostEffect
+
+	// An empty vertex array, core profiles need one bound to draw
+	vao uint32
+
+	active bool
+}
+
+// NewPostChain creates a chain for a viewport of width x height pixels.
+func NewPostChain(width, height int) *PostChain {
+	o := new(PostChain)
+	o.width = width
+	o.height = height
+	return o
+}
+
+// AddEffect appends an effect. Effects added after Build are built here.
+func (c *PostChain) AddEffect(e *PostEffect) {
+	c.effects = append(c.effects, e)
+	if c.vao != 0 {
+		for _, p := range e.passes {
+			p.Build()
+		}
+	}
+}
+
+// Effect returns the effect with the name, nil if there's none.
+func (c *PostChain) Effect(name string) *PostEffect {
+	for _, e := range c.effects {
+		if e.name == name {
+			return e
+		}
+	}
+	return nil
+}
+
+func (c *PostChain) Effects() []*PostEffect {
+	return c.effects
+}
+
+func (c *PostChain) Build() {
+	for i := range c.targets {
+		c.targets[i] = NewRenderTarget(c.width, c.height)
+		c.targets[i].SetFilter(gl.LINEAR)
+		c.targets[i].Build()
+	}
+
+	c.vao = glstate.GenVertexArray()
+
+	for _, e := range c.effects {
+		for _, p := range e.passes {
+			p.Build()
+		}
+	}
+}
+
+// Resize matches the chain to the viewport, e.g. when the window resizes.
+func (c *PostChain) Resize(width, height int) {
+	c.width, c.height = width, height
+	for _, t := range c.targets {
+		t.Resize(width, height)
+	}
+}
+
+// Enabled reports whether any effect is enabled.
+func (c *PostChain) Enabled() bool {
+	for _, e := range c.effects {
+		if e.enabled {
+			return true
+		}
+	}
+	return false
+}
+
+// Begin redirects drawing into the chain, if an effect is enabled.
+func (c *PostChain) Begin() {
+	if !c.Enabled() {
+		return
+	}
+	c.active = true
+	c.targets[0].Bind()
+}
+
+// End runs the enabled effects and draws the result where Begin would
+// have drawn.
+func (c *PostChain) End() {
+	if !c.active {
+		return
+	}
+	c.active = false
+	c.targets[0].Unbind()
+
+	// Passes overwrite every pixel, nothing to blend with
+	blend := glstate.IsEnabled(gl.BLEND)
+	mode := glstate.GetPolygonMode()
+	glstate.Disable(gl.BLEND)
+	glstate.PolygonMode(gl.FILL)
+	glstate.BindVertexArray(c.vao)
+
+	enabled := make([]*PostEffect, 0, len(c.effects))
+	for _, e := range c.effects {
+		if e.enabled && len(e.passes) > 0 {
+			enabled = append(enabled, e)
+		}
+	}
+
+	input := 0
+	for i, e := range enabled {
+		base := input
+		for j, p := range e.passes {
+			source, baseTex := c.targets[input].Texture(0), c.targets[base].Texture(0)
+			if i == len(enabled)-1 && j == len(e.passes)-1 {
+				p.draw(source, baseTex, c.width, c.height)
+				break
+			}
+
+			output := c.free(input, base)
+			c.targets[output].Bind()
+			p.draw(source, baseTex, c.width, c.height)
+			c.targets[output].Unbind()
+			input = output
+		}
+	}
+
+	glstate.ActiveTexture(gl.TEXTURE0)
+	glstate.SetEnabled(gl.BLEND, blend)
+	glstate.PolygonMode(mode)
+}
+
+// free returns a target that's neither the pass's source nor its base.
+func (c *PostChain) free(input, base int) int {
+	for i := range c.targets {
+		if i != input && i != base {
+			return i
+		}
+	}
+	return -1
+}
+
+func (c *PostChain) Delete() {
+	for _, e := range c.effects {
+		for _, p := range e.passes {
+			p.Delete()
+		}
+	}
+	for _, t := range c.targets {
+		t.Delete()
+	}
+	glstate.DeleteVertexArray(c.vao)
+	c.vao = 0
+}
@@ -0,0 +1,214 @@
+package render
+
+import (
+	"SimpleOpenGL-Go/SeparateTexturesWithProjection/glstate"
+	"sort"
+
+	"github.com/go-gl/gl/v4.5-core/gl"
+)
+
+// DrawCommand is one draw collected by a RenderQueue.
+type DrawCommand struct {
+	// Layer orders coarsely, lower layers are drawn first.
+	Layer uint8
+	// Translucent commands are drawn after the opaque ones of their layer,
+	// back to front.
+	Translucent bool
+	// Depth in [0, 1], 0 is nearest. Opaque commands are grouped by state
+	// and then drawn front to back, translucent ones back to front. Give
+	// overlapping translucent commands distinct depths, equal ones may be
+	// reordered to share state.
+	Depth float32
+
+	// Program and Texture (on unit 0) are bound by the queue before Draw,
+	// 0 means none is needed.
+	Program, Texture uint32
+	Model            [16]float32
+	Draw             func(model *[16]float32)
+
+	// Custom draws bind their own state, e.g. the batching renderers. Set
+	// it instead of Draw.
+	Custom func()
+
+	// Name is the profiler scope, runs of commands with the same name are
+	// timed as one. Unnamed commands are timed as "draw" or "custom".
+	Name string
+
+	key uint64
+}
+
+// Profiler times the scopes of a Flush, see profile.Profiler.
+type Profiler interface {
+	Begin(name string)
+	End()
+}
+
+// QueueStats counts the work done by the last Flush.
+type QueueStats struct {
+	Commands       int
+	ProgramChanges int
+	TextureChanges int
+	// Custom commands, their own state changes aren't counted
+	Custom int
+}
+
+// Sort key, from the most significant bits:
+//
+//	opaque:      layer:8 translucent:1 program:12 texture:12 depth:24
+//	translucent: layer:8 translucent:1 depth:24 program:12 texture:12
+//
+// Program and texture names are truncated, a collision only costs a state
+// change.
+const (
+	depthBits = 24
+	nameBits  = 12
+	maxDepth  = 1<<depthBits - 1
+	nameMask  = 1<<nameBits - 1
+)
+
+// RenderQueue collects a frame's draws and issues them sorted to keep
+// state changes down and blend correctly.
+type RenderQueue struct {
+	commands []DrawCommand
+	sorted   bool
+	stats    QueueStats
+	profiler Profiler
+}
+
+func NewRenderQueue() *RenderQueue {
+	o := new(RenderQueue)
+	o.sorted = true
+	return o
+}
+
+// SetSorted turns sorting off to compare against submission order.
+func (q *RenderQueue) SetSorted(sorted bool) {
+	q.sorted = sorted
+}
+
+func (q *RenderQueue) Sorted() bool {
+	return q.sorted
+}
+
+// SetProfiler times the draws of each Flush by command name, nil stops.
+func (q *RenderQueue) SetProfiler(p Profiler) {
+	q.profiler = p
+}
+
+// Submit adds a command for the next Flush.
+func (q *RenderQueue) Submit(cmd DrawCommand) {
+	if cmd.Draw == nil && cmd.Custom == nil {
+		panic("RenderQueue: command has neither Draw nor Custom")
+	}
+	cmd.key = sortKey(&cmd)
+	q.commands = append(q.commands, cmd)
+}
+
+// SubmitCustom adds a draw that binds its own state.
+func (q *RenderQueue) SubmitCustom(layer uint8, translucent bool, depth float32, draw func()) {
+	q.Submit(DrawCommand{Layer: layer, Translucent: translucent, Depth: depth, Custom: draw})
+}
+
+// Len returns the number of commands waiting.
+func (q *RenderQueue) Len() int {
+	return len(q.commands)
+}
+
+// Flush sorts and draws the commands, then empties the queue.
+func (q *RenderQueue) Flush() {
+	if q.sorted {
+		// Stable so equal keys keep submission order
+		sort.SliceStable(q.commands, func(i, j int) bool {
+			return q.commands[i].key < q.commands[j].key
+		})
+	}
+
+	q.stats = QueueStats{Commands: len(q.commands)}
+
+	// What the queue last bound, 0 is unknown
+	var program, texture uint32
+
+	// The profiler scope open, "" when none
+	var scope string
+
+	for i := range q.commands {
+		cmd := &q.commands[i]
+
+		if q.profiler != nil {
+			if name := scopeName(cmd); name != scope {
+				if scope != "" {
+					q.profiler.End()
+				}
+				q.profiler.Begin(name)
+				scope = name
+			}
+		}
+
+		if cmd.Custom != nil {
+			cmd.Custom()
+			q.stats.Custom++
+			program, texture = 0, 0
+			continue
+		}
+
+		if cmd.Program != 0 && cmd.Program != program {
+			glstate.UseProgram(cmd.Program)
+			program = cmd.Program
+			q.stats.ProgramChanges++
+		}
+		if cmd.Texture != 0 && cmd.Texture != texture {
+			// A custom command may have left another unit active
+			glstate.ActiveTexture(gl.TEXTURE0)
+			glstate.BindTexture(gl.TEXTURE_2D, cmd.Texture)
+			texture = cmd.Texture
+			q.stats.TextureChanges++
+		}
+
+		cmd.Draw(&cmd.Model)
+	}
+	if scope != "" {
+		q.profiler.End()
+	}
+
+	// Drop the closures' references but keep the memory
+	for i := range q.commands {
+		q.commands[i] = DrawCommand{}
+	}
+	q.commands = q.commands[:0]
+}
+
+// Stats returns the counts of the last Flush.
+func (q *RenderQueue) Stats() QueueStats {
+	return q.stats
+}
+
+func scopeName(cmd *DrawCommand) string {
+	switch {
+	case cmd.Name != "":
+		return cmd.Name
+	case cmd.Custom != nil:
+		return "custom"
+	}
+	return "draw"
+}
+
+func sortKey(cmd *DrawCommand) uint64 {
+	depth := cmd.Depth
+	if depth < 0.0 {
+		depth = 0.0
+	} else if depth > 1.0 {
+		depth = 1.0
+	}
+	d := uint64(depth * maxDepth)
+	program := uint64(cmd.Program & nameMask)
+	texture := uint64(cmd.Texture & nameMask)
+
+	key := uint64(cmd.Layer) << 56
+	if !cmd.Translucent {
+		return key | program<<(nameBits+depthBits) | texture<<depthBits | d
+	}
+
+	// Back to front, the farthest first
+	d = maxDepth - d
+	return key | 1<<55 | d<<(2*nameBits) | program<<nameBits | texture
+}
@@ -0,0 +1,223 @@
+package render
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSortKey(t *testing.T) {
+	// Depth 0.5, truncated
+	half := uint64(maxDepth / 2)
+
+	tests := []struct {
+		name string
+		cmd  DrawCommand
+		key  uint64
+	}{
+		{
+			name: "opaque",
+			cmd:  DrawCommand{Layer: 2, Program: 5, Texture: 7, Depth: 0.5},
+			key:  2<<56 | 5<<36 | 7<<24 | half,
+		},
+		{
+			name: "translucent inverts the depth",
+			cmd:  DrawCommand{Layer: 2, Translucent: true, Program: 5, Texture: 7, Depth: 0.5},
+			key:  2<<56 | 1<<55 | (maxDepth-half)<<24 | 5<<12 | 7,
+		},
+		{
+			name: "names truncated to 12 bits",
+			cmd:  DrawCommand{Program: 0x1005, Texture: 0xf007},
+			key:  5<<36 | 7<<24,
+		},
+		{
+			name: "depth clamped below",
+			cmd:  DrawCommand{Depth: -1},
+			key:  0,
+		},
+		{
+			name: "depth clamped above",
+			cmd:  DrawCommand{Depth: 2},
+			key:  maxDepth,
+		},
+		{
+			name: "translucent nearest",
+			cmd:  DrawCommand{Layer: 255, Translucent: true, Depth: 0},
+			key:  255<<56 | 1<<55 | maxDepth<<24,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := sortKey(&test.cmd); got != test.key {
+				t.Fatalf("key 0x%016x, want 0x%016x", got, test.key)
+			}
+		})
+	}
+}
+
+func TestSortKeyOrder(t *testing.T) {
+	tests := []struct {
+		name          string
+		first, second DrawCommand
+	}{
+		{
+			name:   "lower layer first",
+			first:  DrawCommand{Layer: 0, Translucent: true, Depth: 1},
+			second: DrawCommand{Layer: 1, Program: 1, Depth: 0},
+		},
+		{
+			name:   "opaque before translucent",
+			first:  DrawCommand{Program: 4095, Texture: 4095, Depth: 1},
+			second: DrawCommand{Translucent: true, Depth: 1},
+		},
+		{
+			name:   "opaque grouped by program before depth",
+			first:  DrawCommand{Program: 1, Depth: 1},
+			second: DrawCommand{Program: 2, Depth: 0},
+		},
+		{
+			name:   "opaque grouped by texture before depth",
+			first:  DrawCommand{Program: 1, Texture: 1, Depth: 1},
+			second: DrawCommand{Program: 1, Texture: 2, Depth: 0},
+		},
+		{
+			name:   "opaque front to back",
+			first:  DrawCommand{Program: 1, Depth: 0.25},
+			second: DrawCommand{Program: 1, Depth: 0.75},
+		},
+		{
+			name:   "translucent back to front before program",
+			first:  DrawCommand{Translucent: true, Program: 2, Depth: 0.75},
+			second: DrawCommand{Translucent: true, Program: 1, Depth: 0.25},
+		},
+		{
+			name:   "translucent grouped by program at a depth",
+			first:  DrawCommand{Translucent: true, Program: 1, Texture: 2, Depth: 0.5},
+			second: DrawCommand{Translucent: true, Program: 2, Texture: 1, Depth: 0.5},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if a, b := sortKey(&test.first), sortKey(&test.second); a >= b {
+				t.Fatalf("0x%016x isn't before 0x%016x", a, b)
+			}
+		})
+	}
+}
+
+// fakeProfiler records the scopes a Flush opens and checks they nest.
+type fakeProfiler struct {
+	t      *testing.T
+	scopes []string
+	open   bool
+}
+
+func (p *fakeProfiler) Begin(name string) {
+	if p.open {
+		p.t.Errorf("%s begun inside another scope", name)
+	}
+	p.scopes = append(p.scopes, name)
+	p.open = true
+}
+
+func (p *fakeProfiler) End() {
+	if !p.open {
+		p.t.Error("End without Begin")
+	}
+	p.open = false
+}
+
+func TestRenderQueueFlush(t *testing.T) {
+	// Program and Texture 0 commands bind nothing, so no GL is needed
+	type draw struct {
+		name        string
+		layer       uint8
+		translucent bool
+		depth       float32
+		custom      bool
+	}
+
+	draws := []draw{
+		{"hud", 3, true, 0.5, true},
+		{"near glass", 1, true, 0.1, false},
+		{"far glass", 1, true, 0.9, true},
+		{"far wall", 1, false, 0.9, false},
+		{"near wall", 1, false, 0.1, false},
+		{"background", 0, false, 0.5, true},
+		{"tie a", 2, true, 0.5, false},
+		{"tie b", 2, true, 0.5, false},
+	}
+
+	tests := []struct {
+		name   string
+		sorted bool
+		order  []string
+		// Runs of the same name share a scope
+		scopes []string
+	}{
+		{
+			name:   "sorted",
+			sorted: true,
+			order:  []string{"background", "near wall", "far wall", "far glass", "near glass", "tie a", "tie b", "hud"},
+			scopes: []string{"custom", "sprites", "custom", "sprites", "custom"},
+		},
+		{
+			name:   "submission order",
+			order:  []string{"hud", "near glass", "far glass", "far wall", "near wall", "background", "tie a", "tie b"},
+			scopes: []string{"custom", "sprites", "custom", "sprites", "custom", "sprites"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			q := NewRenderQueue()
+			q.SetSorted(test.sorted)
+			profiler := &fakeProfiler{t: t}
+			q.SetProfiler(profiler)
+
+			order := []string{}
+			for _, d := range draws {
+				name := d.name
+				cmd := DrawCommand{Layer: d.layer, Translucent: d.translucent, Depth: d.depth}
+				if d.custom {
+					cmd.Custom = func() { order = append(order, name) }
+				} else {
+					cmd.Name = "sprites"
+					cmd.Draw = func(model *[16]float32) { order = append(order, name) }
+				}
+				q.Submit(cmd)
+			}
+			if q.Len() != len(draws) {
+				t.Fatalf("Len %d, want %d", q.Len(), len(draws))
+			}
+
+			q.Flush()
+
+			if !reflect.DeepEqual(order, test.order) {
+				t.Errorf("drawn %v, want %v", order, test.order)
+			}
+			if q.Len() != 0 {
+				t.Errorf("%d commands left", q.Len())
+			}
+			if want := (QueueStats{Commands: 8, Custom: 3}); q.Stats() != want {
+				t.Errorf("stats %+v, want %+v", q.Stats(), want)
+			}
+			if !reflect.DeepEqual(profiler.scopes, test.scopes) {
+				t.Errorf("scopes %v, want %v", profiler.scopes, test.scopes)
+			}
+			if profiler.open {
+				t.Error("scope left open")
+			}
+		})
+	}
+}
+
+func TestRenderQueueSubmitWithoutDraw(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("no panic")
+		}
+	}()
+	NewRenderQueue().Submit(DrawCommand{})
+}
@@ -0,0 +1,67 @@
+package render
+
+import (
+	"SimpleOpenGL-Go/SeparateTexturesWithProjection/gldebug"
+	"SimpleOpenGL-Go/SeparateTexturesWithProjection/glstate"
+	"image"
+
+	"github.com/go-gl/gl/v4.5-core/gl"
+)
+
+// ReadScreen reads a rectangle of the default framebuffer's back buffer,
+// i.e. the frame drawn but not yet swapped, in framebuffer pixels with the
+// origin lower left. The image is top-down and opaque, the window's alpha
+// is whatever blending left there.
+func ReadScreen(x, y, width, height int) *image.NRGBA {
+	prev := glstate.ReadFramebuffer()
+	glstate.BindFramebuffer(gl.READ_FRAMEBUFFER, 0)
+	gl.ReadBuffer(gl.BACK)
+	gldebug.Check()
+
+	img := readPixels(x, y, width, height)
+	for i := 3; i < len(img.Pix); i += 4 {
+		img.Pix[i] = 255
+	}
+
+	glstate.BindFramebuffer(gl.READ_FRAMEBUFFER, prev)
+	return img
+}
+
+// ReadPixels reads color attachment i, resolved if multisampled, as a
+// top-down image.
+func (r *RenderTarget) ReadPixels(i int) *image.NRGBA {
+	prev := glstate.ReadFramebuffer()
+	glstate.BindFramebuffer(gl.READ_FRAMEBUFFER, r.fbo)
+	gl.ReadBuffer(gl.COLOR_ATTACHMENT0 + uint32(i))
+	gldebug.Check()
+
+	img := readPixels(0, 0, int(r.width), int(r.height))
+
+	glstate.BindFramebuffer(gl.READ_FRAMEBUFFER, prev)
+	return img
+}
+
+// readPixels reads from the read framebuffer. GL's rows go bottom-up so
+// they're flipped.
+func readPixels(x, y, width, height int) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	if width <= 0 || height <= 0 {
+		return img
+	}
+
+	gl.PixelStorei(gl.PACK_ALIGNMENT, 1)
+	gldebug.Check()
+	gl.ReadPixels(int32(x), int32(y), int32(width), int32(height), gl.RGBA, gl.UNSIGNED_BYTE, gl.Ptr(img.Pix))
+	gldebug.Check()
+
+	stride := img.Stride
+	row := make([]byte, stride)
+	for top, bottom := 0, height-1; top < bottom; top, bottom = top+1, bottom-1 {
+		t := img.Pix[top*stride : (top+1)*stride]
+		b := img.Pix[bottom*stride : (bottom+1)*stride]
+		copy(row, t)
+		copy(t, b)
+		copy(b, row)
+	}
+	return img
+}
@@ -0,0 +1,289 @@
+package render
+
+import (
+	"SimpleOpenGL-Go/SeparateTexturesWithProjection/gldebug"
+	"SimpleOpenGL-Go/SeparateTexturesWithProjection/glstate"
+	"fmt"
+
+	"github.com/go-gl/gl/v4.5-core/gl"
+)
+
+// RenderTarget is an off-screen framebuffer whose color attachments are
+// textures, e.g. for a minimap or for drawing pixel art at a low
+// resolution and upscaling it. Draw into it between Bind and Unbind, then
+// sample Texture, e.g. with a TextureRender from NewTargetTextureRender.
+//
+// With multisampling the drawing goes to multisampled renderbuffers that
+// Unbind resolves into the textures.
+type RenderTarget struct {
+	width, height int32
+
+	colors       int
+	depthStencil bool
+	samples      int32
+	filter       int32
+
+	// The framebuffer sampled from, and the one drawn into when it's
+	// multisampled (otherwise the same)
+	fbo, msFbo uint32
+	textures   []uint32
+	// Multisampled color renderbuffers
+	msColors []uint32
+	// Depth and stencil renderbuffer, on the framebuffer drawn into
+	depthRbo uint32
+
+	// Restored by Unbind
+	prevFbo      uint32
+	prevViewport [4]int32
+	bound        bool
+}
+
+// NewRenderTarget creates a target of width x height pixels with one
+// color texture. Configure it before calling Build.
+func NewRenderTarget(width, height int) *RenderTarget {
+	o := new(RenderTarget)
+	o.width = int32(width)
+	o.height = int32(height)
+	o.colors = 1
+	o.samples = 1
+	o.filter = gl.NEAREST
+	return o
+}
+
+// SetColorAttachments sets the number of color textures, gl_FragData
+// index i writes to Texture(i).
+func (r *RenderTarget) SetColorAttachments(n int) {
+	r.colors = n
+}
+
+// SetDepthStencil adds a depth and stencil buffer.
+func (r *RenderTarget) SetDepthStencil(enabled bool) {
+	r.depthStencil = enabled
+}
+
+// SetSamples turns on multisampling with n samples per pixel, 1 is off.
+func (r *RenderTarget) SetSamples(n int) {
+	r.samples = int32(n)
+}
+
+// SetFilter sets how the textures are sampled, gl.NEAREST (the default)
+// keeps upscaled pixel art sharp, gl.LINEAR smooths it.
+func (r *RenderTarget) SetFilter(filter int32) {
+	r.filter = filter
+	for _, tex := range r.textures {
+		glstate.ActiveTexture(gl.TEXTURE0)
+		glstate.BindTexture(gl.TEXTURE_2D, tex)
+		gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, filter)
+		gldebug.Check()
+		gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MAG_FILTER, filter)
+		gldebug.Check()
+	}
+}
+
+func (r *RenderTarget) Build() {
+	r.fbo = glstate.GenFramebuffer()
+
+	r.textures = make([]uint32, r.colors)
+	for i := range r.textures {
+		r.textures[i] = glstate.GenTexture()
+	}
+
+	r.msFbo = r.fbo
+	if r.multisampled() {
+		r.msFbo = glstate.GenFramebuffer()
+		r.msColors = make([]uint32, r.colors)
+		for i := range r.msColors {
+			r.msColors[i] = glstate.GenRenderbuffer()
+		}
+	}
+
+	if r.depthStencil {
+		r.depthRbo = glstate.GenRenderbuffer()
+	}
+
+	r.allocate()
+
+	prev := glstate.Framebuffer()
+
+	glstate.BindFramebuffer(gl.FRAMEBUFFER, r.fbo)
+	r.SetFilter(r.filter)
+	for i, tex := range r.textures {
+		gl.FramebufferTexture2D(gl.FRAMEBUFFER, gl.COLOR_ATTACHMENT0+uint32(i), gl.TEXTURE_2D, tex, 0)
+		gldebug.Check()
+	}
+
+	if r.multisampled() {
+		r.checkStatus("resolve")
+		glstate.BindFramebuffer(gl.FRAMEBUFFER, r.msFbo)
+		for i, rbo := range r.msColors {
+			gl.FramebufferRenderbuffer(gl.FRAMEBUFFER, gl.COLOR_ATTACHMENT0+uint32(i), gl.RENDERBUFFER, rbo)
+			gldebug.Check()
+		}
+	}
+
+	if r.depthStencil {
+		gl.FramebufferRenderbuffer(gl.FRAMEBUFFER, gl.DEPTH_STENCIL_ATTACHMENT, gl.RENDERBUFFER, r.depthRbo)
+		gldebug.Check()
+	}
+
+	buffers := make([]uint32, r.colors)
+	for i := range buffers {
+		buffers[i] = gl.COLOR_ATTACHMENT0 + uint32(i)
+	}
+	gl.DrawBuffers(int32(r.colors), &buffers[0])
+	gldebug.Check()
+
+	r.checkStatus("draw")
+
+	glstate.BindFramebuffer(gl.FRAMEBUFFER, prev)
+}
+
+// allocate (re)specifies the storage of the attachments at the current size.
+func (r *RenderTarget) allocate() {
+	for _, tex := range r.textures {
+		glstate.ActiveTexture(gl.TEXTURE0)
+		glstate.BindTexture(gl.TEXTURE_2D, tex)
+		gl.TexImage2D(gl.TEXTURE_2D, 0, gl.RGBA8, r.width, r.height, 0, gl.RGBA, gl.UNSIGNED_BYTE, nil)
+		gldebug.Check()
+	}
+
+	for _, rbo := range r.msColors {
+		gl.BindRenderbuffer(gl.RENDERBUFFER, rbo)
+		gldebug.Check()
+		gl.RenderbufferStorageMultisample(gl.RENDERBUFFER, r.samples, gl.RGBA8, r.width, r.height)
+		gldebug.Check()
+	}
+
+	if r.depthStencil {
+		gl.BindRenderbuffer(gl.RENDERBUFFER, r.depthRbo)
+		gldebug.Check()
+		if r.multisampled() {
+			gl.RenderbufferStorageMultisample(gl.RENDERBUFFER, r.samples, gl.DEPTH24_STENCIL8, r.width, r.height)
+			gldebug.Check()
+		} else {
+			gl.RenderbufferStorage(gl.RENDERBUFFER, gl.DEPTH24_STENCIL8, r.width, r.height)
+			gldebug.Check()
+		}
+	}
+
+	gl.BindRenderbuffer(gl.RENDERBUFFER, 0)
+	gldebug.Check()
+}
+
+// checkStatus panics if the bound framebuffer is incomplete.
+func (r *RenderTarget) checkStatus(which string) {
+	status := gl.CheckFramebufferStatus(gl.FRAMEBUFFER)
+	gldebug.Check()
+	if status != gl.FRAMEBUFFER_COMPLETE {
+		panic(fmt.Sprintf("RenderTarget: %s framebuffer incomplete, status 0x%x", which, status))
+	}
+}
+
+func (r *RenderTarget) multisampled() bool {
+	return r.samples > 1
+}
+
+// Resize changes the size in pixels, the contents are lost. The texture
+// names stay the same.
+func (r *RenderTarget) Resize(width, height int) {
+	if int32(width) == r.width && int32(height) == r.height {
+		return
+	}
+	r.width = int32(width)
+	r.height = int32(height)
+	r.allocate()
+	if r.bound {
+		glstate.Viewport(0, 0, r.width, r.height)
+	}
+}
+
+func (r *RenderTarget) Size() (width, height int) {
+	return int(r.width), int(r.height)
+}
+
+// Texture returns color attachment i, resolved if multisampled.
+func (r *RenderTarget) Texture(i int) uint32 {
+	return r.textures[i]
+}
+
+// Bind directs drawing into the target and sets the viewport to cover it.
+func (r *RenderTarget) Bind() {
+	if r.bound {
+		return
+	}
+	r.prevFbo = glstate.Framebuffer()
+	x, y, w, h := glstate.GetViewport()
+	r.prevViewport = [4]int32{x, y, w, h}
+	r.bound = true
+
+	glstate.BindFramebuffer(gl.FRAMEBUFFER, r.msFbo)
+	glstate.Viewport(0, 0, r.width, r.height)
+}
+
+// Unbind resolves the samples, if multisampled, and restores the
+// framebuffer and viewport Bind replaced.
+func (r *RenderTarget) Unbind() {
+	if !r.bound {
+		return
+	}
+	r.bound = false
+
+	if r.multisampled() {
+		r.resolve()
+	}
+
+	glstate.BindFramebuffer(gl.FRAMEBUFFER, r.prevFbo)
+	// Zero if the viewport was never set through glstate
+	if v := r.prevViewport; v[2] > 0 {
+		glstate.Viewport(v[0], v[1], v[2], v[3])
+	}
+}
+
+// resolve blits each multisampled attachment into its texture.
+func (r *RenderTarget) resolve() {
+	glstate.BindFramebuffer(gl.READ_FRAMEBUFFER, r.msFbo)
+	glstate.BindFramebuffer(gl.DRAW_FRAMEBUFFER, r.fbo)
+	for i := 0; i < r.colors; i++ {
+		attachment := gl.COLOR_ATTACHMENT0 + uint32(i)
+		gl.ReadBuffer(attachment)
+		gldebug.Check()
+		gl.DrawBuffer(attachment)
+		gldebug.Check()
+		gl.BlitFramebuffer(0, 0, r.width, r.height, 0, 0, r.width, r.height, gl.COLOR_BUFFER_BIT, gl.NEAREST)
+		gldebug.Check()
+	}
+}
+
+// Clear clears the color textures to a color and, if present, the depth
+// and stencil buffer. The target must be bound. The clear color of the
+// default framebuffer isn't changed.
+func (r *RenderTarget) Clear(red, green, blue, alpha float32) {
+	color := [4]float32{red, green, blue, alpha}
+	for i := 0; i < r.colors; i++ {
+		gl.ClearBufferfv(gl.COLOR, int32(i), &color[0])
+		gldebug.Check()
+	}
+	if r.depthStencil {
+		gl.ClearBufferfi(gl.DEPTH_STENCIL, 0, 1.0, 0)
+		gldebug.Check()
+	}
+}
+
+func (r *RenderTarget) Delete() {
+	r.Unbind()
+
+	for _, tex := range r.textures {
+		glstate.DeleteTexture(tex)
+	}
+	for _, rbo := range r.msColors {
+		glstate.DeleteRenderbuffer(rbo)
+	}
+	glstate.DeleteRenderbuffer(r.depthRbo)
+	if r.msFbo != r.fbo {
+		glstate.DeleteFramebuffer(r.msFbo)
+	}
+	glstate.DeleteFramebuffer(r.fbo)
+
+	r.textures = nil
+	r.msColors = nil
+}
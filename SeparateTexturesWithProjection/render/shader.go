@@ -1,6 +1,8 @@
 package render
 
 import (
+	"SimpleOpenGL-Go/SeparateTexturesWithProjection/gldebug"
+	"SimpleOpenGL-Go/SeparateTexturesWithProjection/glstate"
 	"fmt"
 	"strings"
 
@@ -84,27 +86,218 @@ const (
         FragColor = texture(texture1, TexCoord);
     }
 ` + "\x00"
+
+	// ----------------------------------------------
+	// Glyph pages are typically white with an alpha channel, so the
+	// sample is simply tinted.
+	fragmentTextShaderSource = `
+    #version 450
+    out vec4 FragColor;
+
+    in vec2 TexCoord;
+
+    uniform sampler2D texture1;
+    uniform vec4 color;
+
+    void main()
+    {
+        FragColor = texture(texture1, TexCoord) * color;
+    }
+` + "\x00"
+
+	// ----------------------------------------------
+	// Shapes carry a color per vertex and are already in world space so
+	// there is no model matrix.
+	vertexShapeShaderSource = `
+    #version 450
+    layout (location = 0) in vec2 aPos;
+    layout (location = 1) in vec4 aColor;
+
+    uniform mat4 view;
+    uniform mat4 projection;
+
+    out vec4 Color;
+
+    void main() {
+        gl_Position = projection * view * vec4(aPos, 0.0, 1.0);
+        Color = aColor;
+    }
+` + "\x00"
+
+	fragmentShapeShaderSource = `
+    #version 450
+    out vec4 FragColor;
+
+    in vec4 Color;
+
+    void main()
+    {
+        FragColor = Color;
+    }
+` + "\x00"
+
+	// ----------------------------------------------
+	// Instanced sprites: a unit quad scaled, rotated and moved by the
+	// per-instance attributes. The UV rect is lower-left, upper-right.
+	vertexSpriteInstancedShaderSource = `
+    #version 450
+    layout (location = 0) in vec2 aPos;
+    layout (location = 2) in vec3 iPosition; // x, y, rotation
+    layout (location = 3) in vec2 iSize;
+    layout (location = 4) in vec4 iUVRect;
+    layout (location = 5) in vec4 iTint;
+
+    uniform mat4 view;
+    uniform mat4 projection;
+
+    out vec2 TexCoord;
+    out vec4 Color;
+
+    void main() {
+        vec2 p = aPos * iSize;
+        float c = cos(iPosition.z);
+        float s = sin(iPosition.z);
+        p = vec2(p.x * c - p.y * s, p.x * s + p.y * c) + iPosition.xy;
+
+        gl_Position = projection * view * vec4(p, 0.0, 1.0);
+        TexCoord = mix(iUVRect.xy, iUVRect.zw, aPos + 0.5);
+        Color = iTint;
+    }
+` + "\x00"
+
+	// The batch fallback transforms the sprites on the CPU.
+	vertexSpriteBatchShaderSource = `
+    #version 450
+    layout (location = 0) in vec2 aPos;
+    layout (location = 1) in vec2 aTexCoord;
+    layout (location = 2) in vec4 aColor;
+
+    uniform mat4 view;
+    uniform mat4 projection;
+
+    out vec2 TexCoord;
+    out vec4 Color;
+
+    void main() {
+        gl_Position = projection * view * vec4(aPos, 0.0, 1.0);
+        TexCoord = aTexCoord;
+        Color = aColor;
+    }
+` + "\x00"
+
+	fragmentSpriteShaderSource = `
+    #version 450
+    out vec4 FragColor;
+
+    in vec2 TexCoord;
+    in vec4 Color;
+
+    uniform sampler2D texture1;
+
+    void main()
+    {
+        FragColor = texture(texture1, TexCoord) * Color;
+    }
+` + "\x00"
+
+	// ----------------------------------------------
+	// Signed distance field: alpha holds the distance where 0.5 is the
+	// edge. fwidth gives the screen space rate of change so edges stay
+	// one pixel wide regardless of zoom.
+	fragmentSDFShaderSource = `
+    #version 450
+    out vec4 FragColor;
+
+    in vec2 TexCoord;
+
+    uniform sampler2D texture1;
+    uniform vec4 color;
+
+    uniform float outlineWidth;  // in distance units (0.0 - 0.5)
+    uniform vec4 outlineColor;
+    uniform vec2 shadowOffset;   // in texture coords
+    uniform float shadowSoftness;
+    uniform vec4 shadowColor;
+
+    void main()
+    {
+        float dist = texture(texture1, TexCoord).a;
+        float w = max(fwidth(dist), 0.0001);
+
+        float fill = smoothstep(0.5 - w, 0.5 + w, dist);
+        float outerEdge = 0.5 - outlineWidth;
+        float outer = smoothstep(outerEdge - w, outerEdge + w, dist);
+
+        vec4 body = mix(outlineColor, color, fill);
+        body.a *= outer;
+
+        float sd = texture(texture1, TexCoord - shadowOffset).a;
+        float shadow = smoothstep(outerEdge - shadowSoftness - w, outerEdge + shadowSoftness + w, sd) * shadowColor.a;
+
+        // body over shadow
+        float a = body.a + shadow * (1.0 - body.a);
+        vec3 rgb = (body.rgb * body.a + shadowColor.rgb * shadow * (1.0 - body.a)) / max(a, 0.0001);
+
+        FragColor = vec4(rgb, a);
+    }
+` + "\x00"
 )
 
 func compileShader(source string, shaderType uint32) (uint32, error) {
 	shader := gl.CreateShader(shaderType)
+	gldebug.Check()
 
 	csources, free := gl.Strs(source)
 	gl.ShaderSource(shader, 1, csources, nil)
+	gldebug.Check()
 	free()
 	gl.CompileShader(shader)
+	gldebug.Check()
 
 	var status int32
 	gl.GetShaderiv(shader, gl.COMPILE_STATUS, &status)
+	gldebug.Check()
 	if status == gl.FALSE {
 		var logLength int32
 		gl.GetShaderiv(shader, gl.INFO_LOG_LENGTH, &logLength)
+		gldebug.Check()
 
 		log := strings.Repeat("\x00", int(logLength+1))
 		gl.GetShaderInfoLog(shader, logLength, nil, gl.Str(log))
+		gldebug.Check()
 
 		return 0, fmt.Errorf("failed to compile %v: %v", source, log)
 	}
 
 	return shader, nil
 }
+
+// linkProgram links the shaders into a program. The error holds the info
+// log when linking fails, e.g. a varying missing from the vertex shader.
+func linkProgram(shaders ...uint32) (uint32, error) {
+	prog := glstate.CreateProgram()
+	for _, shader := range shaders {
+		gl.AttachShader(prog, shader)
+		gldebug.Check()
+	}
+	gl.LinkProgram(prog)
+	gldebug.Check()
+
+	var status int32
+	gl.GetProgramiv(prog, gl.LINK_STATUS, &status)
+	gldebug.Check()
+	if status == gl.FALSE {
+		var logLength int32
+		gl.GetProgramiv(prog, gl.INFO_LOG_LENGTH, &logLength)
+		gldebug.Check()
+
+		log := strings.Repeat("\x00", int(logLength+1))
+		gl.GetProgramInfoLog(prog, logLength, nil, gl.Str(log))
+		gldebug.Check()
+
+		glstate.DeleteProgram(prog)
+		return 0, fmt.Errorf("failed to link program: %v", log)
+	}
+
+	return prog, nil
+}
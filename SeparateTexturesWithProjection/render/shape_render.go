@@ -0,0 +1,264 @@
+package render
+
+import (
+	"SimpleOpenGL-Go/SeparateTexturesWithProjection/api"
+	"SimpleOpenGL-Go/SeparateTexturesWithProjection/display"
+	"SimpleOpenGL-Go/SeparateTexturesWithProjection/geometry"
+	"SimpleOpenGL-Go/SeparateTexturesWithProjection/gldebug"
+	"SimpleOpenGL-Go/SeparateTexturesWithProjection/glstate"
+	"log"
+	"math"
+
+	"github.com/go-gl/gl/v4.5-core/gl"
+)
+
+// Color is a non-premultiplied RGBA color
+type Color struct {
+	R, G, B, A float32
+}
+
+// ShapeRenderer is an immediate mode renderer for lines and filled
+// shapes. Shapes are queued between Begin and End, then drawn with a
+// single buffer upload and draw call. Coordinates are in world space.
+type ShapeRenderer struct {
+	vao, vbo uint32
+
+	shaderProgram uint32
+
+	projLoc, viewLoc int32
+
+	// x,y,r,g,b,a per vertex, 3 vertices per triangle
+	vertices []float32
+	// capacity of the vbo in floats
+	capacity int
+}
+
+func NewShapeRenderer() *ShapeRenderer {
+	o := new(ShapeRenderer)
+	return o
+}
+
+func (s *ShapeRenderer) Build() {
+	s.vao = glstate.GenVertexArray()
+	s.vbo = glstate.GenBuffer()
+
+	glstate.BindVertexArray(s.vao)
+
+	s.shaderProgram = s.initShaderProgram()
+	if err := ShapeLayout.Validate(s.shaderProgram); err != nil {
+		panic(err)
+	}
+
+	gl.BindBuffer(gl.ARRAY_BUFFER, s.vbo)
+	gldebug.Check()
+
+	// Our data layout is x,y,r,g,b,a
+	ShapeLayout.Apply()
+
+	glstate.BindVertexArray(0) // close scope
+}
+
+func (s *ShapeRenderer) SetUniforms(proj *display.Projection, view api.IMatrix4) {
+	glstate.UseProgram(s.shaderProgram)
+
+	pm := proj.Matrix().Matrix()
+	gl.UniformMatrix4fv(s.projLoc, 1, false, &pm[0])
+	gldebug.Check()
+
+	gl.UniformMatrix4fv(s.viewLoc, 1, false, &view.Matrix()[0])
+	gldebug.Check()
+}
+
+// Begin starts a new batch, discarding anything queued previously.
+func (s *ShapeRenderer) Begin() {
+	s.vertices = s.vertices[:0]
+}
+
+// End uploads the batch and draws it.
+func (s *ShapeRenderer) End() {
+	if len(s.vertices) == 0 {
+		return
+	}
+
+	gl.BindBuffer(gl.ARRAY_BUFFER, s.vbo)
+	gldebug.Check()
+	if len(s.vertices) > s.capacity {
+		s.capacity = len(s.vertices)
+		gl.BufferData(gl.ARRAY_BUFFER, 4*s.capacity, gl.Ptr(s.vertices), gl.STREAM_DRAW)
+		gldebug.Check()
+	} else {
+		// Orphan the old storage so the driver doesn't have to wait on it
+		gl.BufferData(gl.ARRAY_BUFFER, 4*s.capacity, nil, gl.STREAM_DRAW)
+		gldebug.Check()
+		gl.BufferSubData(gl.ARRAY_BUFFER, 0, 4*len(s.vertices), gl.Ptr(s.vertices))
+		gldebug.Check()
+	}
+	gl.BindBuffer(gl.ARRAY_BUFFER, 0)
+	gldebug.Check()
+
+	glstate.UseProgram(s.shaderProgram)
+	glstate.BindVertexArray(s.vao)
+	count := len(s.vertices) / ShapeLayout.FloatsPerVertex()
+	gl.DrawArrays(gl.TRIANGLES, 0, int32(count))
+	gldebug.Check()
+	countDraw(count, 1)
+}
+
+// VertexCount returns the number of vertices queued since Begin.
+func (s *ShapeRenderer) VertexCount() int {
+	return len(s.vertices) / ShapeLayout.FloatsPerVertex()
+}
+
+// DrawLine draws a segment as a quad of the given thickness.
+func (s *ShapeRenderer) DrawLine(x1, y1, x2, y2, thickness float32, c Color) {
+	dx := x2 - x1
+	dy := y2 - y1
+	length := float32(math.Sqrt(float64(dx*dx + dy*dy)))
+	if length == 0 {
+		return
+	}
+
+	// Perpendicular scaled to half the thickness
+	nx := -dy / length * thickness / 2.0
+	ny := dx / length * thickness / 2.0
+
+	s.quad(x1+nx, y1+ny, x1-nx, y1-ny, x2-nx, y2-ny, x2+nx, y2+ny, c)
+}
+
+// DrawRect outlines a rectangle whose lower left corner is x,y.
+func (s *ShapeRenderer) DrawRect(x, y, width, height, thickness float32, c Color) {
+	h := thickness / 2.0
+	// The horizontal edges cover the corners
+	s.FillRect(x-h, y-h, width+thickness, thickness, c)
+	s.FillRect(x-h, y+height-h, width+thickness, thickness, c)
+	s.FillRect(x-h, y+h, thickness, height-thickness, c)
+	s.FillRect(x+width-h, y+h, thickness, height-thickness, c)
+}
+
+// FillRect fills a rectangle whose lower left corner is x,y.
+func (s *ShapeRenderer) FillRect(x, y, width, height float32, c Color) {
+	s.quad(x, y, x+width, y, x+width, y+height, x, y+height, c)
+}
+
+// DrawCircle outlines a circle approximated by segments.
+func (s *ShapeRenderer) DrawCircle(cx, cy, radius, thickness float32, segments int, c Color) {
+	if segments < 3 {
+		segments = 3
+	}
+
+	inner := radius - thickness/2.0
+	outer := radius + thickness/2.0
+	step := 2.0 * math.Pi / float64(segments)
+
+	for i := 0; i < segments; i++ {
+		a0 := float64(i) * step
+		a1 := float64(i+1) * step
+		c0, s0 := float32(math.Cos(a0)), float32(math.Sin(a0))
+		c1, s1 := float32(math.Cos(a1)), float32(math.Sin(a1))
+
+		s.quad(
+			cx+c0*inner, cy+s0*inner,
+			cx+c0*outer, cy+s0*outer,
+			cx+c1*outer, cy+s1*outer,
+			cx+c1*inner, cy+s1*inner,
+			c)
+	}
+}
+
+// FillCircle fills a circle approximated by segments.
+func (s *ShapeRenderer) FillCircle(cx, cy, radius float32, segments int, c Color) {
+	if segments < 3 {
+		segments = 3
+	}
+
+	step := 2.0 * math.Pi / float64(segments)
+	for i := 0; i < segments; i++ {
+		a0 := float64(i) * step
+		a1 := float64(i+1) * step
+		s.triangle(
+			cx, cy,
+			cx+float32(math.Cos(a0))*radius, cy+float32(math.Sin(a0))*radius,
+			cx+float32(math.Cos(a1))*radius, cy+float32(math.Sin(a1))*radius,
+			c)
+	}
+}
+
+// DrawPolygon outlines a closed polygon given as x,y pairs. Corners are
+// mitered.
+func (s *ShapeRenderer) DrawPolygon(points []float32, thickness float32, c Color) {
+	vertices, strip := geometry.Stroke(points, geometry.StrokeOptions{
+		Width:  thickness,
+		Join:   geometry.JoinMiter,
+		Closed: true,
+	})
+	s.FillTriangles(vertices, geometry.StripToTriangles(strip), c)
+}
+
+// FillPolygon fills a simple polygon given as x,y pairs. It may be
+// concave and have holes.
+func (s *ShapeRenderer) FillPolygon(points []float32, c Color, holes ...[]float32) {
+	vertices, indices, err := geometry.Triangulate(points, holes...)
+	if err != nil {
+		// Draw what could be triangulated
+		log.Println("ShapeRenderer:", err)
+	}
+	s.FillTriangles(vertices, indices, c)
+}
+
+// FillTriangles queues indexed triangles given as x,y pairs.
+func (s *ShapeRenderer) FillTriangles(vertices []float32, indices []uint32, c Color) {
+	for i := 0; i+2 < len(indices); i += 3 {
+		a, b, d := indices[i]*2, indices[i+1]*2, indices[i+2]*2
+		s.triangle(
+			vertices[a], vertices[a+1],
+			vertices[b], vertices[b+1],
+			vertices[d], vertices[d+1],
+			c)
+	}
+}
+
+func (s *ShapeRenderer) quad(x0, y0, x1, y1, x2, y2, x3, y3 float32, c Color) {
+	s.triangle(x0, y0, x1, y1, x2, y2, c)
+	s.triangle(x0, y0, x2, y2, x3, y3, c)
+}
+
+func (s *ShapeRenderer) triangle(x0, y0, x1, y1, x2, y2 float32, c Color) {
+	s.vertices = append(s.vertices,
+		x0, y0, c.R, c.G, c.B, c.A,
+		x1, y1, c.R, c.G, c.B, c.A,
+		x2, y2, c.R, c.G, c.B, c.A,
+	)
+}
+
+func (s *ShapeRenderer) initShaderProgram() uint32 {
+	vertexShader, err := compileShader(vertexShapeShaderSource, gl.VERTEX_SHADER)
+	if err != nil {
+		panic(err)
+	}
+
+	fragmentShader, err := compileShader(fragmentShapeShaderSource, gl.FRAGMENT_SHADER)
+	if err != nil {
+		panic(err)
+	}
+
+	prog, err := linkProgram(vertexShader, fragmentShader)
+	if err != nil {
+		panic(err)
+	}
+
+	glstate.UseProgram(prog)
+
+	s.projLoc = gl.GetUniformLocation(prog, gl.Str("projection\x00"))
+	gldebug.Check()
+	if s.projLoc < 0 {
+		panic("ShapeRenderer: couldn't find 'projection' uniform variable")
+	}
+
+	s.viewLoc = gl.GetUniformLocation(prog, gl.Str("view\x00"))
+	gldebug.Check()
+	if s.viewLoc < 0 {
+		panic("ShapeRenderer: couldn't find 'view' uniform variable")
+	}
+
+	return prog
+}
@@ -0,0 +1,280 @@
+package render
+
+import (
+	"SimpleOpenGL-Go/SeparateTexturesWithProjection/api"
+	"SimpleOpenGL-Go/SeparateTexturesWithProjection/display"
+	"SimpleOpenGL-Go/SeparateTexturesWithProjection/gldebug"
+	"SimpleOpenGL-Go/SeparateTexturesWithProjection/glstate"
+	"SimpleOpenGL-Go/SeparateTexturesWithProjection/textures"
+	"math"
+
+	"github.com/go-gl/gl/v4.5-core/gl"
+)
+
+// UVRect is a sub texture's lower-left s,t followed by its upper-right s,t.
+type UVRect [4]float32
+
+// SpriteRenderer draws many sprites from one atlas in a single draw call.
+// Sprites are queued between Begin and End. With instancing each sprite
+// is one instance of a shared unit quad, otherwise the quads are expanded
+// on the CPU into one batch.
+type SpriteRenderer struct {
+	textureAtlas *textures.TextureAtlas
+	atlasVersion uint64
+	tbo          uint32
+
+	instanced bool
+
+	// Instanced path
+	quad        *Mesh
+	instanceVbo uint32
+	// capacity of the instance vbo in floats
+	instanceCapacity int
+	instanceProgram  uint32
+
+	// Batch path
+	batch        *Mesh
+	batchIndices []uint32
+	batchProgram uint32
+
+	projLocs, viewLocs [2]int32
+
+	// Per-instance data in SpriteInstanceLayout order
+	instances []float32
+}
+
+func NewSpriteRenderer(textureAtlas *textures.TextureAtlas) *SpriteRenderer {
+	o := new(SpriteRenderer)
+	o.textureAtlas = textureAtlas
+	return o
+}
+
+// Build compiles both paths and selects instancing.
+func (s *SpriteRenderer) Build() {
+	s.instanced = true
+
+	s.instanceProgram = s.initShaderProgram(vertexSpriteInstancedShaderSource, 0)
+	if err := ValidateLayouts(s.instanceProgram, SpriteQuadLayout, SpriteInstanceLayout); err != nil {
+		panic(err)
+	}
+
+	s.batchProgram = s.initShaderProgram(vertexSpriteBatchShaderSource, 1)
+	if err := SpriteBatchLayout.Validate(s.batchProgram); err != nil {
+		panic(err)
+	}
+
+	s.quad = NewMesh(SpriteQuadLayout, Triangles, StaticDraw)
+	s.quad.SetVertices([]float32{
+		-0.5, -0.5,
+		0.5, -0.5,
+		0.5, 0.5,
+		-0.5, 0.5,
+	})
+	s.quad.SetIndices16([]uint16{0, 1, 2, 0, 2, 3})
+	s.quad.Build()
+
+	// Attach the instance buffer to the quad's VAO
+	s.instanceVbo = glstate.GenBuffer()
+	glstate.BindVertexArray(s.quad.VAO())
+	gl.BindBuffer(gl.ARRAY_BUFFER, s.instanceVbo)
+	gldebug.Check()
+	SpriteInstanceLayout.Apply()
+	glstate.BindVertexArray(0)
+	gl.BindBuffer(gl.ARRAY_BUFFER, 0)
+	gldebug.Check()
+
+	s.batch = NewMesh(SpriteBatchLayout, Triangles, StreamDraw)
+	s.batch.Build()
+
+	s.tbo = glstate.GenTexture()
+	s.uploadAtlas()
+}
+
+// SetInstanced selects the instanced path or the batch fallback.
+func (s *SpriteRenderer) SetInstanced(instanced bool) {
+	s.instanced = instanced
+}
+
+// Instanced reports whether the instanced path is in use.
+func (s *SpriteRenderer) Instanced() bool {
+	return s.instanced
+}
+
+func (s *SpriteRenderer) SetUniforms(proj *display.Projection, view api.IMatrix4) {
+	pm := proj.Matrix().Matrix()
+
+	for i, prog := range []uint32{s.instanceProgram, s.batchProgram} {
+		glstate.UseProgram(prog)
+		gl.UniformMatrix4fv(s.projLocs[i], 1, false, &pm[0])
+		gldebug.Check()
+		gl.UniformMatrix4fv(s.viewLocs[i], 1, false, &view.Matrix()[0])
+		gldebug.Check()
+	}
+}
+
+// Region returns the UV rect of a named sub texture. Look it up once and
+// reuse it rather than per sprite.
+func (s *SpriteRenderer) Region(name string) UVRect {
+	coords := s.textureAtlas.TextureCoords(name)
+	if coords == nil {
+		panic("Sub texture not found")
+	}
+	return UVRect{coords[0].S, coords[0].T, coords[2].S, coords[2].T}
+}
+
+// Begin starts a new batch, discarding anything queued previously.
+func (s *SpriteRenderer) Begin() {
+	s.instances = s.instances[:0]
+}
+
+// Add queues a sprite centered on x,y. Rotation is in radians.
+func (s *SpriteRenderer) Add(uv UVRect, x, y, width, height, rotation float32, tint Color) {
+	s.instances = append(s.instances,
+		x, y, rotation,
+		width, height,
+		uv[0], uv[1], uv[2], uv[3],
+		tint.R, tint.G, tint.B, tint.A,
+	)
+}
+
+// Count returns the number of sprites queued since Begin.
+func (s *SpriteRenderer) Count() int {
+	return len(s.instances) / SpriteInstanceLayout.FloatsPerVertex()
+}
+
+// End uploads the sprites and draws them.
+func (s *SpriteRenderer) End() {
+	count := s.Count()
+	if count == 0 {
+		return
+	}
+
+	// Dynamic atlases can change after Build.
+	if s.textureAtlas.Version() != s.atlasVersion {
+		s.uploadAtlas()
+	}
+
+	glstate.ActiveTexture(gl.TEXTURE0)
+	glstate.BindTexture(gl.TEXTURE_2D, s.tbo)
+
+	if s.instanced {
+		s.drawInstanced(count)
+	} else {
+		s.drawBatch(count)
+	}
+}
+
+func (s *SpriteRenderer) drawInstanced(count int) {
+	gl.BindBuffer(gl.ARRAY_BUFFER, s.instanceVbo)
+	gldebug.Check()
+	if len(s.instances) > s.instanceCapacity {
+		s.instanceCapacity = len(s.instances)
+		gl.BufferData(gl.ARRAY_BUFFER, 4*s.instanceCapacity, gl.Ptr(s.instances), gl.STREAM_DRAW)
+		gldebug.Check()
+	} else {
+		// Orphan the old storage so the driver doesn't have to wait on it
+		gl.BufferData(gl.ARRAY_BUFFER, 4*s.instanceCapacity, nil, gl.STREAM_DRAW)
+		gldebug.Check()
+		gl.BufferSubData(gl.ARRAY_BUFFER, 0, 4*len(s.instances), gl.Ptr(s.instances))
+		gldebug.Check()
+	}
+	gl.BindBuffer(gl.ARRAY_BUFFER, 0)
+	gldebug.Check()
+
+	glstate.UseProgram(s.instanceProgram)
+	s.quad.DrawInstanced(count)
+}
+
+// drawBatch does on the CPU what the instanced vertex shader does.
+func (s *SpriteRenderer) drawBatch(count int) {
+	stride := SpriteInstanceLayout.FloatsPerVertex()
+	corners := [4][2]float32{{-0.5, -0.5}, {0.5, -0.5}, {0.5, 0.5}, {-0.5, 0.5}}
+
+	vertices := s.batch.Vertices()[:0]
+	for i := 0; i < count; i++ {
+		in := s.instances[i*stride : (i+1)*stride]
+		x, y, rotation := in[0], in[1], in[2]
+		width, height := in[3], in[4]
+		uv := in[5:9]
+		tint := in[9:13]
+
+		sin, cos := math.Sincos(float64(rotation))
+		c, sn := float32(cos), float32(sin)
+
+		for _, k := range corners {
+			px := k[0] * width
+			py := k[1] * height
+			vertices = append(vertices,
+				px*c-py*sn+x, px*sn+py*c+y,
+				uv[0]+(k[0]+0.5)*(uv[2]-uv[0]), uv[1]+(k[1]+0.5)*(uv[3]-uv[1]),
+				tint[0], tint[1], tint[2], tint[3],
+			)
+		}
+	}
+	s.batch.SetVertices(vertices)
+
+	// The indices only change when the batch outgrows them
+	if len(s.batchIndices) < count*6 {
+		for q := uint32(len(s.batchIndices) / 6); q < uint32(count); q++ {
+			base := q * 4
+			s.batchIndices = append(s.batchIndices, base, base+1, base+2, base, base+2, base+3)
+		}
+		s.batch.SetIndices(s.batchIndices)
+	}
+
+	glstate.UseProgram(s.batchProgram)
+	s.batch.DrawRange(0, count*6)
+}
+
+func (s *SpriteRenderer) uploadAtlas() {
+	texture := s.textureAtlas.Atlas()
+
+	glstate.BindTexture(gl.TEXTURE_2D, s.tbo)
+
+	gl.PixelStorei(gl.UNPACK_ALIGNMENT, 1)
+	gldebug.Check()
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, gl.NEAREST)
+	gldebug.Check()
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MAG_FILTER, gl.NEAREST)
+	gldebug.Check()
+
+	width := int32(texture.Bounds().Dx())
+	height := int32(texture.Bounds().Dy())
+	gl.TexImage2D(gl.TEXTURE_2D, 0, gl.RGBA, width, height, 0, gl.RGBA, gl.UNSIGNED_BYTE, gl.Ptr(texture.Pix))
+	gldebug.Check()
+
+	s.atlasVersion = s.textureAtlas.Version()
+}
+
+func (s *SpriteRenderer) initShaderProgram(vertexSource string, slot int) uint32 {
+	vertexShader, err := compileShader(vertexSource, gl.VERTEX_SHADER)
+	if err != nil {
+		panic(err)
+	}
+
+	fragmentShader, err := compileShader(fragmentSpriteShaderSource, gl.FRAGMENT_SHADER)
+	if err != nil {
+		panic(err)
+	}
+
+	prog, err := linkProgram(vertexShader, fragmentShader)
+	if err != nil {
+		panic(err)
+	}
+
+	glstate.UseProgram(prog)
+
+	s.projLocs[slot] = gl.GetUniformLocation(prog, gl.Str("projection\x00"))
+	gldebug.Check()
+	if s.projLocs[slot] < 0 {
+		panic("SpriteRenderer: couldn't find 'projection' uniform variable")
+	}
+
+	s.viewLocs[slot] = gl.GetUniformLocation(prog, gl.Str("view\x00"))
+	gldebug.Check()
+	if s.viewLocs[slot] < 0 {
+		panic("SpriteRenderer: couldn't find 'view' uniform variable")
+	}
+
+	return prog
+}
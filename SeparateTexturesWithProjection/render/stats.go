@@ -0,0 +1,27 @@
+package render
+
+// FrameStats counts the draws made by the renderers since the last
+// ResetFrameStats, e.g. over a frame.
+type FrameStats struct {
+	DrawCalls int
+	// Vertices processed, indices for indexed draws, times the instances
+	Vertices  int
+	Instances int
+}
+
+var frameStats FrameStats
+
+func GetFrameStats() FrameStats {
+	return frameStats
+}
+
+func ResetFrameStats() {
+	frameStats = FrameStats{}
+}
+
+// countDraw records a draw call of vertices per instance.
+func countDraw(vertices, instances int) {
+	frameStats.DrawCalls++
+	frameStats.Vertices += vertices * instances
+	frameStats.Instances += instances
+}
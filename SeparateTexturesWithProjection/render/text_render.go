@@ -0,0 +1,345 @@
+package render
+
+import (
+	"SimpleOpenGL-Go/SeparateTexturesWithProjection/api"
+	"SimpleOpenGL-Go/SeparateTexturesWithProjection/display"
+	"SimpleOpenGL-Go/SeparateTexturesWithProjection/gldebug"
+	"SimpleOpenGL-Go/SeparateTexturesWithProjection/glstate"
+	"SimpleOpenGL-Go/SeparateTexturesWithProjection/maths"
+	"SimpleOpenGL-Go/SeparateTexturesWithProjection/textures"
+	"sort"
+
+	"github.com/go-gl/gl/v4.5-core/gl"
+)
+
+// TextRenderer draws a string using a font's glyph pages. The layout
+// is only rebuilt when the text or one of the layout options change.
+type TextRenderer struct {
+	vao, vbo, ebo uint32
+	tbos          []uint32
+
+	shaderProgram uint32
+	font          textures.Font
+	fontVersion   uint64
+
+	projLoc, viewLoc, modelLoc, colorLoc int32
+
+	modelM api.IMatrix4
+	color  [4]float32
+
+	// Non-nil when the font's pages are distance fields
+	distanceField *DistanceField
+	dfUniforms    distanceFieldUniforms
+
+	text    string
+	options textures.LayoutOptions
+	layout  *textures.TextLayout
+	dirty   bool
+	upload  bool
+
+	vertices []float32
+	indices  []uint32
+	// Each batch is a run of indices that share a page.
+	batches []textBatch
+}
+
+type textBatch struct {
+	page          int
+	offset, count int32
+}
+
+// NewTextRenderer creates a renderer for the given font. The font must
+// already be built.
+func NewTextRenderer(font textures.Font) *TextRenderer {
+	o := new(TextRenderer)
+	o.modelM = maths.NewMatrix4()
+	o.font = font
+	o.color = [4]float32{1.0, 1.0, 1.0, 1.0}
+	o.options.Scale = 1.0
+	return o
+}
+
+// Build creates the GL objects and uploads the font's pages.
+func (t *TextRenderer) Build() {
+	t.vao = glstate.GenVertexArray()
+	t.vbo = glstate.GenBuffer()
+	t.ebo = glstate.GenBuffer()
+
+	glstate.BindVertexArray(t.vao)
+
+	t.shaderProgram = t.initShaderProgram()
+	if err := PositionTextureLayout.Validate(t.shaderProgram); err != nil {
+		panic(err)
+	}
+
+	gl.BindBuffer(gl.ARRAY_BUFFER, t.vbo)
+	gldebug.Check()
+
+	// Our data layout is x,y,z,s,t
+	PositionTextureLayout.Apply()
+
+	gl.BindBuffer(gl.ELEMENT_ARRAY_BUFFER, t.ebo)
+	gldebug.Check()
+
+	glstate.BindVertexArray(0) // close scope
+
+	t.uploadPages()
+
+	t.upload = true
+}
+
+// uploadPages gives the font's pages to OpenGL, creating textures as needed.
+func (t *TextRenderer) uploadPages() {
+	for i, page := range t.font.Pages() {
+		if i >= len(t.tbos) {
+			t.tbos = append(t.tbos, glstate.GenTexture())
+		}
+
+		glstate.BindTexture(gl.TEXTURE_2D, t.tbos[i])
+
+		gl.PixelStorei(gl.UNPACK_ALIGNMENT, 1)
+		gldebug.Check()
+		// Linear filtering keeps scaled text smooth
+		gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, gl.LINEAR)
+		gldebug.Check()
+		gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MAG_FILTER, gl.LINEAR)
+		gldebug.Check()
+		gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_S, gl.CLAMP_TO_EDGE)
+		gldebug.Check()
+		gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_T, gl.CLAMP_TO_EDGE)
+		gldebug.Check()
+
+		width := int32(page.Bounds().Dx())
+		height := int32(page.Bounds().Dy())
+		gl.TexImage2D(gl.TEXTURE_2D, 0, gl.RGBA, width, height, 0, gl.RGBA, gl.UNSIGNED_BYTE, gl.Ptr(page.Pix))
+		gldebug.Check()
+	}
+
+	t.fontVersion = t.font.Version()
+}
+
+// SetText changes the string drawn.
+func (t *TextRenderer) SetText(text string) {
+	if text != t.text {
+		t.text = text
+		t.dirty = true
+	}
+}
+
+// SetPosition sets the upper left corner of the text block.
+func (t *TextRenderer) SetPosition(x, y float32) {
+	t.modelM.SetTranslate3Comp(x, y, 0.0)
+}
+
+// SetColor sets the tint applied to the glyphs.
+func (t *TextRenderer) SetColor(r, g, b, a float32) {
+	t.color = [4]float32{r, g, b, a}
+}
+
+// UseDistanceField draws the glyphs with the SDF shader, see cmd/sdfgen
+// for baking a font. It must be called before Build to select the shader,
+// later calls only change the parameters.
+func (t *TextRenderer) UseDistanceField(df DistanceField) {
+	t.distanceField = &df
+}
+
+// SetScale scales the font's metrics.
+func (t *TextRenderer) SetScale(scale float32) {
+	t.options.Scale = scale
+	t.dirty = true
+}
+
+// SetAlignment sets the horizontal alignment of each line.
+func (t *TextRenderer) SetAlignment(align textures.Alignment) {
+	t.options.Align = align
+	t.dirty = true
+}
+
+// SetWrapWidth sets the maximum line width. Zero disables wrapping.
+func (t *TextRenderer) SetWrapWidth(width float32) {
+	t.options.WrapWidth = width
+	t.dirty = true
+}
+
+// Layout returns the current layout, rebuilding it if needed.
+func (t *TextRenderer) Layout() *textures.TextLayout {
+	if t.dirty || t.layout == nil {
+		t.layout = textures.Layout(t.font, t.text, t.options)
+		t.dirty = false
+		t.upload = true
+	}
+	return t.layout
+}
+
+func (t *TextRenderer) SetUniforms(proj *display.Projection, view api.IMatrix4) {
+	glstate.UseProgram(t.shaderProgram)
+
+	pm := proj.Matrix().Matrix()
+	gl.UniformMatrix4fv(t.projLoc, 1, false, &pm[0])
+	gldebug.Check()
+
+	gl.UniformMatrix4fv(t.viewLoc, 1, false, &view.Matrix()[0])
+	gldebug.Check()
+}
+
+// glyphUser is a font that evicts glyphs nobody has drawn lately, see
+// textures.GlyphCache.
+type glyphUser interface {
+	MarkUsed(text string)
+}
+
+func (t *TextRenderer) Draw() {
+	// Glyphs may have moved or been evicted since the layout was made
+	if t.font.Version() != t.fontVersion {
+		t.dirty = true
+	}
+	t.Layout()
+	if u, ok := t.font.(glyphUser); ok {
+		u.MarkUsed(t.text)
+	}
+
+	// A dynamic font may have rasterized glyphs during layout, and a font
+	// built after the renderer has pages it hasn't seen
+	if t.font.Version() != t.fontVersion || len(t.tbos) < len(t.font.Pages()) {
+		t.uploadPages()
+		t.upload = true
+	}
+
+	if t.upload {
+		t.rebuild()
+	}
+
+	if len(t.indices) == 0 {
+		return
+	}
+
+	glstate.UseProgram(t.shaderProgram)
+
+	gl.UniformMatrix4fv(t.modelLoc, 1, false, &t.modelM.Matrix()[0])
+	gldebug.Check()
+	gl.Uniform4fv(t.colorLoc, 1, &t.color[0])
+	gldebug.Check()
+
+	if t.distanceField != nil {
+		page := t.font.Pages()[0].Bounds()
+		t.dfUniforms.apply(t.distanceField, page.Dx(), page.Dy())
+	}
+
+	glstate.BindVertexArray(t.vao)
+
+	glstate.ActiveTexture(gl.TEXTURE0)
+
+	sizeOfUInt32 := int32(4)
+	for _, b := range t.batches {
+		glstate.BindTexture(gl.TEXTURE_2D, t.tbos[b.page])
+		gl.DrawElements(gl.TRIANGLES, b.count, gl.UNSIGNED_INT, gl.PtrOffset(int(b.offset*sizeOfUInt32)))
+		gldebug.Check()
+		countDraw(int(b.count), 1)
+	}
+}
+
+// rebuild converts the layout into quads, grouped by page so that each
+// page is bound once.
+func (t *TextRenderer) rebuild() {
+	layout := t.Layout()
+	t.upload = false
+
+	quads := make([]textures.GlyphQuad, len(layout.Quads))
+	copy(quads, layout.Quads)
+	sort.SliceStable(quads, func(i, j int) bool { return quads[i].Page < quads[j].Page })
+
+	t.vertices = t.vertices[:0]
+	t.indices = t.indices[:0]
+	t.batches = t.batches[:0]
+
+	for _, q := range quads {
+		if q.Page >= len(t.tbos) {
+			continue
+		}
+
+		if len(t.batches) == 0 || t.batches[len(t.batches)-1].page != q.Page {
+			t.batches = append(t.batches, textBatch{page: q.Page, offset: int32(len(t.indices))})
+		}
+
+		base := uint32(len(t.vertices) / PositionTextureLayout.FloatsPerVertex())
+		c := q.Coords
+		x0, y0 := q.X, q.Y
+		x1, y1 := q.X+q.Width, q.Y+q.Height
+		t.vertices = append(t.vertices,
+			x0, y0, 0.0, c[0].S, c[0].T,
+			x1, y0, 0.0, c[1].S, c[1].T,
+			x1, y1, 0.0, c[2].S, c[2].T,
+			x0, y1, 0.0, c[3].S, c[3].T,
+		)
+
+		t.indices = append(t.indices, base, base+1, base+2, base, base+2, base+3)
+		t.batches[len(t.batches)-1].count += 6
+	}
+
+	if len(t.indices) == 0 {
+		return
+	}
+
+	glstate.BindVertexArray(t.vao)
+	gl.BindBuffer(gl.ARRAY_BUFFER, t.vbo)
+	gldebug.Check()
+	gl.BufferData(gl.ARRAY_BUFFER, 4*len(t.vertices), gl.Ptr(t.vertices), gl.DYNAMIC_DRAW)
+	gldebug.Check()
+	gl.BufferData(gl.ELEMENT_ARRAY_BUFFER, 4*len(t.indices), gl.Ptr(t.indices), gl.DYNAMIC_DRAW)
+	gldebug.Check()
+	glstate.BindVertexArray(0)
+}
+
+func (t *TextRenderer) initShaderProgram() uint32 {
+	vertexShader, err := compileShader(vertexTextureShaderSourcePrj, gl.VERTEX_SHADER)
+	if err != nil {
+		panic(err)
+	}
+
+	fragmentSource := fragmentTextShaderSource
+	if t.distanceField != nil {
+		fragmentSource = fragmentSDFShaderSource
+	}
+
+	fragmentShader, err := compileShader(fragmentSource, gl.FRAGMENT_SHADER)
+	if err != nil {
+		panic(err)
+	}
+
+	prog, err := linkProgram(vertexShader, fragmentShader)
+	if err != nil {
+		panic(err)
+	}
+
+	glstate.UseProgram(prog)
+
+	t.projLoc = gl.GetUniformLocation(prog, gl.Str("projection\x00"))
+	gldebug.Check()
+	if t.projLoc < 0 {
+		panic("TextRenderer: couldn't find 'projection' uniform variable")
+	}
+
+	t.viewLoc = gl.GetUniformLocation(prog, gl.Str("view\x00"))
+	gldebug.Check()
+	if t.viewLoc < 0 {
+		panic("TextRenderer: couldn't find 'view' uniform variable")
+	}
+
+	t.modelLoc = gl.GetUniformLocation(prog, gl.Str("model\x00"))
+	gldebug.Check()
+	if t.modelLoc < 0 {
+		panic("TextRenderer: couldn't find 'model' uniform variable")
+	}
+
+	t.colorLoc = gl.GetUniformLocation(prog, gl.Str("color\x00"))
+	gldebug.Check()
+	if t.colorLoc < 0 {
+		panic("TextRenderer: couldn't find 'color' uniform variable")
+	}
+
+	if t.distanceField != nil {
+		t.dfUniforms.locate(prog, "TextRenderer")
+	}
+
+	return prog
+}
@@ -3,145 +3,271 @@ package render
 import (
 	"SimpleOpenGL-Go/SeparateTexturesWithProjection/api"
 	"SimpleOpenGL-Go/SeparateTexturesWithProjection/display"
+	"SimpleOpenGL-Go/SeparateTexturesWithProjection/gldebug"
+	"SimpleOpenGL-Go/SeparateTexturesWithProjection/glstate"
 	"SimpleOpenGL-Go/SeparateTexturesWithProjection/maths"
 	"SimpleOpenGL-Go/SeparateTexturesWithProjection/textures"
 	"image"
-	"log"
 
 	"github.com/go-gl/gl/v4.5-core/gl"
 )
 
 type TextureRender struct {
-	vao, tbo, vbo, ebo uint32
+	mesh *Mesh
+	tbo  uint32
 
 	shaderProgram uint32
 	textureAtlas  *textures.TextureAtlas
+	atlasVersion  uint64
+	shape         string
+	// Drawn instead of the atlas, see NewTargetTextureRender
+	target *RenderTarget
+
+	x, y          float32
+	width, height float32
 
 	projLoc, viewLoc, modelLoc int32
 
 	modelM api.IMatrix4
-
-	quad    []float32
-	indices []uint32
+	// The model matrix when drawn by a scene node
+	nodeM api.IMatrix4
+	// drawQueued as a value, made once so submitting doesn't allocate
+	queuedDraw func(model *[16]float32)
+
+	// Only used when the atlas is a distance field
+	distanceField *DistanceField
+	dfUniforms    distanceFieldUniforms
+	colorLoc      int32
+	color         [4]float32
 }
 
 func NewTextureRender(textureAtlas *textures.TextureAtlas) *TextureRender {
 	o := new(TextureRender)
 	o.modelM = maths.NewMatrix4()
-	o.modelM.ScaleByComp(64.0, 64.0, 1.0)
+	o.nodeM = maths.NewMatrix4()
+	o.queuedDraw = o.drawQueued
 
 	o.textureAtlas = textureAtlas
+	o.color = [4]float32{1.0, 1.0, 1.0, 1.0}
+	o.SetSize(64.0, 64.0)
 	return o
 }
 
-func (t *TextureRender) Build(name string) {
-	gl.GenVertexArrays(1, &t.vao)
+// NewTargetTextureRender creates a render drawing the first color texture
+// of a RenderTarget, stretched over the quad. Build's name is ignored.
+func NewTargetTextureRender(target *RenderTarget) *TextureRender {
+	o := NewTextureRender(nil)
+	o.target = target
+	return o
+}
 
-	gl.GenBuffers(1, &t.vbo)
+// targetCoords cover the whole texture. A framebuffer's origin is lower
+// left, like st coordinates, so it isn't flipped.
+var targetCoords = []*textures.TextureCoord{
+	{S: 0.0, T: 0.0}, {S: 1.0, T: 0.0}, {S: 1.0, T: 1.0}, {S: 0.0, T: 1.0},
+}
+
+func (t *TextureRender) textureCoords(name string) []*textures.TextureCoord {
+	if t.target != nil {
+		return targetCoords
+	}
+	return t.textureAtlas.TextureCoords(name)
+}
 
-	// Activate VBO buffer while in the VAOs scope
-	gl.BindVertexArray(t.vao)
+// texture returns the texture drawn, a target's can't be cached as it
+// may be rebuilt.
+func (t *TextureRender) texture() uint32 {
+	if t.target != nil {
+		return t.target.Texture(0)
+	}
+	return t.tbo
+}
 
+func (t *TextureRender) Build(name string) {
 	t.shaderProgram = t.initShaderProgram()
+	if err := PositionTextureLayout.Validate(t.shaderProgram); err != nil {
+		panic(err)
+	}
+
+	coords := t.textureCoords(name)
+	if coords == nil {
+		panic("Sub texture not found")
+	}
+	t.shape = name
+
+	// The texture coords change with the shape
+	t.mesh = NewMesh(PositionTextureLayout, Triangles, DynamicDraw)
+
+	quad := []float32{}
+	quad = append(quad, -0.5, -0.5, 0.0)          // xy = aPos
+	quad = append(quad, coords[0].S, coords[0].T) // uv = aTexCoord
+	quad = append(quad, 0.5, -0.5, 0.0)
+	quad = append(quad, coords[1].S, coords[1].T) // uv
+	quad = append(quad, 0.5, 0.5, 0.0)
+	quad = append(quad, coords[2].S, coords[2].T) // uv
+	quad = append(quad, -0.5, 0.5, 0.0)
+	quad = append(quad, coords[3].S, coords[3].T) // uv
+	t.mesh.SetVertices(quad)
 
 	// Indices defined in CCW order
-	t.indices = []uint32{
+	t.mesh.SetIndices([]uint32{
 		// 0, 1, 3, // first triangle
 		// 1, 2, 3, // second triangle
 		// OR
 		0, 1, 2, // first triangle
 		0, 2, 3, // second triangle
-	}
-
-	coords := t.textureAtlas.TextureCoords(name)
-	if coords == nil {
-		panic("Sub texture not found")
-	}
+	})
 
-	t.quad = append(t.quad, -0.5, -0.5, 0.0)          // xy = aPos
-	t.quad = append(t.quad, coords[0].S, coords[0].T) // uv = aTexCoord
-	t.quad = append(t.quad, 0.5, -0.5, 0.0)
-	t.quad = append(t.quad, coords[1].S, coords[1].T) // uv
-	t.quad = append(t.quad, 0.5, 0.5, 0.0)
-	t.quad = append(t.quad, coords[2].S, coords[2].T) // uv
-	t.quad = append(t.quad, -0.5, 0.5, 0.0)
-	t.quad = append(t.quad, coords[3].S, coords[3].T) // uv
+	t.mesh.Build()
 
-	t.bindTextureVbo()
-
-	// Activate EBO buffer while in the VAOs scope
-	gl.GenBuffers(1, &t.ebo)
-
-	gl.BindBuffer(gl.ELEMENT_ARRAY_BUFFER, t.ebo)
-	sizeOfUInt32 := 4
-	gl.BufferData(gl.ELEMENT_ARRAY_BUFFER, sizeOfUInt32*len(t.indices), gl.Ptr(t.indices), gl.STATIC_DRAW)
-
-	if errNum := gl.GetError(); errNum != gl.NO_ERROR {
-		log.Fatal("(ebo)GL Error: ", errNum)
+	if t.target != nil {
+		return
 	}
 
-	gl.GenTextures(1, &t.tbo)
+	t.tbo = glstate.GenTexture()
 
 	t.bindTbo(t.textureAtlas.Atlas())
+	t.atlasVersion = t.textureAtlas.Version()
+}
 
-	gl.BindVertexArray(0) // close scope
-	// --------- Scope capturing ENDs here -------------------
+// UseDistanceField draws the sub textures as silhouettes with the SDF
+// shader, see cmd/sdfgen for baking an atlas. It must be called before
+// Build to select the shader, later calls only change the parameters.
+func (t *TextureRender) UseDistanceField(df DistanceField) {
+	t.distanceField = &df
+}
+
+// SetColor sets the fill color of a distance field silhouette.
+func (t *TextureRender) SetColor(r, g, b, a float32) {
+	t.color = [4]float32{r, g, b, a}
 }
 
 func (t *TextureRender) SetPosition(x, y float32) {
+	t.x, t.y = x, y
 	t.modelM.SetTranslate3Comp(x, y, 0.0)
-	t.modelM.ScaleByComp(64.0, 64.0, 1.0)
+	t.modelM.ScaleByComp(t.width, t.height, 1.0)
+}
+
+// SetSize sets the size of the quad in world units, 64 x 64 by default.
+func (t *TextureRender) SetSize(width, height float32) {
+	t.width, t.height = width, height
+	t.SetPosition(t.x, t.y)
 }
 
 func (t *TextureRender) Draw() {
-	gl.UseProgram(t.shaderProgram)
+	t.draw(t.modelM)
+}
 
-	gl.UniformMatrix4fv(t.modelLoc, 1, false, &t.modelM.Matrix()[0])
+// DrawWith draws the texture with a scene node's world matrix instead of
+// the position.
+func (t *TextureRender) DrawWith(world api.IMatrix4) {
+	t.nodeM.Set(world)
+	t.nodeM.ScaleByComp(t.width, t.height, 1.0)
+	t.draw(t.nodeM)
+}
 
-	gl.BindVertexArray(t.vao)
+// Submit queues the texture at its position. Textures have alpha so they
+// are translucent.
+func (t *TextureRender) Submit(q *RenderQueue, layer uint8, depth float32) {
+	t.submit(q, t.modelM, layer, depth)
+}
 
-	gl.ActiveTexture(gl.TEXTURE0)
+// SubmitWith queues the texture with a scene node's world matrix.
+func (t *TextureRender) SubmitWith(q *RenderQueue, world api.IMatrix4, layer uint8, depth float32) {
+	t.nodeM.Set(world)
+	t.nodeM.ScaleByComp(t.width, t.height, 1.0)
+	t.submit(q, t.nodeM, layer, depth)
+}
 
-	gl.BindTexture(gl.TEXTURE_2D, t.tbo)
-	gl.DrawElements(gl.TRIANGLES, int32(len(t.indices)), gl.UNSIGNED_INT, gl.PtrOffset(0))
+func (t *TextureRender) submit(q *RenderQueue, model api.IMatrix4, layer uint8, depth float32) {
+	t.refreshAtlas()
+
+	q.Submit(DrawCommand{
+		Name:        "TextureRender",
+		Layer:       layer,
+		Translucent: true,
+		Depth:       depth,
+		Program:     t.shaderProgram,
+		Texture:     t.texture(),
+		Model:       *model.Matrix(),
+		Draw:        t.queuedDraw,
+	})
+}
 
-	gl.BindVertexArray(0)
+func (t *TextureRender) draw(model api.IMatrix4) {
+	t.refreshAtlas()
+
+	glstate.UseProgram(t.shaderProgram)
+
+	glstate.ActiveTexture(gl.TEXTURE0)
+	glstate.BindTexture(gl.TEXTURE_2D, t.texture())
+
+	t.drawQueued(model.Matrix())
+}
+
+// drawQueued draws with the program and texture already bound.
+func (t *TextureRender) drawQueued(model *[16]float32) {
+	gl.UniformMatrix4fv(t.modelLoc, 1, false, &model[0])
+	gldebug.Check()
+
+	if t.distanceField != nil {
+		gl.Uniform4fv(t.colorLoc, 1, &t.color[0])
+		gldebug.Check()
+		width, height := t.sourceSize()
+		t.dfUniforms.apply(t.distanceField, width, height)
+	}
+
+	t.mesh.Draw()
+}
+
+func (t *TextureRender) sourceSize() (width, height int) {
+	if t.target != nil {
+		return t.target.Size()
+	}
+	return t.textureAtlas.Size()
+}
+
+// Dynamic atlases (e.g. a GlyphCache) can change after Build.
+func (t *TextureRender) refreshAtlas() {
+	if t.target != nil {
+		return
+	}
+	if t.textureAtlas.Version() != t.atlasVersion {
+		// Looking the shape up again may change the atlas, e.g. a glyph
+		// cache rasterizing an evicted glyph, so it's uploaded after
+		t.ChangeShape(t.shape)
+		t.bindTbo(t.textureAtlas.Atlas())
+		t.atlasVersion = t.textureAtlas.Version()
+	}
 }
 
 func (t *TextureRender) ChangeShape(name string) {
-	coords := t.textureAtlas.TextureCoords(name)
+	coords := t.textureCoords(name)
 	if coords == nil {
 		panic("Sub texture not found")
 	}
+	t.shape = name
+
+	quad := t.mesh.Vertices()
+	stride := PositionTextureLayout.FloatsPerVertex()
+	st := int(PositionTextureLayout.Attribute("aTexCoord").Offset() / 4)
+	for i, c := range coords[:4] {
+		quad[i*stride+st] = c.S
+		quad[i*stride+st+1] = c.T
+	}
 
-	i := 3
-	t.quad[i] = coords[0].S
-	t.quad[i+1] = coords[0].T
-	i += 3 + 2
-	t.quad[i] = coords[1].S
-	t.quad[i+1] = coords[1].T
-	i += 3 + 2
-	t.quad[i] = coords[2].S
-	t.quad[i+1] = coords[2].T
-	i += 3 + 2
-	t.quad[i] = coords[3].S
-	t.quad[i+1] = coords[3].T
-
-	t.updateTextureVbo()
+	t.mesh.UpdateVertices(0, quad)
 }
 
 func (t *TextureRender) SetUniforms(proj *display.Projection, view api.IMatrix4) {
+	glstate.UseProgram(t.shaderProgram)
+
 	pm := proj.Matrix().Matrix()
 	gl.UniformMatrix4fv(t.projLoc, 1, false, &pm[0])
+	gldebug.Check()
 
 	gl.UniformMatrix4fv(t.viewLoc, 1, false, &view.Matrix()[0])
-}
-
-// Update moves any modified data to the buffer.
-func (t *TextureRender) updateTextureVbo() {
-	gl.BindBuffer(gl.ARRAY_BUFFER, t.vbo)
-	gl.BufferSubData(gl.ARRAY_BUFFER, 0, len(t.quad)*4, gl.Ptr(t.quad))
-	gl.BindBuffer(gl.ARRAY_BUFFER, 0)
+	gldebug.Check()
 }
 
 func (t *TextureRender) initShaderProgram() uint32 {
@@ -154,74 +280,70 @@ func (t *TextureRender) initShaderProgram() uint32 {
 		panic(err)
 	}
 
-	fragmentShader, err := compileShader(fragmentTextureShaderSource, gl.FRAGMENT_SHADER)
+	fragmentSource := fragmentTextureShaderSource
+	if t.distanceField != nil {
+		fragmentSource = fragmentSDFShaderSource
+	}
+
+	fragmentShader, err := compileShader(fragmentSource, gl.FRAGMENT_SHADER)
 	if err != nil {
 		panic(err)
 	}
 
-	prog := gl.CreateProgram()
-	gl.AttachShader(prog, vertexShader)
-	gl.AttachShader(prog, fragmentShader)
-	gl.LinkProgram(prog)
+	prog, err := linkProgram(vertexShader, fragmentShader)
+	if err != nil {
+		panic(err)
+	}
 
-	gl.UseProgram(prog)
+	glstate.UseProgram(prog)
 
 	t.projLoc = gl.GetUniformLocation(prog, gl.Str("projection\x00"))
+	gldebug.Check()
 	if t.projLoc < 0 {
 		panic("TextureRender: couldn't find 'projection' uniform variable")
 	}
 
 	t.viewLoc = gl.GetUniformLocation(prog, gl.Str("view\x00"))
+	gldebug.Check()
 	if t.viewLoc < 0 {
 		panic("TextureRender: couldn't find 'view' uniform variable")
 	}
 
 	t.modelLoc = gl.GetUniformLocation(prog, gl.Str("model\x00"))
+	gldebug.Check()
 	if t.modelLoc < 0 {
 		panic("TextureRender: couldn't find 'model' uniform variable")
 	}
 
-	return prog
-}
-
-func (t *TextureRender) bindTextureVbo() {
-	gl.BindBuffer(gl.ARRAY_BUFFER, t.vbo)
-	gl.BufferData(gl.ARRAY_BUFFER, 4*len(t.quad), gl.Ptr(t.quad), gl.DYNAMIC_DRAW)
-
-	sizeOfFloat := int32(4)
-
-	// If the data per-vertex is (x,y,z,s,t = 5) then
-	// Stride = 5 * size of float
-	// OR
-	// If the data per-vertex is (x,y,z,r,g,b,s,t = 8) then
-	// Stride = 8 * size of float
-
-	// Our data layout is x,y,z,s,t
-	stride := 5 * sizeOfFloat
-
-	// position attribute
-	size := int32(3)   // x,y,z
-	offset := int32(0) // position is first thus this attrib is offset by 0
-	attribIndex := uint32(0)
-	gl.VertexAttribPointer(attribIndex, size, gl.FLOAT, false, stride, gl.PtrOffset(int(offset)))
-	gl.EnableVertexAttribArray(0)
+	if t.distanceField != nil {
+		t.colorLoc = gl.GetUniformLocation(prog, gl.Str("color\x00"))
+		gldebug.Check()
+		if t.colorLoc < 0 {
+			panic("TextureRender: couldn't find 'color' uniform variable")
+		}
+		t.dfUniforms.locate(prog, "TextureRender")
+	}
 
-	// texture coord attribute is offset by 3 (i.e. x,y,z)
-	size = int32(2)   // s,t
-	offset = int32(3) // the preceeding component size = 3, thus this attrib is offset by 3
-	attribIndex = uint32(1)
-	gl.VertexAttribPointer(attribIndex, size, gl.FLOAT, false, stride, gl.PtrOffset(int(offset*sizeOfFloat)))
-	gl.EnableVertexAttribArray(1)
+	return prog
 }
 
 func (t *TextureRender) bindTbo(texture *image.NRGBA) {
-	gl.ActiveTexture(gl.TEXTURE0)
-	gl.BindTexture(gl.TEXTURE_2D, t.tbo)
+	glstate.ActiveTexture(gl.TEXTURE0)
+	glstate.BindTexture(gl.TEXTURE_2D, t.tbo)
 
 	gl.PixelStorei(gl.UNPACK_ALIGNMENT, 1)
+	gldebug.Check()
 
-	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, gl.NEAREST)
-	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MAG_FILTER, gl.NEAREST)
+	// A distance field is interpolated, nearest filtering turns its
+	// smooth edges back into stairs
+	filter := int32(gl.NEAREST)
+	if t.distanceField != nil {
+		filter = gl.LINEAR
+	}
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, filter)
+	gldebug.Check()
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MAG_FILTER, filter)
+	gldebug.Check()
 
 	width := int32(texture.Bounds().Dx())
 	height := int32(texture.Bounds().Dy())
@@ -231,5 +353,6 @@ func (t *TextureRender) bindTbo(texture *image.NRGBA) {
 
 	// Give the image to OpenGL
 	gl.TexImage2D(gl.TEXTURE_2D, 0, gl.RGBA, width, height, 0, gl.RGBA, gl.UNSIGNED_BYTE, gl.Ptr(texture.Pix))
+	gldebug.Check()
 	// gl.GenerateMipmap(gl.TEXTURE_2D)
 }
@@ -0,0 +1,456 @@
+package render
+
+import (
+	"SimpleOpenGL-Go/SeparateTexturesWithProjection/api"
+	"SimpleOpenGL-Go/SeparateTexturesWithProjection/display"
+	"SimpleOpenGL-Go/SeparateTexturesWithProjection/gldebug"
+	"SimpleOpenGL-Go/SeparateTexturesWithProjection/glstate"
+	"SimpleOpenGL-Go/SeparateTexturesWithProjection/maths"
+	"SimpleOpenGL-Go/SeparateTexturesWithProjection/textures"
+	"SimpleOpenGL-Go/SeparateTexturesWithProjection/tiled"
+
+	"github.com/go-gl/gl/v4.5-core/gl"
+)
+
+// TilemapRenderer draws the tile layers of an orthogonal Tiled map. Each
+// layer is cut into square chunks and each chunk is one static mesh per
+// tileset it uses, built once from the tilesets' atlas coords. Chunks
+// outside the visible bounds aren't drawn.
+//
+// Within a chunk the tiles of the first tileset are drawn first, so tiles
+// of different tilesets overlapping there may not be in Tiled's order.
+// Object layers aren't drawn.
+type TilemapRenderer struct {
+	tilemap   *tiled.Map
+	chunkSize int
+
+	shaderProgram uint32
+	tbos          []uint32
+
+	projLoc, viewLoc, modelLoc, colorLoc int32
+
+	// The map's upper left corner
+	x, y   float32
+	modelM api.IMatrix4
+
+	layers []*tileLayer
+
+	culling                bool
+	minX, minY, maxX, maxY float32
+
+	// Chunks drawn and culled by the last Draw
+	drawn, culled int
+}
+
+type tileLayer struct {
+	layer   *tiled.Layer
+	visible bool
+	chunks  []*tileChunk
+}
+
+// tileChunk is a chunk's tiles of one tileset.
+type tileChunk struct {
+	tileset int
+	mesh    *Mesh
+	// Bounds relative to the map's corner, tiles may stick out of the grid
+	minX, minY, maxX, maxY float32
+}
+
+// NewTilemapRenderer creates a renderer for a built map. Chunks are 16 x
+// 16 tiles by default.
+func NewTilemapRenderer(tilemap *tiled.Map) *TilemapRenderer {
+	o := new(TilemapRenderer)
+	o.tilemap = tilemap
+	o.chunkSize = 16
+	o.modelM = maths.NewMatrix4()
+	return o
+}
+
+// SetChunkSize sets the chunks' size in tiles, it must be called before
+// Build. Smaller chunks cull closer to the view but cost more draws.
+func (t *TilemapRenderer) SetChunkSize(tiles int) {
+	if tiles < 1 {
+		tiles = 1
+	}
+	t.chunkSize = tiles
+}
+
+// Build uploads the tilesets and builds the chunks' meshes.
+func (t *TilemapRenderer) Build() {
+	m := t.tilemap
+	if m.Orientation != tiled.Orthogonal {
+		panic("TilemapRenderer: only orthogonal maps are supported, not " + m.Orientation)
+	}
+
+	t.shaderProgram = t.initShaderProgram()
+	if err := PositionTextureLayout.Validate(t.shaderProgram); err != nil {
+		panic(err)
+	}
+
+	// Each tile's coords by tileset and id
+	coords := make([][][]*textures.TextureCoord, len(m.Tilesets))
+	indexOf := map[*tiled.Tileset]int{}
+	for i, ts := range m.Tilesets {
+		indexOf[ts] = i
+		t.tbos = append(t.tbos, t.uploadTileset(ts))
+
+		coords[i] = make([][]*textures.TextureCoord, ts.TileCount)
+		for id := range coords[i] {
+			coords[i][id] = ts.Atlas().TextureCoords(tiled.TileName(id))
+		}
+	}
+
+	b := tileChunkBuilder{tilemap: m, coords: coords, indexOf: indexOf}
+	for _, l := range m.Layers {
+		if l.Kind != tiled.TileLayer {
+			continue
+		}
+
+		tl := &tileLayer{layer: l, visible: l.Visible}
+		n := t.chunkSize
+		for cy := floorDiv(l.Y, n); cy*n < l.Y+l.Height; cy++ {
+			for cx := floorDiv(l.X, n); cx*n < l.X+l.Width; cx++ {
+				tl.chunks = append(tl.chunks, b.build(l, cx*n, cy*n, n)...)
+			}
+		}
+		t.layers = append(t.layers, tl)
+	}
+}
+
+// tileChunkBuilder turns a chunk of a layer into a mesh per tileset.
+type tileChunkBuilder struct {
+	tilemap *tiled.Map
+	coords  [][][]*textures.TextureCoord
+	indexOf map[*tiled.Tileset]int
+
+	// Per tileset, reused between chunks
+	vertices [][]float32
+	indices  [][]uint32
+}
+
+func (b *tileChunkBuilder) build(l *tiled.Layer, x0, y0, size int) []*tileChunk {
+	m := b.tilemap
+	if b.vertices == nil {
+		b.vertices = make([][]float32, len(m.Tilesets))
+		b.indices = make([][]uint32, len(m.Tilesets))
+	}
+	for i := range b.vertices {
+		b.vertices[i] = b.vertices[i][:0]
+		b.indices[i] = b.indices[i][:0]
+	}
+
+	chunks := make([]*tileChunk, len(m.Tilesets))
+
+	// Right-down, the order Tiled draws in by default
+	for ty := y0; ty < y0+size; ty++ {
+		for tx := x0; tx < x0+size; tx++ {
+			gid := l.Tile(tx, ty)
+			ts, id := m.Tileset(gid)
+			if ts == nil {
+				continue
+			}
+			i := b.indexOf[ts]
+			c := b.coords[i][id]
+			if c == nil {
+				continue
+			}
+
+			// Tiles taller or wider than the grid grow up and right from
+			// the cell's lower left corner. Tiled's y is down, ours is up.
+			left := float32(tx*m.TileWidth+ts.OffsetX) + l.OffsetX
+			bottom := -float32((ty+1)*m.TileHeight+ts.OffsetY) - l.OffsetY
+			right := left + float32(ts.TileWidth)
+			top := bottom + float32(ts.TileHeight)
+
+			st := flippedCoords(c, gid)
+			base := uint32(len(b.vertices[i]) / PositionTextureLayout.FloatsPerVertex())
+			b.vertices[i] = append(b.vertices[i],
+				left, bottom, 0.0, st[0].S, st[0].T,
+				right, bottom, 0.0, st[1].S, st[1].T,
+				right, top, 0.0, st[2].S, st[2].T,
+				left, top, 0.0, st[3].S, st[3].T,
+			)
+			b.indices[i] = append(b.indices[i], base, base+1, base+2, base, base+2, base+3)
+
+			chunk := chunks[i]
+			if chunk == nil {
+				chunk = &tileChunk{tileset: i, minX: left, minY: bottom, maxX: right, maxY: top}
+				chunks[i] = chunk
+			}
+			chunk.minX = minf(chunk.minX, left)
+			chunk.minY = minf(chunk.minY, bottom)
+			chunk.maxX = maxf(chunk.maxX, right)
+			chunk.maxY = maxf(chunk.maxY, top)
+		}
+	}
+
+	built := []*tileChunk{}
+	for i, chunk := range chunks {
+		if chunk == nil {
+			continue
+		}
+
+		// The builder's slices are reused, the mesh keeps copies
+		vertices := make([]float32, len(b.vertices[i]))
+		copy(vertices, b.vertices[i])
+
+		chunk.mesh = NewMesh(PositionTextureLayout, Triangles, StaticDraw)
+		chunk.mesh.SetVertices(vertices)
+		if len(vertices)/PositionTextureLayout.FloatsPerVertex() <= 1<<16 {
+			indices := make([]uint16, len(b.indices[i]))
+			for j, index := range b.indices[i] {
+				indices[j] = uint16(index)
+			}
+			chunk.mesh.SetIndices16(indices)
+		} else {
+			indices := make([]uint32, len(b.indices[i]))
+			copy(indices, b.indices[i])
+			chunk.mesh.SetIndices(indices)
+		}
+		chunk.mesh.Build()
+
+		built = append(built, chunk)
+	}
+	return built
+}
+
+// flippedCoords returns the coords for the quad's lower left, lower right,
+// upper right and upper left corners with the gid's flips applied.
+func flippedCoords(c []*textures.TextureCoord, gid tiled.GID) [4]textures.TextureCoord {
+	// The corners as u,v with v down, and the atlas coords at each
+	corners := [4][2]int{{0, 1}, {1, 1}, {1, 0}, {0, 0}}
+	index := func(u, v int) int {
+		switch {
+		case u == 0 && v == 1:
+			return 0
+		case u == 1 && v == 1:
+			return 1
+		case u == 1 && v == 0:
+			return 2
+		}
+		return 3
+	}
+
+	// Tiled flips diagonally, then horizontally, then vertically, so a
+	// corner shows the image where the flips take it back to
+	var st [4]textures.TextureCoord
+	for i, corner := range corners {
+		u, v := corner[0], corner[1]
+		if gid.Has(tiled.FlipVertical) {
+			v = 1 - v
+		}
+		if gid.Has(tiled.FlipHorizontal) {
+			u = 1 - u
+		}
+		if gid.Has(tiled.FlipDiagonal) {
+			u, v = v, u
+		}
+		st[i] = *c[index(u, v)]
+	}
+	return st
+}
+
+// SetPosition places the map's upper left corner.
+func (t *TilemapRenderer) SetPosition(x, y float32) {
+	t.x, t.y = x, y
+	t.modelM.SetTranslate3Comp(x, y, 0.0)
+}
+
+// Size returns the map's size in world units. An infinite map's layers
+// may go beyond it.
+func (t *TilemapRenderer) Size() (width, height float32) {
+	m := t.tilemap
+	return float32(m.Width * m.TileWidth), float32(m.Height * m.TileHeight)
+}
+
+// SetVisibleBounds culls the chunks outside the world rectangle, usually
+// the camera's VisibleBounds.
+func (t *TilemapRenderer) SetVisibleBounds(minX, minY, maxX, maxY float32) {
+	t.culling = true
+	t.minX, t.minY, t.maxX, t.maxY = minX, minY, maxX, maxY
+}
+
+// ClearVisibleBounds draws every chunk.
+func (t *TilemapRenderer) ClearVisibleBounds() {
+	t.culling = false
+}
+
+// SetLayerVisible shows or hides the named tile layer.
+func (t *TilemapRenderer) SetLayerVisible(name string, visible bool) {
+	for _, tl := range t.layers {
+		if tl.layer.Name == name {
+			tl.visible = visible
+		}
+	}
+}
+
+// ChunkStats returns the chunks drawn and culled by the last Draw.
+func (t *TilemapRenderer) ChunkStats() (drawn, culled int) {
+	return t.drawn, t.culled
+}
+
+func (t *TilemapRenderer) SetUniforms(proj *display.Projection, view api.IMatrix4) {
+	glstate.UseProgram(t.shaderProgram)
+
+	pm := proj.Matrix().Matrix()
+	gl.UniformMatrix4fv(t.projLoc, 1, false, &pm[0])
+	gldebug.Check()
+
+	gl.UniformMatrix4fv(t.viewLoc, 1, false, &view.Matrix()[0])
+	gldebug.Check()
+}
+
+// Submit queues the map. Tiles have alpha so it is translucent, give it
+// the largest depth of its layer to draw it first.
+func (t *TilemapRenderer) Submit(q *RenderQueue, layer uint8, depth float32) {
+	q.Submit(DrawCommand{
+		Name:        "TilemapRenderer",
+		Layer:       layer,
+		Translucent: true,
+		Depth:       depth,
+		Custom:      t.Draw,
+	})
+}
+
+func (t *TilemapRenderer) Draw() {
+	t.drawn, t.culled = 0, 0
+
+	glstate.UseProgram(t.shaderProgram)
+	gl.UniformMatrix4fv(t.modelLoc, 1, false, &t.modelM.Matrix()[0])
+	gldebug.Check()
+
+	glstate.ActiveTexture(gl.TEXTURE0)
+
+	for _, tl := range t.layers {
+		if !tl.visible {
+			continue
+		}
+
+		color := [4]float32{1.0, 1.0, 1.0, tl.layer.Opacity}
+		gl.Uniform4fv(t.colorLoc, 1, &color[0])
+		gldebug.Check()
+
+		for _, c := range tl.chunks {
+			if t.culling && (t.x+c.maxX < t.minX || t.x+c.minX > t.maxX ||
+				t.y+c.maxY < t.minY || t.y+c.minY > t.maxY) {
+				t.culled++
+				continue
+			}
+
+			glstate.BindTexture(gl.TEXTURE_2D, t.tbos[c.tileset])
+			c.mesh.Draw()
+			t.drawn++
+		}
+	}
+}
+
+// Delete releases the meshes and textures.
+func (t *TilemapRenderer) Delete() {
+	for _, tl := range t.layers {
+		for _, c := range tl.chunks {
+			c.mesh.Delete()
+		}
+	}
+	t.layers = nil
+
+	for _, tbo := range t.tbos {
+		glstate.DeleteTexture(tbo)
+	}
+	t.tbos = nil
+	glstate.DeleteProgram(t.shaderProgram)
+}
+
+// uploadTileset creates a texture with the tileset's atlas. Filtering is
+// nearest so neighbouring tiles don't bleed into each other.
+func (t *TilemapRenderer) uploadTileset(ts *tiled.Tileset) uint32 {
+	tbo := glstate.GenTexture()
+	glstate.ActiveTexture(gl.TEXTURE0)
+	glstate.BindTexture(gl.TEXTURE_2D, tbo)
+
+	gl.PixelStorei(gl.UNPACK_ALIGNMENT, 1)
+	gldebug.Check()
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, gl.NEAREST)
+	gldebug.Check()
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MAG_FILTER, gl.NEAREST)
+	gldebug.Check()
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_S, gl.CLAMP_TO_EDGE)
+	gldebug.Check()
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_T, gl.CLAMP_TO_EDGE)
+	gldebug.Check()
+
+	img := ts.Atlas().Atlas()
+	width := int32(img.Bounds().Dx())
+	height := int32(img.Bounds().Dy())
+	gl.TexImage2D(gl.TEXTURE_2D, 0, gl.RGBA, width, height, 0, gl.RGBA, gl.UNSIGNED_BYTE, gl.Ptr(img.Pix))
+	gldebug.Check()
+	return tbo
+}
+
+func (t *TilemapRenderer) initShaderProgram() uint32 {
+	vertexShader, err := compileShader(vertexTextureShaderSourcePrj, gl.VERTEX_SHADER)
+	if err != nil {
+		panic(err)
+	}
+
+	// The text shader's color tints, here it carries the layer's opacity
+	fragmentShader, err := compileShader(fragmentTextShaderSource, gl.FRAGMENT_SHADER)
+	if err != nil {
+		panic(err)
+	}
+
+	prog, err := linkProgram(vertexShader, fragmentShader)
+	if err != nil {
+		panic(err)
+	}
+
+	glstate.UseProgram(prog)
+
+	t.projLoc = gl.GetUniformLocation(prog, gl.Str("projection\x00"))
+	gldebug.Check()
+	if t.projLoc < 0 {
+		panic("TilemapRenderer: couldn't find 'projection' uniform variable")
+	}
+
+	t.viewLoc = gl.GetUniformLocation(prog, gl.Str("view\x00"))
+	gldebug.Check()
+	if t.viewLoc < 0 {
+		panic("TilemapRenderer: couldn't find 'view' uniform variable")
+	}
+
+	t.modelLoc = gl.GetUniformLocation(prog, gl.Str("model\x00"))
+	gldebug.Check()
+	if t.modelLoc < 0 {
+		panic("TilemapRenderer: couldn't find 'model' uniform variable")
+	}
+
+	t.colorLoc = gl.GetUniformLocation(prog, gl.Str("color\x00"))
+	gldebug.Check()
+	if t.colorLoc < 0 {
+		panic("TilemapRenderer: couldn't find 'color' uniform variable")
+	}
+
+	return prog
+}
+
+// floorDiv divides rounding down, an infinite map's tiles can be negative.
+func floorDiv(a, b int) int {
+	q := a / b
+	if a%b != 0 && (a < 0) != (b < 0) {
+		q--
+	}
+	return q
+}
+
+func minf(a, b float32) float32 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxf(a, b float32) float32 {
+	if a > b {
+		return a
+	}
+	return b
+}
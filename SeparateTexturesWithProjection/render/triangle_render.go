@@ -3,69 +3,57 @@ package render
 import (
 	"SimpleOpenGL-Go/SeparateTexturesWithProjection/api"
 	"SimpleOpenGL-Go/SeparateTexturesWithProjection/display"
+	"SimpleOpenGL-Go/SeparateTexturesWithProjection/gldebug"
+	"SimpleOpenGL-Go/SeparateTexturesWithProjection/glstate"
 	"SimpleOpenGL-Go/SeparateTexturesWithProjection/maths"
-	"log"
 
 	"github.com/go-gl/gl/v4.5-core/gl"
 )
 
 type TriangleRender struct {
-	vao, tbo, vbo, ebo uint32
+	mesh *Mesh
 
 	shaderProgram uint32
 
 	projLoc, viewLoc, modelLoc int32
 
 	modelM api.IMatrix4
-
-	triangle []float32
-	indices  []uint32
+	// The model matrix when drawn by a scene node
+	nodeM api.IMatrix4
+	// drawQueued as a value, made once so submitting doesn't allocate
+	queuedDraw func(model *[16]float32)
 }
 
 func NewTriangleRender() *TriangleRender {
 	o := new(TriangleRender)
 	o.modelM = maths.NewMatrix4()
 	o.modelM.ScaleByComp(25.0, 25.0, 1.0)
+	o.nodeM = maths.NewMatrix4()
+	o.queuedDraw = o.drawQueued
 
 	return o
 }
 
 func (t *TriangleRender) Build(name string) {
-	gl.GenVertexArrays(1, &t.vao)
-
-	gl.GenBuffers(1, &t.vbo)
-
-	// Activate VBO buffer while in the VAOs scope
-	gl.BindVertexArray(t.vao)
-
 	t.shaderProgram = t.initShaderProgram()
-
-	// Indices defined in CCW order
-	t.indices = []uint32{
-		0, 1, 2, // triangle
+	if err := PositionLayout.Validate(t.shaderProgram); err != nil {
+		panic(err)
 	}
 
-	t.triangle = []float32{
+	t.mesh = NewMesh(PositionLayout, Triangles, StaticDraw)
+
+	t.mesh.SetVertices([]float32{
 		-0.2, -0.5, 0.0,
 		0.8, -0.5, 0.0,
 		0.3, 0.314, 0.0,
-	}
-
-	t.bindVbo()
-
-	// Activate EBO buffer while in the VAOs scope
-	gl.GenBuffers(1, &t.ebo)
+	})
 
-	gl.BindBuffer(gl.ELEMENT_ARRAY_BUFFER, t.ebo)
-	sizeOfUInt32 := 4
-	gl.BufferData(gl.ELEMENT_ARRAY_BUFFER, sizeOfUInt32*len(t.indices), gl.Ptr(t.indices), gl.STATIC_DRAW)
-
-	if errNum := gl.GetError(); errNum != gl.NO_ERROR {
-		log.Fatal("(ebo)GL Error: ", errNum)
-	}
+	// Indices defined in CCW order
+	t.mesh.SetIndices([]uint32{
+		0, 1, 2, // triangle
+	})
 
-	gl.BindVertexArray(0) // close scope
-	// --------- Scope capturing ENDs here -------------------
+	t.mesh.Build()
 }
 
 func (t *TriangleRender) SetAngle(radians float64) {
@@ -75,35 +63,64 @@ func (t *TriangleRender) SetAngle(radians float64) {
 }
 
 func (t *TriangleRender) Draw() {
-	gl.UseProgram(t.shaderProgram)
+	t.draw(t.modelM)
+}
+
+// DrawWith draws the triangle with a scene node's world matrix instead of
+// the orbit.
+func (t *TriangleRender) DrawWith(world api.IMatrix4) {
+	t.nodeM.Set(world)
+	t.nodeM.ScaleByComp(25.0, 25.0, 1.0)
+	t.draw(t.nodeM)
+}
+
+// Submit queues the triangle on its orbit. It's a solid color so it's
+// opaque.
+func (t *TriangleRender) Submit(q *RenderQueue, layer uint8, depth float32) {
+	t.submit(q, t.modelM, layer, depth)
+}
+
+// SubmitWith queues the triangle with a scene node's world matrix.
+func (t *TriangleRender) SubmitWith(q *RenderQueue, world api.IMatrix4, layer uint8, depth float32) {
+	t.nodeM.Set(world)
+	t.nodeM.ScaleByComp(25.0, 25.0, 1.0)
+	t.submit(q, t.nodeM, layer, depth)
+}
+
+func (t *TriangleRender) submit(q *RenderQueue, model api.IMatrix4, layer uint8, depth float32) {
+	q.Submit(DrawCommand{
+		Name:    "TriangleRender",
+		Layer:   layer,
+		Depth:   depth,
+		Program: t.shaderProgram,
+		Model:   *model.Matrix(),
+		Draw:    t.queuedDraw,
+	})
+}
 
-	gl.UniformMatrix4fv(t.modelLoc, 1, false, &t.modelM.Matrix()[0])
+func (t *TriangleRender) draw(model api.IMatrix4) {
+	glstate.UseProgram(t.shaderProgram)
 
-	gl.BindVertexArray(t.vao)
+	t.drawQueued(model.Matrix())
+}
 
-	gl.DrawElements(gl.TRIANGLES, int32(len(t.indices)), gl.UNSIGNED_INT, gl.PtrOffset(0))
+// drawQueued draws with the program already bound.
+func (t *TriangleRender) drawQueued(model *[16]float32) {
+	gl.UniformMatrix4fv(t.modelLoc, 1, false, &model[0])
+	gldebug.Check()
 
-	gl.BindVertexArray(0)
+	t.mesh.Draw()
 }
 
 func (t *TriangleRender) SetUniforms(proj *display.Projection, view api.IMatrix4) {
+	glstate.UseProgram(t.shaderProgram)
+
 	pm := proj.Matrix().Matrix()
 	gl.UniformMatrix4fv(t.projLoc, 1, false, &pm[0])
+	gldebug.Check()
 
 	gl.UniformMatrix4fv(t.viewLoc, 1, false, &view.Matrix()[0])
-}
-
-func (t *TriangleRender) bindVbo() {
-	gl.BindBuffer(gl.ARRAY_BUFFER, t.vbo)
-	gl.BufferData(gl.ARRAY_BUFFER, 4*len(t.triangle), gl.Ptr(t.triangle), gl.STATIC_DRAW)
-
-	// Specify the vertex attribute layout. This specifies--how during transmission--opengl
-	// will pass the data to the shader and how the shader will extract the data.
-	vertexInputAttrb := uint32(0)
-	sizeOfInputAttrb := int32(3)
-	stride := int32(0)
-	gl.VertexAttribPointer(vertexInputAttrb, sizeOfInputAttrb, gl.FLOAT, false, stride, nil)
-	gl.EnableVertexAttribArray(vertexInputAttrb)
+	gldebug.Check()
 }
 
 func (t *TriangleRender) initShaderProgram() uint32 {
@@ -121,24 +138,27 @@ func (t *TriangleRender) initShaderProgram() uint32 {
 		panic(err)
 	}
 
-	prog := gl.CreateProgram()
-	gl.AttachShader(prog, vertexShader)
-	gl.AttachShader(prog, fragmentShader)
-	gl.LinkProgram(prog)
+	prog, err := linkProgram(vertexShader, fragmentShader)
+	if err != nil {
+		panic(err)
+	}
 
-	gl.UseProgram(prog)
+	glstate.UseProgram(prog)
 
 	t.projLoc = gl.GetUniformLocation(prog, gl.Str("projection\x00"))
+	gldebug.Check()
 	if t.projLoc < 0 {
 		panic("TriangleRender: couldn't find 'projection' uniform variable")
 	}
 
 	t.viewLoc = gl.GetUniformLocation(prog, gl.Str("view\x00"))
+	gldebug.Check()
 	if t.viewLoc < 0 {
 		panic("TriangleRender: couldn't find 'view' uniform variable")
 	}
 
 	t.modelLoc = gl.GetUniformLocation(prog, gl.Str("model\x00"))
+	gldebug.Check()
 	if t.modelLoc < 0 {
 		panic("TriangleRender: couldn't find 'model' uniform variable")
 	}
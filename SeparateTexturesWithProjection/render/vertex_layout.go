@@ -0,0 +1,278 @@
+package render
+
+import (
+	"SimpleOpenGL-Go/SeparateTexturesWithProjection/gldebug"
+	"fmt"
+	"strings"
+
+	"github.com/go-gl/gl/v4.5-core/gl"
+)
+
+// VertexAttribute describes one attribute of an interleaved vertex.
+type VertexAttribute struct {
+	// Name must match the shader's "in" variable.
+	Name       string
+	Location   uint32
+	Components int32
+	// Type is the component type, e.g. gl.FLOAT or gl.UNSIGNED_BYTE.
+	Type       uint32
+	Normalized bool
+	// Integer attributes are passed to ivec/uvec inputs unconverted.
+	Integer bool
+	// Divisor advances the attribute once per Divisor instances instead
+	// of once per vertex. Zero is per vertex.
+	Divisor uint32
+
+	offset int32
+}
+
+// Offset returns the attribute's byte offset within a vertex.
+func (a *VertexAttribute) Offset() int32 {
+	return a.offset
+}
+
+// Size returns the attribute's size in bytes.
+func (a *VertexAttribute) Size() int32 {
+	return a.Components * typeSize(a.Type)
+}
+
+// VertexLayout describes an interleaved vertex format. Strides and
+// offsets are computed from the attributes in the order given.
+type VertexLayout struct {
+	attributes []VertexAttribute
+	stride     int32
+}
+
+var (
+	// PositionLayout is x,y,z as used by the projection shaders
+	PositionLayout = NewVertexLayout(
+		VertexAttribute{Name: "vp", Location: 0, Components: 3, Type: gl.FLOAT},
+	)
+
+	// PositionTextureLayout is x,y,z,s,t as used by the texture shaders
+	PositionTextureLayout = NewVertexLayout(
+		VertexAttribute{Name: "aPos", Location: 0, Components: 3, Type: gl.FLOAT},
+		VertexAttribute{Name: "aTexCoord", Location: 1, Components: 2, Type: gl.FLOAT},
+	)
+
+	// ShapeLayout is x,y,r,g,b,a as used by the ShapeRenderer
+	ShapeLayout = NewVertexLayout(
+		VertexAttribute{Name: "aPos", Location: 0, Components: 2, Type: gl.FLOAT},
+		VertexAttribute{Name: "aColor", Location: 1, Components: 4, Type: gl.FLOAT},
+	)
+
+	// SpriteQuadLayout is the x,y unit quad shared by instanced sprites
+	SpriteQuadLayout = NewVertexLayout(
+		VertexAttribute{Name: "aPos", Location: 0, Components: 2, Type: gl.FLOAT},
+	)
+
+	// SpriteInstanceLayout is x,y,rotation,w,h,s0,t0,s1,t1,r,g,b,a per
+	// sprite instance
+	SpriteInstanceLayout = NewVertexLayout(
+		VertexAttribute{Name: "iPosition", Location: 2, Components: 3, Type: gl.FLOAT, Divisor: 1},
+		VertexAttribute{Name: "iSize", Location: 3, Components: 2, Type: gl.FLOAT, Divisor: 1},
+		VertexAttribute{Name: "iUVRect", Location: 4, Components: 4, Type: gl.FLOAT, Divisor: 1},
+		VertexAttribute{Name: "iTint", Location: 5, Components: 4, Type: gl.FLOAT, Divisor: 1},
+	)
+
+	// SpriteBatchLayout is x,y,s,t,r,g,b,a for sprites expanded on the CPU
+	SpriteBatchLayout = NewVertexLayout(
+		VertexAttribute{Name: "aPos", Location: 0, Components: 2, Type: gl.FLOAT},
+		VertexAttribute{Name: "aTexCoord", Location: 1, Components: 2, Type: gl.FLOAT},
+		VertexAttribute{Name: "aColor", Location: 2, Components: 4, Type: gl.FLOAT},
+	)
+)
+
+// NewVertexLayout creates a layout and computes each attribute's offset.
+func NewVertexLayout(attributes ...VertexAttribute) *VertexLayout {
+	l := new(VertexLayout)
+	l.attributes = append(l.attributes, attributes...)
+
+	offset := int32(0)
+	for i := range l.attributes {
+		l.attributes[i].offset = offset
+		offset += l.attributes[i].Size()
+	}
+	l.stride = offset
+
+	return l
+}
+
+// Stride returns the size of one vertex in bytes.
+func (l *VertexLayout) Stride() int32 {
+	return l.stride
+}
+
+// Attributes returns the attributes with their offsets.
+func (l *VertexLayout) Attributes() []VertexAttribute {
+	return l.attributes
+}
+
+// Attribute returns the named attribute or nil.
+func (l *VertexLayout) Attribute(name string) *VertexAttribute {
+	for i := range l.attributes {
+		if l.attributes[i].Name == name {
+			return &l.attributes[i]
+		}
+	}
+	return nil
+}
+
+// FloatsPerVertex is Stride in float32 units, handy for sizing []float32
+// vertex data.
+func (l *VertexLayout) FloatsPerVertex() int {
+	return int(l.stride / 4)
+}
+
+// Apply configures and enables every attribute. The VAO and the VBO
+// holding the vertices must be bound.
+func (l *VertexLayout) Apply() {
+	for _, a := range l.attributes {
+		if a.Integer {
+			gl.VertexAttribIPointer(a.Location, a.Components, a.Type, l.stride, gl.PtrOffset(int(a.offset)))
+			gldebug.Check()
+		} else {
+			gl.VertexAttribPointer(a.Location, a.Components, a.Type, a.Normalized, l.stride, gl.PtrOffset(int(a.offset)))
+			gldebug.Check()
+		}
+		gl.EnableVertexAttribArray(a.Location)
+		gldebug.Check()
+		gl.VertexAttribDivisor(a.Location, a.Divisor)
+		gldebug.Check()
+	}
+}
+
+// ActiveAttribute is an attribute reported by a linked program. Matrix
+// attributes occupy one location per column.
+type ActiveAttribute struct {
+	Name       string
+	Location   int32
+	Components int32
+	Columns    int32
+}
+
+// Validate checks the layout against the program's active attributes:
+// every attribute the shader reads must be in the layout at the same
+// location with no more components than the shader input. Fewer is fine,
+// GL fills the missing ones with 0, 0, 0, 1.
+func (l *VertexLayout) Validate(program uint32) error {
+	return l.ValidateAttributes(activeAttributes(program))
+}
+
+// ValidateAttributes is Validate without the GL queries.
+func (l *VertexLayout) ValidateAttributes(active []ActiveAttribute) error {
+	return validateAttributes(l.attributes, active)
+}
+
+// ValidateLayouts is Validate for programs fed from several buffers, e.g.
+// a per-vertex and a per-instance layout.
+func ValidateLayouts(program uint32, layouts ...*VertexLayout) error {
+	attributes := []VertexAttribute{}
+	for _, l := range layouts {
+		attributes = append(attributes, l.attributes...)
+	}
+	return validateAttributes(attributes, activeAttributes(program))
+}
+
+func validateAttributes(attributes []VertexAttribute, active []ActiveAttribute) error {
+	problems := []string{}
+
+	for _, sa := range active {
+		for col := int32(0); col < sa.Columns; col++ {
+			location := sa.Location + col
+
+			var la *VertexAttribute
+			for i := range attributes {
+				if int32(attributes[i].Location) == location {
+					la = &attributes[i]
+					break
+				}
+			}
+
+			switch {
+			case la == nil:
+				problems = append(problems, fmt.Sprintf("'%s' at location %d isn't in the layout", sa.Name, location))
+			case sa.Columns == 1 && la.Name != "" && la.Name != sa.Name:
+				problems = append(problems, fmt.Sprintf("location %d is '%s' in the shader but '%s' in the layout", location, sa.Name, la.Name))
+			case la.Components > sa.Components:
+				problems = append(problems, fmt.Sprintf("'%s' has %d components in the shader but %d in the layout", sa.Name, sa.Components, la.Components))
+			}
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("vertex layout mismatch: %s", strings.Join(problems, "; "))
+	}
+
+	return nil
+}
+
+func activeAttributes(program uint32) []ActiveAttribute {
+	var count, maxLength int32
+	gl.GetProgramiv(program, gl.ACTIVE_ATTRIBUTES, &count)
+	gldebug.Check()
+	gl.GetProgramiv(program, gl.ACTIVE_ATTRIBUTE_MAX_LENGTH, &maxLength)
+	gldebug.Check()
+
+	attributes := []ActiveAttribute{}
+	for i := int32(0); i < count; i++ {
+		var length, size int32
+		var xtype uint32
+		name := strings.Repeat("\x00", int(maxLength+1))
+		gl.GetActiveAttrib(program, uint32(i), maxLength, &length, &size, &xtype, gl.Str(name))
+		gldebug.Check()
+		name = name[:length]
+
+		// Built-ins such as gl_VertexID have no location
+		if strings.HasPrefix(name, "gl_") {
+			continue
+		}
+
+		location := gl.GetAttribLocation(program, gl.Str(name+"\x00"))
+		gldebug.Check()
+
+		components, columns := typeComponents(xtype)
+		attributes = append(attributes, ActiveAttribute{
+			Name:       name,
+			Location:   location,
+			Components: components,
+			Columns:    columns,
+		})
+	}
+
+	return attributes
+}
+
+func typeSize(xtype uint32) int32 {
+	switch xtype {
+	case gl.BYTE, gl.UNSIGNED_BYTE:
+		return 1
+	case gl.SHORT, gl.UNSIGNED_SHORT, gl.HALF_FLOAT:
+		return 2
+	case gl.DOUBLE:
+		return 8
+	default: // gl.FLOAT, gl.INT, gl.UNSIGNED_INT, gl.FIXED
+		return 4
+	}
+}
+
+// typeComponents returns the component count of a GLSL attribute type
+// and, for matrices, the number of columns (locations) it occupies.
+func typeComponents(xtype uint32) (components, columns int32) {
+	switch xtype {
+	case gl.FLOAT_VEC2, gl.INT_VEC2, gl.UNSIGNED_INT_VEC2, gl.DOUBLE_VEC2:
+		return 2, 1
+	case gl.FLOAT_VEC3, gl.INT_VEC3, gl.UNSIGNED_INT_VEC3, gl.DOUBLE_VEC3:
+		return 3, 1
+	case gl.FLOAT_VEC4, gl.INT_VEC4, gl.UNSIGNED_INT_VEC4, gl.DOUBLE_VEC4:
+		return 4, 1
+	case gl.FLOAT_MAT2:
+		return 2, 2
+	case gl.FLOAT_MAT3:
+		return 3, 3
+	case gl.FLOAT_MAT4:
+		return 4, 4
+	default:
+		return 1, 1
+	}
+}
@@ -0,0 +1,102 @@
+package render
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateAttributes(t *testing.T) {
+	tests := []struct {
+		name   string
+		layout *VertexLayout
+		active []ActiveAttribute
+		// Substring of the error, empty if valid
+		err string
+	}{
+		{
+			name:   "exact",
+			layout: PositionTextureLayout,
+			active: []ActiveAttribute{
+				{Name: "aPos", Location: 0, Components: 3, Columns: 1},
+				{Name: "aTexCoord", Location: 1, Components: 2, Columns: 1},
+			},
+		},
+		{
+			name:   "fewer components than the shader",
+			layout: ShapeLayout,
+			active: []ActiveAttribute{
+				{Name: "aPos", Location: 0, Components: 4, Columns: 1},
+				{Name: "aColor", Location: 1, Components: 4, Columns: 1},
+			},
+		},
+		{
+			name:   "more components than the shader",
+			layout: PositionTextureLayout,
+			active: []ActiveAttribute{
+				{Name: "aPos", Location: 0, Components: 2, Columns: 1},
+			},
+			err: "'aPos' has 2 components in the shader but 3 in the layout",
+		},
+		{
+			name:   "unused layout attribute",
+			layout: PositionTextureLayout,
+			active: []ActiveAttribute{
+				{Name: "aPos", Location: 0, Components: 3, Columns: 1},
+			},
+		},
+		{
+			name:   "missing location",
+			layout: PositionLayout,
+			active: []ActiveAttribute{
+				{Name: "vp", Location: 0, Components: 3, Columns: 1},
+				{Name: "aNormal", Location: 1, Components: 3, Columns: 1},
+			},
+			err: "'aNormal' at location 1 isn't in the layout",
+		},
+		{
+			name:   "wrong name",
+			layout: PositionLayout,
+			active: []ActiveAttribute{
+				{Name: "aPos", Location: 0, Components: 3, Columns: 1},
+			},
+			err: "location 0 is 'aPos' in the shader but 'vp' in the layout",
+		},
+		{
+			name: "matrix columns",
+			layout: NewVertexLayout(
+				VertexAttribute{Name: "iModel0", Location: 2, Components: 4},
+				VertexAttribute{Name: "iModel1", Location: 3, Components: 4},
+				VertexAttribute{Name: "iModel2", Location: 4, Components: 4},
+				VertexAttribute{Name: "iModel3", Location: 5, Components: 4},
+			),
+			active: []ActiveAttribute{
+				{Name: "iModel", Location: 2, Components: 4, Columns: 4},
+			},
+		},
+		{
+			name: "matrix column missing",
+			layout: NewVertexLayout(
+				VertexAttribute{Name: "iModel0", Location: 2, Components: 3},
+				VertexAttribute{Name: "iModel1", Location: 3, Components: 3},
+			),
+			active: []ActiveAttribute{
+				{Name: "iModel", Location: 2, Components: 3, Columns: 3},
+			},
+			err: "'iModel' at location 4 isn't in the layout",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.layout.ValidateAttributes(test.active)
+			switch {
+			case test.err == "" && err != nil:
+				t.Fatalf("unexpected error: %v", err)
+			case test.err != "" && err == nil:
+				t.Fatalf("no error, want %q", test.err)
+			case test.err != "" && !strings.Contains(err.Error(), test.err):
+				t.Fatalf("error %q, want %q", err, test.err)
+			}
+		})
+	}
+}
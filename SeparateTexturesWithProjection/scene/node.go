@@ -0,0 +1,345 @@
+package scene
+
+import (
+	"SimpleOpenGL-Go/SeparateTexturesWithProjection/api"
+	"SimpleOpenGL-Go/SeparateTexturesWithProjection/maths"
+	"SimpleOpenGL-Go/SeparateTexturesWithProjection/render"
+	"sort"
+)
+
+// Drawable is a component drawn at its node's world transform, e.g. a
+// render.TextureRender or render.TriangleRender.
+type Drawable interface {
+	DrawWith(world api.IMatrix4)
+}
+
+// Submitter is a Drawable that can also be put on a render queue.
+type Submitter interface {
+	SubmitWith(q *render.RenderQueue, world api.IMatrix4, layer uint8, depth float32)
+}
+
+// depthStep separates the depths of consecutive nodes, enough for a
+// million nodes in a layer.
+const depthStep = 1.0 / (1 << 20)
+
+// Node is an element of a scene graph. Its transform is relative to its
+// parent, so moving a ship moves the turret attached to it.
+//
+// The local matrix is pivot, scale, rotate then translate. World matrices
+// are cached and only recomputed after the node or an ancestor changes.
+type Node struct {
+	name string
+
+	parent   *Node
+	children []*Node
+	// Children need sorting by z before the next draw
+	unsorted bool
+
+	x, y           float32
+	rotation       float64
+	scaleX, scaleY float32
+	pivotX, pivotY float32
+
+	visible bool
+	z       int
+
+	local, world           api.IMatrix4
+	localDirty, worldDirty bool
+
+	drawable Drawable
+}
+
+func NewNode(name string) *Node {
+	o := new(Node)
+	o.name = name
+	o.scaleX = 1.0
+	o.scaleY = 1.0
+	o.visible = true
+	o.local = maths.NewMatrix4()
+	o.world = maths.NewMatrix4()
+	o.localDirty = true
+	o.worldDirty = true
+	return o
+}
+
+func (n *Node) Name() string {
+	return n.name
+}
+
+// --------------------------------------------------------------------------
+// Hierarchy
+// --------------------------------------------------------------------------
+
+// AddChild attaches child, detaching it from any previous parent first.
+func (n *Node) AddChild(child *Node) {
+	if child.parent != nil {
+		child.parent.RemoveChild(child)
+	}
+	child.parent = n
+	n.children = append(n.children, child)
+	n.unsorted = true
+	child.invalidate()
+}
+
+// RemoveChild detaches child, its world transform becomes its local one.
+func (n *Node) RemoveChild(child *Node) {
+	for i, c := range n.children {
+		if c == child {
+			n.children = append(n.children[:i], n.children[i+1:]...)
+			child.parent = nil
+			child.invalidate()
+			return
+		}
+	}
+}
+
+// RemoveFromParent detaches the node from its parent.
+func (n *Node) RemoveFromParent() {
+	if n.parent != nil {
+		n.parent.RemoveChild(n)
+	}
+}
+
+func (n *Node) Parent() *Node {
+	return n.parent
+}
+
+// Children returns the children in draw order.
+func (n *Node) Children() []*Node {
+	n.sortChildren()
+	return n.children
+}
+
+// Find returns the first descendant, depth first, with the name.
+func (n *Node) Find(name string) *Node {
+	for _, c := range n.children {
+		if c.name == name {
+			return c
+		}
+		if found := c.Find(name); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// --------------------------------------------------------------------------
+// Transform
+// --------------------------------------------------------------------------
+
+func (n *Node) SetPosition(x, y float32) {
+	n.x, n.y = x, y
+	n.changed()
+}
+
+func (n *Node) Position() (x, y float32) {
+	return n.x, n.y
+}
+
+// Move offsets the position.
+func (n *Node) Move(dx, dy float32) {
+	n.SetPosition(n.x+dx, n.y+dy)
+}
+
+// SetRotation sets the counter-clockwise rotation in radians.
+func (n *Node) SetRotation(radians float64) {
+	n.rotation = radians
+	n.changed()
+}
+
+func (n *Node) Rotation() float64 {
+	return n.rotation
+}
+
+// Rotate adds to the rotation.
+func (n *Node) Rotate(radians float64) {
+	n.SetRotation(n.rotation + radians)
+}
+
+func (n *Node) SetScale(sx, sy float32) {
+	n.scaleX, n.scaleY = sx, sy
+	n.changed()
+}
+
+func (n *Node) Scale() (sx, sy float32) {
+	return n.scaleX, n.scaleY
+}
+
+// SetPivot sets the local point the node rotates and scales about, it's
+// placed at the position.
+func (n *Node) SetPivot(x, y float32) {
+	n.pivotX, n.pivotY = x, y
+	n.changed()
+}
+
+func (n *Node) Pivot() (x, y float32) {
+	return n.pivotX, n.pivotY
+}
+
+// Local returns the transform relative to the parent.
+func (n *Node) Local() api.IMatrix4 {
+	if n.localDirty {
+		n.local.ToIdentity()
+		n.local.TranslateBy2Comps(n.x, n.y)
+		n.local.Rotate(n.rotation)
+		n.local.ScaleByComp(n.scaleX, n.scaleY, 1.0)
+		n.local.TranslateBy2Comps(-n.pivotX, -n.pivotY)
+		n.localDirty = false
+	}
+	return n.local
+}
+
+// World returns the transform to world space, parent world * local.
+func (n *Node) World() api.IMatrix4 {
+	if n.worldDirty {
+		if n.parent != nil {
+			n.world.Multiply(n.parent.World(), n.Local())
+		} else {
+			n.world.Set(n.Local())
+		}
+		n.worldDirty = false
+	}
+	return n.world
+}
+
+// LocalToWorld transforms a point in the node's space to world space.
+func (n *Node) LocalToWorld(x, y float32) (wx, wy float32) {
+	e := n.World().Matrix()
+	return e[maths.M00]*x + e[maths.M01]*y + e[maths.M03], e[maths.M10]*x + e[maths.M11]*y + e[maths.M13]
+}
+
+// WorldPosition returns where the node's position ends up in world space.
+func (n *Node) WorldPosition() (x, y float32) {
+	return n.LocalToWorld(n.pivotX, n.pivotY)
+}
+
+func (n *Node) changed() {
+	n.localDirty = true
+	n.invalidate()
+}
+
+// invalidate marks the world transforms of the node and its descendants.
+// A dirty node's descendants are always dirty too, so the walk stops there.
+func (n *Node) invalidate() {
+	if n.worldDirty {
+		return
+	}
+	n.worldDirty = true
+	for _, c := range n.children {
+		c.invalidate()
+	}
+}
+
+// --------------------------------------------------------------------------
+// Drawing
+// --------------------------------------------------------------------------
+
+// SetVisible hides or shows the node and its descendants.
+func (n *Node) SetVisible(visible bool) {
+	n.visible = visible
+}
+
+func (n *Node) Visible() bool {
+	return n.visible
+}
+
+// SetZ sets the draw order among siblings, higher is drawn later (on top).
+// Children with a negative z are drawn before their parent, the rest
+// after it. Siblings with the same z keep the order they were added in.
+func (n *Node) SetZ(z int) {
+	n.z = z
+	if n.parent != nil {
+		n.parent.unsorted = true
+	}
+}
+
+func (n *Node) Z() int {
+	return n.z
+}
+
+// SetDrawable attaches the component drawn at the node, nil for a plain
+// grouping node.
+func (n *Node) SetDrawable(d Drawable) {
+	n.drawable = d
+}
+
+func (n *Node) Drawable() Drawable {
+	return n.drawable
+}
+
+// Draw draws the visible subtree. The renderers' uniforms must already be
+// set for the frame.
+func (n *Node) Draw() {
+	if !n.visible {
+		return
+	}
+
+	n.sortChildren()
+
+	i := 0
+	for ; i < len(n.children) && n.children[i].z < 0; i++ {
+		n.children[i].Draw()
+	}
+
+	if n.drawable != nil {
+		n.drawable.DrawWith(n.World())
+	}
+
+	for ; i < len(n.children); i++ {
+		n.children[i].Draw()
+	}
+}
+
+// Submit queues the visible subtree on a layer. Nodes get decreasing
+// depths in draw order so translucent ones still blend back to front.
+// Drawables that can't be queued are drawn as custom commands.
+func (n *Node) Submit(q *render.RenderQueue, layer uint8) {
+	depth := float32(1.0)
+	n.submit(q, layer, &depth)
+}
+
+func (n *Node) submit(q *render.RenderQueue, layer uint8, depth *float32) {
+	if !n.visible {
+		return
+	}
+
+	n.sortChildren()
+
+	i := 0
+	for ; i < len(n.children) && n.children[i].z < 0; i++ {
+		n.children[i].submit(q, layer, depth)
+	}
+
+	if n.drawable != nil {
+		if s, ok := n.drawable.(Submitter); ok {
+			s.SubmitWith(q, n.World(), layer, *depth)
+		} else {
+			drawable := n.drawable
+			world := n.World().Clone()
+			q.Submit(render.DrawCommand{
+				Name:        "Node",
+				Layer:       layer,
+				Translucent: true,
+				Depth:       *depth,
+				Custom: func() {
+					drawable.DrawWith(world)
+				},
+			})
+		}
+		*depth -= depthStep
+	}
+
+	for ; i < len(n.children); i++ {
+		n.children[i].submit(q, layer, depth)
+	}
+}
+
+func (n *Node) sortChildren() {
+	if !n.unsorted {
+		return
+	}
+	sort.SliceStable(n.children, func(i, j int) bool {
+		return n.children[i].z < n.children[j].z
+	})
+	n.unsorted = false
+}
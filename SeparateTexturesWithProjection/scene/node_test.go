@@ -0,0 +1,336 @@
+package scene
+
+import (
+	"SimpleOpenGL-Go/SeparateTexturesWithProjection/api"
+	"SimpleOpenGL-Go/SeparateTexturesWithProjection/maths"
+	"SimpleOpenGL-Go/SeparateTexturesWithProjection/render"
+	"math"
+	"reflect"
+	"testing"
+)
+
+// fakeDrawable records the order it's drawn in and where.
+type fakeDrawable struct {
+	name  string
+	drawn *[]string
+	// The translation of the last world matrix drawn with
+	x, y float32
+}
+
+func (d *fakeDrawable) DrawWith(world api.IMatrix4) {
+	*d.drawn = append(*d.drawn, d.name)
+	e := world.Matrix()
+	d.x, d.y = e[maths.M03], e[maths.M13]
+}
+
+// fakeSubmitter is queued directly and records the depth it was given.
+type fakeSubmitter struct {
+	fakeDrawable
+	depth float32
+}
+
+func (s *fakeSubmitter) SubmitWith(q *render.RenderQueue, world api.IMatrix4, layer uint8, depth float32) {
+	s.depth = depth
+	world = world.Clone()
+	q.SubmitCustom(layer, true, depth, func() {
+		s.DrawWith(world)
+	})
+}
+
+func near(a, b float32) bool {
+	return math.Abs(float64(a-b)) < 1e-4
+}
+
+func checkPoint(t *testing.T, what string, x, y, wantX, wantY float32) {
+	t.Helper()
+	if !near(x, wantX) || !near(y, wantY) {
+		t.Errorf("%s is %g,%g, want %g,%g", what, x, y, wantX, wantY)
+	}
+}
+
+func TestNodeParentChain(t *testing.T) {
+	parent := NewNode("ship")
+	child := NewNode("turret")
+	parent.AddChild(child)
+	child.SetPosition(10, 0)
+
+	// Each step changes the parent after the child's world transform was
+	// cached, so it checks the cache is invalidated.
+	tests := []struct {
+		name   string
+		change func()
+		// The child's world position and its local 1,0 in world space
+		x, y, px, py float32
+	}{
+		{
+			name:   "identity",
+			change: func() {},
+			x:      10, y: 0, px: 11, py: 0,
+		},
+		{
+			name:   "parent moved",
+			change: func() { parent.SetPosition(100, 50) },
+			x:      110, y: 50, px: 111, py: 50,
+		},
+		{
+			name:   "parent rotated a quarter turn",
+			change: func() { parent.SetRotation(math.Pi / 2) },
+			x:      100, y: 60, px: 100, py: 61,
+		},
+		{
+			name:   "parent moved again",
+			change: func() { parent.Move(5, -5) },
+			x:      105, y: 55, px: 105, py: 56,
+		},
+		{
+			name:   "parent scaled",
+			change: func() { parent.SetScale(2, 2) },
+			x:      105, y: 65, px: 105, py: 67,
+		},
+		{
+			name:   "child rotated",
+			change: func() { child.Rotate(math.Pi / 2) },
+			x:      105, y: 65, px: 103, py: 65,
+		},
+		{
+			name:   "grandparent added",
+			change: func() { NewNode("fleet").AddChild(parent) },
+			x:      105, y: 65, px: 103, py: 65,
+		},
+		{
+			name:   "grandparent moved",
+			change: func() { parent.Parent().SetPosition(-5, -5) },
+			x:      100, y: 60, px: 98, py: 60,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.change()
+
+			x, y := child.WorldPosition()
+			checkPoint(t, "world position", x, y, test.x, test.y)
+			px, py := child.LocalToWorld(1, 0)
+			checkPoint(t, "local 1,0", px, py, test.px, test.py)
+
+			e := child.World().Matrix()
+			checkPoint(t, "world translation", e[maths.M03], e[maths.M13], test.x, test.y)
+		})
+	}
+}
+
+func TestNodePivot(t *testing.T) {
+	tests := []struct {
+		name     string
+		rotation float64
+		scale    float32
+		// Where the local origin ends up
+		x, y float32
+	}{
+		{"unrotated", 0, 1, 92, 92},
+		{"half turn", math.Pi, 1, 108, 108},
+		{"scaled", 0, 2, 84, 84},
+		{"quarter turn scaled", math.Pi / 2, 2, 116, 84},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			n := NewNode("sprite")
+			n.SetPivot(8, 8)
+			n.SetPosition(100, 100)
+			n.SetRotation(test.rotation)
+			n.SetScale(test.scale, test.scale)
+
+			// The pivot stays at the position
+			x, y := n.WorldPosition()
+			checkPoint(t, "world position", x, y, 100, 100)
+
+			ox, oy := n.LocalToWorld(0, 0)
+			checkPoint(t, "local origin", ox, oy, test.x, test.y)
+		})
+	}
+}
+
+func TestNodeRemoveChild(t *testing.T) {
+	parent := NewNode("parent")
+	parent.SetPosition(100, 0)
+	child := NewNode("child")
+	child.SetPosition(10, 0)
+	parent.AddChild(child)
+
+	x, y := child.WorldPosition()
+	checkPoint(t, "attached", x, y, 110, 0)
+
+	parent.RemoveChild(child)
+	if child.Parent() != nil || len(parent.Children()) != 0 {
+		t.Fatal("child still attached")
+	}
+	x, y = child.WorldPosition()
+	checkPoint(t, "detached", x, y, 10, 0)
+
+	// Adding to another parent detaches from the first
+	other := NewNode("other")
+	other.SetPosition(0, 20)
+	parent.AddChild(child)
+	other.AddChild(child)
+	if len(parent.Children()) != 0 || child.Parent() != other {
+		t.Fatal("child wasn't moved to the new parent")
+	}
+	x, y = child.WorldPosition()
+	checkPoint(t, "reparented", x, y, 10, 20)
+
+	child.RemoveFromParent()
+	if child.Parent() != nil || len(other.Children()) != 0 {
+		t.Fatal("RemoveFromParent left the child attached")
+	}
+
+	// Removing a node that isn't a child does nothing
+	other.AddChild(child)
+	parent.RemoveChild(child)
+	if child.Parent() != other {
+		t.Fatal("removed from the wrong parent")
+	}
+
+	if other.Find("child") != child || NewNode("root").Find("child") != nil {
+		t.Error("Find")
+	}
+}
+
+// testTree builds a root with children at assorted z. Returns the
+// drawables by name.
+func testTree(drawn *[]string) (*Node, map[string]*fakeSubmitter) {
+	drawables := map[string]*fakeSubmitter{}
+	node := func(name string, z int, x float32) *Node {
+		n := NewNode(name)
+		n.SetZ(z)
+		n.SetPosition(x, 0)
+		d := &fakeSubmitter{fakeDrawable: fakeDrawable{name: name, drawn: drawn}}
+		drawables[name] = d
+		n.SetDrawable(d)
+		return n
+	}
+
+	root := node("root", 0, 100)
+	a := node("a", 0, 1)
+	b := node("b", -1, 2)
+	c := node("c", 0, 3)
+	d := node("d", 0, 4)
+	root.AddChild(a)
+	root.AddChild(b)
+	root.AddChild(c)
+	root.AddChild(d)
+
+	// Raised after being added, still drawn after its parent
+	c.SetZ(2)
+
+	b.AddChild(node("b1", 0, 10))
+	// Negative z, drawn before b
+	b.AddChild(node("b0", -5, 20))
+
+	hidden := node("hidden", 1, 0)
+	hidden.AddChild(node("hidden child", 0, 0))
+	hidden.SetVisible(false)
+	root.AddChild(hidden)
+
+	// A grouping node without a drawable
+	group := NewNode("group")
+	group.SetZ(3)
+	group.AddChild(node("grouped", 0, 5))
+	root.AddChild(group)
+
+	return root, drawables
+}
+
+var testTreeOrder = []string{"b0", "b", "b1", "root", "a", "d", "c", "grouped"}
+
+func TestNodeDraw(t *testing.T) {
+	drawn := []string{}
+	root, drawables := testTree(&drawn)
+
+	root.Draw()
+	if !reflect.DeepEqual(drawn, testTreeOrder) {
+		t.Fatalf("drawn %v, want %v", drawn, testTreeOrder)
+	}
+
+	// Drawn at their world transforms
+	if d := drawables["b1"]; d.x != 112 || d.y != 0 {
+		t.Errorf("b1 drawn at %g,%g, want 112,0", d.x, d.y)
+	}
+
+	// Children returns the sorted order too
+	names := []string{}
+	for _, c := range root.Children() {
+		names = append(names, c.Name())
+	}
+	if want := []string{"b", "a", "d", "hidden", "c", "group"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("children %v, want %v", names, want)
+	}
+}
+
+func TestNodeSubmit(t *testing.T) {
+	drawn := []string{}
+	root, drawables := testTree(&drawn)
+
+	// A plain Drawable is queued as a custom command
+	plain := &fakeDrawable{name: "plain", drawn: &drawn}
+	last := NewNode("plain")
+	last.SetZ(10)
+	last.SetPosition(7, 0)
+	last.SetDrawable(plain)
+	root.AddChild(last)
+
+	q := render.NewRenderQueue()
+	root.Submit(q, 1)
+
+	// The world transform is captured at submission
+	last.SetPosition(-7, 0)
+
+	// Something nearer on the same layer is drawn after the whole tree
+	q.SubmitCustom(1, true, 0.0, func() { drawn = append(drawn, "front") })
+	q.Flush()
+
+	want := append(append([]string{}, testTreeOrder...), "plain", "front")
+	if !reflect.DeepEqual(drawn, want) {
+		t.Fatalf("drawn %v, want %v", drawn, want)
+	}
+
+	// Depths decrease in draw order
+	depth := float32(1.0)
+	for i, name := range testTreeOrder {
+		d := drawables[name]
+		if want := 1.0 - float32(i)*depthStep; d.depth != want {
+			t.Errorf("%s depth %g, want %g", name, d.depth, want)
+		}
+		if d.depth > depth {
+			t.Errorf("%s depth %g isn't behind the previous %g", name, d.depth, depth)
+		}
+		depth = d.depth
+	}
+
+	if plain.x != 107 {
+		t.Errorf("plain drawn at x %g, want 107", plain.x)
+	}
+}
+
+func TestNodeStableZ(t *testing.T) {
+	// Enough siblings that an unstable sort would reorder equal z
+	root := NewNode("root")
+	for i := 0; i < 50; i++ {
+		child := NewNode("child")
+		child.SetZ(i % 3)
+		child.SetPosition(float32(i), 0)
+		root.AddChild(child)
+	}
+
+	prevZ, prevX := -1, float32(-1)
+	for _, c := range root.Children() {
+		x, _ := c.Position()
+		switch {
+		case c.Z() < prevZ:
+			t.Fatalf("z %d after %d", c.Z(), prevZ)
+		case c.Z() == prevZ && x < prevX:
+			t.Fatalf("added %g before %g but drawn after it", x, prevX)
+		}
+		prevZ, prevX = c.Z(), x
+	}
+}
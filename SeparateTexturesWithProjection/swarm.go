@@ -0,0 +1,127 @@
+package main
+
+import (
+	"SimpleOpenGL-Go/SeparateTexturesWithProjection/render"
+	"log"
+	"math"
+	"math/rand"
+	"time"
+)
+
+// swarmSize is the number of mines the benchmark spawns.
+const swarmSize = 10000
+
+type mine struct {
+	x, y, vx, vy, angle, spin float32
+	tint                      render.Color
+}
+
+// swarm is a benchmark scene of mines bouncing around the window, drawn
+// by a SpriteRenderer. It logs the average frame time every few seconds
+// so the instanced and batch paths can be compared.
+type swarm struct {
+	sprites *render.SpriteRenderer
+	region  render.UVRect
+	mines   []mine
+
+	// The area the mines bounce around in
+	halfW, halfH float32
+
+	enabled bool
+
+	frames    int
+	elapsed   time.Duration
+	lastFrame time.Time
+}
+
+func newSwarm(sprites *render.SpriteRenderer, width, height float32) *swarm {
+	o := new(swarm)
+	o.halfW = width / 2.0
+	o.halfH = height / 2.0
+	o.sprites = sprites
+	o.region = sprites.Region("mine")
+
+	rnd := rand.New(rand.NewSource(1))
+	halfW, halfH := o.halfW, o.halfH
+
+	o.mines = make([]mine, swarmSize)
+	for i := range o.mines {
+		heading := rnd.Float64() * 2.0 * math.Pi
+		speed := 50.0 + rnd.Float64()*150.0
+		o.mines[i] = mine{
+			x:     (rnd.Float32()*2.0 - 1.0) * halfW,
+			y:     (rnd.Float32()*2.0 - 1.0) * halfH,
+			vx:    float32(math.Cos(heading) * speed),
+			vy:    float32(math.Sin(heading) * speed),
+			angle: rnd.Float32() * 2.0 * math.Pi,
+			spin:  (rnd.Float32()*2.0 - 1.0) * 3.0,
+			tint:  render.Color{R: 0.5 + rnd.Float32()*0.5, G: 0.5 + rnd.Float32()*0.5, B: 0.5 + rnd.Float32()*0.5, A: 1.0},
+		}
+	}
+
+	return o
+}
+
+func (s *swarm) Toggle() {
+	s.enabled = !s.enabled
+	s.frames = 0
+	s.elapsed = 0
+	s.lastFrame = time.Time{}
+	log.Printf("Swarm: %v, instanced: %v", s.enabled, s.sprites.Instanced())
+}
+
+func (s *swarm) ToggleInstancing() {
+	s.sprites.SetInstanced(!s.sprites.Instanced())
+	s.frames = 0
+	s.elapsed = 0
+	log.Printf("Swarm instanced: %v", s.sprites.Instanced())
+}
+
+// Update moves the mines by dt seconds.
+func (s *swarm) Update(dt float32) {
+	if !s.enabled {
+		return
+	}
+
+	for i := range s.mines {
+		m := &s.mines[i]
+		m.x += m.vx * dt
+		m.y += m.vy * dt
+		m.angle += m.spin * dt
+
+		if m.x < -s.halfW || m.x > s.halfW {
+			m.vx = -m.vx
+		}
+		if m.y < -s.halfH || m.y > s.halfH {
+			m.vy = -m.vy
+		}
+	}
+}
+
+func (s *swarm) Draw() {
+	if !s.enabled {
+		return
+	}
+
+	now := time.Now()
+	if !s.lastFrame.IsZero() {
+		s.frames++
+		s.elapsed += now.Sub(s.lastFrame)
+	}
+	s.lastFrame = now
+
+	s.sprites.Begin()
+	for i := range s.mines {
+		m := &s.mines[i]
+		s.sprites.Add(s.region, m.x, m.y, 16.0, 16.0, m.angle, m.tint)
+	}
+	s.sprites.End()
+
+	if s.elapsed >= 3*time.Second {
+		log.Printf("Swarm: %d sprites, instanced: %v, %.2fms/frame",
+			s.sprites.Count(), s.sprites.Instanced(),
+			float64(s.elapsed.Microseconds())/float64(s.frames)/1000.0)
+		s.frames = 0
+		s.elapsed = 0
+	}
+}
@@ -0,0 +1,327 @@
+package textures
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"image"
+	"io/fs"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// BMChar is a single glyph description from an AngelCode BMFont file.
+// X,Y locate the glyph in its page using image coordinates
+// (origin upper left).
+type BMChar struct {
+	Glyph
+	ID   rune
+	X, Y int
+}
+
+type kerningPair struct {
+	first, second rune
+}
+
+// BMFont is an AngelCode bitmap font. Both the text and XML
+// descriptor formats are supported.
+type BMFont struct {
+	fsys     fs.FS
+	manifest string
+
+	Face       string
+	Size       int
+	LineHeight int
+	Base       int
+	ScaleW     int
+	ScaleH     int
+
+	pageFiles []string
+	pages     []*image.NRGBA
+
+	chars    map[rune]*BMChar
+	kernings map[kerningPair]int
+}
+
+// NewBMFont creates a font whose descriptor (.fnt) is read from fsys.
+// Page images are resolved relative to the descriptor.
+func NewBMFont(fsys fs.FS, manifest string) *BMFont {
+	o := new(BMFont)
+	o.fsys = fsys
+	o.manifest = manifest
+	o.chars = map[rune]*BMChar{}
+	o.kernings = map[kerningPair]int{}
+	return o
+}
+
+// Build loads the descriptor and every page image.
+func (f *BMFont) Build() {
+	if err := f.Load(); err != nil {
+		panic(err)
+	}
+}
+
+// Load is the non-panicking version of Build.
+func (f *BMFont) Load() error {
+	data, err := fs.ReadFile(f.fsys, f.manifest)
+	if err != nil {
+		return err
+	}
+
+	if err := f.Parse(data); err != nil {
+		return fmt.Errorf("%s: %v", f.manifest, err)
+	}
+
+	f.pages = nil
+	for _, file := range f.pageFiles {
+		img, err := loadImage(f.fsys, path.Join(path.Dir(f.manifest), file))
+		if err != nil {
+			return err
+		}
+		f.pages = append(f.pages, img)
+	}
+
+	return nil
+}
+
+// Parse decodes a descriptor without loading any page images, which is
+// all that is needed for layout.
+func (f *BMFont) Parse(data []byte) error {
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("<")) {
+		return f.parseXML(data)
+	}
+	return f.parseText(data)
+}
+
+// Pages returns the page images (flipped for OpenGL).
+func (f *BMFont) Pages() []*image.NRGBA {
+	return f.pages
+}
+
+// Version implements Font. The pages of a bitmap font never change.
+func (f *BMFont) Version() uint64 {
+	return 0
+}
+
+// Char returns the glyph for r or nil if the font doesn't have it.
+func (f *BMFont) Char(r rune) *BMChar {
+	return f.chars[r]
+}
+
+// Glyph implements Font.
+func (f *BMFont) Glyph(r rune) *Glyph {
+	if c, ok := f.chars[r]; ok {
+		return &c.Glyph
+	}
+	return nil
+}
+
+// LineSpacing implements Font.
+func (f *BMFont) LineSpacing() int {
+	return f.LineHeight
+}
+
+// TextureCoords returns the coords of a glyph in the same form as
+// TextureAtlas.TextureCoords.
+func (f *BMFont) TextureCoords(r rune) []*TextureCoord {
+	if c, ok := f.chars[r]; ok {
+		return c.Coords
+	}
+	return nil
+}
+
+// Kerning returns the horizontal adjustment applied between two glyphs.
+func (f *BMFont) Kerning(first, second rune) int {
+	return f.kernings[kerningPair{first, second}]
+}
+
+func (f *BMFont) addChar(c *BMChar) {
+	if f.ScaleW > 0 && f.ScaleH > 0 {
+		w := float32(f.ScaleW)
+		h := float32(f.ScaleH)
+		// Pages are flipped vertically when loaded so t = 1 - v
+		s0 := float32(c.X) / w
+		s1 := float32(c.X+c.Width) / w
+		t0 := 1.0 - float32(c.Y+c.Height)/h
+		t1 := 1.0 - float32(c.Y)/h
+		c.Coords = []*TextureCoord{{S: s0, T: t0}, {S: s1, T: t0}, {S: s1, T: t1}, {S: s0, T: t1}}
+	}
+	f.chars[c.ID] = c
+}
+
+func (f *BMFont) parseText(data []byte) error {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		tag, attrs := parseTextLine(scanner.Text())
+
+		switch tag {
+		case "info":
+			f.Face = attrs["face"]
+			f.Size = atoi(attrs["size"])
+		case "common":
+			f.LineHeight = atoi(attrs["lineHeight"])
+			f.Base = atoi(attrs["base"])
+			f.ScaleW = atoi(attrs["scaleW"])
+			f.ScaleH = atoi(attrs["scaleH"])
+		case "page":
+			id := atoi(attrs["id"])
+			for len(f.pageFiles) <= id {
+				f.pageFiles = append(f.pageFiles, "")
+			}
+			f.pageFiles[id] = attrs["file"]
+		case "char":
+			f.addChar(&BMChar{
+				ID: rune(atoi(attrs["id"])),
+				X:  atoi(attrs["x"]),
+				Y:  atoi(attrs["y"]),
+				Glyph: Glyph{
+					Width:    atoi(attrs["width"]),
+					Height:   atoi(attrs["height"]),
+					XOffset:  atoi(attrs["xoffset"]),
+					YOffset:  atoi(attrs["yoffset"]),
+					XAdvance: atoi(attrs["xadvance"]),
+					Page:     atoi(attrs["page"]),
+				},
+			})
+		case "kerning":
+			first := rune(atoi(attrs["first"]))
+			second := rune(atoi(attrs["second"]))
+			f.kernings[kerningPair{first, second}] = atoi(attrs["amount"])
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	if f.LineHeight == 0 {
+		return fmt.Errorf("missing 'common' line")
+	}
+
+	return nil
+}
+
+// parseTextLine splits a line such as: page id=0 file="font.png"
+// into its tag and key/value pairs. Quoted values may contain spaces.
+func parseTextLine(line string) (tag string, attrs map[string]string) {
+	attrs = map[string]string{}
+
+	line = strings.TrimSpace(line)
+	i := strings.IndexByte(line, ' ')
+	if i < 0 {
+		return line, attrs
+	}
+	tag = line[:i]
+	rest := line[i+1:]
+
+	for {
+		rest = strings.TrimLeft(rest, " \t")
+		eq := strings.IndexByte(rest, '=')
+		if eq < 0 {
+			break
+		}
+		key := rest[:eq]
+		rest = rest[eq+1:]
+
+		var value string
+		if strings.HasPrefix(rest, "\"") {
+			end := strings.IndexByte(rest[1:], '"')
+			if end < 0 {
+				value, rest = rest[1:], ""
+			} else {
+				value, rest = rest[1:end+1], rest[end+2:]
+			}
+		} else {
+			end := strings.IndexAny(rest, " \t")
+			if end < 0 {
+				value, rest = rest, ""
+			} else {
+				value, rest = rest[:end], rest[end:]
+			}
+		}
+		attrs[key] = value
+	}
+
+	return tag, attrs
+}
+
+func atoi(s string) int {
+	v, _ := strconv.Atoi(s)
+	return v
+}
+
+type xmlFont struct {
+	Info struct {
+		Face string `xml:"face,attr"`
+		Size int    `xml:"size,attr"`
+	} `xml:"info"`
+	Common struct {
+		LineHeight int `xml:"lineHeight,attr"`
+		Base       int `xml:"base,attr"`
+		ScaleW     int `xml:"scaleW,attr"`
+		ScaleH     int `xml:"scaleH,attr"`
+	} `xml:"common"`
+	Pages []struct {
+		ID   int    `xml:"id,attr"`
+		File string `xml:"file,attr"`
+	} `xml:"pages>page"`
+	Chars []struct {
+		ID       int `xml:"id,attr"`
+		X        int `xml:"x,attr"`
+		Y        int `xml:"y,attr"`
+		Width    int `xml:"width,attr"`
+		Height   int `xml:"height,attr"`
+		XOffset  int `xml:"xoffset,attr"`
+		YOffset  int `xml:"yoffset,attr"`
+		XAdvance int `xml:"xadvance,attr"`
+		Page     int `xml:"page,attr"`
+	} `xml:"chars>char"`
+	Kernings []struct {
+		First  int `xml:"first,attr"`
+		Second int `xml:"second,attr"`
+		Amount int `xml:"amount,attr"`
+	} `xml:"kernings>kerning"`
+}
+
+func (f *BMFont) parseXML(data []byte) error {
+	var x xmlFont
+	if err := xml.Unmarshal(data, &x); err != nil {
+		return err
+	}
+
+	f.Face = x.Info.Face
+	f.Size = x.Info.Size
+	f.LineHeight = x.Common.LineHeight
+	f.Base = x.Common.Base
+	f.ScaleW = x.Common.ScaleW
+	f.ScaleH = x.Common.ScaleH
+
+	for _, p := range x.Pages {
+		for len(f.pageFiles) <= p.ID {
+			f.pageFiles = append(f.pageFiles, "")
+		}
+		f.pageFiles[p.ID] = p.File
+	}
+
+	for _, c := range x.Chars {
+		f.addChar(&BMChar{
+			ID: rune(c.ID), X: c.X, Y: c.Y,
+			Glyph: Glyph{
+				Width: c.Width, Height: c.Height,
+				XOffset: c.XOffset, YOffset: c.YOffset, XAdvance: c.XAdvance, Page: c.Page,
+			},
+		})
+	}
+
+	for _, k := range x.Kernings {
+		f.kernings[kerningPair{rune(k.First), rune(k.Second)}] = k.Amount
+	}
+
+	if f.LineHeight == 0 {
+		return fmt.Errorf("missing 'common' element")
+	}
+
+	return nil
+}
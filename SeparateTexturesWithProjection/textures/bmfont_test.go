@@ -0,0 +1,146 @@
+package textures
+
+import (
+	"testing"
+	"testing/fstest"
+)
+
+const testFontText = `info face="Test Sans" size=16 bold=0
+common lineHeight=20 base=16 scaleW=64 scaleH=64 pages=1
+page id=0 file="test.png"
+chars count=4
+char id=32 x=0 y=0 width=0 height=0 xoffset=0 yoffset=0 xadvance=5 page=0
+char id=63 x=16 y=0 width=8 height=10 xoffset=1 yoffset=2 xadvance=10 page=0
+char id=65 x=0 y=0 width=8 height=10 xoffset=1 yoffset=2 xadvance=10 page=0
+char id=66 x=8 y=16 width=8 height=10 xoffset=0 yoffset=2 xadvance=10 page=0
+kernings count=1
+kerning first=65 second=66 amount=-2
+`
+
+const testFontXML = `<?xml version="1.0"?>
+<font>
+  <info face="Test Sans" size="16" bold="0"/>
+  <common lineHeight="20" base="16" scaleW="64" scaleH="64" pages="1"/>
+  <pages>
+    <page id="0" file="test.png"/>
+  </pages>
+  <chars count="4">
+    <char id="32" x="0" y="0" width="0" height="0" xoffset="0" yoffset="0" xadvance="5" page="0"/>
+    <char id="63" x="16" y="0" width="8" height="10" xoffset="1" yoffset="2" xadvance="10" page="0"/>
+    <char id="65" x="0" y="0" width="8" height="10" xoffset="1" yoffset="2" xadvance="10" page="0"/>
+    <char id="66" x="8" y="16" width="8" height="10" xoffset="0" yoffset="2" xadvance="10" page="0"/>
+  </chars>
+  <kernings count="1">
+    <kerning first="65" second="66" amount="-2"/>
+  </kernings>
+</font>
+`
+
+// testFont returns the font of testFontText without its page.
+func testFont(t *testing.T) *BMFont {
+	t.Helper()
+	f := NewBMFont(nil, "test.fnt")
+	if err := f.Parse([]byte(testFontText)); err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+func TestBMFontParse(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"text", testFontText},
+		{"xml", testFontXML},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			f := NewBMFont(nil, "test.fnt")
+			if err := f.Parse([]byte(test.data)); err != nil {
+				t.Fatal(err)
+			}
+
+			if f.Face != "Test Sans" || f.Size != 16 {
+				t.Errorf("face %q size %d, want \"Test Sans\" 16", f.Face, f.Size)
+			}
+			if f.LineHeight != 20 || f.Base != 16 || f.ScaleW != 64 || f.ScaleH != 64 {
+				t.Errorf("common %d %d %d %d, want 20 16 64 64", f.LineHeight, f.Base, f.ScaleW, f.ScaleH)
+			}
+			if len(f.pageFiles) != 1 || f.pageFiles[0] != "test.png" {
+				t.Errorf("pages %q, want [test.png]", f.pageFiles)
+			}
+
+			c := f.Char('B')
+			if c == nil {
+				t.Fatal("no 'B'")
+			}
+			want := Glyph{Width: 8, Height: 10, XOffset: 0, YOffset: 2, XAdvance: 10}
+			if c.X != 8 || c.Y != 16 || c.Width != want.Width || c.Height != want.Height ||
+				c.XOffset != want.XOffset || c.YOffset != want.YOffset || c.XAdvance != want.XAdvance {
+				t.Errorf("'B' %+v", *c)
+			}
+
+			// Pages are flipped, the glyph's top row is at t = 1 - y/h
+			coords := []TextureCoord{{0.125, 0.59375}, {0.25, 0.59375}, {0.25, 0.75}, {0.125, 0.75}}
+			if len(c.Coords) != len(coords) {
+				t.Fatalf("%d coords, want %d", len(c.Coords), len(coords))
+			}
+			for i, tc := range coords {
+				if *c.Coords[i] != tc {
+					t.Errorf("coord %d is %v, want %v", i, *c.Coords[i], tc)
+				}
+			}
+
+			if k := f.Kerning('A', 'B'); k != -2 {
+				t.Errorf("kerning A B is %d, want -2", k)
+			}
+			if k := f.Kerning('B', 'A'); k != 0 {
+				t.Errorf("kerning B A is %d, want 0", k)
+			}
+			if f.Glyph('Z') != nil {
+				t.Error("glyph for a missing char")
+			}
+		})
+	}
+}
+
+func TestBMFontParseErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"text without common", "info face=\"x\" size=1\nchar id=65\n"},
+		{"xml without common", "<font><info face=\"x\" size=\"1\"/></font>"},
+		{"broken xml", "<font><common lineHeight=\"1\"></font>"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if err := NewBMFont(nil, "test.fnt").Parse([]byte(test.data)); err == nil {
+				t.Error("no error")
+			}
+		})
+	}
+}
+
+func TestBMFontLoad(t *testing.T) {
+	fsys := fstest.MapFS{
+		"fonts/test.fnt": {Data: []byte(testFontText)},
+		"fonts/test.png": {Data: testPNG(t, 64, 64)},
+	}
+
+	f := NewBMFont(fsys, "fonts/test.fnt")
+	if err := f.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if len(f.Pages()) != 1 || f.Pages()[0].Bounds().Dx() != 64 {
+		t.Errorf("pages not loaded relative to the descriptor")
+	}
+
+	delete(fsys, "fonts/test.png")
+	if err := NewBMFont(fsys, "fonts/test.fnt").Load(); err == nil {
+		t.Error("no error for a missing page")
+	}
+}
@@ -0,0 +1,127 @@
+package textures
+
+import (
+	"image"
+	"image/draw"
+)
+
+// NewDynamicTextureAtlas creates an empty atlas whose sub textures are
+// added at runtime rather than from a manifest.
+func NewDynamicTextureAtlas(width, height int) *TextureAtlas {
+	o := new(TextureAtlas)
+	o.subTextures = []*SubTexture{}
+	o.width = int64(width)
+	o.height = int64(height)
+	o.atlas = image.NewNRGBA(image.Rect(0, 0, width, height))
+	o.version++
+
+	return o
+}
+
+// Size returns the atlas dimensions in pixels.
+func (t *TextureAtlas) Size() (width, height int) {
+	return int(t.width), int(t.height)
+}
+
+// SetSubTexture places img at bounds (origin upper left) and assigns it
+// to name. If name already exists its coords are updated in place so
+// that anyone holding them sees the new location. A nil img only
+// updates the coords.
+func (t *TextureAtlas) SetSubTexture(name string, bounds image.Rectangle, img image.Image) {
+	ts := t.subTexture(name)
+	if ts == nil {
+		ts = NewSubTexture(name)
+		for i := 0; i < 4; i++ {
+			ts.textureCoords = append(ts.textureCoords, &TextureCoord{})
+		}
+		t.subTextures = append(t.subTextures, ts)
+	}
+
+	ts.bounds = bounds
+	t.updateCoords(ts)
+
+	if img != nil {
+		t.blit(bounds, img)
+		t.version++
+	}
+}
+
+// RemoveSubTexture forgets name. The pixels are left untouched.
+func (t *TextureAtlas) RemoveSubTexture(name string) {
+	for i, subTex := range t.subTextures {
+		if name == subTex.name {
+			t.subTextures = append(t.subTextures[:i], t.subTextures[i+1:]...)
+			return
+		}
+	}
+}
+
+// ClearImage makes every pixel transparent.
+func (t *TextureAtlas) ClearImage() {
+	for i := range t.atlas.Pix {
+		t.atlas.Pix[i] = 0
+	}
+	t.version++
+}
+
+// Resize changes the atlas dimensions. Existing blocks keep their pixel
+// position (origin upper left) and their coords are recomputed.
+func (t *TextureAtlas) Resize(width, height int) {
+	old := t.atlas
+	oldHeight := int(t.height)
+
+	t.width = int64(width)
+	t.height = int64(height)
+	t.atlas = image.NewNRGBA(image.Rect(0, 0, width, height))
+
+	// The image is stored flipped so the rows have to be shifted by the
+	// change in height.
+	if old != nil {
+		dy := height - oldHeight
+		draw.Draw(t.atlas, old.Bounds().Add(image.Pt(0, dy)), old, image.Point{}, draw.Src)
+	}
+
+	for _, subTex := range t.subTextures {
+		t.updateCoords(subTex)
+	}
+
+	t.version++
+}
+
+func (t *TextureAtlas) subTexture(name string) *SubTexture {
+	for _, subTex := range t.subTextures {
+		if name == subTex.name {
+			return subTex
+		}
+	}
+	return nil
+}
+
+func (t *TextureAtlas) updateCoords(ts *SubTexture) {
+	w := float32(t.width)
+	h := float32(t.height)
+	b := ts.bounds
+
+	s0 := float32(b.Min.X) / w
+	s1 := float32(b.Max.X) / w
+	t0 := 1.0 - float32(b.Max.Y)/h
+	t1 := 1.0 - float32(b.Min.Y)/h
+
+	// lower-left, lower-right, upper-right, upper-left
+	*ts.textureCoords[0] = TextureCoord{S: s0, T: t0}
+	*ts.textureCoords[1] = TextureCoord{S: s1, T: t0}
+	*ts.textureCoords[2] = TextureCoord{S: s1, T: t1}
+	*ts.textureCoords[3] = TextureCoord{S: s0, T: t1}
+}
+
+// blit copies img into the flipped atlas image.
+func (t *TextureAtlas) blit(bounds image.Rectangle, img image.Image) {
+	src := img.Bounds()
+	height := int(t.height)
+	for j := 0; j < bounds.Dy() && j < src.Dy(); j++ {
+		y := height - 1 - (bounds.Min.Y + j)
+		for i := 0; i < bounds.Dx() && i < src.Dx(); i++ {
+			t.atlas.Set(bounds.Min.X+i, y, img.At(src.Min.X+i, src.Min.Y+j))
+		}
+	}
+}
@@ -0,0 +1,361 @@
+package textures
+
+import (
+	"fmt"
+	"image"
+	"image/draw"
+	"io/fs"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+)
+
+const (
+	glyphCacheInitialSize = 256
+	glyphCacheMaxSize     = 2048
+	glyphPadding          = 1
+)
+
+type cachedGlyph struct {
+	glyph    Glyph
+	lastUsed uint64
+	// present is false for glyphs the face doesn't have.
+	present bool
+	// noRoom is set when the atlas was full of glyphs in use, the glyph
+	// isn't cached so it is tried again later.
+	noRoom bool
+}
+
+// GlyphCache rasterizes glyphs from a TrueType/OpenType font on demand
+// into a dynamic TextureAtlas. The atlas starts small and doubles until
+// it reaches its maximum size, after which glyphs that haven't been
+// used since the last NextFrame are evicted. Renderers drawing a layout
+// made in an earlier frame call MarkUsed to keep its glyphs.
+//
+// Each glyph is also a sub texture of the atlas named string(r), so a
+// TextureRender can draw it like any manifest sub texture. An evicted
+// glyph is rasterized again when the atlas is asked for it.
+type GlyphCache struct {
+	ttf  []byte
+	size float64
+
+	face        font.Face
+	ascent      int
+	lineSpacing int
+
+	atlas   *TextureAtlas
+	maxSize int
+
+	// Shelf packing state
+	penX, penY, shelfHeight int
+
+	glyphs map[rune]*cachedGlyph
+	frame  uint64
+}
+
+// NewGlyphCache creates a cache for the font data at the given pixel size.
+func NewGlyphCache(ttf []byte, size float64) *GlyphCache {
+	o := new(GlyphCache)
+	o.ttf = ttf
+	o.size = size
+	o.maxSize = glyphCacheMaxSize
+	o.glyphs = map[rune]*cachedGlyph{}
+	return o
+}
+
+// LoadGlyphCache reads a font file from fsys and builds a cache for it.
+func LoadGlyphCache(fsys fs.FS, name string, size float64) (*GlyphCache, error) {
+	data, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return nil, err
+	}
+
+	o := NewGlyphCache(data, size)
+	if err := o.Load(); err != nil {
+		return nil, fmt.Errorf("%s: %v", name, err)
+	}
+
+	return o, nil
+}
+
+// SetMaxSize limits how large the atlas can grow. It must be called
+// before Build.
+func (g *GlyphCache) SetMaxSize(size int) {
+	g.maxSize = size
+}
+
+// Build parses the font and creates the atlas.
+func (g *GlyphCache) Build() {
+	if err := g.Load(); err != nil {
+		panic(err)
+	}
+}
+
+// Load is the non-panicking version of Build.
+func (g *GlyphCache) Load() error {
+	f, err := opentype.Parse(g.ttf)
+	if err != nil {
+		return err
+	}
+
+	g.face, err = opentype.NewFace(f, &opentype.FaceOptions{
+		Size:    g.size,
+		DPI:     72,
+		Hinting: font.HintingFull,
+	})
+	if err != nil {
+		return err
+	}
+
+	metrics := g.face.Metrics()
+	g.ascent = metrics.Ascent.Ceil()
+	g.lineSpacing = metrics.Height.Ceil()
+
+	size := glyphCacheInitialSize
+	if size > g.maxSize {
+		size = g.maxSize
+	}
+	g.atlas = NewDynamicTextureAtlas(size, size)
+	g.atlas.SetFallback(g.TextureCoords)
+	g.resetPacking()
+
+	return nil
+}
+
+// Atlas returns the atlas the glyphs are packed into.
+func (g *GlyphCache) Atlas() *TextureAtlas {
+	return g.atlas
+}
+
+// NextFrame marks the start of a frame. Glyphs not requested since the
+// previous call become candidates for eviction.
+func (g *GlyphCache) NextFrame() {
+	g.frame++
+}
+
+// Glyph implements Font, rasterizing r if it isn't cached yet.
+func (g *GlyphCache) Glyph(r rune) *Glyph {
+	cg, ok := g.glyphs[r]
+	if !ok {
+		cg = g.rasterize(r)
+		if !cg.noRoom {
+			g.glyphs[r] = cg
+		}
+	}
+
+	if !cg.present {
+		return nil
+	}
+
+	cg.lastUsed = g.frame
+	return &cg.glyph
+}
+
+// MarkUsed marks the glyphs of text as used this frame without laying
+// it out again.
+func (g *GlyphCache) MarkUsed(text string) {
+	for _, r := range text {
+		if cg, ok := g.glyphs[r]; ok {
+			cg.lastUsed = g.frame
+		}
+	}
+}
+
+// Kerning implements Font.
+func (g *GlyphCache) Kerning(first, second rune) int {
+	return g.face.Kern(first, second).Round()
+}
+
+// LineSpacing implements Font.
+func (g *GlyphCache) LineSpacing() int {
+	return g.lineSpacing
+}
+
+// Pages implements Font. A cache always has a single page.
+func (g *GlyphCache) Pages() []*image.NRGBA {
+	return []*image.NRGBA{g.atlas.Atlas()}
+}
+
+// Version implements Font.
+func (g *GlyphCache) Version() uint64 {
+	return g.atlas.Version()
+}
+
+// TextureCoords returns the coords of the glyph named by the first rune
+// of name, rasterizing it if needed. This mirrors TextureAtlas.TextureCoords.
+func (g *GlyphCache) TextureCoords(name string) []*TextureCoord {
+	for _, r := range name {
+		if glyph := g.Glyph(r); glyph != nil {
+			return glyph.Coords
+		}
+		break
+	}
+	return nil
+}
+
+func (g *GlyphCache) rasterize(r rune) *cachedGlyph {
+	cg := new(cachedGlyph)
+
+	bounds, advance, ok := g.face.GlyphBounds(r)
+	if !ok {
+		return cg
+	}
+	cg.present = true
+	cg.lastUsed = g.frame
+
+	x0, y0 := bounds.Min.X.Floor(), bounds.Min.Y.Floor()
+	x1, y1 := bounds.Max.X.Ceil(), bounds.Max.Y.Ceil()
+
+	cg.glyph = Glyph{
+		Width:    x1 - x0,
+		Height:   y1 - y0,
+		XOffset:  x0,
+		YOffset:  g.ascent + y0,
+		XAdvance: advance.Round(),
+	}
+
+	if cg.glyph.Width <= 0 || cg.glyph.Height <= 0 {
+		// Whitespace has an advance but no pixels
+		cg.glyph.Width = 0
+		cg.glyph.Height = 0
+		return cg
+	}
+
+	img := g.glyphImage(r, x0, y0, cg.glyph.Width, cg.glyph.Height)
+	rect, ok := g.allocate(cg.glyph.Width, cg.glyph.Height, r)
+	if !ok {
+		// The glyphs in use fill the atlas, or it is too large for any
+		cg.present = false
+		cg.noRoom = true
+		return cg
+	}
+
+	g.atlas.SetSubTexture(string(r), rect, img)
+	cg.glyph.Coords = g.atlas.TextureCoords(string(r))
+
+	return cg
+}
+
+func (g *GlyphCache) glyphImage(r rune, x0, y0, width, height int) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	dr, mask, maskp, _, _ := g.face.Glyph(fixed.P(-x0, -y0), r)
+
+	// The mask is coverage only, the color comes from the shader's tint.
+	draw.DrawMask(img, dr, image.White, image.Point{}, mask, maskp, draw.Src)
+
+	return img
+}
+
+// allocate finds room for a w x h block, growing the atlas or evicting
+// glyphs when it is full. Glyphs used in the current frame are never
+// evicted, if they fill the atlas there is no room. keep is the glyph
+// being added.
+func (g *GlyphCache) allocate(w, h int, keep rune) (image.Rectangle, bool) {
+	for {
+		if rect, ok := g.pack(w, h); ok {
+			return rect, true
+		}
+
+		width, height := g.atlas.Size()
+		if height < g.maxSize || width < g.maxSize {
+			// Grow the shorter side first, the shelves stay valid either way.
+			if height <= width && height < g.maxSize {
+				g.atlas.Resize(width, height*2)
+			} else {
+				g.atlas.Resize(width*2, height)
+			}
+			continue
+		}
+
+		// First what wasn't drawn last frame, then what hasn't been drawn
+		// yet in this one
+		stale := g.frame
+		if stale > 0 {
+			stale--
+		}
+		for _, before := range []uint64{stale, g.frame} {
+			if g.evict(keep, before) {
+				if rect, ok := g.pack(w, h); ok {
+					return rect, true
+				}
+			}
+		}
+		return image.Rectangle{}, false
+	}
+}
+
+// pack places a block using a simple shelf packer.
+func (g *GlyphCache) pack(w, h int) (image.Rectangle, bool) {
+	width, height := g.atlas.Size()
+
+	if g.penX+w+glyphPadding > width {
+		g.penX = glyphPadding
+		g.penY += g.shelfHeight + glyphPadding
+		g.shelfHeight = 0
+	}
+
+	if g.penX+w+glyphPadding > width || g.penY+h+glyphPadding > height {
+		return image.Rectangle{}, false
+	}
+
+	rect := image.Rect(g.penX, g.penY, g.penX+w, g.penY+h)
+	g.penX += w + glyphPadding
+	if h > g.shelfHeight {
+		g.shelfHeight = h
+	}
+
+	return rect, true
+}
+
+func (g *GlyphCache) resetPacking() {
+	g.penX = glyphPadding
+	g.penY = glyphPadding
+	g.shelfHeight = 0
+}
+
+// evict drops every glyph last used before the given frame and repacks
+// the rest. It returns false if nothing could be evicted.
+func (g *GlyphCache) evict(keep rune, before uint64) bool {
+	evicted := false
+	for r, cg := range g.glyphs {
+		if r != keep && cg.lastUsed < before {
+			g.atlas.RemoveSubTexture(string(r))
+			delete(g.glyphs, r)
+			evicted = true
+		}
+	}
+
+	if !evicted {
+		return false
+	}
+
+	g.repack()
+	return true
+}
+
+// repack clears the atlas and places the remaining glyphs again. Their
+// coords are updated in place, layouts only need making again when a
+// glyph didn't fit and was dropped.
+func (g *GlyphCache) repack() {
+	g.atlas.ClearImage()
+	g.resetPacking()
+
+	for r, cg := range g.glyphs {
+		if !cg.present || cg.glyph.Width == 0 {
+			continue
+		}
+
+		x0 := cg.glyph.XOffset
+		y0 := cg.glyph.YOffset - g.ascent
+		rect, ok := g.pack(cg.glyph.Width, cg.glyph.Height)
+		if !ok {
+			g.atlas.RemoveSubTexture(string(r))
+			delete(g.glyphs, r)
+			continue
+		}
+
+		img := g.glyphImage(r, x0, y0, cg.glyph.Width, cg.glyph.Height)
+		g.atlas.SetSubTexture(string(r), rect, img)
+	}
+}
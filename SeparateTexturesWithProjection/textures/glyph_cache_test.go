@@ -0,0 +1,330 @@
+package textures
+
+import (
+	"image"
+	"testing"
+
+	"golang.org/x/image/font/gofont/goregular"
+)
+
+// At 24px "ABCDEFGHIJK" fill a 64x64 atlas, M needs an eviction.
+const (
+	testGlyphSize  = 24
+	testGlyphPage  = 64
+	testGlyphsFull = "ABCDEFGHIJK"
+)
+
+func testGlyphCache(t *testing.T, size float64, maxSize int) *GlyphCache {
+	t.Helper()
+	g := NewGlyphCache(goregular.TTF, size)
+	g.SetMaxSize(maxSize)
+	if err := g.Load(); err != nil {
+		t.Fatal(err)
+	}
+	return g
+}
+
+// glyphRect returns the pixels a cached glyph occupies in the atlas.
+func glyphRect(t *testing.T, g *GlyphCache, r rune) image.Rectangle {
+	t.Helper()
+	st := g.atlas.subTexture(string(r))
+	if st == nil {
+		t.Fatalf("%q isn't in the atlas", r)
+	}
+	return st.bounds
+}
+
+// checkGlyphs checks every cached glyph is packed inside the atlas
+// without overlapping another, that its coords match its block and that
+// the block holds its pixels.
+func checkGlyphs(t *testing.T, g *GlyphCache) {
+	t.Helper()
+	width, height := g.atlas.Size()
+	pageRect := image.Rect(glyphPadding, glyphPadding, width-glyphPadding+1, height-glyphPadding+1)
+
+	rects := map[rune]image.Rectangle{}
+	for r, cg := range g.glyphs {
+		if !cg.present || cg.glyph.Width == 0 {
+			continue
+		}
+		rect := glyphRect(t, g, r)
+		rects[r] = rect
+
+		if rect.Dx() != cg.glyph.Width || rect.Dy() != cg.glyph.Height {
+			t.Errorf("%q is %v in the atlas but %dx%d", r, rect, cg.glyph.Width, cg.glyph.Height)
+		}
+		if !rect.In(pageRect) {
+			t.Errorf("%q at %v is outside the %dx%d atlas", r, rect, width, height)
+		}
+
+		coords := cg.glyph.Coords
+		s0, t1 := float32(rect.Min.X)/float32(width), 1.0-float32(rect.Min.Y)/float32(height)
+		if len(coords) != 4 || coords[0].S != s0 || coords[3].T != t1 {
+			t.Errorf("%q coords %v don't match %v in %dx%d", r, coords, rect, width, height)
+		}
+
+		// The atlas image is stored flipped
+		x0, y0 := cg.glyph.XOffset, cg.glyph.YOffset-g.ascent
+		img := g.glyphImage(r, x0, y0, cg.glyph.Width, cg.glyph.Height)
+		for y := 0; y < rect.Dy(); y++ {
+			for x := 0; x < rect.Dx(); x++ {
+				got := g.atlas.Atlas().NRGBAAt(rect.Min.X+x, height-1-(rect.Min.Y+y))
+				if want := img.NRGBAAt(x, y); got != want {
+					t.Fatalf("%q pixel %d,%d is %v, want %v", r, x, y, got, want)
+				}
+			}
+		}
+	}
+
+	for a, ra := range rects {
+		for b, rb := range rects {
+			if a < b && ra.Overlaps(rb) {
+				t.Errorf("%q at %v overlaps %q at %v", a, ra, b, rb)
+			}
+		}
+	}
+}
+
+func requireGlyphs(t *testing.T, g *GlyphCache, text string) {
+	t.Helper()
+	for _, r := range text {
+		if g.Glyph(r) == nil {
+			t.Fatalf("no glyph for %q", r)
+		}
+	}
+}
+
+func checkCached(t *testing.T, g *GlyphCache, cached, evicted string) {
+	t.Helper()
+	for _, r := range cached {
+		if _, ok := g.glyphs[r]; !ok {
+			t.Errorf("%q was evicted", r)
+		}
+	}
+	for _, r := range evicted {
+		if _, ok := g.glyphs[r]; ok {
+			t.Errorf("%q wasn't evicted", r)
+		}
+		if g.atlas.subTexture(string(r)) != nil {
+			t.Errorf("%q is still in the atlas", r)
+		}
+	}
+}
+
+func TestGlyphCachePacking(t *testing.T) {
+	g := testGlyphCache(t, testGlyphSize, testGlyphPage)
+	requireGlyphs(t, g, testGlyphsFull)
+	checkGlyphs(t, g)
+
+	if width, height := g.atlas.Size(); width != testGlyphPage || height != testGlyphPage {
+		t.Errorf("atlas is %dx%d, want %dx%d", width, height, testGlyphPage, testGlyphPage)
+	}
+
+	// Whitespace advances without taking room
+	space := g.Glyph(' ')
+	if space == nil || space.Width != 0 || space.XAdvance <= 0 {
+		t.Errorf("space is %+v", space)
+	}
+
+	// A glyph the face lacks is cached as missing
+	if g.Glyph('￿') != nil {
+		t.Error("got a glyph for U+FFFF")
+	}
+	if _, ok := g.glyphs['￿']; !ok {
+		t.Error("missing glyph isn't cached")
+	}
+}
+
+func TestGlyphCacheGrowth(t *testing.T) {
+	g := testGlyphCache(t, 48, glyphCacheMaxSize)
+	version := g.Version()
+
+	requireGlyphs(t, g, "A")
+	first := g.Glyph('A').Coords
+	s0 := first[0].S
+
+	for r := '!'; r <= '~'; r++ {
+		requireGlyphs(t, g, string(r))
+	}
+
+	width, height := g.atlas.Size()
+	if width == glyphCacheInitialSize && height == glyphCacheInitialSize {
+		t.Fatalf("atlas didn't grow from %dx%d", width, height)
+	}
+	if height < width {
+		t.Errorf("atlas is %dx%d, the height should grow first", width, height)
+	}
+	if g.Version() == version {
+		t.Error("version unchanged")
+	}
+
+	// Coords handed out before the growth are updated in place
+	if g.Glyph('A').Coords[0] != first[0] {
+		t.Error("A's coords were replaced")
+	}
+	if width != glyphCacheInitialSize && first[0].S == s0 {
+		t.Error("A's coords weren't updated for the new width")
+	}
+
+	checkGlyphs(t, g)
+}
+
+func TestGlyphCacheEviction(t *testing.T) {
+	tests := []struct {
+		name string
+		// Glyphs used in frames 0, 1 and 2 after the atlas was filled
+		// in frame 0
+		frames []string
+		// Glyphs marked used in the last frame
+		marked string
+		// The glyph requested last
+		request rune
+		// Whether it fits
+		fits            bool
+		cached, evicted string
+	}{
+		{
+			name:    "stale before last frame",
+			frames:  []string{"", "CD", "A"},
+			request: 'M',
+			fits:    true,
+			cached:  "ACDM",
+			evicted: "BEFGHIJK",
+		},
+		{
+			name:    "not yet used this frame",
+			frames:  []string{"", "AB"},
+			request: 'M',
+			fits:    true,
+			cached:  "ABM",
+			evicted: "CDEFGHIJK",
+		},
+		{
+			name:    "marked used",
+			frames:  []string{"", ""},
+			marked:  "AB",
+			request: 'M',
+			fits:    true,
+			cached:  "ABM",
+			evicted: "CDEFGHIJK",
+		},
+		{
+			name:    "all in use",
+			frames:  []string{""},
+			request: 'M',
+			cached:  testGlyphsFull,
+			evicted: "M",
+		},
+		{
+			name:    "all marked used",
+			frames:  []string{"", ""},
+			marked:  testGlyphsFull,
+			request: 'M',
+			cached:  testGlyphsFull,
+			evicted: "M",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			g := testGlyphCache(t, testGlyphSize, testGlyphPage)
+			requireGlyphs(t, g, testGlyphsFull)
+
+			before := map[rune]image.Rectangle{}
+			for _, r := range testGlyphsFull {
+				before[r] = glyphRect(t, g, r)
+			}
+			version := g.Version()
+
+			for i, text := range test.frames {
+				if i > 0 {
+					g.NextFrame()
+				}
+				requireGlyphs(t, g, text)
+			}
+			g.MarkUsed(test.marked)
+
+			glyph := g.Glyph(test.request)
+			if fits := glyph != nil; fits != test.fits {
+				t.Fatalf("got a glyph %v, want %v", fits, test.fits)
+			}
+
+			checkCached(t, g, test.cached, test.evicted)
+			checkGlyphs(t, g)
+
+			if test.fits {
+				if g.Version() == version {
+					t.Error("version unchanged after the eviction")
+				}
+				return
+			}
+
+			// Nothing moved and the glyph fits once the others go stale
+			for r, rect := range before {
+				if got := glyphRect(t, g, r); got != rect {
+					t.Errorf("%q moved from %v to %v", r, rect, got)
+				}
+			}
+			g.NextFrame()
+			g.NextFrame()
+			if g.Glyph(test.request) == nil {
+				t.Errorf("no glyph for %q two frames later", test.request)
+			}
+			checkGlyphs(t, g)
+		})
+	}
+}
+
+func TestGlyphCacheAtlasFallback(t *testing.T) {
+	g := testGlyphCache(t, testGlyphSize, testGlyphPage)
+	requireGlyphs(t, g, testGlyphsFull)
+	g.NextFrame()
+	g.NextFrame()
+	requireGlyphs(t, g, "M")
+	checkCached(t, g, "M", "C")
+
+	// The atlas rasterizes an evicted glyph again when asked for it
+	coords := g.Atlas().TextureCoords("C")
+	if len(coords) != 4 {
+		t.Fatalf("got %d coords for C", len(coords))
+	}
+	checkCached(t, g, "CM", "")
+	checkGlyphs(t, g)
+}
+
+func TestGlyphCacheTooLarge(t *testing.T) {
+	g := testGlyphCache(t, testGlyphSize, 16)
+	if g.Glyph('W') != nil {
+		t.Fatal("got a glyph larger than the atlas")
+	}
+	if _, ok := g.glyphs['W']; ok {
+		t.Error("a glyph that didn't fit was cached")
+	}
+}
+
+func TestGlyphCacheLayoutAfterEviction(t *testing.T) {
+	g := testGlyphCache(t, testGlyphSize, testGlyphPage)
+	Layout(g, testGlyphsFull, LayoutOptions{})
+	version := g.Version()
+
+	g.NextFrame()
+	g.NextFrame()
+	g.MarkUsed("A")
+	requireGlyphs(t, g, "M")
+
+	// Layouts made before still point at the evicted glyphs' coords, the
+	// version change tells renderers to lay out again.
+	if g.Version() == version {
+		t.Fatal("version unchanged after the eviction")
+	}
+	relaid := Layout(g, "ABC", LayoutOptions{})
+	if len(relaid.Quads) != 3 {
+		t.Fatalf("%d quads, want 3", len(relaid.Quads))
+	}
+	for _, q := range relaid.Quads {
+		if want := g.glyphs[q.Rune].glyph.Coords; len(q.Coords) == 0 || q.Coords[0] != want[0] {
+			t.Errorf("%q quad doesn't use the cached coords", q.Rune)
+		}
+	}
+	checkGlyphs(t, g)
+}
@@ -0,0 +1,119 @@
+package textures
+
+import (
+	"image"
+	"math"
+)
+
+// sdfPoint is the offset from a pixel to the nearest seed pixel.
+type sdfPoint struct {
+	dx, dy int
+}
+
+func (p sdfPoint) distSq() int {
+	return p.dx*p.dx + p.dy*p.dy
+}
+
+const sdfFar = 1 << 14
+
+// GenerateSDF converts the silhouette of src (alpha >= 0.5) into a signed
+// distance field using the 8SSEDT algorithm. Distances are stored in the
+// alpha channel (RGB is white) where 0.5 is the edge, values above are
+// inside and spread is the distance in pixels that maps to 0 or 1.
+//
+// The result has the same bounds as src. Leave at least spread pixels of
+// padding around a shape so the field isn't clipped.
+func GenerateSDF(src image.Image, spread int) *image.NRGBA {
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+
+	inside := make([]sdfPoint, w*h)
+	outside := make([]sdfPoint, w*h)
+
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			_, _, _, a := src.At(b.Min.X+x, b.Min.Y+y).RGBA()
+			i := y*w + x
+			if a >= 0x8000 {
+				// Inside: zero distance to the shape, far from empty space
+				inside[i] = sdfPoint{sdfFar, sdfFar}
+				outside[i] = sdfPoint{0, 0}
+			} else {
+				inside[i] = sdfPoint{0, 0}
+				outside[i] = sdfPoint{sdfFar, sdfFar}
+			}
+		}
+	}
+
+	sweepSDF(inside, w, h)
+	sweepSDF(outside, w, h)
+
+	dst := image.NewNRGBA(image.Rect(0, 0, w, h))
+	scale := 0.5 / float64(spread)
+
+	for i := range inside {
+		// Positive inside the shape
+		d := math.Sqrt(float64(inside[i].distSq())) - math.Sqrt(float64(outside[i].distSq()))
+		v := 0.5 + d*scale
+		if v < 0 {
+			v = 0
+		} else if v > 1 {
+			v = 1
+		}
+
+		o := i * 4
+		dst.Pix[o+0] = 0xff
+		dst.Pix[o+1] = 0xff
+		dst.Pix[o+2] = 0xff
+		dst.Pix[o+3] = uint8(v*255.0 + 0.5)
+	}
+
+	return dst
+}
+
+// sweepSDF propagates nearest seed offsets with two raster passes.
+func sweepSDF(g []sdfPoint, w, h int) {
+	at := func(x, y int) sdfPoint {
+		if x < 0 || y < 0 || x >= w || y >= h {
+			return sdfPoint{sdfFar, sdfFar}
+		}
+		return g[y*w+x]
+	}
+
+	compare := func(p *sdfPoint, x, y, ox, oy int) {
+		other := at(x+ox, y+oy)
+		other.dx += ox
+		other.dy += oy
+		if other.distSq() < p.distSq() {
+			*p = other
+		}
+	}
+
+	// Pass 0: top to bottom
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			p := &g[y*w+x]
+			compare(p, x, y, -1, 0)
+			compare(p, x, y, 0, -1)
+			compare(p, x, y, -1, -1)
+			compare(p, x, y, 1, -1)
+		}
+		for x := w - 1; x >= 0; x-- {
+			compare(&g[y*w+x], x, y, 1, 0)
+		}
+	}
+
+	// Pass 1: bottom to top
+	for y := h - 1; y >= 0; y-- {
+		for x := w - 1; x >= 0; x-- {
+			p := &g[y*w+x]
+			compare(p, x, y, 1, 0)
+			compare(p, x, y, 0, 1)
+			compare(p, x, y, -1, 1)
+			compare(p, x, y, 1, 1)
+		}
+		for x := 0; x < w; x++ {
+			compare(&g[y*w+x], x, y, -1, 0)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package textures
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+)
+
+// testMask returns a size x size alpha mask, opaque where inside is true.
+func testMask(size int, inside func(x, y int) bool) *image.Alpha {
+	mask := image.NewAlpha(image.Rect(0, 0, size, size))
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			if inside(x, y) {
+				mask.SetAlpha(x, y, color.Alpha{A: 0xff})
+			}
+		}
+	}
+	return mask
+}
+
+// sdfValue is the alpha GenerateSDF stores for a signed distance in
+// pixels, positive inside.
+func sdfValue(d float64, spread int) float64 {
+	return math.Max(0, math.Min(1, 0.5+d*0.5/float64(spread))) * 255.0
+}
+
+func TestGenerateSDFSquare(t *testing.T) {
+	// A 16x16 square at 8,8 in a 32x32 mask, with the same spread
+	// distances are exact along the middle row.
+	const size, spread, lo, hi, row = 32, 4, 8, 24, 16
+	sdf := GenerateSDF(testMask(size, func(x, y int) bool {
+		return x >= lo && x < hi && y >= lo && y < hi
+	}), spread)
+
+	if sdf.Bounds() != image.Rect(0, 0, size, size) {
+		t.Fatalf("bounds %v", sdf.Bounds())
+	}
+
+	alpha := func(x int) int {
+		return int(sdf.NRGBAAt(x, row).A)
+	}
+
+	// Inside pixels are their distance to the nearest outside pixel
+	// away, outside pixels their distance to the nearest inside one.
+	for x := 0; x < size; x++ {
+		var d float64
+		switch {
+		case x < lo:
+			d = -float64(lo - x)
+		case x >= hi:
+			d = -float64(x - hi + 1)
+		default:
+			d = math.Min(float64(x-lo+1), float64(hi-x))
+		}
+		if want := int(sdfValue(d, spread) + 0.5); alpha(x) != want {
+			t.Errorf("x %d at distance %g is %d, want %d", x, d, alpha(x), want)
+		}
+		if c := sdf.NRGBAAt(x, row); c.R != 0xff || c.G != 0xff || c.B != 0xff {
+			t.Errorf("x %d isn't white: %v", x, c)
+		}
+	}
+
+	// The edge sits between the last outside and first inside pixel
+	if a, b := alpha(lo-1), alpha(lo); a >= 128 || b < 128 || a+b != 255 {
+		t.Errorf("edge values %d and %d don't straddle 0.5", a, b)
+	}
+
+	// Symmetric about the edge within the spread, clamped beyond it
+	for k := 1; k <= spread; k++ {
+		in, out := alpha(lo+k-1), alpha(lo-k)
+		if in+out != 255 {
+			t.Errorf("%d pixels in is %d but %d out is %d", k, in, k, out)
+		}
+		if k > 1 && (in <= alpha(lo+k-2) || out >= alpha(lo-k+1)) {
+			t.Errorf("values don't increase inward at %d pixels", k)
+		}
+	}
+	if alpha(0) != 0 || alpha(lo+spread+1) != 255 {
+		t.Errorf("values beyond the spread are %d and %d", alpha(0), alpha(lo+spread+1))
+	}
+}
+
+// bruteForceSDF is the exact signed distance from each pixel center to
+// the nearest pixel center on the other side of the edge.
+func bruteForceSDF(mask *image.Alpha) []float64 {
+	b := mask.Bounds()
+	w, h := b.Dx(), b.Dy()
+	field := make([]float64, w*h)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			inside := mask.AlphaAt(x, y).A != 0
+			nearest := math.Inf(1)
+			for v := 0; v < h; v++ {
+				for u := 0; u < w; u++ {
+					if (mask.AlphaAt(u, v).A != 0) != inside {
+						nearest = math.Min(nearest, math.Hypot(float64(u-x), float64(v-y)))
+					}
+				}
+			}
+			if !inside {
+				nearest = -nearest
+			}
+			field[y*w+x] = nearest
+		}
+	}
+	return field
+}
+
+func TestGenerateSDFDisc(t *testing.T) {
+	const size, spread = 40, 6
+	const cx, cy, radius = 20.0, 20.0, 10.0
+
+	mask := testMask(size, func(x, y int) bool {
+		return math.Hypot(float64(x)+0.5-cx, float64(y)+0.5-cy) <= radius
+	})
+	sdf := GenerateSDF(mask, spread)
+	exact := bruteForceSDF(mask)
+
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			got := int(sdf.NRGBAAt(x, y).A)
+			d := exact[y*size+x]
+
+			if (d > 0) != (got >= 128) {
+				t.Fatalf("%d,%d at distance %g is %d", x, y, d, got)
+			}
+			if want := int(sdfValue(d, spread) + 0.5); got != want {
+				t.Errorf("%d,%d at distance %g is %d, want %d", x, y, d, got, want)
+			}
+		}
+	}
+}
+
+func TestGenerateSDFBounds(t *testing.T) {
+	// A sub image keeps its offset, the field starts at 0,0
+	mask := testMask(16, func(x, y int) bool {
+		return x >= 8 && y >= 8
+	})
+	sub := mask.SubImage(image.Rect(4, 4, 16, 16))
+
+	sdf := GenerateSDF(sub, 2)
+	if sdf.Bounds() != image.Rect(0, 0, 12, 12) {
+		t.Fatalf("bounds %v", sdf.Bounds())
+	}
+	if a := sdf.NRGBAAt(0, 0).A; a != 0 {
+		t.Errorf("outside corner is %d", a)
+	}
+	if a := sdf.NRGBAAt(11, 11).A; a != 255 {
+		t.Errorf("inside corner is %d", a)
+	}
+}
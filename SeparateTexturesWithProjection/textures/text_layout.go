@@ -0,0 +1,211 @@
+package textures
+
+import (
+	"image"
+	"strings"
+)
+
+// Glyph holds the metrics of a character. Offsets are measured from the
+// pen position to the glyph's upper left corner with y facing down,
+// the same convention BMFont uses.
+type Glyph struct {
+	Width, Height int
+	XOffset       int
+	YOffset       int
+	XAdvance      int
+	Page          int
+
+	// Coords are the st coordinates in the order
+	// lower-left, lower-right, upper-right, upper-left.
+	Coords []*TextureCoord
+}
+
+// Font is anything that can supply glyphs for layout.
+type Font interface {
+	Glyph(r rune) *Glyph
+	Kerning(first, second rune) int
+	LineSpacing() int
+	Pages() []*image.NRGBA
+	// Version changes whenever the page images or glyph coords change.
+	Version() uint64
+}
+
+// Alignment controls the horizontal placement of each line.
+type Alignment int
+
+const (
+	// AlignLeft aligns lines to the left edge
+	AlignLeft Alignment = iota
+	// AlignCenter centers lines within the block
+	AlignCenter
+	// AlignRight aligns lines to the right edge
+	AlignRight
+)
+
+// LayoutOptions controls how text is laid out.
+type LayoutOptions struct {
+	Align Alignment
+	// WrapWidth is the maximum line width. Zero disables wrapping.
+	WrapWidth float32
+	// Scale multiplies the font's metrics. Zero is treated as 1.
+	Scale float32
+}
+
+// GlyphQuad is a positioned glyph. X,Y is the lower left corner in
+// y-up space where the block's upper left corner is the origin.
+type GlyphQuad struct {
+	Rune          rune
+	X, Y          float32
+	Width, Height float32
+	Page          int
+	Coords        []*TextureCoord
+}
+
+// TextLayout is the result of Layout.
+type TextLayout struct {
+	Quads  []GlyphQuad
+	Width  float32
+	Height float32
+	Lines  int
+}
+
+// Layout positions the glyphs of text. Lines are broken on '\n' and,
+// if a wrap width is given, on spaces. Words longer than the wrap width
+// are broken between characters.
+func Layout(font Font, text string, opts LayoutOptions) *TextLayout {
+	scale := opts.Scale
+	if scale == 0 {
+		scale = 1.0
+	}
+
+	lines := []string{}
+	for _, paragraph := range strings.Split(text, "\n") {
+		if opts.WrapWidth > 0 {
+			lines = append(lines, wrap(font, paragraph, opts.WrapWidth/scale)...)
+		} else {
+			lines = append(lines, paragraph)
+		}
+	}
+
+	widths := make([]float32, len(lines))
+	block := float32(0.0)
+	for i, line := range lines {
+		widths[i] = MeasureLine(font, line) * scale
+		if widths[i] > block {
+			block = widths[i]
+		}
+	}
+
+	if opts.WrapWidth > 0 {
+		block = opts.WrapWidth
+	}
+
+	layout := &TextLayout{Width: block, Lines: len(lines)}
+	lineSpacing := float32(font.LineSpacing()) * scale
+
+	for i, line := range lines {
+		penX := float32(0.0)
+		switch opts.Align {
+		case AlignCenter:
+			penX = (block - widths[i]) / 2.0
+		case AlignRight:
+			penX = block - widths[i]
+		}
+		penY := -float32(i) * lineSpacing
+
+		prev := rune(-1)
+		for _, r := range line {
+			g := glyphOrFallback(font, r)
+			if g == nil {
+				continue
+			}
+
+			if prev >= 0 {
+				penX += float32(font.Kerning(prev, r)) * scale
+			}
+
+			if g.Width > 0 && g.Height > 0 {
+				top := penY - float32(g.YOffset)*scale
+				h := float32(g.Height) * scale
+				layout.Quads = append(layout.Quads, GlyphQuad{
+					Rune:   r,
+					X:      penX + float32(g.XOffset)*scale,
+					Y:      top - h,
+					Width:  float32(g.Width) * scale,
+					Height: h,
+					Page:   g.Page,
+					Coords: g.Coords,
+				})
+			}
+
+			penX += float32(g.XAdvance) * scale
+			prev = r
+		}
+	}
+
+	layout.Height = float32(len(lines)) * lineSpacing
+
+	return layout
+}
+
+// MeasureLine returns the unscaled advance width of a single line.
+func MeasureLine(font Font, line string) float32 {
+	width := 0
+	prev := rune(-1)
+	for _, r := range line {
+		g := glyphOrFallback(font, r)
+		if g == nil {
+			continue
+		}
+		if prev >= 0 {
+			width += font.Kerning(prev, r)
+		}
+		width += g.XAdvance
+		prev = r
+	}
+	return float32(width)
+}
+
+func glyphOrFallback(font Font, r rune) *Glyph {
+	if g := font.Glyph(r); g != nil {
+		return g
+	}
+	return font.Glyph('?')
+}
+
+// wrap greedily fills lines with words up to maxWidth (in font units).
+func wrap(font Font, paragraph string, maxWidth float32) []string {
+	lines := []string{}
+	line := ""
+
+	for _, word := range strings.Split(paragraph, " ") {
+		candidate := word
+		if line != "" {
+			candidate = line + " " + word
+		}
+
+		if MeasureLine(font, candidate) <= maxWidth {
+			line = candidate
+			continue
+		}
+
+		if line != "" {
+			lines = append(lines, line)
+			line = ""
+		}
+
+		// The word doesn't fit on a line of its own so break it.
+		for MeasureLine(font, word) > maxWidth {
+			runes := []rune(word)
+			n := 1
+			for n < len(runes) && MeasureLine(font, string(runes[:n+1])) <= maxWidth {
+				n++
+			}
+			lines = append(lines, string(runes[:n]))
+			word = string(runes[n:])
+		}
+		line = word
+	}
+
+	return append(lines, line)
+}
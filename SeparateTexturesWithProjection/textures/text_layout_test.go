@@ -0,0 +1,147 @@
+package textures
+
+import (
+	"testing"
+)
+
+func TestLayout(t *testing.T) {
+	type quad struct {
+		r    rune
+		x, y float32
+	}
+
+	// A and B are 8x10 glyphs 2 below the pen advancing 10, A also 1 to
+	// the right. A B kern by -2. Lines are 20 apart.
+	tests := []struct {
+		name   string
+		text   string
+		opts   LayoutOptions
+		quads  []quad
+		width  float32
+		height float32
+		lines  int
+	}{
+		{
+			name:  "kerning",
+			text:  "AB",
+			quads: []quad{{'A', 1, -12}, {'B', 8, -12}},
+			width: 18, height: 20, lines: 1,
+		},
+		{
+			name:  "no kerning",
+			text:  "BA",
+			quads: []quad{{'B', 0, -12}, {'A', 11, -12}},
+			width: 20, height: 20, lines: 1,
+		},
+		{
+			name:  "spaces advance without quads",
+			text:  "A A",
+			quads: []quad{{'A', 1, -12}, {'A', 16, -12}},
+			width: 25, height: 20, lines: 1,
+		},
+		{
+			name:  "fallback",
+			text:  "Z",
+			quads: []quad{{'Z', 1, -12}},
+			width: 10, height: 20, lines: 1,
+		},
+		{
+			name:  "new lines",
+			text:  "A\nB",
+			quads: []quad{{'A', 1, -12}, {'B', 0, -32}},
+			width: 10, height: 40, lines: 2,
+		},
+		{
+			name:  "wrap on spaces",
+			text:  "AB AB",
+			opts:  LayoutOptions{WrapWidth: 20},
+			quads: []quad{{'A', 1, -12}, {'B', 8, -12}, {'A', 1, -32}, {'B', 8, -32}},
+			width: 20, height: 40, lines: 2,
+		},
+		{
+			name:  "wrap keeps what fits",
+			text:  "A A AB",
+			opts:  LayoutOptions{WrapWidth: 25},
+			quads: []quad{{'A', 1, -12}, {'A', 16, -12}, {'A', 1, -32}, {'B', 8, -32}},
+			width: 25, height: 40, lines: 2,
+		},
+		{
+			name:  "break long words",
+			text:  "AAA",
+			opts:  LayoutOptions{WrapWidth: 25},
+			quads: []quad{{'A', 1, -12}, {'A', 11, -12}, {'A', 1, -32}},
+			width: 25, height: 40, lines: 2,
+		},
+		{
+			name:  "center",
+			text:  "A\nAB",
+			opts:  LayoutOptions{Align: AlignCenter},
+			quads: []quad{{'A', 5, -12}, {'A', 1, -32}, {'B', 8, -32}},
+			width: 18, height: 40, lines: 2,
+		},
+		{
+			name:  "right",
+			text:  "A\nAB",
+			opts:  LayoutOptions{Align: AlignRight},
+			quads: []quad{{'A', 9, -12}, {'A', 1, -32}, {'B', 8, -32}},
+			width: 18, height: 40, lines: 2,
+		},
+		{
+			name:  "center in the wrap width",
+			text:  "A",
+			opts:  LayoutOptions{Align: AlignCenter, WrapWidth: 30},
+			quads: []quad{{'A', 11, -12}},
+			width: 30, height: 20, lines: 1,
+		},
+		{
+			name:  "scale",
+			text:  "AB",
+			opts:  LayoutOptions{Scale: 2},
+			quads: []quad{{'A', 2, -24}, {'B', 16, -24}},
+			width: 36, height: 40, lines: 1,
+		},
+	}
+
+	font := testFont(t)
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			layout := Layout(font, test.text, test.opts)
+
+			if layout.Width != test.width || layout.Height != test.height || layout.Lines != test.lines {
+				t.Errorf("%vx%v with %d lines, want %vx%v with %d lines",
+					layout.Width, layout.Height, layout.Lines, test.width, test.height, test.lines)
+			}
+
+			if len(layout.Quads) != len(test.quads) {
+				t.Fatalf("%d quads, want %d", len(layout.Quads), len(test.quads))
+			}
+			for i, want := range test.quads {
+				q := layout.Quads[i]
+				if q.Rune != want.r || q.X != want.x || q.Y != want.y {
+					t.Errorf("quad %d is %q at %v,%v, want %q at %v,%v", i, q.Rune, q.X, q.Y, want.r, want.x, want.y)
+				}
+			}
+		})
+	}
+}
+
+func TestMeasureLine(t *testing.T) {
+	tests := []struct {
+		line  string
+		width float32
+	}{
+		{"", 0},
+		{"A", 10},
+		{"AB", 18},
+		{"BA", 20},
+		{"AB AB", 41},
+		{"Z", 10},
+	}
+
+	font := testFont(t)
+	for _, test := range tests {
+		if width := MeasureLine(font, test.line); width != test.width {
+			t.Errorf("%q is %v wide, want %v", test.line, width, test.width)
+		}
+	}
+}
@@ -5,7 +5,8 @@ import (
 	"image"
 	"image/draw"
 	_ "image/png" // Required for png images
-	"os"
+	"io/fs"
+	"path"
 	"strconv"
 	"strings"
 )
@@ -23,6 +24,10 @@ type TextureCoord struct {
 type SubTexture struct {
 	name          string
 	textureCoords []*TextureCoord
+
+	// bounds is the block's pixel rectangle (origin upper left). Only
+	// dynamic atlases track it.
+	bounds image.Rectangle
 }
 
 // NewSubTexture creates a
@@ -35,16 +40,26 @@ func NewSubTexture(name string) *SubTexture {
 
 // TextureAtlas contains an image atlas
 type TextureAtlas struct {
+	fsys          fs.FS
 	manifest      string
 	width, height int64
 	atlas         *image.NRGBA
 
 	subTextures []*SubTexture
+	// fallback provides the sub textures the atlas doesn't have, see
+	// SetFallback
+	fallback func(name string) []*TextureCoord
+
+	// version changes whenever the atlas image changes.
+	version uint64
 }
 
-// NewTextureAtlas creates a new atlas
-func NewTextureAtlas(manifest string) *TextureAtlas {
+// NewTextureAtlas creates a new atlas. The manifest is read from fsys,
+// which can be an embed.FS, os.DirFS, zip.Reader or fstest.MapFS.
+// The image named in the manifest is resolved relative to the manifest.
+func NewTextureAtlas(fsys fs.FS, manifest string) *TextureAtlas {
 	o := new(TextureAtlas)
+	o.fsys = fsys
 	o.manifest = manifest
 	o.subTextures = []*SubTexture{}
 
@@ -53,7 +68,7 @@ func NewTextureAtlas(manifest string) *TextureAtlas {
 
 // Build setups the atlas based on manifest
 func (t *TextureAtlas) Build() {
-	manifestFile, err := os.Open(t.manifest)
+	manifestFile, err := t.fsys.Open(t.manifest)
 	if err != nil {
 		panic(err)
 	}
@@ -67,9 +82,10 @@ func (t *TextureAtlas) Build() {
 		lines = append(lines, scanner.Text())
 	}
 
-	textureFile := lines[0]
+	// The image path is relative to the manifest's directory
+	textureFile := path.Join(path.Dir(t.manifest), lines[0])
 
-	t.atlas, err = t.loadImage(textureFile)
+	t.atlas, err = loadImage(t.fsys, textureFile)
 	if err != nil {
 		panic(err)
 	}
@@ -98,6 +114,8 @@ func (t *TextureAtlas) Build() {
 
 		t.subTextures = append(t.subTextures, ts)
 	}
+
+	t.version++
 }
 
 // Atlas returns image atlas
@@ -105,6 +123,12 @@ func (t *TextureAtlas) Atlas() *image.NRGBA {
 	return t.atlas
 }
 
+// Version returns a counter that changes whenever the atlas image
+// changes, renderers use it to know when to upload the image again.
+func (t *TextureAtlas) Version() uint64 {
+	return t.version
+}
+
 // TextureCoords returns the assigned coords of named sub texture
 func (t *TextureAtlas) TextureCoords(name string) []*TextureCoord {
 	for _, subTex := range t.subTextures {
@@ -113,15 +137,28 @@ func (t *TextureAtlas) TextureCoords(name string) []*TextureCoord {
 		}
 	}
 
+	if t.fallback != nil {
+		return t.fallback(name)
+	}
 	return nil
 }
 
-func (t *TextureAtlas) loadImage(path string) (*image.NRGBA, error) {
-	file, err := os.Open(path)
+// SetFallback sets a function TextureCoords calls for names the atlas
+// doesn't have, e.g. a GlyphCache rasterizing an evicted glyph.
+func (t *TextureAtlas) SetFallback(fallback func(name string) []*TextureCoord) {
+	t.fallback = fallback
+}
+
+// loadImage decodes an image from fsys and flips it vertically so that
+// row 0 is the bottom row, which is what OpenGL expects.
+func loadImage(fsys fs.FS, name string) (*image.NRGBA, error) {
+	file, err := fsys.Open(name)
 	if err != nil {
 		return nil, err
 	}
 
+	defer file.Close()
+
 	img, _, err := image.Decode(file)
 	if err != nil {
 		return nil, err
@@ -0,0 +1,83 @@
+package textures
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"testing"
+	"testing/fstest"
+)
+
+func testPNG(t *testing.T, width, height int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewNRGBA(image.Rect(0, 0, width, height))); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestTextureAtlasBuild(t *testing.T) {
+	// The image is named relative to the manifest's directory
+	tests := []struct {
+		name     string
+		manifest string
+		image    string
+		path     string
+	}{
+		{"same directory at the root", "manifest.txt", "atlas.png", "atlas.png"},
+		{"same directory", "assets/manifest.txt", "atlas.png", "assets/atlas.png"},
+		{"sub directory", "assets/manifest.txt", "images/atlas.png", "assets/images/atlas.png"},
+		{"parent directory", "assets/atlases/manifest.txt", "../images/atlas.png", "assets/images/atlas.png"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fsys := fstest.MapFS{
+				test.manifest: {Data: []byte(test.image + "\n256x128\nmine|0,64:64,64:64,128:0,128\nship|64,0:128,0:128,64:64,64\n")},
+				// A decoy the atlas must not pick
+				"atlas.png": {Data: testPNG(t, 8, 8)},
+			}
+			fsys[test.path] = &fstest.MapFile{Data: testPNG(t, 256, 128)}
+
+			atlas := NewTextureAtlas(fsys, test.manifest)
+			atlas.Build()
+
+			if b := atlas.Atlas().Bounds(); b.Dx() != 256 || b.Dy() != 128 {
+				t.Errorf("loaded a %dx%d image, want 256x128", b.Dx(), b.Dy())
+			}
+			if atlas.Version() == 0 {
+				t.Error("version unchanged by Build")
+			}
+
+			want := []TextureCoord{{0, 0.5}, {0.25, 0.5}, {0.25, 1}, {0, 1}}
+			coords := atlas.TextureCoords("mine")
+			if len(coords) != len(want) {
+				t.Fatalf("%d coords for 'mine', want %d", len(coords), len(want))
+			}
+			for i := range want {
+				if *coords[i] != want[i] {
+					t.Errorf("coord %d is %v, want %v", i, *coords[i], want[i])
+				}
+			}
+			if len(atlas.TextureCoords("ship")) != 4 || atlas.TextureCoords("nope") != nil {
+				t.Error("wrong sub textures")
+			}
+		})
+	}
+}
+
+func TestTextureAtlasBuildMissingImage(t *testing.T) {
+	fsys := fstest.MapFS{
+		"assets/manifest.txt": {Data: []byte("atlas.png\n64x64\n")},
+		// Not relative to the manifest
+		"atlas.png": {Data: testPNG(t, 64, 64)},
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("no panic for a missing image")
+		}
+	}()
+	NewTextureAtlas(fsys, "assets/manifest.txt").Build()
+}
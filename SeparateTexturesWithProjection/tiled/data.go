@@ -0,0 +1,181 @@
+package tiled
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// decodeCSV decodes comma separated gids, newlines are allowed anywhere.
+func decodeCSV(text string) ([]GID, error) {
+	fields := strings.FieldsFunc(text, func(r rune) bool {
+		return r == ',' || r == '\n' || r == '\r' || r == ' ' || r == '\t'
+	})
+	gids := make([]GID, len(fields))
+	for i, f := range fields {
+		v, err := strconv.ParseUint(f, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("csv data: %v", err)
+		}
+		gids[i] = GID(v)
+	}
+	return gids, nil
+}
+
+// decodeBase64 decodes little endian 32 bit gids, compressed with
+// "zlib", "gzip" or "" for none.
+func decodeBase64(text, compression string) ([]GID, error) {
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(text))
+	if err != nil {
+		return nil, fmt.Errorf("base64 data: %v", err)
+	}
+
+	var r io.Reader
+	switch compression {
+	case "":
+		r = bytes.NewReader(raw)
+	case "zlib":
+		if r, err = zlib.NewReader(bytes.NewReader(raw)); err != nil {
+			return nil, fmt.Errorf("zlib data: %v", err)
+		}
+	case "gzip":
+		if r, err = gzip.NewReader(bytes.NewReader(raw)); err != nil {
+			return nil, fmt.Errorf("gzip data: %v", err)
+		}
+	default:
+		return nil, fmt.Errorf("%s compression isn't supported", compression)
+	}
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("%s data: %v", compression, err)
+	}
+	if len(data)%4 != 0 {
+		return nil, fmt.Errorf("base64 data: %d bytes isn't a whole number of gids", len(data))
+	}
+
+	gids := make([]GID, len(data)/4)
+	for i := range gids {
+		gids[i] = GID(binary.LittleEndian.Uint32(data[4*i:]))
+	}
+	return gids, nil
+}
+
+// decodeData decodes a layer's or chunk's text by its encoding.
+func decodeData(text, encoding, compression string) ([]GID, error) {
+	switch encoding {
+	case "csv":
+		return decodeCSV(text)
+	case "base64":
+		return decodeBase64(text, compression)
+	}
+	return nil, fmt.Errorf("%q encoding isn't supported", encoding)
+}
+
+// chunk is a piece of an infinite map's layer.
+type chunk struct {
+	x, y, width, height int
+	tiles               []GID
+}
+
+// setTiles fills a layer from its chunks, the layer covering them all.
+func (l *Layer) setTiles(chunks []chunk) error {
+	if len(chunks) == 0 {
+		return nil
+	}
+
+	minX, minY := chunks[0].x, chunks[0].y
+	maxX, maxY := minX, minY
+	for _, c := range chunks {
+		if len(c.tiles) != c.width*c.height {
+			return fmt.Errorf("layer %q: chunk at %d,%d has %d tiles, expected %d",
+				l.Name, c.x, c.y, len(c.tiles), c.width*c.height)
+		}
+		if c.x < minX {
+			minX = c.x
+		}
+		if c.y < minY {
+			minY = c.y
+		}
+		if c.x+c.width > maxX {
+			maxX = c.x + c.width
+		}
+		if c.y+c.height > maxY {
+			maxY = c.y + c.height
+		}
+	}
+
+	l.X, l.Y = minX, minY
+	l.Width, l.Height = maxX-minX, maxY-minY
+	l.Tiles = make([]GID, l.Width*l.Height)
+	for _, c := range chunks {
+		for row := 0; row < c.height; row++ {
+			dst := (c.y-minY+row)*l.Width + c.x - minX
+			copy(l.Tiles[dst:dst+c.width], c.tiles[row*c.width:(row+1)*c.width])
+		}
+	}
+	return nil
+}
+
+// checkTiles checks a finite layer's tile count.
+func (l *Layer) checkTiles() error {
+	if len(l.Tiles) != l.Width*l.Height {
+		return fmt.Errorf("layer %q has %d tiles, expected %d", l.Name, len(l.Tiles), l.Width*l.Height)
+	}
+	return nil
+}
+
+// group is what a group layer passes on to the layers it contains.
+type group struct {
+	name             string
+	visible          bool
+	opacity          float32
+	offsetX, offsetY float32
+}
+
+var rootGroup = group{visible: true, opacity: 1.0}
+
+// apply combines the group with a layer of it.
+func (g group) apply(l *Layer) {
+	if g.name != "" {
+		l.Name = g.name + "/" + l.Name
+	}
+	l.Visible = l.Visible && g.visible
+	l.Opacity *= g.opacity
+	l.OffsetX += g.offsetX
+	l.OffsetY += g.offsetY
+}
+
+// child returns the group for the layers of a group layer of g.
+func (g group) child(name string, visible bool, opacity, offsetX, offsetY float32) group {
+	l := Layer{Name: name, Visible: visible, Opacity: opacity, OffsetX: offsetX, OffsetY: offsetY}
+	g.apply(&l)
+	return group{name: l.Name, visible: l.Visible, opacity: l.Opacity, offsetX: l.OffsetX, offsetY: l.OffsetY}
+}
+
+// parsePoints parses a TMX point list, "x,y x,y ...".
+func parsePoints(text string) ([]PointF, error) {
+	var points []PointF
+	for _, pair := range strings.Fields(text) {
+		xy := strings.Split(pair, ",")
+		if len(xy) != 2 {
+			return nil, fmt.Errorf("invalid point %q", pair)
+		}
+		x, err := strconv.ParseFloat(xy[0], 32)
+		if err != nil {
+			return nil, err
+		}
+		y, err := strconv.ParseFloat(xy[1], 32)
+		if err != nil {
+			return nil, err
+		}
+		points = append(points, PointF{X: float32(x), Y: float32(y)})
+	}
+	return points, nil
+}
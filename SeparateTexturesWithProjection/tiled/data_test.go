@@ -0,0 +1,186 @@
+package tiled
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/base64"
+	"encoding/binary"
+	"image"
+	"image/png"
+	"io"
+	"testing"
+)
+
+// encodeGIDs encodes gids as Tiled does for base64 data, compressed with
+// "zlib", "gzip" or "" for none.
+func encodeGIDs(t *testing.T, gids []GID, compression string) string {
+	t.Helper()
+	raw := make([]byte, 4*len(gids))
+	for i, g := range gids {
+		binary.LittleEndian.PutUint32(raw[4*i:], uint32(g))
+	}
+
+	var buf bytes.Buffer
+	var w io.WriteCloser
+	switch compression {
+	case "":
+		buf.Write(raw)
+	case "zlib":
+		w = zlib.NewWriter(&buf)
+	case "gzip":
+		w = gzip.NewWriter(&buf)
+	default:
+		t.Fatalf("unknown compression %q", compression)
+	}
+	if w != nil {
+		if _, err := w.Write(raw); err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return base64.StdEncoding.EncodeToString(buf.Bytes())
+}
+
+func testPNG(t *testing.T, width, height int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewNRGBA(image.Rect(0, 0, width, height))); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestDecodeData(t *testing.T) {
+	gids := []GID{1, 2, 0, FlipHorizontal | 3, FlipVertical | FlipDiagonal | 4, 0xffff}
+
+	tests := []struct {
+		name        string
+		text        string
+		encoding    string
+		compression string
+		ok          bool
+	}{
+		{"csv", "1,2,0,2147483651,1610612740,65535", "csv", "", true},
+		{"csv across lines", "\n1,2,0,\r\n2147483651, 1610612740,\t65535\n", "csv", "", true},
+		{"base64", encodeGIDs(t, gids, ""), "base64", "", true},
+		{"base64 zlib", encodeGIDs(t, gids, "zlib"), "base64", "zlib", true},
+		{"base64 gzip", "\n   " + encodeGIDs(t, gids, "gzip") + "\n  ", "base64", "gzip", true},
+		{"csv garbage", "1,x,3", "csv", "", false},
+		{"csv overflow", "4294967296", "csv", "", false},
+		{"base64 garbage", "!!!!", "base64", "", false},
+		{"base64 partial gid", base64.StdEncoding.EncodeToString([]byte{1, 0, 0}), "base64", "", false},
+		{"base64 wrong compression", encodeGIDs(t, gids, "zlib"), "base64", "gzip", false},
+		{"zstd", encodeGIDs(t, gids, ""), "base64", "zstd", false},
+		{"unknown encoding", "1,2", "hex", "", false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := decodeData(test.text, test.encoding, test.compression)
+			if (err == nil) != test.ok {
+				t.Fatalf("error %v", err)
+			}
+			if !test.ok {
+				return
+			}
+			if len(got) != len(gids) {
+				t.Fatalf("gids %v, want %v", got, gids)
+			}
+			for i := range gids {
+				if got[i] != gids[i] {
+					t.Fatalf("gids %v, want %v", got, gids)
+				}
+			}
+		})
+	}
+}
+
+func TestSetTiles(t *testing.T) {
+	// Two 2x2 chunks with a gap, the first left of and above the origin
+	chunks := []chunk{
+		{x: 2, y: 0, width: 2, height: 2, tiles: []GID{5, 6, 7, 8}},
+		{x: -2, y: -2, width: 2, height: 2, tiles: []GID{1, 2, 3, 4}},
+	}
+
+	var l Layer
+	if err := l.setTiles(chunks); err != nil {
+		t.Fatal(err)
+	}
+	if l.X != -2 || l.Y != -2 || l.Width != 6 || l.Height != 4 {
+		t.Fatalf("layer covers %d,%d %dx%d, want -2,-2 6x4", l.X, l.Y, l.Width, l.Height)
+	}
+
+	tests := []struct {
+		x, y int
+		gid  GID
+	}{
+		{-2, -2, 1}, {-1, -2, 2}, {-2, -1, 3}, {-1, -1, 4},
+		{2, 0, 5}, {3, 0, 6}, {2, 1, 7}, {3, 1, 8},
+		// The gap and outside
+		{0, 0, 0}, {1, -1, 0}, {-3, -2, 0}, {4, 1, 0}, {3, 2, 0},
+	}
+	for _, test := range tests {
+		if gid := l.Tile(test.x, test.y); gid != test.gid {
+			t.Errorf("tile %d,%d is %d, want %d", test.x, test.y, gid, test.gid)
+		}
+	}
+
+	bad := []chunk{{x: 0, y: 0, width: 2, height: 2, tiles: []GID{1, 2, 3}}}
+	if err := l.setTiles(bad); err == nil {
+		t.Error("no error for a short chunk")
+	}
+}
+
+func TestGroups(t *testing.T) {
+	outer := rootGroup.child("details", true, 0.5, 4, 0)
+	inner := outer.child("inner", false, 0.5, 0, 2)
+
+	tests := []struct {
+		name    string
+		g       group
+		layer   Layer
+		want    string
+		visible bool
+		opacity float32
+		x, y    float32
+	}{
+		{"root", rootGroup, Layer{Name: "ground", Visible: true, Opacity: 1}, "ground", true, 1, 0, 0},
+		{"outer", outer, Layer{Name: "flowers", Visible: true, Opacity: 0.5, OffsetY: 1}, "details/flowers", true, 0.25, 4, 1},
+		{"inner", inner, Layer{Name: "deep", Visible: true, Opacity: 1}, "details/inner/deep", false, 0.25, 4, 2},
+		{"hidden layer", outer, Layer{Name: "off", Visible: false, Opacity: 1}, "details/off", false, 0.5, 4, 0},
+	}
+
+	for _, test := range tests {
+		l := test.layer
+		test.g.apply(&l)
+		if l.Name != test.want || l.Visible != test.visible || l.Opacity != test.opacity || l.OffsetX != test.x || l.OffsetY != test.y {
+			t.Errorf("%s: %q visible %v opacity %v offset %v,%v, want %q %v %v %v,%v", test.name,
+				l.Name, l.Visible, l.Opacity, l.OffsetX, l.OffsetY, test.want, test.visible, test.opacity, test.x, test.y)
+		}
+	}
+}
+
+func TestParsePoints(t *testing.T) {
+	points, err := parsePoints(" 0,0  4.5,-1\n3,2 ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []PointF{{0, 0}, {4.5, -1}, {3, 2}}
+	if len(points) != len(want) {
+		t.Fatalf("points %v, want %v", points, want)
+	}
+	for i := range want {
+		if points[i] != want[i] {
+			t.Fatalf("points %v, want %v", points, want)
+		}
+	}
+
+	for _, text := range []string{"1,2,3", "1", "x,1", "1,y"} {
+		if _, err := parsePoints(text); err == nil {
+			t.Errorf("no error for %q", text)
+		}
+	}
+}
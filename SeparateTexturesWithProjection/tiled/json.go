@@ -0,0 +1,301 @@
+package tiled
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+)
+
+type jsonProperty struct {
+	Name  string      `json:"name"`
+	Value interface{} `json:"value"`
+}
+
+type jsonMap struct {
+	Orientation string           `json:"orientation"`
+	RenderOrder string           `json:"renderorder"`
+	Width       int              `json:"width"`
+	Height      int              `json:"height"`
+	TileWidth   int              `json:"tilewidth"`
+	TileHeight  int              `json:"tileheight"`
+	Infinite    bool             `json:"infinite"`
+	Properties  []jsonProperty   `json:"properties"`
+	Tilesets    []jsonTilesetRef `json:"tilesets"`
+	Layers      []jsonLayer      `json:"layers"`
+}
+
+type jsonTilesetRef struct {
+	FirstGID uint32 `json:"firstgid"`
+	Source   string `json:"source"`
+	jsonTileset
+}
+
+type jsonTileset struct {
+	Name        string `json:"name"`
+	TileWidth   int    `json:"tilewidth"`
+	TileHeight  int    `json:"tileheight"`
+	Spacing     int    `json:"spacing"`
+	Margin      int    `json:"margin"`
+	TileCount   int    `json:"tilecount"`
+	Columns     int    `json:"columns"`
+	Image       string `json:"image"`
+	ImageWidth  int    `json:"imagewidth"`
+	ImageHeight int    `json:"imageheight"`
+	TileOffset  struct {
+		X int `json:"x"`
+		Y int `json:"y"`
+	} `json:"tileoffset"`
+	Tiles []struct {
+		ID         int            `json:"id"`
+		Properties []jsonProperty `json:"properties"`
+	} `json:"tiles"`
+}
+
+type jsonLayer struct {
+	Type    string   `json:"type"`
+	Name    string   `json:"name"`
+	Visible *bool    `json:"visible"`
+	Opacity *float32 `json:"opacity"`
+	OffsetX float32  `json:"offsetx"`
+	OffsetY float32  `json:"offsety"`
+	Width   int      `json:"width"`
+	Height  int      `json:"height"`
+	// An array of gids, or a string with an encoding
+	Data        json.RawMessage `json:"data"`
+	Encoding    string          `json:"encoding"`
+	Compression string          `json:"compression"`
+	Chunks      []struct {
+		X      int             `json:"x"`
+		Y      int             `json:"y"`
+		Width  int             `json:"width"`
+		Height int             `json:"height"`
+		Data   json.RawMessage `json:"data"`
+	} `json:"chunks"`
+	Objects    []jsonObject   `json:"objects"`
+	Layers     []jsonLayer    `json:"layers"`
+	Properties []jsonProperty `json:"properties"`
+}
+
+type jsonObject struct {
+	ID         int             `json:"id"`
+	Name       string          `json:"name"`
+	Type       string          `json:"type"`
+	Class      string          `json:"class"`
+	X          float32         `json:"x"`
+	Y          float32         `json:"y"`
+	Width      float32         `json:"width"`
+	Height     float32         `json:"height"`
+	Rotation   float32         `json:"rotation"`
+	GID        uint32          `json:"gid"`
+	Visible    *bool           `json:"visible"`
+	Ellipse    bool            `json:"ellipse"`
+	Point      bool            `json:"point"`
+	Polygon    []PointF        `json:"polygon"`
+	Polyline   []PointF        `json:"polyline"`
+	Text       json.RawMessage `json:"text"`
+	Properties []jsonProperty  `json:"properties"`
+}
+
+func (m *Map) parseJSON(data []byte) error {
+	var x jsonMap
+	if err := json.Unmarshal(data, &x); err != nil {
+		return err
+	}
+
+	m.Orientation = x.Orientation
+	m.RenderOrder = x.RenderOrder
+	m.Width, m.Height = x.Width, x.Height
+	m.TileWidth, m.TileHeight = x.TileWidth, x.TileHeight
+	m.Infinite = x.Infinite
+	m.Properties = jsonProperties(x.Properties)
+
+	for i := range x.Tilesets {
+		ref := &x.Tilesets[i]
+		if ref.Source != "" {
+			ts, err := m.loadTileset(ref.FirstGID, m.resolve(ref.Source))
+			if err != nil {
+				return err
+			}
+			m.Tilesets = append(m.Tilesets, ts)
+			continue
+		}
+		m.Tilesets = append(m.Tilesets, ref.tileset(ref.FirstGID, path.Dir(m.name)))
+	}
+	sortTilesets(m.Tilesets)
+
+	return m.addJSONLayers(x.Layers, rootGroup)
+}
+
+// parseJSONTileset parses an external JSON tileset, dir is the tileset's
+// directory.
+func parseJSONTileset(data []byte, firstGID uint32, dir string) (*Tileset, error) {
+	var x jsonTileset
+	if err := json.Unmarshal(data, &x); err != nil {
+		return nil, err
+	}
+	return x.tileset(firstGID, dir), nil
+}
+
+func (x *jsonTileset) tileset(firstGID uint32, dir string) *Tileset {
+	ts := &Tileset{
+		FirstGID:       firstGID,
+		Name:           x.Name,
+		TileWidth:      x.TileWidth,
+		TileHeight:     x.TileHeight,
+		Spacing:        x.Spacing,
+		Margin:         x.Margin,
+		TileCount:      x.TileCount,
+		Columns:        x.Columns,
+		OffsetX:        x.TileOffset.X,
+		OffsetY:        x.TileOffset.Y,
+		ImageWidth:     x.ImageWidth,
+		ImageHeight:    x.ImageHeight,
+		TileProperties: map[int]Properties{},
+	}
+	if x.Image != "" {
+		ts.Image = path.Join(dir, x.Image)
+	}
+	for _, t := range x.Tiles {
+		if len(t.Properties) > 0 {
+			ts.TileProperties[t.ID] = jsonProperties(t.Properties)
+		}
+	}
+	return ts
+}
+
+func (m *Map) addJSONLayers(nodes []jsonLayer, g group) error {
+	for i := range nodes {
+		x := &nodes[i]
+		visible := x.Visible == nil || *x.Visible
+		opacity := float32(1.0)
+		if x.Opacity != nil {
+			opacity = *x.Opacity
+		}
+
+		l := &Layer{
+			Name:       x.Name,
+			Visible:    visible,
+			Opacity:    opacity,
+			OffsetX:    x.OffsetX,
+			OffsetY:    x.OffsetY,
+			Properties: jsonProperties(x.Properties),
+		}
+
+		switch x.Type {
+		case "tilelayer":
+			l.Kind = TileLayer
+			if err := x.tiles(l); err != nil {
+				return fmt.Errorf("layer %q: %v", x.Name, err)
+			}
+		case "objectgroup":
+			l.Kind = ObjectLayer
+			for j := range x.Objects {
+				l.Objects = append(l.Objects, x.Objects[j].object())
+			}
+		case "imagelayer":
+			l.Kind = ImageLayer
+		case "group":
+			if err := m.addJSONLayers(x.Layers, g.child(x.Name, visible, opacity, x.OffsetX, x.OffsetY)); err != nil {
+				return err
+			}
+			continue
+		default:
+			return fmt.Errorf("layer %q: unknown type %q", x.Name, x.Type)
+		}
+
+		g.apply(l)
+		m.Layers = append(m.Layers, l)
+	}
+	return nil
+}
+
+// tiles decodes a tile layer's data, from chunks for infinite maps.
+func (x *jsonLayer) tiles(l *Layer) error {
+	l.Width, l.Height = x.Width, x.Height
+
+	if len(x.Chunks) > 0 {
+		chunks := make([]chunk, len(x.Chunks))
+		for i, c := range x.Chunks {
+			tiles, err := jsonTiles(c.Data, x.Encoding, x.Compression)
+			if err != nil {
+				return err
+			}
+			chunks[i] = chunk{x: c.X, y: c.Y, width: c.Width, height: c.Height, tiles: tiles}
+		}
+		return l.setTiles(chunks)
+	}
+
+	tiles, err := jsonTiles(x.Data, x.Encoding, x.Compression)
+	if err != nil {
+		return err
+	}
+	l.Tiles = tiles
+	return l.checkTiles()
+}
+
+// jsonTiles decodes data, an array of gids or a base64 string.
+func jsonTiles(data json.RawMessage, encoding, compression string) ([]GID, error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+
+	if encoding == "base64" {
+		var text string
+		if err := json.Unmarshal(data, &text); err != nil {
+			return nil, fmt.Errorf("base64 data: %v", err)
+		}
+		return decodeBase64(text, compression)
+	}
+
+	var tiles []GID
+	if err := json.Unmarshal(data, &tiles); err != nil {
+		return nil, fmt.Errorf("data: %v", err)
+	}
+	return tiles, nil
+}
+
+func (x *jsonObject) object() *Object {
+	o := &Object{
+		ID:         x.ID,
+		Name:       x.Name,
+		Type:       x.Type,
+		X:          x.X,
+		Y:          x.Y,
+		Width:      x.Width,
+		Height:     x.Height,
+		Rotation:   x.Rotation,
+		GID:        GID(x.GID),
+		Visible:    x.Visible == nil || *x.Visible,
+		Properties: jsonProperties(x.Properties),
+	}
+	if o.Type == "" {
+		o.Type = x.Class
+	}
+
+	switch {
+	case x.Ellipse:
+		o.Shape = Ellipse
+	case x.Point:
+		o.Shape = Point
+	case x.Polygon != nil:
+		o.Shape = Polygon
+		o.Points = x.Polygon
+	case x.Polyline != nil:
+		o.Shape = Polyline
+		o.Points = x.Polyline
+	case len(x.Text) > 0:
+		o.Shape = Text
+	}
+	return o
+}
+
+func jsonProperties(properties []jsonProperty) Properties {
+	if len(properties) == 0 {
+		return nil
+	}
+	p := Properties{}
+	for _, x := range properties {
+		p[x.Name] = fmt.Sprint(x.Value)
+	}
+	return p
+}
@@ -0,0 +1,142 @@
+package tiled
+
+import (
+	"fmt"
+	"testing"
+)
+
+const jsonTerrain = `{
+ "type": "tileset",
+ "name": "terrain",
+ "tilewidth": 16,
+ "tileheight": 16,
+ "tilecount": 8,
+ "columns": 4,
+ "image": "../images/terrain.png",
+ "imagewidth": 64,
+ "imageheight": 32,
+ "tiles": [
+  {"id": 3, "properties": [{"name": "solid", "type": "bool", "value": true}]}
+ ]
+}`
+
+// jsonLevelFiles returns the level fixture as JSON, see checkLevel.
+func jsonLevelFiles(t *testing.T) map[string]string {
+	level := fmt.Sprintf(`{
+ "type": "map",
+ "orientation": "orthogonal",
+ "renderorder": "right-down",
+ "width": 3,
+ "height": 2,
+ "tilewidth": 16,
+ "tileheight": 16,
+ "infinite": false,
+ "properties": [{"name": "music", "type": "string", "value": "calm.ogg"}],
+ "tilesets": [
+  {"firstgid": 9, "name": "items", "tilewidth": 16, "tileheight": 16, "tilecount": 4, "columns": 2,
+   "image": "items.png", "imagewidth": 32, "imageheight": 32},
+  {"firstgid": 1, "source": "tiles/terrain.tsj"}
+ ],
+ "layers": [
+  {"type": "tilelayer", "name": "ground", "width": 3, "height": 2, "data": [1, 2, 3, 4, 5, 6]},
+  {"type": "group", "name": "details", "opacity": 0.5, "offsetx": 4, "layers": [
+   {"type": "group", "name": "inner", "visible": false, "layers": [
+    {"type": "tilelayer", "name": "deep", "width": 3, "height": 2,
+     "encoding": "base64", "compression": "gzip", "data": %q}
+   ]},
+   {"type": "tilelayer", "name": "flowers", "width": 3, "height": 2, "opacity": 0.5, "offsety": 2,
+    "encoding": "base64", "compression": "zlib", "data": %q}
+  ]},
+  {"type": "tilelayer", "name": "raw", "width": 3, "height": 2, "encoding": "base64", "data": %q},
+  {"type": "objectgroup", "name": "objects", "objects": [
+   {"id": 1, "name": "spawn", "type": "start", "x": 8, "y": 16, "point": true,
+    "properties": [{"name": "team", "type": "string", "value": "red"}]},
+   {"id": 2, "class": "zone", "x": 0, "y": 0, "width": 10, "height": 5, "ellipse": true},
+   {"id": 3, "x": 1, "y": 2, "polygon": [{"x": 0, "y": 0}, {"x": 4, "y": 0}, {"x": 4, "y": 3}]},
+   {"id": 4, "gid": %d, "x": 16, "y": 32, "width": 16, "height": 16, "rotation": 90},
+   {"id": 5, "x": 0, "y": 0, "width": 1, "height": 1, "visible": false}
+  ]}
+ ]
+}`, encodeGIDs(t, []GID{9, 0, 0, 0, 0, 10}, "gzip"), encodeGIDs(t, flowersTiles, "zlib"), encodeGIDs(t, rawTiles, ""), FlipHorizontal|10)
+
+	return map[string]string{
+		"maps/level.tmj":         level,
+		"maps/tiles/terrain.tsj": jsonTerrain,
+	}
+}
+
+func TestLoadJSON(t *testing.T) {
+	checkLevel(t, loadMap(t, levelFS(t, jsonLevelFiles(t)), "maps/level.tmj"))
+}
+
+func TestLoadJSONFlipBits(t *testing.T) {
+	// The flip bits make gids too large for an int32
+	files := map[string]string{
+		"maps/flips.json": fmt.Sprintf(`{"orientation": "orthogonal", "width": 3, "height": 2, "tilewidth": 16, "tileheight": 16,
+ "tilesets": [{"firstgid": 1, "source": "tiles/terrain.tsj"}],
+ "layers": [{"type": "tilelayer", "name": "flowers", "width": 3, "height": 2, "data": [%d, %d, %d, %d, %d, %d]}]}`,
+			flowersTiles[0], flowersTiles[1], flowersTiles[2], flowersTiles[3], flowersTiles[4], flowersTiles[5]),
+		"maps/tiles/terrain.tsj": jsonTerrain,
+	}
+
+	m := loadMap(t, levelFS(t, files), "maps/flips.json")
+	if tiles := m.Layer("flowers").Tiles; !equalGIDs(tiles, flowersTiles) {
+		t.Errorf("tiles %v, want %v", tiles, flowersTiles)
+	}
+}
+
+func TestLoadJSONInfinite(t *testing.T) {
+	for _, encoding := range []string{"array", "gzip"} {
+		t.Run(encoding, func(t *testing.T) {
+			layer := fmt.Sprintf(`{"type": "tilelayer", "name": "ground", "width": 10, "height": 10, "chunks": [
+   {"x": 2, "y": 0, "width": 2, "height": 2, "data": [5, 6, 7, 8]},
+   {"x": -2, "y": -2, "width": 2, "height": 2, "data": [1, 2, 3, %d]}
+  ]}`, FlipHorizontal|4)
+			if encoding == "gzip" {
+				layer = fmt.Sprintf(`{"type": "tilelayer", "name": "ground", "width": 10, "height": 10,
+  "encoding": "base64", "compression": "gzip", "chunks": [
+   {"x": 2, "y": 0, "width": 2, "height": 2, "data": %q},
+   {"x": -2, "y": -2, "width": 2, "height": 2, "data": %q}
+  ]}`, encodeGIDs(t, []GID{5, 6, 7, 8}, "gzip"), encodeGIDs(t, []GID{1, 2, 3, FlipHorizontal | 4}, "gzip"))
+			}
+
+			files := map[string]string{
+				"maps/endless.json": `{"orientation": "orthogonal", "width": 10, "height": 10, "tilewidth": 16, "tileheight": 16,
+ "infinite": true, "tilesets": [{"firstgid": 1, "source": "tiles/terrain.tsj"}], "layers": [` + layer + `]}`,
+				"maps/tiles/terrain.tsj": jsonTerrain,
+			}
+			checkInfinite(t, loadMap(t, levelFS(t, files), "maps/endless.json"))
+		})
+	}
+}
+
+func TestLoadJSONErrors(t *testing.T) {
+	layer := func(fields string) string {
+		return `{"orientation": "orthogonal", "width": 2, "height": 1, "tilewidth": 16, "tileheight": 16,
+ "tilesets": [{"firstgid": 1, "source": "tiles/terrain.tsj"}],
+ "layers": [{"type": "tilelayer", "name": "ground", "width": 2, "height": 1, ` + fields + `}]}`
+	}
+
+	tests := []struct {
+		name  string
+		files map[string]string
+	}{
+		{"missing map", map[string]string{}},
+		{"broken json", map[string]string{"maps/bad.json": `{"layers": [`}},
+		{"missing tileset", map[string]string{"maps/bad.json": layer(`"data": [1, 2]`)}},
+		{"broken tileset", map[string]string{"maps/bad.json": layer(`"data": [1, 2]`), "maps/tiles/terrain.tsj": `{"name": 1}`}},
+		{"short layer", map[string]string{"maps/bad.json": layer(`"data": [1]`), "maps/tiles/terrain.tsj": jsonTerrain}},
+		{"negative gid", map[string]string{"maps/bad.json": layer(`"data": [1, -2]`), "maps/tiles/terrain.tsj": jsonTerrain}},
+		{"base64 array", map[string]string{"maps/bad.json": layer(`"encoding": "base64", "data": [1, 2]`), "maps/tiles/terrain.tsj": jsonTerrain}},
+		{"unknown compression", map[string]string{"maps/bad.json": layer(`"encoding": "base64", "compression": "zstd", "data": "AAAA"`), "maps/tiles/terrain.tsj": jsonTerrain}},
+		{"unknown layer type", map[string]string{"maps/bad.json": `{"layers": [{"type": "mystery", "name": "l"}]}`}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if err := NewMap(levelFS(t, test.files), "maps/bad.json").Load(); err == nil {
+				t.Error("no error")
+			}
+		})
+	}
+}
@@ -0,0 +1,348 @@
+// Package tiled loads maps made with the Tiled editor (https://mapeditor.org)
+// in either of its formats, TMX (XML) or JSON. Tile layers may be CSV or
+// base64, uncompressed or zlib/gzip compressed, and infinite maps' chunks
+// are merged into one grid per layer. Group layers are flattened into the
+// layers they contain.
+//
+// Each tileset's image is cut into a TextureAtlas whose sub textures are
+// the tiles, named by TileName.
+package tiled
+
+import (
+	"SimpleOpenGL-Go/SeparateTexturesWithProjection/textures"
+	"fmt"
+	"image"
+	"image/draw"
+	_ "image/png" // Required for png images
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Orientations. Only orthogonal maps can be drawn so far.
+const (
+	Orthogonal = "orthogonal"
+	Isometric  = "isometric"
+	Staggered  = "staggered"
+	Hexagonal  = "hexagonal"
+)
+
+// GID is a global tile id, the tile's tileset FirstGID plus its id in the
+// tileset, with the flip flags in the top bits. 0 is no tile.
+type GID uint32
+
+const (
+	FlipHorizontal GID = 0x80000000
+	FlipVertical   GID = 0x40000000
+	// FlipDiagonal swaps x and y, it is applied before the other flips
+	FlipDiagonal GID = 0x20000000
+	// RotateHexagonal120 is only used by hexagonal maps
+	RotateHexagonal120 GID = 0x10000000
+
+	flipMask = FlipHorizontal | FlipVertical | FlipDiagonal | RotateHexagonal120
+)
+
+// ID returns the gid without the flip flags.
+func (g GID) ID() uint32 {
+	return uint32(g &^ flipMask)
+}
+
+func (g GID) Has(flag GID) bool {
+	return g&flag != 0
+}
+
+// Properties are the custom properties of a map, layer, object or tile.
+// Every type is kept as its text, e.g. "true" or "1.5".
+type Properties map[string]string
+
+// LayerKind tells tile layers from object layers.
+type LayerKind int
+
+const (
+	TileLayer LayerKind = iota
+	ObjectLayer
+	// ImageLayer is kept for its properties and offset, the image isn't
+	// loaded
+	ImageLayer
+)
+
+// Layer is a tile, object or image layer. Layers of a group are named
+// "group/layer" and have the group's offset, opacity and visibility
+// combined with their own.
+type Layer struct {
+	Kind    LayerKind
+	Name    string
+	Visible bool
+	Opacity float32
+	// Offset in pixels, +y down
+	OffsetX, OffsetY float32
+
+	// The tiles' area, in tiles. Usually the whole map, an infinite map's
+	// layer covers its chunks and X,Y can be negative.
+	X, Y, Width, Height int
+	// Row major, Width * Height
+	Tiles []GID
+
+	Objects []*Object
+
+	Properties Properties
+}
+
+// Tile returns the gid at map tile x,y, 0 outside the layer.
+func (l *Layer) Tile(x, y int) GID {
+	x -= l.X
+	y -= l.Y
+	if x < 0 || y < 0 || x >= l.Width || y >= l.Height {
+		return 0
+	}
+	return l.Tiles[y*l.Width+x]
+}
+
+// ObjectShape is an object's geometry.
+type ObjectShape int
+
+const (
+	Rectangle ObjectShape = iota
+	Ellipse
+	Point
+	Polygon
+	Polyline
+	// Text objects are kept as rectangles without their text
+	Text
+)
+
+// Object is an item of an object layer. Coordinates are in pixels, +y
+// down. A tile object (GID != 0) is anchored at its lower left corner,
+// the other shapes at their upper left.
+type Object struct {
+	ID       int
+	Name     string
+	Type     string
+	Shape    ObjectShape
+	X, Y     float32
+	Width    float32
+	Height   float32
+	Rotation float32
+	GID      GID
+	Visible  bool
+	// Polygon and polyline vertices relative to X,Y
+	Points []PointF
+
+	Properties Properties
+}
+
+type PointF struct {
+	X float32 `json:"x"`
+	Y float32 `json:"y"`
+}
+
+// Tileset is a grid of tiles cut from one image.
+type Tileset struct {
+	FirstGID   uint32
+	Name       string
+	TileWidth  int
+	TileHeight int
+	Spacing    int
+	Margin     int
+	TileCount  int
+	Columns    int
+	// Drawing offset of every tile in pixels, +y down
+	OffsetX, OffsetY int
+
+	// The image's path in the map's file system
+	Image       string
+	ImageWidth  int
+	ImageHeight int
+
+	// Properties of the tiles that have some, by id
+	TileProperties map[int]Properties
+
+	atlas *textures.TextureAtlas
+}
+
+// Atlas returns the tiles as sub textures named by TileName.
+func (t *Tileset) Atlas() *textures.TextureAtlas {
+	return t.atlas
+}
+
+// TileName returns the atlas name of tile id.
+func TileName(id int) string {
+	return strconv.Itoa(id)
+}
+
+// TileBounds returns tile id's pixel rectangle in the image (origin upper
+// left).
+func (t *Tileset) TileBounds(id int) image.Rectangle {
+	col := id % t.Columns
+	row := id / t.Columns
+	x := t.Margin + col*(t.TileWidth+t.Spacing)
+	y := t.Margin + row*(t.TileHeight+t.Spacing)
+	return image.Rect(x, y, x+t.TileWidth, y+t.TileHeight)
+}
+
+// Map is a Tiled map. Sizes are in pixels unless said otherwise.
+type Map struct {
+	fsys fs.FS
+	name string
+
+	Orientation string
+	RenderOrder string
+	// In tiles, an infinite map's layers may go beyond it
+	Width, Height int
+	TileWidth     int
+	TileHeight    int
+	Infinite      bool
+
+	// By increasing FirstGID
+	Tilesets []*Tileset
+	// In drawing order
+	Layers []*Layer
+
+	Properties Properties
+}
+
+// NewMap creates a map read from fsys. The format is chosen by extension,
+// .tmx for TMX and .json or .tmj for JSON. Tilesets and images are
+// resolved relative to the map.
+func NewMap(fsys fs.FS, name string) *Map {
+	o := new(Map)
+	o.fsys = fsys
+	o.name = name
+	return o
+}
+
+// Build loads the map, its tilesets and their images.
+func (m *Map) Build() {
+	if err := m.Load(); err != nil {
+		panic(err)
+	}
+}
+
+// Load is the non-panicking version of Build.
+func (m *Map) Load() error {
+	data, err := fs.ReadFile(m.fsys, m.name)
+	if err != nil {
+		return err
+	}
+
+	if isJSON(m.name) {
+		err = m.parseJSON(data)
+	} else {
+		err = m.parseTMX(data)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %v", m.name, err)
+	}
+
+	for _, ts := range m.Tilesets {
+		if err := ts.buildAtlas(m.fsys); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Layer returns the named layer or nil.
+func (m *Map) Layer(name string) *Layer {
+	for _, l := range m.Layers {
+		if l.Name == name {
+			return l
+		}
+	}
+	return nil
+}
+
+// Tileset returns the tileset gid belongs to and the tile's id in it, nil
+// for 0 or an unknown gid.
+func (m *Map) Tileset(gid GID) (*Tileset, int) {
+	id := gid.ID()
+	if id == 0 {
+		return nil, 0
+	}
+	for i := len(m.Tilesets) - 1; i >= 0; i-- {
+		ts := m.Tilesets[i]
+		if id >= ts.FirstGID {
+			local := int(id - ts.FirstGID)
+			if local >= ts.TileCount {
+				return nil, 0
+			}
+			return ts, local
+		}
+	}
+	return nil, 0
+}
+
+// resolve returns the path of a file referenced by the map.
+func (m *Map) resolve(source string) string {
+	return path.Join(path.Dir(m.name), source)
+}
+
+// loadTileset loads an external tileset, .tsx or JSON.
+func (m *Map) loadTileset(firstGID uint32, name string) (*Tileset, error) {
+	data, err := fs.ReadFile(m.fsys, name)
+	if err != nil {
+		return nil, err
+	}
+
+	var ts *Tileset
+	if isJSON(name) {
+		ts, err = parseJSONTileset(data, firstGID, path.Dir(name))
+	} else {
+		ts, err = parseTSX(data, firstGID, path.Dir(name))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", name, err)
+	}
+	return ts, nil
+}
+
+func sortTilesets(tilesets []*Tileset) {
+	sort.Slice(tilesets, func(i, j int) bool {
+		return tilesets[i].FirstGID < tilesets[j].FirstGID
+	})
+}
+
+func isJSON(name string) bool {
+	ext := strings.ToLower(path.Ext(name))
+	return ext == ".json" || ext == ".tmj" || ext == ".tsj"
+}
+
+// buildAtlas loads the image and gives every tile a sub texture.
+func (t *Tileset) buildAtlas(fsys fs.FS) error {
+	if t.Image == "" {
+		return fmt.Errorf("tileset %q: image collection tilesets aren't supported", t.Name)
+	}
+
+	file, err := fsys.Open(t.Image)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	src, _, err := image.Decode(file)
+	if err != nil {
+		return fmt.Errorf("%s: %v", t.Image, err)
+	}
+
+	bounds := src.Bounds()
+	img := image.NewNRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(img, img.Bounds(), src, bounds.Min, draw.Src)
+
+	t.ImageWidth, t.ImageHeight = bounds.Dx(), bounds.Dy()
+	if t.Columns <= 0 {
+		t.Columns = (t.ImageWidth - 2*t.Margin + t.Spacing) / (t.TileWidth + t.Spacing)
+	}
+	if t.TileCount <= 0 {
+		rows := (t.ImageHeight - 2*t.Margin + t.Spacing) / (t.TileHeight + t.Spacing)
+		t.TileCount = rows * t.Columns
+	}
+
+	t.atlas = textures.NewDynamicTextureAtlas(t.ImageWidth, t.ImageHeight)
+	for id := 0; id < t.TileCount; id++ {
+		r := t.TileBounds(id)
+		t.atlas.SetSubTexture(TileName(id), r, img.SubImage(r))
+	}
+	return nil
+}
@@ -0,0 +1,237 @@
+package tiled
+
+import (
+	"testing"
+	"testing/fstest"
+)
+
+// The level fixtures of tmx_test.go and json_test.go describe the same
+// 3x2 map of 16 pixel tiles, this is what loading either gives.
+
+// Flip bits of the level's layers
+var (
+	flowersTiles = []GID{FlipHorizontal | 9, FlipVertical | FlipDiagonal | 3, 0, 0, 0, 12}
+	rawTiles     = []GID{0, 0, 0, 0, 0, FlipHorizontal | FlipVertical | FlipDiagonal | 8}
+)
+
+// levelFS returns the level's images and the given files. The map lives
+// in maps/, the external tileset in maps/tiles/ with its image in
+// maps/images/.
+func levelFS(t *testing.T, files map[string]string) fstest.MapFS {
+	fsys := fstest.MapFS{
+		"maps/images/terrain.png": {Data: testPNG(t, 64, 32)},
+		"maps/items.png":          {Data: testPNG(t, 32, 32)},
+	}
+	for name, data := range files {
+		fsys[name] = &fstest.MapFile{Data: []byte(data)}
+	}
+	return fsys
+}
+
+func loadMap(t *testing.T, fsys fstest.MapFS, name string) *Map {
+	t.Helper()
+	m := NewMap(fsys, name)
+	if err := m.Load(); err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func checkLevel(t *testing.T, m *Map) {
+	t.Helper()
+
+	if m.Orientation != Orthogonal || m.RenderOrder != "right-down" || m.Infinite {
+		t.Errorf("orientation %q render order %q infinite %v", m.Orientation, m.RenderOrder, m.Infinite)
+	}
+	if m.Width != 3 || m.Height != 2 || m.TileWidth != 16 || m.TileHeight != 16 {
+		t.Errorf("%dx%d of %dx%d tiles, want 3x2 of 16x16", m.Width, m.Height, m.TileWidth, m.TileHeight)
+	}
+	if m.Properties["music"] != "calm.ogg" {
+		t.Errorf("map properties %v", m.Properties)
+	}
+
+	if len(m.Tilesets) != 2 {
+		t.Fatalf("%d tilesets, want 2", len(m.Tilesets))
+	}
+	terrain, items := m.Tilesets[0], m.Tilesets[1]
+	if terrain.Name != "terrain" || terrain.FirstGID != 1 || terrain.Image != "maps/images/terrain.png" {
+		t.Errorf("terrain tileset %q from %d, image %q", terrain.Name, terrain.FirstGID, terrain.Image)
+	}
+	if terrain.Columns != 4 || terrain.TileCount != 8 || terrain.TileProperties[3]["solid"] != "true" {
+		t.Errorf("terrain has %d tiles in %d columns, tile properties %v", terrain.TileCount, terrain.Columns, terrain.TileProperties)
+	}
+	if items.Name != "items" || items.FirstGID != 9 || items.Image != "maps/items.png" || items.TileCount != 4 {
+		t.Errorf("items tileset %q from %d, image %q, %d tiles", items.Name, items.FirstGID, items.Image, items.TileCount)
+	}
+	if terrain.Atlas() == nil || terrain.Atlas().TextureCoords(TileName(7)) == nil || terrain.Atlas().TextureCoords(TileName(8)) != nil {
+		t.Error("terrain atlas doesn't have tiles 0 to 7")
+	}
+
+	layers := []struct {
+		name             string
+		kind             LayerKind
+		visible          bool
+		opacity          float32
+		offsetX, offsetY float32
+		tiles            []GID
+	}{
+		{"ground", TileLayer, true, 1, 0, 0, []GID{1, 2, 3, 4, 5, 6}},
+		{"details/inner/deep", TileLayer, false, 0.5, 4, 0, []GID{9, 0, 0, 0, 0, 10}},
+		{"details/flowers", TileLayer, true, 0.25, 4, 2, flowersTiles},
+		{"raw", TileLayer, true, 1, 0, 0, rawTiles},
+		{"objects", ObjectLayer, true, 1, 0, 0, nil},
+	}
+	if len(m.Layers) != len(layers) {
+		names := []string{}
+		for _, l := range m.Layers {
+			names = append(names, l.Name)
+		}
+		t.Fatalf("layers %q, want %d", names, len(layers))
+	}
+	for i, want := range layers {
+		l := m.Layers[i]
+		if l.Name != want.name || l.Kind != want.kind || l.Visible != want.visible || l.Opacity != want.opacity ||
+			l.OffsetX != want.offsetX || l.OffsetY != want.offsetY {
+			t.Errorf("layer %d is %q kind %d visible %v opacity %v offset %v,%v, want %q kind %d visible %v opacity %v offset %v,%v",
+				i, l.Name, l.Kind, l.Visible, l.Opacity, l.OffsetX, l.OffsetY,
+				want.name, want.kind, want.visible, want.opacity, want.offsetX, want.offsetY)
+		}
+		if want.kind != TileLayer {
+			continue
+		}
+		if l.X != 0 || l.Y != 0 || l.Width != 3 || l.Height != 2 || !equalGIDs(l.Tiles, want.tiles) {
+			t.Errorf("layer %q covers %d,%d %dx%d with %v, want 0,0 3x2 with %v", l.Name, l.X, l.Y, l.Width, l.Height, l.Tiles, want.tiles)
+		}
+	}
+	if m.Layer("ground") != m.Layers[0] || m.Layer("flowers") != nil {
+		t.Error("Layer doesn't find layers by their full name")
+	}
+
+	objects := m.Layer("objects").Objects
+	wantObjects := []Object{
+		{ID: 1, Name: "spawn", Type: "start", Shape: Point, X: 8, Y: 16, Visible: true},
+		{ID: 2, Type: "zone", Shape: Ellipse, Width: 10, Height: 5, Visible: true},
+		{ID: 3, Shape: Polygon, X: 1, Y: 2, Visible: true},
+		{ID: 4, Shape: Rectangle, X: 16, Y: 32, Width: 16, Height: 16, GID: FlipHorizontal | 10, Rotation: 90, Visible: true},
+		{ID: 5, Shape: Rectangle, Width: 1, Height: 1, Visible: false},
+	}
+	if len(objects) != len(wantObjects) {
+		t.Fatalf("%d objects, want %d", len(objects), len(wantObjects))
+	}
+	for i, want := range wantObjects {
+		o := objects[i]
+		if o.ID != want.ID || o.Name != want.Name || o.Type != want.Type || o.Shape != want.Shape ||
+			o.X != want.X || o.Y != want.Y || o.Width != want.Width || o.Height != want.Height ||
+			o.Rotation != want.Rotation || o.GID != want.GID || o.Visible != want.Visible {
+			t.Errorf("object %d is %+v, want %+v", i, *o, want)
+		}
+	}
+	if points := objects[2].Points; len(points) != 3 || points[1] != (PointF{4, 0}) || points[2] != (PointF{4, 3}) {
+		t.Errorf("polygon points %v", points)
+	}
+	if objects[0].Properties["team"] != "red" {
+		t.Errorf("object properties %v", objects[0].Properties)
+	}
+}
+
+// checkInfinite checks the infinite fixtures, one layer of two 2x2 chunks
+// at -2,-2 and 2,0.
+func checkInfinite(t *testing.T, m *Map) {
+	t.Helper()
+
+	if !m.Infinite || len(m.Layers) != 1 {
+		t.Fatalf("infinite %v with %d layers", m.Infinite, len(m.Layers))
+	}
+	l := m.Layers[0]
+	if l.X != -2 || l.Y != -2 || l.Width != 6 || l.Height != 4 {
+		t.Fatalf("layer covers %d,%d %dx%d, want -2,-2 6x4", l.X, l.Y, l.Width, l.Height)
+	}
+	want := []GID{
+		1, 2, 0, 0, 0, 0,
+		3, FlipHorizontal | 4, 0, 0, 0, 0,
+		0, 0, 0, 0, 5, 6,
+		0, 0, 0, 0, 7, 8,
+	}
+	if !equalGIDs(l.Tiles, want) {
+		t.Errorf("tiles %v, want %v", l.Tiles, want)
+	}
+	if l.Tile(-1, -1) != FlipHorizontal|4 || l.Tile(3, 1) != 8 || l.Tile(-3, 0) != 0 {
+		t.Error("Tile doesn't use map coordinates")
+	}
+}
+
+func equalGIDs(a, b []GID) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestMapTileset(t *testing.T) {
+	m := loadMap(t, levelFS(t, tmxLevelFiles(t)), "maps/level.tmx")
+	terrain, items := m.Tilesets[0], m.Tilesets[1]
+
+	tests := []struct {
+		gid     GID
+		tileset *Tileset
+		id      int
+	}{
+		{0, nil, 0},
+		{FlipHorizontal, nil, 0},
+		{1, terrain, 0},
+		{8, terrain, 7},
+		{FlipVertical | FlipDiagonal | 3, terrain, 2},
+		{9, items, 0},
+		{FlipHorizontal | 10, items, 1},
+		{FlipHorizontal | FlipVertical | FlipDiagonal | RotateHexagonal120 | 12, items, 3},
+		// Past the last tileset's tiles
+		{13, nil, 0},
+	}
+
+	for _, test := range tests {
+		ts, id := m.Tileset(test.gid)
+		if ts != test.tileset || id != test.id {
+			name := "nil"
+			if ts != nil {
+				name = ts.Name
+			}
+			t.Errorf("gid %#x is tile %d of %s", uint32(test.gid), id, name)
+		}
+	}
+
+	if r := terrain.TileBounds(5); r.Min.X != 16 || r.Min.Y != 16 || r.Dx() != 16 || r.Dy() != 16 {
+		t.Errorf("tile 5 bounds %v, want (16,16)-(32,32)", r)
+	}
+}
+
+func TestGIDFlags(t *testing.T) {
+	tests := []struct {
+		gid                  GID
+		id                   uint32
+		horizontal, vertical bool
+		diagonal             bool
+	}{
+		{0, 0, false, false, false},
+		{7, 7, false, false, false},
+		{0x80000007, 7, true, false, false},
+		{0x40000007, 7, false, true, false},
+		{0x20000007, 7, false, false, true},
+		{0xe0000007, 7, true, true, true},
+		// Hexagonal rotation isn't part of the id either
+		{0x10000007, 7, false, false, false},
+		{0x0fffffff, 0x0fffffff, false, false, false},
+	}
+
+	for _, test := range tests {
+		if test.gid.ID() != test.id || test.gid.Has(FlipHorizontal) != test.horizontal ||
+			test.gid.Has(FlipVertical) != test.vertical || test.gid.Has(FlipDiagonal) != test.diagonal {
+			t.Errorf("gid %#x is id %d flips h %v v %v d %v", uint32(test.gid), test.gid.ID(),
+				test.gid.Has(FlipHorizontal), test.gid.Has(FlipVertical), test.gid.Has(FlipDiagonal))
+		}
+	}
+}
@@ -0,0 +1,339 @@
+package tiled
+
+import (
+	"encoding/xml"
+	"fmt"
+	"path"
+	"strconv"
+)
+
+type tmxProperty struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+	// Multi-line strings are the element's text instead
+	Text string `xml:",chardata"`
+}
+
+type tmxMap struct {
+	Orientation string           `xml:"orientation,attr"`
+	RenderOrder string           `xml:"renderorder,attr"`
+	Width       int              `xml:"width,attr"`
+	Height      int              `xml:"height,attr"`
+	TileWidth   int              `xml:"tilewidth,attr"`
+	TileHeight  int              `xml:"tileheight,attr"`
+	Infinite    int              `xml:"infinite,attr"`
+	Properties  []tmxProperty    `xml:"properties>property"`
+	Tilesets    []tmxTilesetNode `xml:"tileset"`
+	// Layers, object groups, image layers and groups in document order
+	Layers []tmxLayer `xml:",any"`
+}
+
+type tmxTilesetNode struct {
+	FirstGID uint32 `xml:"firstgid,attr"`
+	Source   string `xml:"source,attr"`
+	tmxTileset
+}
+
+type tmxTileset struct {
+	Name       string `xml:"name,attr"`
+	TileWidth  int    `xml:"tilewidth,attr"`
+	TileHeight int    `xml:"tileheight,attr"`
+	Spacing    int    `xml:"spacing,attr"`
+	Margin     int    `xml:"margin,attr"`
+	TileCount  int    `xml:"tilecount,attr"`
+	Columns    int    `xml:"columns,attr"`
+	TileOffset struct {
+		X int `xml:"x,attr"`
+		Y int `xml:"y,attr"`
+	} `xml:"tileoffset"`
+	Image struct {
+		Source string `xml:"source,attr"`
+		Width  int    `xml:"width,attr"`
+		Height int    `xml:"height,attr"`
+	} `xml:"image"`
+	Tiles []struct {
+		ID         int           `xml:"id,attr"`
+		Properties []tmxProperty `xml:"properties>property"`
+	} `xml:"tile"`
+}
+
+type tmxLayer struct {
+	XMLName    xml.Name
+	Name       string        `xml:"name,attr"`
+	Visible    string        `xml:"visible,attr"`
+	Opacity    string        `xml:"opacity,attr"`
+	OffsetX    float32       `xml:"offsetx,attr"`
+	OffsetY    float32       `xml:"offsety,attr"`
+	Width      int           `xml:"width,attr"`
+	Height     int           `xml:"height,attr"`
+	Properties []tmxProperty `xml:"properties>property"`
+	Data       *tmxData      `xml:"data"`
+	Objects    []tmxObject   `xml:"object"`
+	// A group's layers
+	Layers []tmxLayer `xml:",any"`
+}
+
+type tmxData struct {
+	Encoding    string     `xml:"encoding,attr"`
+	Compression string     `xml:"compression,attr"`
+	Text        string     `xml:",chardata"`
+	Tiles       []tmxTile  `xml:"tile"`
+	Chunks      []tmxChunk `xml:"chunk"`
+}
+
+// tmxTile is a tile of the XML encoding, deprecated but still written.
+type tmxTile struct {
+	GID uint32 `xml:"gid,attr"`
+}
+
+type tmxChunk struct {
+	X      int       `xml:"x,attr"`
+	Y      int       `xml:"y,attr"`
+	Width  int       `xml:"width,attr"`
+	Height int       `xml:"height,attr"`
+	Text   string    `xml:",chardata"`
+	Tiles  []tmxTile `xml:"tile"`
+}
+
+type tmxObject struct {
+	ID       int       `xml:"id,attr"`
+	Name     string    `xml:"name,attr"`
+	Type     string    `xml:"type,attr"`
+	Class    string    `xml:"class,attr"`
+	X        float32   `xml:"x,attr"`
+	Y        float32   `xml:"y,attr"`
+	Width    float32   `xml:"width,attr"`
+	Height   float32   `xml:"height,attr"`
+	Rotation float32   `xml:"rotation,attr"`
+	GID      uint32    `xml:"gid,attr"`
+	Visible  string    `xml:"visible,attr"`
+	Ellipse  *struct{} `xml:"ellipse"`
+	Point    *struct{} `xml:"point"`
+	Polygon  *struct {
+		Points string `xml:"points,attr"`
+	} `xml:"polygon"`
+	Polyline *struct {
+		Points string `xml:"points,attr"`
+	} `xml:"polyline"`
+	Text       *struct{}     `xml:"text"`
+	Properties []tmxProperty `xml:"properties>property"`
+}
+
+func (m *Map) parseTMX(data []byte) error {
+	var x tmxMap
+	if err := xml.Unmarshal(data, &x); err != nil {
+		return err
+	}
+
+	m.Orientation = x.Orientation
+	m.RenderOrder = x.RenderOrder
+	m.Width, m.Height = x.Width, x.Height
+	m.TileWidth, m.TileHeight = x.TileWidth, x.TileHeight
+	m.Infinite = x.Infinite != 0
+	m.Properties = tmxProperties(x.Properties)
+
+	for _, node := range x.Tilesets {
+		if node.Source != "" {
+			ts, err := m.loadTileset(node.FirstGID, m.resolve(node.Source))
+			if err != nil {
+				return err
+			}
+			m.Tilesets = append(m.Tilesets, ts)
+			continue
+		}
+		m.Tilesets = append(m.Tilesets, node.tileset(node.FirstGID, path.Dir(m.name)))
+	}
+	sortTilesets(m.Tilesets)
+
+	return m.addTMXLayers(x.Layers, rootGroup)
+}
+
+// parseTSX parses an external TMX tileset, dir is the tileset's directory.
+func parseTSX(data []byte, firstGID uint32, dir string) (*Tileset, error) {
+	var x tmxTileset
+	if err := xml.Unmarshal(data, &x); err != nil {
+		return nil, err
+	}
+	return x.tileset(firstGID, dir), nil
+}
+
+func (x *tmxTileset) tileset(firstGID uint32, dir string) *Tileset {
+	ts := &Tileset{
+		FirstGID:       firstGID,
+		Name:           x.Name,
+		TileWidth:      x.TileWidth,
+		TileHeight:     x.TileHeight,
+		Spacing:        x.Spacing,
+		Margin:         x.Margin,
+		TileCount:      x.TileCount,
+		Columns:        x.Columns,
+		OffsetX:        x.TileOffset.X,
+		OffsetY:        x.TileOffset.Y,
+		ImageWidth:     x.Image.Width,
+		ImageHeight:    x.Image.Height,
+		TileProperties: map[int]Properties{},
+	}
+	if x.Image.Source != "" {
+		ts.Image = path.Join(dir, x.Image.Source)
+	}
+	for _, t := range x.Tiles {
+		if len(t.Properties) > 0 {
+			ts.TileProperties[t.ID] = tmxProperties(t.Properties)
+		}
+	}
+	return ts
+}
+
+func (m *Map) addTMXLayers(nodes []tmxLayer, g group) error {
+	for i := range nodes {
+		x := &nodes[i]
+		visible := x.Visible != "0"
+		opacity, err := parseOpacity(x.Opacity)
+		if err != nil {
+			return fmt.Errorf("layer %q: %v", x.Name, err)
+		}
+
+		l := &Layer{
+			Name:       x.Name,
+			Visible:    visible,
+			Opacity:    opacity,
+			OffsetX:    x.OffsetX,
+			OffsetY:    x.OffsetY,
+			Properties: tmxProperties(x.Properties),
+		}
+
+		switch x.XMLName.Local {
+		case "layer":
+			l.Kind = TileLayer
+			if err := x.tiles(l); err != nil {
+				return fmt.Errorf("layer %q: %v", x.Name, err)
+			}
+		case "objectgroup":
+			l.Kind = ObjectLayer
+			for _, o := range x.Objects {
+				obj, err := o.object()
+				if err != nil {
+					return fmt.Errorf("layer %q: object %d: %v", x.Name, o.ID, err)
+				}
+				l.Objects = append(l.Objects, obj)
+			}
+		case "imagelayer":
+			l.Kind = ImageLayer
+		case "group":
+			if err := m.addTMXLayers(x.Layers, g.child(x.Name, visible, opacity, x.OffsetX, x.OffsetY)); err != nil {
+				return err
+			}
+			continue
+		default:
+			// Editor settings and the like
+			continue
+		}
+
+		g.apply(l)
+		m.Layers = append(m.Layers, l)
+	}
+	return nil
+}
+
+// tiles decodes a tile layer's data, from chunks for infinite maps.
+func (x *tmxLayer) tiles(l *Layer) error {
+	l.Width, l.Height = x.Width, x.Height
+	if x.Data == nil {
+		l.Tiles = make([]GID, l.Width*l.Height)
+		return nil
+	}
+	d := x.Data
+
+	if len(d.Chunks) > 0 {
+		chunks := make([]chunk, len(d.Chunks))
+		for i, c := range d.Chunks {
+			tiles, err := tmxTiles(d.Encoding, d.Compression, c.Text, c.Tiles)
+			if err != nil {
+				return err
+			}
+			chunks[i] = chunk{x: c.X, y: c.Y, width: c.Width, height: c.Height, tiles: tiles}
+		}
+		return l.setTiles(chunks)
+	}
+
+	tiles, err := tmxTiles(d.Encoding, d.Compression, d.Text, d.Tiles)
+	if err != nil {
+		return err
+	}
+	l.Tiles = tiles
+	return l.checkTiles()
+}
+
+// tmxTiles decodes text, or the tile elements without an encoding.
+func tmxTiles(encoding, compression, text string, elements []tmxTile) ([]GID, error) {
+	if encoding == "" {
+		tiles := make([]GID, len(elements))
+		for i, t := range elements {
+			tiles[i] = GID(t.GID)
+		}
+		return tiles, nil
+	}
+	return decodeData(text, encoding, compression)
+}
+
+func (x *tmxObject) object() (*Object, error) {
+	o := &Object{
+		ID:         x.ID,
+		Name:       x.Name,
+		Type:       x.Type,
+		X:          x.X,
+		Y:          x.Y,
+		Width:      x.Width,
+		Height:     x.Height,
+		Rotation:   x.Rotation,
+		GID:        GID(x.GID),
+		Visible:    x.Visible != "0",
+		Properties: tmxProperties(x.Properties),
+	}
+	if o.Type == "" {
+		o.Type = x.Class
+	}
+
+	var err error
+	switch {
+	case x.Ellipse != nil:
+		o.Shape = Ellipse
+	case x.Point != nil:
+		o.Shape = Point
+	case x.Polygon != nil:
+		o.Shape = Polygon
+		o.Points, err = parsePoints(x.Polygon.Points)
+	case x.Polyline != nil:
+		o.Shape = Polyline
+		o.Points, err = parsePoints(x.Polyline.Points)
+	case x.Text != nil:
+		o.Shape = Text
+	}
+	return o, err
+}
+
+func tmxProperties(properties []tmxProperty) Properties {
+	if len(properties) == 0 {
+		return nil
+	}
+	p := Properties{}
+	for _, x := range properties {
+		if x.Value == "" {
+			p[x.Name] = x.Text
+		} else {
+			p[x.Name] = x.Value
+		}
+	}
+	return p
+}
+
+func parseOpacity(text string) (float32, error) {
+	if text == "" {
+		return 1.0, nil
+	}
+	v, err := strconv.ParseFloat(text, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid opacity %q", text)
+	}
+	return float32(v), nil
+}
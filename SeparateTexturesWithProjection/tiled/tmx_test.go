@@ -0,0 +1,168 @@
+package tiled
+
+import (
+	"fmt"
+	"testing"
+)
+
+const tmxTerrain = `<?xml version="1.0" encoding="UTF-8"?>
+<tileset version="1.10" name="terrain" tilewidth="16" tileheight="16" tilecount="8" columns="4">
+ <image source="../images/terrain.png" width="64" height="32"/>
+ <tile id="3">
+  <properties>
+   <property name="solid" type="bool" value="true"/>
+  </properties>
+ </tile>
+</tileset>
+`
+
+// tmxLevelFiles returns the level fixture as TMX, see checkLevel.
+func tmxLevelFiles(t *testing.T) map[string]string {
+	level := fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<map version="1.10" orientation="orthogonal" renderorder="right-down" width="3" height="2" tilewidth="16" tileheight="16" infinite="0">
+ <editorsettings>
+  <export target="level.json" format="json"/>
+ </editorsettings>
+ <properties>
+  <property name="music" value="calm.ogg"/>
+ </properties>
+ <tileset firstgid="9" name="items" tilewidth="16" tileheight="16" tilecount="4" columns="2">
+  <image source="items.png" width="32" height="32"/>
+ </tileset>
+ <tileset firstgid="1" source="tiles/terrain.tsx"/>
+ <layer id="1" name="ground" width="3" height="2">
+  <data encoding="csv">
+1,2,3,
+4,5,6
+</data>
+ </layer>
+ <group id="2" name="details" opacity="0.5" offsetx="4">
+  <group id="3" name="inner" visible="0">
+   <layer id="4" name="deep" width="3" height="2">
+    <data encoding="base64" compression="gzip">
+     %s
+    </data>
+   </layer>
+  </group>
+  <layer id="5" name="flowers" width="3" height="2" opacity="0.5" offsety="2">
+   <data encoding="base64" compression="zlib">%s</data>
+  </layer>
+ </group>
+ <layer id="6" name="raw" width="3" height="2">
+  <data encoding="base64">%s</data>
+ </layer>
+ <objectgroup id="7" name="objects">
+  <object id="1" name="spawn" type="start" x="8" y="16">
+   <properties>
+    <property name="team" value="red"/>
+   </properties>
+   <point/>
+  </object>
+  <object id="2" class="zone" x="0" y="0" width="10" height="5">
+   <ellipse/>
+  </object>
+  <object id="3" x="1" y="2">
+   <polygon points="0,0 4,0 4,3"/>
+  </object>
+  <object id="4" gid="%d" x="16" y="32" width="16" height="16" rotation="90"/>
+  <object id="5" x="0" y="0" width="1" height="1" visible="0"/>
+ </objectgroup>
+</map>
+`, encodeGIDs(t, []GID{9, 0, 0, 0, 0, 10}, "gzip"), encodeGIDs(t, flowersTiles, "zlib"), encodeGIDs(t, rawTiles, ""), FlipHorizontal|10)
+
+	return map[string]string{
+		"maps/level.tmx":         level,
+		"maps/tiles/terrain.tsx": tmxTerrain,
+	}
+}
+
+func TestLoadTMX(t *testing.T) {
+	checkLevel(t, loadMap(t, levelFS(t, tmxLevelFiles(t)), "maps/level.tmx"))
+}
+
+func TestLoadTMXTileElements(t *testing.T) {
+	files := map[string]string{
+		"maps/tiles.tmx": `<map orientation="orthogonal" width="2" height="1" tilewidth="16" tileheight="16">
+ <tileset firstgid="1" source="tiles/terrain.tsx"/>
+ <layer name="old" width="2" height="1">
+  <data>
+   <tile gid="2147483649"/>
+   <tile/>
+  </data>
+ </layer>
+ <layer name="empty" width="2" height="1"/>
+</map>`,
+		"maps/tiles/terrain.tsx": tmxTerrain,
+	}
+
+	m := loadMap(t, levelFS(t, files), "maps/tiles.tmx")
+	if tiles := m.Layer("old").Tiles; !equalGIDs(tiles, []GID{FlipHorizontal | 1, 0}) {
+		t.Errorf("tile elements decoded as %v", tiles)
+	}
+	if tiles := m.Layer("empty").Tiles; !equalGIDs(tiles, []GID{0, 0}) {
+		t.Errorf("layer without data has tiles %v", tiles)
+	}
+}
+
+func TestLoadTMXInfinite(t *testing.T) {
+	for _, encoding := range []string{"csv", "gzip"} {
+		t.Run(encoding, func(t *testing.T) {
+			data := `<data encoding="csv">
+   <chunk x="2" y="0" width="2" height="2">5,6,
+7,8</chunk>
+   <chunk x="-2" y="-2" width="2" height="2">1,2,
+3,2147483652</chunk>
+  </data>`
+			if encoding == "gzip" {
+				data = fmt.Sprintf(`<data encoding="base64" compression="gzip">
+   <chunk x="2" y="0" width="2" height="2">%s</chunk>
+   <chunk x="-2" y="-2" width="2" height="2">%s</chunk>
+  </data>`, encodeGIDs(t, []GID{5, 6, 7, 8}, "gzip"), encodeGIDs(t, []GID{1, 2, 3, FlipHorizontal | 4}, "gzip"))
+			}
+
+			files := map[string]string{
+				"maps/endless.tmx": `<map orientation="orthogonal" width="10" height="10" tilewidth="16" tileheight="16" infinite="1">
+ <tileset firstgid="1" source="tiles/terrain.tsx"/>
+ <layer name="ground" width="10" height="10">
+  ` + data + `
+ </layer>
+</map>`,
+				"maps/tiles/terrain.tsx": tmxTerrain,
+			}
+			checkInfinite(t, loadMap(t, levelFS(t, files), "maps/endless.tmx"))
+		})
+	}
+}
+
+func TestLoadTMXErrors(t *testing.T) {
+	layer := func(data string) string {
+		return `<map orientation="orthogonal" width="2" height="1" tilewidth="16" tileheight="16">
+ <tileset firstgid="1" source="tiles/terrain.tsx"/>
+ <layer name="ground" width="2" height="1">` + data + `</layer>
+</map>`
+	}
+
+	tests := []struct {
+		name  string
+		files map[string]string
+	}{
+		{"missing map", map[string]string{}},
+		{"broken xml", map[string]string{"maps/bad.tmx": "<map"}},
+		{"missing tileset", map[string]string{"maps/bad.tmx": layer(`<data encoding="csv">1,2</data>`)}},
+		{"broken tileset", map[string]string{"maps/bad.tmx": layer(`<data encoding="csv">1,2</data>`), "maps/tiles/terrain.tsx": "<tileset"}},
+		{"missing image", map[string]string{"maps/bad.tmx": layer(`<data encoding="csv">1,2</data>`), "maps/tiles/terrain.tsx": `<tileset name="t" tilewidth="16" tileheight="16"><image source="nope.png"/></tileset>`}},
+		{"image collection", map[string]string{"maps/bad.tmx": layer(`<data encoding="csv">1,2</data>`), "maps/tiles/terrain.tsx": `<tileset name="t" tilewidth="16" tileheight="16"/>`}},
+		{"short layer", map[string]string{"maps/bad.tmx": layer(`<data encoding="csv">1</data>`), "maps/tiles/terrain.tsx": tmxTerrain}},
+		{"unknown compression", map[string]string{"maps/bad.tmx": layer(`<data encoding="base64" compression="zstd">AAAA</data>`), "maps/tiles/terrain.tsx": tmxTerrain}},
+		{"bad opacity", map[string]string{"maps/bad.tmx": `<map><layer name="l" opacity="half"/></map>`}},
+		{"bad points", map[string]string{"maps/bad.tmx": `<map><objectgroup name="o"><object id="1"><polygon points="0,0 1"/></object></objectgroup></map>`}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if err := NewMap(levelFS(t, test.files), "maps/bad.tmx").Load(); err == nil {
+				t.Error("no error")
+			}
+		})
+	}
+}